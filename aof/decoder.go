@@ -28,13 +28,13 @@ func NewDecoder(filename string) *Decoder {
 
 type CmdOutput struct {
 	Argc int
-	Argv []string
+	Argv [][]byte
 }
 
 func (decoder *Decoder) DecodeAppendOnlyFile() (*CmdOutput, error) {
 	out := &CmdOutput{
 		Argc: 0,
-		Argv: make([]string, 0),
+		Argv: make([][]byte, 0),
 	}
 	argc, err := decoder.readMultiBulkLength()
 	if err != nil {
@@ -49,7 +49,7 @@ func (decoder *Decoder) DecodeAppendOnlyFile() (*CmdOutput, error) {
 		if err != nil {
 			return nil, err
 		}
-		out.Argv = append(out.Argv, string(argv))
+		out.Argv = append(out.Argv, argv)
 	}
 	out.Argc = argc
 	return out, nil
@@ -33,6 +33,15 @@ func (encoder *Encoder) Write(buf []byte) (int, error) {
 	return encoder.f.Write(buf)
 }
 
+// 把写入的数据刷到磁盘上
+func (encoder *Encoder) Sync() error {
+	return encoder.f.Sync()
+}
+
+func (encoder *Encoder) Close() error {
+	return encoder.f.Close()
+}
+
 func (encoder *Encoder) rewriteStringObject(obj database.TBase) {
 	if obj.GetObjectType() != encodings.RedisTypeString {
 		loggers.Errorf("obj type is not string %s", obj.GetObjectType())
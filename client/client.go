@@ -2,15 +2,46 @@ package client
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/SwanSpouse/redis_go/conf"
 	"github.com/SwanSpouse/redis_go/database"
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/loggers"
 	"github.com/SwanSpouse/redis_go/raw_type"
 	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/* Client flags */
+const (
+	RedisClientSlave   = 1 << 0 /* This client is a slave server */
+	RedisClientMaster  = 1 << 1 /* This client is a master server */
+	RedisClientMonitor = 1 << 2 /* This client is a slave monitor, see MONITOR */
+
+	RedisClientCloseAfterReply = 1 << 3 /* Close after writing entire reply. */
+	RedisClientReplyOff        = 1 << 4 /* Don't send replies to client. */
+	RedisClientReplySkipNext   = 1 << 5 /* Set RedisClientReplySkip for next cmd */
+	RedisClientReplySkip       = 1 << 6 /* Don't send just this reply. */
+	RedisClientNoEvict         = 1 << 7 /* This client is protected against client memory eviction. */
+
+	RedisClientTracking        = 1 << 9  /* Client enabled keys tracking in order to perform client side caching. */
+	RedisClientTrackingBcast   = 1 << 11 /* Tracking in BCAST mode. */
+	RedisClientTrackingOptIn   = 1 << 12 /* Tracking in opt-in mode. */
+	RedisClientTrackingOptOut  = 1 << 13 /* Tracking in opt-out mode. */
+	RedisClientTrackingCaching = 1 << 14 /* CACHING yes/no was given, depending on optin/optout mode. */
+	RedisClientTrackingNoLoop  = 1 << 15 /* Don't send invalidation messages about writes performed by myself.*/
+	RedisClientBlocked         = 1 << 16 /* The client is waiting in a blocking operation */
+)
+
+const (
+	RedisMBulkBigArg      = 32 * 1024   // 超过这个大小的参数单独分配内存
+	RedisArgBufferMaxSize = 1024 * 1024 // 参数缓冲区超过这个大小之后不再复用
 )
 
 var clientPool = &sync.Pool{
@@ -22,38 +53,88 @@ var clientPool = &sync.Pool{
 type Client struct {
 	id             int64              // Client ID
 	Name           string             // client name
+	Flags          int                // client flags: RedisClientSlave | RedisClientMonitor | ...
 	cn             net.Conn           // TCP connection
 	db             *database.Database // chosen database
 	Closed         bool               // isClientClosed
 	reader         *tcp.BufIoReader   // request reader
 	writer         *tcp.BufIoWriter   // response writer
-	Argv           []string           // arguments vector
+	writeLock      sync.Mutex         // 保护writer, MONITOR等其他goroutine也会向客户端写回复
+	Argv           [][]byte           // arguments vector, 只在处理当前请求期间有效
 	Argc           int                // arguments counter
+	argBuf         []byte             // 复用的参数缓冲区, 保存一个请求中所有较小参数的内容
+	argEnds        []int              // 每个参数在argBuf中的结束位置, 单独分配内存的参数为-1
+	blockedReadErr error              // 阻塞期间读取连接出现的错误, 解除阻塞之后由ProcessInputBuffer返回
 	Cmd            *Command           // current command
 	LastCmd        *Command           // last command
 	Dirty          int64
+	BlockedTime    time.Duration  // 当前命令阻塞等待的时间, 不计入命令的执行时间
 	PubSubChannels *raw_type.Dict /* channels a client is interested in (SUBSCRIBE) */
 	PubSubPatterns *raw_type.List /* patterns a client is interested in (SUBSCRIBE) */
+	TrackRedirect  int64          // CLIENT TRACKING REDIRECT的客户端ID, 0表示发送给自己
+	TrackPrefixes  []string       // CLIENT TRACKING BCAST模式下关注的key前缀
+	brokenRedir    int32          // REDIRECT的客户端已经关闭, 发送失效消息的其他goroutine会设置, 需要原子操作
 	execTimeout    time.Time
-	idleTimeout    time.Time // timeout
+	idleTimeout    time.Time        // timeout
+	ErrorReplies   int64            // number of error replies sent to client
+	errorReplyHook func(msg string) // called for each error reply, used by server error stats
+	ctime          time.Time        // client creation time
+	lastActive     time.Time        // time of the last interaction, used for idle time
+	obufSoftSince  time.Time        // time when the output buffer soft limit was reached, protected by writeLock
+	obufClass      int32            // client-output-buffer-limit中的类型, 小于0表示不限制
+	obufLimitHook  func(class int) conf.ClientBufferLimit
+	obufCloseHook  func()        // 输出缓冲区超过限制, 客户端被关闭的时候调用
+	closeAsap      int32         // 输出缓冲区超过了限制, 客户端即将被关闭, 其他goroutine也会设置, 需要原子操作
+	pushNotify     chan struct{} // 其他goroutine写入了推送消息, 通知pushLoop发送
+	pushState      int32         // pushLoop的状态: 0 没有启动, 1 已经启动, 2 客户端已经关闭
+	pushDone       chan struct{} // pushLoop退出之后关闭
+	closing        chan struct{} // 客户端关闭的时候关闭, 通知pushLoop退出
 }
 
+const (
+	pushLoopIdle = iota
+	pushLoopRunning
+	pushLoopClosed
+)
+
 func (c *Client) reset(clientId int64, cn net.Conn, defaultDB *database.Database) {
 	c.id = clientId
+	c.Name = ""
+	c.Flags = 0
 	c.cn = cn
 	c.db = defaultDB
 	c.Closed = false
 	c.reader = tcp.NewBufIoReader(cn)
 	c.writer = tcp.NewBufIoWriter(cn)
-	c.Argv = nil
+	c.Argv = c.Argv[:0]
 	c.Argc = 0
+	c.argBuf = c.argBuf[:0]
+	c.argEnds = c.argEnds[:0]
+	c.blockedReadErr = nil
 	c.Cmd = nil
 	c.LastCmd = nil
 	c.Dirty = 0
+	c.BlockedTime = 0
 	c.PubSubChannels = raw_type.NewDict()
 	c.PubSubPatterns = raw_type.ListCreate()
+	c.TrackRedirect = 0
+	c.TrackPrefixes = nil
+	c.brokenRedir = 0
 	c.execTimeout = time.Time{}
 	c.idleTimeout = time.Time{}
+	c.ErrorReplies = 0
+	c.errorReplyHook = nil
+	c.ctime = time.Now()
+	c.lastActive = c.ctime
+	c.obufSoftSince = time.Time{}
+	c.obufClass = -1
+	c.obufLimitHook = nil
+	c.obufCloseHook = nil
+	c.closeAsap = 0
+	c.pushNotify = make(chan struct{}, 1)
+	c.pushState = pushLoopIdle
+	c.pushDone = make(chan struct{})
+	c.closing = make(chan struct{})
 }
 
 func (c *Client) release() {
@@ -96,6 +177,44 @@ func (c *Client) RemoteAddr() net.Addr {
 	return c.cn.RemoteAddr()
 }
 
+// return the local address of the connection
+func (c *Client) LocalAddr() net.Addr {
+	if c.IsFakeClient() {
+		return nil
+	}
+	return c.cn.LocalAddr()
+}
+
+// 连接对应的文件描述符, 获取不到的时候返回-1
+func (c *Client) FD() int {
+	fd := -1
+	if c.IsFakeClient() {
+		return fd
+	}
+	if sc, ok := c.cn.(syscall.Conn); ok {
+		if raw, err := sc.SyscallConn(); err == nil {
+			raw.Control(func(s uintptr) {
+				fd = int(s)
+			})
+		}
+	}
+	return fd
+}
+
+// 客户端创建的时间
+func (c *Client) CreateTime() time.Time {
+	return c.ctime
+}
+
+// 客户端最后一次发送命令的时间
+func (c *Client) LastInteraction() time.Time {
+	return c.lastActive
+}
+
+func (c *Client) UpdateLastInteraction() {
+	c.lastActive = time.Now()
+}
+
 func (c *Client) SetDatabase(db *database.Database) {
 	c.db = db
 }
@@ -111,11 +230,98 @@ func (c *Client) Buffered() int {
 	return c.reader.Buffered()
 }
 
+// 输入缓冲区中剩余的空间
+func (c *Client) QueryBufFree() int {
+	if c.IsFakeClient() {
+		return 0
+	}
+	return c.reader.Free()
+}
+
+// 设置请求中bulk的最大长度以及输入缓冲区的最大长度, 0表示没有限制
+func (c *Client) SetQueryBufferLimits(maxBulkLen, maxQueryBufLen int64) {
+	if c.IsFakeClient() {
+		return
+	}
+	c.reader.SetLimits(maxBulkLen, maxQueryBufLen)
+}
+
+// 输出缓冲区中还没有发送给客户端的数据长度
+func (c *Client) OutputBuffered() int {
+	if c.IsFakeClient() {
+		return 0
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.writer.Buffered()
+}
+
+// 客户端使用的RESP协议版本, 默认是RESP2, 可以通过HELLO命令切换
+func (c *Client) Protocol() int {
+	if c.IsFakeClient() {
+		return tcp.RespProto2
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.writer.Protocol()
+}
+
+func (c *Client) SetProtocol(proto int) {
+	if c.IsFakeClient() {
+		return
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.writer.SetProtocol(proto)
+}
+
 func (c *Client) Close() {
 	c.Closed = true
+	c.stopPushLoop()
 	c.release()
 }
 
+// 只关闭客户端的连接, 客户端的IOLoop读取失败之后会自己退出并释放客户端
+func (c *Client) CloseConn() {
+	if c.IsFakeClient() {
+		return
+	}
+	c.cn.Close()
+}
+
+/**
+客户端阻塞期间IOLoop不会读取请求, 在单独的goroutine中继续读取连接上的数据, 及时发现客户端断开了连接。
+读取到的数据保留在输入缓冲区中, 解除阻塞之后再处理。连接被关闭或者读取出错的时候调用onClose。
+读取的错误(例如输入缓冲区超过了限制)保存下来, 解除阻塞之后由ProcessInputBuffer返回, IOLoop和平时一样处理之后关闭客户端。
+返回的函数用来停止读取, 它返回之后IOLoop才能继续使用输入缓冲区。
+*/
+func (c *Client) WatchConnWhileBlocked(onClose func()) func() {
+	if c.IsFakeClient() {
+		return func() {}
+	}
+	var stopped int32
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			if err := c.reader.Fill(); err != nil {
+				// 停止读取时设置的deadline导致的错误不是连接关闭
+				if atomic.LoadInt32(&stopped) == 0 {
+					c.blockedReadErr = err
+					onClose()
+				}
+				return
+			}
+		}
+	}()
+	return func() {
+		atomic.StoreInt32(&stopped, 1)
+		c.cn.SetReadDeadline(time.Now())
+		<-exited
+		c.cn.SetReadDeadline(time.Time{})
+	}
+}
+
 func (c *Client) SetIdleTimeout(duration time.Duration) {
 	c.idleTimeout = time.Now().Add(duration)
 }
@@ -153,40 +359,102 @@ command format:
 	3. integer replay   : :1\r\n
 	4. bulk reply       : $4\r\nPING\r\n
 	5. multi bulk reply : *3\r\n$3\r\nSET\r\n$5\r\nMyKey\r\n$7\r\nMyValue\r\n
+和redis一样, 不是以*开头的请求都按照inline command处理, 例如: SET MyKey "My Value"\r\n
 */
 func (c *Client) ProcessInputBuffer() error {
+	if c.blockedReadErr != nil {
+		return c.blockedReadErr
+	}
 	// read one line from buffer
 	line, err := c.reader.PeekLine(0)
 	if err != nil || len(line) == 0 {
 		return err
 	}
-	c.Argv = make([]string, 0)
+	c.Argv = c.Argv[:0]
 	c.Argc = 0
 	switch line[0] {
-	case '+', '-', ':':
-		c.Argv = append(c.Argv, line.FirstWord())
-		c.Argc += 1
-	case '$':
-		cmdName, err := c.reader.ReadBulkString()
-		if err != nil || cmdName == "" {
+	default:
+		if err := c.processInlineBuffer(); err != nil {
 			return err
 		}
-		c.Argv = append(c.Argv, cmdName)
 	case '*':
 		arrayLen, err := c.reader.ReadArrayLen()
 		if err != nil || arrayLen == 0 {
 			return err
 		}
-		for i := 0; i < arrayLen; i++ {
-			arg, err := c.reader.ReadBulkString()
-			if err != nil || arg == "" {
-				return err
-			}
-			c.Argv = append(c.Argv, arg)
+		if arrayLen > tcp.MaxMultiBulkLen {
+			return re.ErrInvalidMultiBulkLength
+		}
+		if err := c.processMultiBulkBuffer(arrayLen); err != nil {
+			return err
 		}
 	}
 	c.Argc = len(c.Argv)
-	loggers.Info("server receive:%d args, argv:%+v", c.Argc, c.Argv)
+	loggers.Info("server receive:%d args, argv:%q", c.Argc, c.Argv)
+	return nil
+}
+
+/**
+读取multi bulk请求中的所有参数, 参数可以是空字符串或者任意的二进制数据。
+较小的参数追加到复用的argBuf中, Argv直接引用argBuf, 处理请求的过程中不需要为参数分配内存;
+超过RedisMBulkBigArg的参数单独分配内存, 避免argBuf过大。
+Argv中的数据在读取下一个请求的时候会被覆盖, 需要保存下来的参数(例如写入数据库的key和value)必须先复制一份。
+*/
+func (c *Client) processMultiBulkBuffer(arrayLen int) error {
+	c.argBuf = c.argBuf[:0]
+	c.argEnds = c.argEnds[:0]
+	for i := 0; i < arrayLen; i++ {
+		sz, err := c.reader.ReadBulkLen()
+		if err != nil {
+			return err
+		}
+		body, err := c.reader.ReadBulkBody(sz)
+		if err != nil {
+			return err
+		}
+		if sz >= RedisMBulkBigArg {
+			c.Argv = append(c.Argv, append([]byte(nil), body...))
+			c.argEnds = append(c.argEnds, -1)
+			continue
+		}
+		c.Argv = append(c.Argv, nil)
+		c.argBuf = append(c.argBuf, body...)
+		c.argEnds = append(c.argEnds, len(c.argBuf))
+	}
+	// argBuf在追加的过程中可能重新分配内存, 所有参数读取完成之后再切分
+	start := 0
+	for i, end := range c.argEnds {
+		if end < 0 {
+			continue
+		}
+		c.Argv[i] = c.argBuf[start:end:end]
+		start = end
+	}
+	if cap(c.argBuf) > RedisArgBufferMaxSize {
+		// 不再复用过大的缓冲区, 当前请求的Argv仍然引用原来的内存
+		c.argBuf = nil
+	}
+	return nil
+}
+
+// 和redis的processInlineBuffer一样, 按照空白字符拆分参数, 支持引号以及转义字符
+func (c *Client) processInlineBuffer() error {
+	line, err := c.reader.ReadLine()
+	if err != nil {
+		return err
+	}
+	// 去掉结尾的\n以及\r\n
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	args, ok := util.SplitArgs(string(line))
+	if !ok {
+		return re.ErrUnbalancedQuotes
+	}
+	for _, arg := range args {
+		c.Argv = append(c.Argv, []byte(arg))
+	}
 	return nil
 }
 
@@ -206,7 +474,7 @@ func (c *Client) peekCmd(offset int) (string, error) {
 		return line.FirstWord(), nil
 	}
 
-	n, err := line.ParseSize('*', re.ErrInvalidMultiBulkLength)
+	n, err := line.ParseArrayLen()
 	if err != nil {
 		return "", err
 	}
@@ -230,31 +498,62 @@ func (c *Client) peekCmd(offset int) (string, error) {
 	return string(data), err
 }
 
+// CLIENT REPLY OFF|SKIP或者客户端即将被关闭的时候不发送回复
+func (c *Client) replySuppressed() bool {
+	return c.Flags&(RedisClientReplyOff|RedisClientReplySkip) != 0 || c.IsCloseAsap()
+}
+
+// 每条命令执行之后调用, CLIENT REPLY SKIP只会跳过下一条命令的回复
+func (c *Client) ResetReplySkip() {
+	c.Flags &^= RedisClientReplySkip
+	if c.Flags&RedisClientReplySkipNext != 0 {
+		c.Flags |= RedisClientReplySkip
+		c.Flags &^= RedisClientReplySkipNext
+	}
+}
+
 func (c *Client) Response(value interface{}) {
-	if c.IsFakeClient() {
+	if c.IsFakeClient() || c.replySuppressed() {
 		return
 	}
 	loggers.Debug("server response:%+v", value)
-	c.writer.Append(value)
+	c.appendReply(func(w *tcp.BufIoWriter) { w.Append(value) })
 }
 
 func (c *Client) ResponseOK() {
-	if c.IsFakeClient() {
+	if c.IsFakeClient() || c.replySuppressed() {
+		return
+	}
+	c.appendReply(func(w *tcp.BufIoWriter) { w.AppendOK() })
+}
+
+// 返回状态回复, 例如: +OK\r\n
+func (c *Client) ResponseStatus(status string) {
+	if c.IsFakeClient() || c.replySuppressed() {
 		return
 	}
-	c.writer.AppendOK()
+	c.appendReply(func(w *tcp.BufIoWriter) { w.AppendInlineString(status) })
 }
 
 func (c *Client) ResponseError(msg string, args ...interface{}) {
 	if c.IsFakeClient() {
 		return
 	}
-	if len(args) == 0 {
-		c.writer.AppendError(msg)
-	} else {
-		c.writer.AppendErrorf(msg, args...)
+	if len(args) != 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	if !c.replySuppressed() {
+		c.appendReply(func(w *tcp.BufIoWriter) { w.AppendError(msg) })
+	}
+	c.ErrorReplies += 1
+	if c.errorReplyHook != nil {
+		c.errorReplyHook(msg)
 	}
-	c.Flush()
+}
+
+// 设置发送错误回复时的回调，server用来统计各种错误的数量
+func (c *Client) SetErrorReplyHook(hook func(msg string)) {
+	c.errorReplyHook = hook
 }
 
 func (c *Client) ResponseReError(err error, args ...interface{}) {
@@ -276,8 +575,154 @@ func (c *Client) ResponseReError(err error, args ...interface{}) {
 }
 
 func (c *Client) Flush() error {
-	if c.IsFakeClient() {
+	if c.IsFakeClient() || c.IsCloseAsap() {
 		return nil
 	}
 	return c.writer.Flush()
 }
+
+/**
+其他goroutine向客户端推送的消息, 例如PUBLISH以及client side caching的失效消息。
+只写入客户端的输出缓冲区, 不会在调用者的goroutine中写客户端的连接,
+由客户端自己的pushLoop发送, 读取得很慢的客户端不会阻塞调用者。
+*/
+func (c *Client) PushResponse(value interface{}) {
+	if c.IsFakeClient() || c.IsCloseAsap() {
+		return
+	}
+	c.appendReply(func(w *tcp.BufIoWriter) { w.Append(value) })
+	c.startPushLoop()
+	select {
+	case c.pushNotify <- struct{}{}:
+	default:
+		// pushLoop还没有发送上一次通知之前的消息, 会一起发送
+	}
+}
+
+// 第一次收到推送消息的时候启动pushLoop
+func (c *Client) startPushLoop() {
+	if atomic.CompareAndSwapInt32(&c.pushState, pushLoopIdle, pushLoopRunning) {
+		go c.pushLoop()
+	}
+}
+
+func (c *Client) pushLoop() {
+	defer close(c.pushDone)
+	for {
+		select {
+		case <-c.pushNotify:
+			c.Flush()
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+// 客户端关闭的时候停止pushLoop, 等它退出之后才能释放输出缓冲区
+func (c *Client) stopPushLoop() {
+	if c.IsFakeClient() {
+		return
+	}
+	state := atomic.SwapInt32(&c.pushState, pushLoopClosed)
+	if state == pushLoopClosed {
+		return
+	}
+	close(c.closing)
+	if state == pushLoopRunning {
+		// 关闭连接避免pushLoop阻塞在写连接上
+		c.CloseConn()
+		<-c.pushDone
+	}
+}
+
+// 向输出缓冲区中写入回复, 写入之后检查输出缓冲区的限制, 一个很大的回复在超过hard limit之后就不再继续写入
+func (c *Client) appendReply(appendFn func(w *tcp.BufIoWriter)) {
+	var limit conf.ClientBufferLimit
+	if class := int(atomic.LoadInt32(&c.obufClass)); class >= 0 && c.obufLimitHook != nil {
+		limit = c.obufLimitHook(class)
+	}
+	c.writeLock.Lock()
+	if limit.HardLimitBytes <= 0 && limit.SoftLimitBytes <= 0 {
+		c.writer.SetMaxBuffered(0)
+		appendFn(c.writer)
+		c.writeLock.Unlock()
+		return
+	}
+	c.writer.SetMaxBuffered(int(limit.HardLimitBytes))
+	appendFn(c.writer)
+	reached := c.outputBufferLimitReached(limit, time.Now())
+	if reached {
+		c.writer.Discard()
+	}
+	c.writeLock.Unlock()
+	if reached && atomic.CompareAndSwapInt32(&c.closeAsap, 0, 1) {
+		// 丢弃缓冲区中的数据并关闭连接, IOLoop读取失败之后会释放客户端
+		if c.obufCloseHook != nil {
+			c.obufCloseHook()
+		}
+		c.CloseConn()
+	}
+}
+
+// 标记REDIRECT的客户端已经关闭, 只有第一次标记的时候返回true
+func (c *Client) MarkTrackingBrokenRedir() bool {
+	return atomic.CompareAndSwapInt32(&c.brokenRedir, 0, 1)
+}
+
+func (c *Client) IsTrackingBrokenRedir() bool {
+	return atomic.LoadInt32(&c.brokenRedir) != 0
+}
+
+// 重新开启或者关闭tracking的时候清除标记
+func (c *Client) ResetTrackingBrokenRedir() {
+	atomic.StoreInt32(&c.brokenRedir, 0)
+}
+
+// 输出缓冲区超过了限制, 客户端即将被关闭
+func (c *Client) IsCloseAsap() bool {
+	return atomic.LoadInt32(&c.closeAsap) != 0
+}
+
+/**
+设置输出缓冲区的限制:
+	limit根据客户端的类型返回对应的限制, 每次写入回复的时候调用, CONFIG SET修改之后马上生效;
+	closed在客户端因为超过限制被关闭的时候调用。
+*/
+func (c *Client) SetOutputBufferLimitHook(limit func(class int) conf.ClientBufferLimit, closed func()) {
+	c.obufLimitHook = limit
+	c.obufCloseHook = closed
+}
+
+// 设置客户端在client-output-buffer-limit中的类型, 只能由客户端自己的goroutine调用, 小于0表示不限制
+func (c *Client) SetOutputBufferClass(class int) {
+	atomic.StoreInt32(&c.obufClass, int32(class))
+}
+
+/**
+和redis的checkClientOutputBufferLimits一样, 下面两种情况返回true:
+	1. 输出缓冲区的大小超过了hard limit;
+	2. 输出缓冲区的大小超过了soft limit, 并且持续的时间超过了soft seconds。
+*/
+func (c *Client) CheckOutputBufferLimit(limit conf.ClientBufferLimit, now time.Time) bool {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.outputBufferLimitReached(limit, now)
+}
+
+// 调用的时候需要持有writeLock
+func (c *Client) outputBufferLimitReached(limit conf.ClientBufferLimit, now time.Time) bool {
+	used := int64(c.writer.Buffered())
+	hard := limit.HardLimitBytes > 0 && used >= limit.HardLimitBytes
+	soft := limit.SoftLimitBytes > 0 && used >= limit.SoftLimitBytes
+	if soft {
+		if c.obufSoftSince.IsZero() {
+			c.obufSoftSince = now
+			soft = false
+		} else if now.Sub(c.obufSoftSince) <= time.Duration(limit.SoftLimitSeconds)*time.Second {
+			soft = false
+		}
+	} else {
+		c.obufSoftSince = time.Time{}
+	}
+	return hard || soft
+}
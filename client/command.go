@@ -2,9 +2,14 @@ package client
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/util"
 )
 
 const (
@@ -22,16 +27,32 @@ const (
 	RedisCmdLoading          = 512  /* "l" flag */
 	RedisCmdStable           = 1024 /* "t" flag */
 	RedisCmdSkipMonitor      = 2048 /* "M" flag */
+
+	/* 命令延迟直方图的范围: 1纳秒到1秒, 精度为2位有效数字 */
+	LatencyHistogramMinValue  = 1
+	LatencyHistogramMaxValue  = 1000 * 1000 * 1000
+	LatencyHistogramPrecision = 2
 )
 
+// 根据命令的参数返回命令中所有的key, 返回的key是参数的拷贝, 可以在请求处理完之后继续使用
+type GetKeysProc func(argv [][]byte) []string
+
 type Command struct {
-	name        string        // command name
-	Arity       int           // command args
-	SFlags      string        //
-	Flags       int           //
-	microsecond int64         // execute time in microsecond
-	calls       int64         // call times
-	Proc        func(*Client) // 处理相应命令的方法
+	name             string          // command name
+	Arity            int             // command args
+	SFlags           string          //
+	Flags            int             //
+	firstKey         int             // 第一个key参数的位置, 0表示命令中没有key
+	lastKey          int             // 最后一个key参数的位置, 负数表示从后往前数, 例如-1表示最后一个参数
+	keyStep          int             // 相邻两个key参数之间的距离
+	getKeysProc      GetKeysProc     // key的位置不固定的命令, 例如BLMPOP, 通过这个方法获取key
+	microsecond      int64           // execute time in microsecond
+	calls            int64           // call times
+	rejectedCalls    int64           // 在执行之前就被拒绝的次数, 例如参数个数错误、OOM等
+	failedCalls      int64           // 执行过程中返回错误的次数
+	latencyLock      sync.Mutex      // 延迟直方图在第一次记录的时候才创建
+	latencyHistogram *util.Histogram // 命令执行时间的直方图, 单位纳秒
+	Proc             func(*Client)   // 处理相应命令的方法
 }
 
 func NewCommand(name string, arity int, sflags string, proc func(*Client)) *Command {
@@ -45,17 +66,99 @@ func NewCommand(name string, arity int, sflags string, proc func(*Client)) *Comm
 			} else {
 				proc(cli)
 			}
-			cli.Flush()
 		},
 	}
 }
 
 func (c *Command) GetMicrosecond() int64 {
-	return c.microsecond
+	return atomic.LoadInt64(&c.microsecond)
 }
 
 func (c *Command) GetCalls() int64 {
-	return c.calls
+	return atomic.LoadInt64(&c.calls)
+}
+
+func (c *Command) GetRejectedCalls() int64 {
+	return atomic.LoadInt64(&c.rejectedCalls)
+}
+
+func (c *Command) GetFailedCalls() int64 {
+	return atomic.LoadInt64(&c.failedCalls)
+}
+
+// 设置命令参数中key的位置, 和redis命令表中的firstkey、lastkey、keystep含义相同
+func (c *Command) SetKeySpec(firstKey, lastKey, keyStep int) *Command {
+	c.firstKey = firstKey
+	c.lastKey = lastKey
+	c.keyStep = keyStep
+	return c
+}
+
+func (c *Command) SetGetKeysProc(proc GetKeysProc) *Command {
+	c.getKeysProc = proc
+	return c
+}
+
+// 根据key的位置返回命令参数中所有的key
+func (c *Command) GetKeys(argv [][]byte) []string {
+	if c.getKeysProc != nil {
+		return c.getKeysProc(argv)
+	}
+	if c.firstKey == 0 || c.keyStep <= 0 {
+		return nil
+	}
+	last := c.lastKey
+	if last < 0 {
+		last = len(argv) + last
+	}
+	keys := make([]string, 0)
+	for i := c.firstKey; i <= last && i < len(argv); i += c.keyStep {
+		keys = append(keys, string(argv[i]))
+	}
+	return keys
+}
+
+// 记录一次命令的执行, failed表示命令执行过程中返回了错误
+func (c *Command) RecordCall(duration time.Duration, failed bool) {
+	atomic.AddInt64(&c.calls, 1)
+	atomic.AddInt64(&c.microsecond, int64(duration/time.Microsecond))
+	if failed {
+		atomic.AddInt64(&c.failedCalls, 1)
+	}
+}
+
+// 记录一次命令在执行之前被拒绝
+func (c *Command) RecordRejectedCall() {
+	atomic.AddInt64(&c.rejectedCalls, 1)
+}
+
+// 把命令的执行时间记录到延迟直方图中
+func (c *Command) RecordLatency(duration time.Duration) {
+	c.latencyLock.Lock()
+	if c.latencyHistogram == nil {
+		c.latencyHistogram = util.NewHistogram(LatencyHistogramMinValue, LatencyHistogramMaxValue, LatencyHistogramPrecision)
+	}
+	histogram := c.latencyHistogram
+	c.latencyLock.Unlock()
+	histogram.RecordValue(int64(duration))
+}
+
+// 返回命令的延迟直方图, 如果从来没有记录过则返回nil
+func (c *Command) GetLatencyHistogram() *util.Histogram {
+	c.latencyLock.Lock()
+	defer c.latencyLock.Unlock()
+	return c.latencyHistogram
+}
+
+// 清空命令的统计信息, 用于CONFIG RESETSTAT
+func (c *Command) ResetStats() {
+	atomic.StoreInt64(&c.calls, 0)
+	atomic.StoreInt64(&c.microsecond, 0)
+	atomic.StoreInt64(&c.rejectedCalls, 0)
+	atomic.StoreInt64(&c.failedCalls, 0)
+	if histogram := c.GetLatencyHistogram(); histogram != nil {
+		histogram.Reset()
+	}
 }
 
 func (c *Command) String() string {
@@ -69,3 +172,17 @@ func (c *Command) GetName() string {
 func (c *Command) GetOriginName() string {
 	return strings.ToUpper(c.name)
 }
+
+// key的个数由numkeys参数指定的命令, numKeysIndex是numkeys参数的位置, key紧跟在numkeys之后
+func NumKeysGetKeys(numKeysIndex int) GetKeysProc {
+	return func(argv [][]byte) []string {
+		if numKeysIndex >= len(argv) {
+			return nil
+		}
+		numKeys, err := strconv.Atoi(string(argv[numKeysIndex]))
+		if err != nil || numKeys <= 0 || numKeysIndex+numKeys >= len(argv) {
+			return nil
+		}
+		return util.BytesToStrings(argv[numKeysIndex+1 : numKeysIndex+1+numKeys])
+	}
+}
@@ -7,11 +7,17 @@ import (
 )
 
 const (
+	RedisVersion = "6.2.0" /* 兼容的redis版本 */
+
 	RedisServerAddr   = ""
 	RedisServerPort   = 9736
 	RedisDefaultDBNum = 16
 	RedisMaxIdleTime  = 0 /* default client timeout: infinite */
 
+	RedisDefaultMaxClients   = 10000
+	RedisDefaultTCPKeepAlive = 300 /* seconds, 0 disables TCP keepalive */
+	RedisDefaultTCPNoDelay   = true
+
 	RedisIOReaderPoolThreadNum = 5
 	RedisIOWriterPoolThreadNum = 5
 
@@ -30,29 +36,126 @@ const (
 
 	/* RDB persistence */
 	RedisRDBDefaultFilePath = "dump.rdb"
+	RedisDefaultSaveParams  = "" /* 例如: "3600 1 300 100 60 10000", 默认关闭自动保存 */
+
+	RedismaxQueryBufLen  = 1024 * 1024 * 1024 /* 1GB max query buffer. */
+	RedisProtoMaxBulkLen = 512 * 1024 * 1024  /* 512MB max bulk length in requests. */
+
+	/* Redis maxmemory strategies */
+	RedisMaxMemoryVolatileLRU    = "volatile-lru"
+	RedisMaxMemoryVolatileLFU    = "volatile-lfu"
+	RedisMaxMemoryVolatileRandom = "volatile-random"
+	RedisMaxMemoryVolatileTTL    = "volatile-ttl"
+	RedisMaxMemoryAllKeysLRU     = "allkeys-lru"
+	RedisMaxMemoryAllKeysLFU     = "allkeys-lfu"
+	RedisMaxMemoryAllKeysRandom  = "allkeys-random"
+	RedisMaxMemoryNoEviction     = "noeviction"
+
+	RedisDefaultMaxMemory        = 0 /* 0 means no limit */
+	RedisDefaultMaxMemorySamples = 5
+	RedisDefaultLFULogFactor     = 10
+	RedisDefaultLFUDecayTime     = 1 /* minutes */
+
+	/* List encoding */
+	RedisDefaultListMaxListPackSize = -2 /* 每个listpack节点最多8KB */
+	RedisDefaultListCompressDepth   = 0  /* 不压缩quicklist的节点 */
+
+	/* Hash encoding */
+	RedisDefaultHashMaxListPackEntries = 128
+	RedisDefaultHashMaxListPackValue   = 64
+
+	/* Set encoding */
+	RedisDefaultSetMaxIntSetEntries = 512
+
+	/* Sorted set encoding */
+	RedisDefaultZSetMaxListPackEntries = 128
+	RedisDefaultZSetMaxListPackValue   = 64
 
-	RedismaxQueryBufLen = 1024 * 1024 * 1024 /* 1GB max query buffer. */
+	/* Latency tracking */
+	RedisDefaultLatencyTracking                = true
+	RedisDefaultLatencyTrackingInfoPercentiles = "50 99 99.9"
+
+	/* Slow log */
+	RedisDefaultSlowLogLogSlowerThan = 10000 /* microseconds */
+	RedisDefaultSlowLogMaxLen        = 128
+
+	/* Latency monitor */
+	RedisDefaultLatencyMonitorThreshold = 0 /* milliseconds, 0 means disabled */
+
+	/* Shutdown */
+	RedisDefaultShutdownTimeout = 10 * time.Second /* max time to wait for replicas to catch up */
+
+	/* Client classes for output buffer limits */
+	RedisObufClassNormal  = 0
+	RedisObufClassReplica = 1
+	RedisObufClassPubSub  = 2
+	RedisObufClassCount   = 3
+
+	RedisDefaultClientOutputBufferLimit = "normal 0 0 0 replica 268435456 67108864 60 pubsub 33554432 8388608 60"
 )
 
 // redis server configuration
 type ServerConfig struct {
 	/* General */
-	SentinelMode int           `flag:"sentinel-mode" cfg:"sentinel-mode"` /* True if this instance is a Sentinel. */
-	LogLevel     int64         `flag:"log-level" cfg:"log-level"`         /* log levels*/
-	Timeout      time.Duration `flag:"timeout" cfg:"timeout"`             // Timeout represents the per-request socket read/write timeout. Default 0(disable)
+	SentinelMode int    `flag:"sentinel-mode" cfg:"sentinel-mode"` /* True if this instance is a Sentinel. */
+	LogLevel     int64  `flag:"log-level" cfg:"log-level"`         /* log levels*/
+	Timeout      int64  `flag:"timeout" cfg:"timeout"`             /* Close the connection after a client is idle for N seconds (0 to disable) */
+	ConfigFile   string /* Absolute config file path, or empty */
 
 	/* Networking */
 	Port           int    `flag:"port" cfg:"port"`                         /* TCP listening Port */
 	BindAddr       string `flag:"addr" cfg:"addr"`                         /* Bind address or NULL */
 	ReaderPoolSize int    `flag:"reader-pool-size" cfg:"reader-pool-size"` /* ReaderPool 的默认大小 */
 	WriterPoolSize int    `flag:"writer-pool-size" cfg:"writer-pool-size"` /* WriterPool 的默认大小*/
+	TCPKeepAlive   int    `flag:"tcp-keepalive" cfg:"tcp-keepalive"`       /* Set SO_KEEPALIVE if non-zero, interval in seconds */
+	TCPNoDelay     bool   `flag:"tcp-nodelay" cfg:"tcp-nodelay"`           /* Set TCP_NODELAY on accepted connections */
 
 	/* Configuration */
-	Verbosity   int   `flag:"verbosity" cfg:"verbosity"`         /* Log level in redis.conf */
-	MaxIdleTime int64 `flag:"max-idle-time" cfg:"max-idle-time"` /* Client timeout in seconds */
-	DBNum       int   `flag:"db-num" cfg:"db-num"`               /* Total number of configured DBs */
+	Verbosity int `flag:"verbosity" cfg:"verbosity"` /* Log level in redis.conf */
+	DBNum     int `flag:"db-num" cfg:"db-num"`       /* Total number of configured DBs */
+
+	ClientMaxQueryBufLen int64 `flag:"client-max-query-buf-len" cfg:"client-max-query-buf-len"` /* Limit for client query buffer length */
+	ProtoMaxBulkLen      int64 `flag:"proto-max-bulk-len" cfg:"proto-max-bulk-len"`             /* Protocol bulk length maximum size. */
+
+	/* Limits */
+	MaxClients       int    `flag:"maxclients" cfg:"maxclients"`               /* Max number of simultaneous clients */
+	MaxMemory        int64  `flag:"maxmemory" cfg:"maxmemory"`                 /* Max number of memory bytes to use */
+	MaxMemoryPolicy  string `flag:"maxmemory-policy" cfg:"maxmemory-policy"`   /* Policy for key eviction */
+	MaxMemorySamples int    `flag:"maxmemory-samples" cfg:"maxmemory-samples"` /* Precision of random sampling */
+	LFULogFactor     int    `flag:"lfu-log-factor" cfg:"lfu-log-factor"`       /* LFU logarithmic counter factor. */
+	LFUDecayTime     int    `flag:"lfu-decay-time" cfg:"lfu-decay-time"`       /* LFU counter decay factor. */
+
+	/* List encoding */
+	ListMaxListPackSize int `flag:"list-max-listpack-size" cfg:"list-max-listpack-size"` /* Max entries or bytes of a listpack node */
+	ListCompressDepth   int `flag:"list-compress-depth" cfg:"list-compress-depth"`       /* Number of quicklist nodes not compressed at each end */
+
+	/* Hash encoding */
+	HashMaxListPackEntries int `flag:"hash-max-listpack-entries" cfg:"hash-max-listpack-entries"` /* Max number of fields of a listpack encoded hash */
+	HashMaxListPackValue   int `flag:"hash-max-listpack-value" cfg:"hash-max-listpack-value"`     /* Max length of fields and values of a listpack encoded hash */
 
-	ClientMaxQueryBufLen int64 `flag:"client-max-query-buf-len" cfg:"client-max-query-buf-len"`
+	/* Set encoding */
+	SetMaxIntSetEntries int `flag:"set-max-intset-entries" cfg:"set-max-intset-entries"` /* Max number of members of an intset encoded set */
+
+	/* Sorted set encoding */
+	ZSetMaxListPackEntries int `flag:"zset-max-listpack-entries" cfg:"zset-max-listpack-entries"` /* Max number of members of a listpack encoded sorted set */
+	ZSetMaxListPackValue   int `flag:"zset-max-listpack-value" cfg:"zset-max-listpack-value"`     /* Max length of members of a listpack encoded sorted set */
+
+	/* Latency tracking */
+	LatencyTracking                bool   `flag:"latency-tracking" cfg:"latency-tracking"`                                   /* 1 if extended latency tracking is enabled */
+	LatencyTrackingInfoPercentiles string `flag:"latency-tracking-info-percentiles" cfg:"latency-tracking-info-percentiles"` /* Percentiles exposed by INFO latencystats */
+
+	/* Slow log */
+	SlowLogLogSlowerThan int64 `flag:"slowlog-log-slower-than" cfg:"slowlog-log-slower-than"` /* SLOWLOG time limit (to get logged) in microseconds */
+	SlowLogMaxLen        int   `flag:"slowlog-max-len" cfg:"slowlog-max-len"`                 /* SLOWLOG max number of items logged */
+
+	/* Latency monitor */
+	LatencyMonitorThreshold int64 `flag:"latency-monitor-threshold" cfg:"latency-monitor-threshold"` /* Latency monitor threshold in milliseconds */
+
+	/* Shutdown */
+	ShutdownTimeout time.Duration `flag:"shutdown-timeout" cfg:"shutdown-timeout"` /* Max time to wait for replicas when shutting down */
+
+	/* Client output buffer limits */
+	ClientOutputBufferLimit string `flag:"client-output-buffer-limit" cfg:"client-output-buffer-limit"` /* <class> <hard limit> <soft limit> <soft seconds> ... */
 
 	/* Aof persistence */
 	AofState    int    `flag:"aof-state" cfg:"aof-state"`
@@ -75,6 +178,7 @@ type ServerConfig struct {
 	Dirty             int64  `flag:"dirty" cfg:"dirty"`
 	DirtyBeforeBgSave int64  `flag:"dirty-before-bg-save" cfg:"dirty-before-bg-save"`
 	RdbFilename       string `flag:"rdb-filename" cfg:"rdb-filename"`
+	Save              string `flag:"save" cfg:"save"` /* Save points: "<seconds> <changes> [<seconds> <changes> ...]" */
 	//RdbCompression    int    `flag:"rdb-compression" cfg:"rdb-compression"`
 	//RdbChecksum       int    `flag:"rdn-checksum" cfg:"rdn-checksum"`
 	//LastSave              time.Time `flag:"last-save" cfg:"last-save"`
@@ -90,12 +194,48 @@ func NewServerConfig() *ServerConfig {
 		Port:           RedisServerPort,
 		DBNum:          RedisDefaultDBNum,
 		LogLevel:       RedisLogLevel,
-		Timeout:        5 * time.Second,
+		Timeout:        RedisMaxIdleTime,
 		ReaderPoolSize: RedisIOReaderPoolThreadNum,
 		WriterPoolSize: RedisIOWriterPoolThreadNum,
+		TCPKeepAlive:   RedisDefaultTCPKeepAlive,
+		TCPNoDelay:     RedisDefaultTCPNoDelay,
 		RdbFilename:    RedisRDBDefaultFilePath,
+		Save:           RedisDefaultSaveParams,
 		AofState:       RedisAofOff,
 		AofFSync:       RedisAofFSyncAlways,
 		AofFilename:    RedisAofDefaultFilePath,
+
+		ClientMaxQueryBufLen: RedismaxQueryBufLen,
+		ProtoMaxBulkLen:      RedisProtoMaxBulkLen,
+
+		MaxClients:       RedisDefaultMaxClients,
+		MaxMemory:        RedisDefaultMaxMemory,
+		MaxMemoryPolicy:  RedisMaxMemoryNoEviction,
+		MaxMemorySamples: RedisDefaultMaxMemorySamples,
+		LFULogFactor:     RedisDefaultLFULogFactor,
+		LFUDecayTime:     RedisDefaultLFUDecayTime,
+
+		ListMaxListPackSize: RedisDefaultListMaxListPackSize,
+		ListCompressDepth:   RedisDefaultListCompressDepth,
+
+		HashMaxListPackEntries: RedisDefaultHashMaxListPackEntries,
+		HashMaxListPackValue:   RedisDefaultHashMaxListPackValue,
+
+		SetMaxIntSetEntries: RedisDefaultSetMaxIntSetEntries,
+
+		ZSetMaxListPackEntries: RedisDefaultZSetMaxListPackEntries,
+		ZSetMaxListPackValue:   RedisDefaultZSetMaxListPackValue,
+
+		LatencyTracking:                RedisDefaultLatencyTracking,
+		LatencyTrackingInfoPercentiles: RedisDefaultLatencyTrackingInfoPercentiles,
+
+		SlowLogLogSlowerThan: RedisDefaultSlowLogLogSlowerThan,
+		SlowLogMaxLen:        RedisDefaultSlowLogMaxLen,
+
+		LatencyMonitorThreshold: RedisDefaultLatencyMonitorThreshold,
+
+		ShutdownTimeout: RedisDefaultShutdownTimeout,
+
+		ClientOutputBufferLimit: RedisDefaultClientOutputBufferLimit,
 	}
 }
@@ -0,0 +1,255 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+运行时配置:
+	ServerConfig中所有带有cfg tag的字段都可以通过CONFIG GET获取，cfg tag就是配置项的名称。
+	CONFIG SET只能修改不在immutableConfigParams中的配置项, 这些配置项只有在重启之后才能生效。
+*/
+var immutableConfigParams = map[string]bool{
+	"sentinel-mode":    true,
+	"port":             true,
+	"addr":             true,
+	"reader-pool-size": true,
+	"writer-pool-size": true,
+	"db-num":           true,
+	"aof-state":        true,
+	"aof-filename":     true,
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 配置项名称 -> ServerConfig中字段的下标
+var configParamFields = func() map[string]int {
+	ret := make(map[string]int)
+	t := reflect.TypeOf(ServerConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("cfg"); name != "" {
+			ret[name] = i
+		}
+	}
+	return ret
+}()
+
+// 返回按照名称排序的所有配置项
+func ConfigParamNames() []string {
+	names := make([]string, 0, len(configParamFields))
+	for name := range configParamFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 配置项是否存在
+func IsConfigParam(name string) bool {
+	_, ok := configParamFields[name]
+	return ok
+}
+
+// 配置项是否只能在启动的时候设置
+func IsImmutableConfigParam(name string) bool {
+	return immutableConfigParams[name]
+}
+
+// 以字符串的形式返回配置项的值
+func (config *ServerConfig) GetParam(name string) (string, bool) {
+	idx, ok := configParamFields[name]
+	if !ok {
+		return "", false
+	}
+	return formatConfigValue(reflect.ValueOf(config).Elem().Field(idx)), true
+}
+
+// 以TOML的格式返回配置项的值, 用于CONFIG REWRITE
+func (config *ServerConfig) GetParamTOML(name string) (string, bool) {
+	idx, ok := configParamFields[name]
+	if !ok {
+		return "", false
+	}
+	field := reflect.ValueOf(config).Elem().Field(idx)
+	switch {
+	case field.Type() == durationType, field.Kind() == reflect.String:
+		return strconv.Quote(formatConfigValue(field)), true
+	case field.Kind() == reflect.Bool:
+		return strconv.FormatBool(field.Bool()), true
+	}
+	return formatConfigValue(field), true
+}
+
+// 把字符串解析成配置项对应的类型并设置, 设置之后会对配置项进行校验
+func (config *ServerConfig) SetParam(name string, value string) *ConfigError {
+	idx, ok := configParamFields[name]
+	if !ok {
+		return &ConfigError{Param: name, Msg: "unknown option"}
+	}
+	field := reflect.ValueOf(config).Elem().Field(idx)
+	switch {
+	case field.Type() == durationType:
+		duration, err := parseDuration(value)
+		if err != nil {
+			return &ConfigError{Param: name, Msg: "argument couldn't be parsed into a duration"}
+		}
+		field.SetInt(int64(duration))
+	case field.Kind() == reflect.Int || field.Kind() == reflect.Int64:
+		n, err := ParseMemory(value)
+		if err != nil {
+			return &ConfigError{Param: name, Msg: "argument couldn't be parsed into an integer"}
+		}
+		field.SetInt(n)
+	case field.Kind() == reflect.Bool:
+		b, err := parseYesNo(value)
+		if err != nil {
+			return &ConfigError{Param: name, Msg: "argument must be 'yes' or 'no'"}
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	default:
+		return &ConfigError{Param: name, Msg: fmt.Sprintf("unsupported type %s", field.Type())}
+	}
+	return config.ValidateParam(name)
+}
+
+func formatConfigValue(field reflect.Value) string {
+	switch {
+	case field.Type() == durationType:
+		return time.Duration(field.Int()).String()
+	case field.Kind() == reflect.Int || field.Kind() == reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10)
+	case field.Kind() == reflect.Bool:
+		if field.Bool() {
+			return "yes"
+		}
+		return "no"
+	}
+	return field.String()
+}
+
+// 纯数字按照秒解析, 否则按照go的duration格式解析, 例如: 300ms 5s
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "true", "1":
+		return true, nil
+	case "no", "false", "0":
+		return false, nil
+	}
+	return false, errors.New("invalid bool value")
+}
+
+// 解析内存大小, 支持redis.conf中的单位: 1k => 1000 bytes, 1kb => 1024 bytes, 1m, 1mb, 1g, 1gb
+func ParseMemory(value string) (int64, error) {
+	lower := strings.ToLower(value)
+	units := []struct {
+		suffix string
+		mul    int64
+	}{
+		{"kb", 1024}, {"mb", 1024 * 1024}, {"gb", 1024 * 1024 * 1024},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+	}
+	for _, unit := range units {
+		if strings.HasSuffix(lower, unit.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSuffix(lower, unit.suffix), 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return n * unit.mul, nil
+		}
+	}
+	return strconv.ParseInt(lower, 10, 64)
+}
+
+// 客户端输出缓冲区的限制, 0表示没有限制
+type ClientBufferLimit struct {
+	HardLimitBytes   int64
+	SoftLimitBytes   int64
+	SoftLimitSeconds int64
+}
+
+var obufClassNames = []string{"normal", "replica", "pubsub"}
+
+/**
+解析client-output-buffer-limit配置, 例如: "normal 0 0 0 replica 256mb 64mb 60 pubsub 32mb 8mb 60"
+	和redis一样只修改limits中配置里出现的类型, 其余的类型保持不变, slave是replica的别名。
+	limits的长度是RedisObufClassCount, 解析失败的时候limits不会被修改。
+*/
+func ParseClientOutputBufferLimit(value string, limits []ClientBufferLimit) error {
+	fields := strings.Fields(value)
+	if len(fields)%4 != 0 {
+		return errors.New("Wrong number of arguments in buffer limit configuration.")
+	}
+	parsed := make([]ClientBufferLimit, len(limits))
+	copy(parsed, limits)
+	for i := 0; i < len(fields); i += 4 {
+		class := -1
+		for idx, name := range obufClassNames {
+			if strings.EqualFold(fields[i], name) {
+				class = idx
+			}
+		}
+		if strings.EqualFold(fields[i], "slave") {
+			class = RedisObufClassReplica
+		}
+		if class < 0 {
+			return errors.New("Invalid client class specified in buffer limit configuration.")
+		}
+		hard, err1 := ParseMemory(fields[i+1])
+		soft, err2 := ParseMemory(fields[i+2])
+		seconds, err3 := strconv.ParseInt(fields[i+3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || hard < 0 || soft < 0 || seconds < 0 {
+			return errors.New("Error in hard, soft or soft_seconds setting in buffer limit configuration.")
+		}
+		parsed[class] = ClientBufferLimit{HardLimitBytes: hard, SoftLimitBytes: soft, SoftLimitSeconds: seconds}
+	}
+	copy(limits, parsed)
+	return nil
+}
+
+// 把所有类型的限制格式化成client-output-buffer-limit配置
+func FormatClientOutputBufferLimit(limits []ClientBufferLimit) string {
+	parts := make([]string, 0, len(limits))
+	for class, limit := range limits {
+		parts = append(parts, fmt.Sprintf("%s %d %d %d", obufClassNames[class], limit.HardLimitBytes, limit.SoftLimitBytes, limit.SoftLimitSeconds))
+	}
+	return strings.Join(parts, " ")
+}
+
+// RDB自动保存的条件: seconds秒内至少有changes次修改
+type SaveParam struct {
+	Seconds int64
+	Changes int64
+}
+
+// 解析save配置, 例如: "3600 1 300 100", 空字符串表示关闭自动保存
+func ParseSaveParams(value string) ([]SaveParam, error) {
+	fields := strings.Fields(value)
+	if len(fields)%2 != 0 {
+		return nil, errors.New("Invalid save parameters")
+	}
+	ret := make([]SaveParam, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err1 := strconv.ParseInt(fields[i], 10, 64)
+		changes, err2 := strconv.ParseInt(fields[i+1], 10, 64)
+		if err1 != nil || err2 != nil || seconds < 1 || changes < 0 {
+			return nil, errors.New("Invalid save parameters")
+		}
+		ret = append(ret, SaveParam{Seconds: seconds, Changes: changes})
+	}
+	return ret, nil
+}
@@ -0,0 +1,214 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SwanSpouse/redis_go/loggers"
+)
+
+/**
+配置校验:
+	每个配置项可以有一个约束(枚举、范围、文件路径等)，在启动和CONFIG SET的时候都会进行校验;
+	配置项之间的约束(例如互斥的配置项)由configRules负责校验。
+	Validate会校验所有的配置项并把错误汇总在一起返回，方便一次性修改配置文件中所有的错误。
+*/
+
+// 单个配置项校验失败的错误
+type ConfigError struct {
+	Param string
+	Msg   string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("'%s': %s", e.Param, e.Msg)
+}
+
+// 所有校验失败的错误
+type ConfigErrors []*ConfigError
+
+func (errs ConfigErrors) Error() string {
+	lines := make([]string, 0, len(errs)+1)
+	lines = append(lines, fmt.Sprintf("found %d invalid config option(s):", len(errs)))
+	for _, err := range errs {
+		lines = append(lines, "  - "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 配置项的约束, 返回空字符串表示合法
+type configConstraint func(value reflect.Value) string
+
+// 取值只能是choices中的一个
+func enumConstraint(choices ...interface{}) configConstraint {
+	return func(value reflect.Value) string {
+		for _, choice := range choices {
+			if reflect.DeepEqual(value.Interface(), reflect.ValueOf(choice).Convert(value.Type()).Interface()) {
+				return ""
+			}
+		}
+		names := make([]string, 0, len(choices))
+		for _, choice := range choices {
+			names = append(names, fmt.Sprintf("%v", choice))
+		}
+		return fmt.Sprintf("argument(s) must be one of the following: %s", strings.Join(names, ", "))
+	}
+}
+
+// 取值在[min, max]之间
+func rangeConstraint(min, max int64) configConstraint {
+	return func(value reflect.Value) string {
+		if n := value.Int(); n < min || n > max {
+			if max == maxConfigValue {
+				return fmt.Sprintf("argument must be greater than or equal to %d", min)
+			}
+			return fmt.Sprintf("argument must be between %d and %d inclusive", min, max)
+		}
+		return ""
+	}
+}
+
+// 文件路径不能为空，不能是一个目录，并且所在的目录必须存在
+func filePathConstraint(value reflect.Value) string {
+	path := value.String()
+	if path == "" {
+		return "file path can not be empty"
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Sprintf("%s is a directory", path)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		return fmt.Sprintf("directory %s does not exist", filepath.Dir(path))
+	}
+	return ""
+}
+
+func saveParamsConstraint(value reflect.Value) string {
+	if _, err := ParseSaveParams(value.String()); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func clientOutputBufferLimitConstraint(value reflect.Value) string {
+	if err := ParseClientOutputBufferLimit(value.String(), make([]ClientBufferLimit, RedisObufClassCount)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func percentilesConstraint(value reflect.Value) string {
+	for _, field := range strings.Fields(value.String()) {
+		if p, err := strconv.ParseFloat(field, 64); err != nil || p < 0 || p > 100 {
+			return fmt.Sprintf("invalid percentile %s, must be between 0 and 100", field)
+		}
+	}
+	return ""
+}
+
+const maxConfigValue = int64(^uint64(0) >> 1)
+
+var configConstraints = map[string]configConstraint{
+	"sentinel-mode":                     enumConstraint(0, 1),
+	"log-level":                         rangeConstraint(loggers.FATAL, loggers.DEBUG),
+	"timeout":                           rangeConstraint(0, maxConfigValue),
+	"port":                              rangeConstraint(0, 65535),
+	"reader-pool-size":                  rangeConstraint(1, maxConfigValue),
+	"writer-pool-size":                  rangeConstraint(1, maxConfigValue),
+	"tcp-keepalive":                     rangeConstraint(0, maxConfigValue),
+	"db-num":                            rangeConstraint(1, maxConfigValue),
+	"client-max-query-buf-len":          rangeConstraint(0, maxConfigValue),
+	"proto-max-bulk-len":                rangeConstraint(1024*1024, maxConfigValue),
+	"maxclients":                        rangeConstraint(1, maxConfigValue),
+	"maxmemory":                         rangeConstraint(0, maxConfigValue),
+	"maxmemory-policy":                  enumConstraint(RedisMaxMemoryVolatileLRU, RedisMaxMemoryVolatileLFU, RedisMaxMemoryVolatileRandom, RedisMaxMemoryVolatileTTL, RedisMaxMemoryAllKeysLRU, RedisMaxMemoryAllKeysLFU, RedisMaxMemoryAllKeysRandom, RedisMaxMemoryNoEviction),
+	"maxmemory-samples":                 rangeConstraint(1, 64),
+	"lfu-log-factor":                    rangeConstraint(0, maxConfigValue),
+	"lfu-decay-time":                    rangeConstraint(0, maxConfigValue),
+	"list-max-listpack-size":            rangeConstraint(-5, maxConfigValue),
+	"list-compress-depth":               rangeConstraint(0, maxConfigValue),
+	"hash-max-listpack-entries":         rangeConstraint(0, maxConfigValue),
+	"hash-max-listpack-value":           rangeConstraint(0, maxConfigValue),
+	"set-max-intset-entries":            rangeConstraint(0, maxConfigValue),
+	"zset-max-listpack-entries":         rangeConstraint(0, maxConfigValue),
+	"zset-max-listpack-value":           rangeConstraint(0, maxConfigValue),
+	"latency-tracking-info-percentiles": percentilesConstraint,
+	"slowlog-max-len":                   rangeConstraint(0, maxConfigValue),
+	"latency-monitor-threshold":         rangeConstraint(0, maxConfigValue),
+	"aof-state":                         enumConstraint(RedisAofOff, RedisAofOn, RedisAofWaitRewrite),
+	"aof-fsync":                         enumConstraint(RedisAofFSyncAlways, RedisAofFSyncEverySec, RedisAofFSyncNo),
+	"aof-filename":                      filePathConstraint,
+	"dirty":                             rangeConstraint(0, maxConfigValue),
+	"dirty-before-bg-save":              rangeConstraint(0, maxConfigValue),
+	"rdb-filename":                      filePathConstraint,
+	"save":                              saveParamsConstraint,
+	"shutdown-timeout":                  rangeConstraint(0, maxConfigValue),
+	"client-output-buffer-limit":        clientOutputBufferLimitConstraint,
+}
+
+// 配置项之间的约束
+var configRules = []func(config *ServerConfig) *ConfigError{
+	func(config *ServerConfig) *ConfigError {
+		if filepath.Clean(config.AofFilename) == filepath.Clean(config.RdbFilename) {
+			return &ConfigError{Param: "aof-filename", Msg: "can not be the same file as 'rdb-filename'"}
+		}
+		return nil
+	},
+	func(config *ServerConfig) *ConfigError {
+		// sentinel不保存数据，不能和持久化的配置一起使用
+		if config.SentinelMode == 1 && (config.AofState != RedisAofOff || config.Save != "") {
+			return &ConfigError{Param: "sentinel-mode", Msg: "is mutually exclusive with 'aof-state' and 'save'"}
+		}
+		return nil
+	},
+}
+
+// 校验单个配置项
+func (config *ServerConfig) ValidateParam(name string) *ConfigError {
+	idx, ok := configParamFields[name]
+	if !ok {
+		return &ConfigError{Param: name, Msg: "unknown option"}
+	}
+	if constraint, ok := configConstraints[name]; ok {
+		if msg := constraint(reflect.ValueOf(config).Elem().Field(idx)); msg != "" {
+			return &ConfigError{Param: name, Msg: msg}
+		}
+	}
+	return nil
+}
+
+// 校验所有的配置项以及配置项之间的约束, 返回所有的错误
+func (config *ServerConfig) Validate() error {
+	errs := make(ConfigErrors, 0)
+	for _, name := range ConfigParamNames() {
+		if err := config.ValidateParam(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, rule := range configRules {
+		if err := rule(config); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// 返回配置文件中所有不认识的配置项
+func UnknownConfigKeys(cfg map[string]interface{}) []string {
+	unknown := make([]string, 0)
+	for key := range cfg {
+		if !IsConfigParam(key) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,109 @@
+package conf
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateDefaultConfig(t *testing.T) {
+	if err := NewServerConfig().Validate(); err != nil {
+		t.Fatalf("default config should be valid, got %s", err)
+	}
+}
+
+func TestValidateAggregatesErrors(t *testing.T) {
+	config := NewServerConfig()
+	config.DBNum = -1
+	config.Port = 70000
+	config.AofFSync = "sometimes"
+	config.RdbFilename = os.TempDir()
+	config.AofFilename = config.RdbFilename
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("expect validation errors")
+	}
+	errs, ok := err.(ConfigErrors)
+	if !ok {
+		t.Fatalf("expect ConfigErrors, got %T", err)
+	}
+	params := make([]string, 0, len(errs))
+	for _, e := range errs {
+		params = append(params, e.Param)
+	}
+	if strings.Join(params, ",") != "aof-filename,aof-fsync,db-num,port,rdb-filename,aof-filename" {
+		t.Fatalf("unexpected invalid params %v", params)
+	}
+	if !strings.HasPrefix(err.Error(), "found 6 invalid config option(s):\n  - 'aof-filename': ") {
+		t.Fatalf("unexpected error message %s", err)
+	}
+}
+
+func TestValidateMutuallyExclusive(t *testing.T) {
+	config := NewServerConfig()
+	config.SentinelMode = 1
+	config.Save = "60 1"
+	err := config.Validate()
+	if err == nil || err.(ConfigErrors)[0].Param != "sentinel-mode" {
+		t.Fatalf("sentinel-mode and save should be mutually exclusive, got %v", err)
+	}
+}
+
+func TestSetParam(t *testing.T) {
+	config := NewServerConfig()
+	if err := config.SetParam("maxmemory", "2mb"); err != nil || config.MaxMemory != 2*1024*1024 {
+		t.Fatalf("set maxmemory failed, err: %v, value: %d", err, config.MaxMemory)
+	}
+	if err := config.SetParam("timeout", "300"); err != nil || config.Timeout != 300 {
+		t.Fatalf("set timeout failed, err: %v, value: %d", err, config.Timeout)
+	}
+	// timeout和redis一样是整数秒
+	if err := config.SetParam("timeout", "300ms"); err == nil {
+		t.Fatal("timeout should be an integer number of seconds")
+	}
+	if err := config.SetParam("latency-tracking", "no"); err != nil || config.LatencyTracking {
+		t.Fatalf("set latency-tracking failed, err: %v", err)
+	}
+	if err := config.SetParam("maxmemory-samples", "0"); err == nil || err.Msg != "argument must be between 1 and 64 inclusive" {
+		t.Fatalf("expect range error, got %v", err)
+	}
+	if err := config.SetParam("maxmemory-policy", "lru"); err == nil || !strings.HasPrefix(err.Msg, "argument(s) must be one of the following: volatile-lru") {
+		t.Fatalf("expect enum error, got %v", err)
+	}
+	if err := config.SetParam("no-such-config", "1"); err == nil {
+		t.Fatal("expect unknown option error")
+	}
+}
+
+func TestUnknownConfigKeys(t *testing.T) {
+	cfg := map[string]interface{}{"port": 1, "foo": 2, "bar": 3}
+	if keys := UnknownConfigKeys(cfg); strings.Join(keys, ",") != "bar,foo" {
+		t.Fatalf("unexpected unknown keys %v", keys)
+	}
+}
+
+func TestParseClientOutputBufferLimit(t *testing.T) {
+	limits := make([]ClientBufferLimit, RedisObufClassCount)
+	if err := ParseClientOutputBufferLimit(RedisDefaultClientOutputBufferLimit, limits); err != nil {
+		t.Fatal(err)
+	}
+	if FormatClientOutputBufferLimit(limits) != RedisDefaultClientOutputBufferLimit {
+		t.Fatalf("unexpected limits %s", FormatClientOutputBufferLimit(limits))
+	}
+	// 只修改配置中出现的类型
+	if err := ParseClientOutputBufferLimit("slave 1mb 512kb 10", limits); err != nil {
+		t.Fatal(err)
+	}
+	if limits[RedisObufClassReplica] != (ClientBufferLimit{1024 * 1024, 512 * 1024, 10}) || limits[RedisObufClassPubSub].HardLimitBytes != 33554432 {
+		t.Fatalf("unexpected limits %+v", limits)
+	}
+	for _, value := range []string{"normal 0 0", "unknown 0 0 0", "pubsub -1 0 0", "pubsub 1mb 1xb 0"} {
+		if err := ParseClientOutputBufferLimit(value, limits); err == nil {
+			t.Fatalf("expect error for %q", value)
+		}
+	}
+	if limits[RedisObufClassPubSub].HardLimitBytes != 33554432 {
+		t.Fatal("limits should not be modified when failed")
+	}
+}
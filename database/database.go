@@ -1,15 +1,29 @@
 package database
 
 import (
+	"sync/atomic"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/encodings"
 	"github.com/SwanSpouse/redis_go/loggers"
 	"github.com/SwanSpouse/redis_go/raw_type"
 )
 
 type Database struct {
-	id   int            // 数据库编号
-	dict *raw_type.Dict // 数据库
+	id             int            // 数据库编号
+	dict           *raw_type.Dict // 数据库
+	usedMemory     int64          // 数据库中所有key和value占用的内存
+	expires        int64          // 设置了过期时间的key的个数
+	avgTTL         int64          // 主动过期时采样估算的平均ttl(毫秒)
+	statHits       int64          // 查找key成功的次数
+	statMisses     int64          // 查找key失败的次数
+	statExpiredKey int64          // 过期被删除的key的个数
+	expiredHook    ExpiredKeyHook // key过期被删除之后的回调
 }
 
+// key过期被删除之后调用, 例如client side caching需要给客户端发送失效消息
+type ExpiredKeyHook func(key string)
+
 func NewDatabase(id int) *Database {
 	return &Database{
 		id:   id,
@@ -21,15 +35,43 @@ func (db *Database) GetID() int {
 	return db.id
 }
 
+func (db *Database) SetExpiredKeyHook(hook ExpiredKeyHook) {
+	db.expiredHook = hook
+}
+
+// 删除已经过期的key
+func (db *Database) expireKey(key string) bool {
+	if db.RemoveKeyInDB([]string{key}) == 0 {
+		return false
+	}
+	atomic.AddInt64(&db.statExpiredKey, 1)
+	if db.expiredHook != nil {
+		db.expiredHook(key)
+	}
+	return true
+}
+
 // 获取Key在数据库中对应的Value
 func (db *Database) SearchKeyInDB(key string) TBase {
 	if obj := db.dict.Get(key); obj == nil {
+		atomic.AddInt64(&db.statMisses, 1)
 		return nil
 	} else {
 		if tBase, ok := obj.(TBase); !ok || tBase.IsExpired() {
 			loggers.Errorf("illegal value in database.dict or tBase is expired. key %s", key)
+			atomic.AddInt64(&db.statMisses, 1)
+			// 惰性删除过期的key
+			if ok {
+				db.expireKey(key)
+			}
 			return nil
 		} else {
+			atomic.AddInt64(&db.statHits, 1)
+			// 更新对象的LRU时钟或者LFU计数，供maxmemory淘汰策略使用。
+			// 共享对象同时被多个key引用, 不更新它的lru, 避免并发写以及不同key之间的统计互相干扰
+			if !isSharedObject(tBase) {
+				tBase.UpdateLRU()
+			}
 			return tBase
 		}
 	}
@@ -48,7 +90,26 @@ func (db *Database) SearchKeysInDB(keys []string) ([]TBase, error) {
 
 // 将TBase写入到redis database
 func (db *Database) SetKeyInDB(key string, obj TBase) {
-	db.dict.Put(key, obj)
+	memory := keyMemoryUsage(key, obj)
+	if !isSharedObject(obj) {
+		obj.SetMemory(memory)
+	}
+	if hasExpire(obj) {
+		atomic.AddInt64(&db.expires, 1)
+	}
+	if oldValue := db.dict.Put(key, obj); oldValue != nil {
+		if oldTBase, ok := oldValue.(TBase); ok {
+			memory -= KeyMemory(key, oldTBase)
+			if hasExpire(oldTBase) {
+				atomic.AddInt64(&db.expires, -1)
+			}
+			// 被覆盖的对象不再被这个key引用
+			if oldTBase != obj {
+				oldTBase.DecrRefCount()
+			}
+		}
+	}
+	atomic.AddInt64(&db.usedMemory, memory)
 }
 
 // 删除redis db 中的key
@@ -57,11 +118,82 @@ func (db *Database) RemoveKeyInDB(keys []string) int64 {
 	for _, key := range keys {
 		if oldValue := db.dict.RemoveKey(key); oldValue != nil {
 			successCount += 1
+			if oldTBase, ok := oldValue.(TBase); ok {
+				atomic.AddInt64(&db.usedMemory, -KeyMemory(key, oldTBase))
+				if hasExpire(oldTBase) {
+					atomic.AddInt64(&db.expires, -1)
+				}
+				oldTBase.DecrRefCount()
+			}
 		}
 	}
 	return successCount
 }
 
+// 估算key以及对应的value占用的内存, samples为估算value时采样的元素个数
+func KeyMemoryUsage(key string, obj TBase, samples int) int64 {
+	return encodings.DictEntrySize + encodings.StringMemory(key) + obj.MemoryUsage(samples)
+}
+
+func keyMemoryUsage(key string, obj TBase) int64 {
+	return KeyMemoryUsage(key, obj, encodings.ObjectMemoryDefaultSamples)
+}
+
+/*
+	过期时间保存在对象上, 只会在写入key的时候随着新的对象一起设置, 所以在SetKeyInDB、RemoveKeyInDB以及FlushDB中
+	增量地维护设置了过期时间的key的个数。
+*/
+func hasExpire(obj TBase) bool {
+	return !obj.GetExpireTime().IsZero()
+}
+
+func isSharedObject(obj TBase) bool {
+	return obj.GetRefCount() == encodings.RedisSharedRefCount
+}
+
+/*
+	返回key以及对应的value在数据库内存统计中占用的内存。
+	共享对象被多个key引用, 不能在对象上保存某一个key的内存, 每次重新估算; 共享对象不会被修改, 所以估算的结果不变。
+*/
+func KeyMemory(key string, obj TBase) int64 {
+	if isSharedObject(obj) {
+		return keyMemoryUsage(key, obj)
+	}
+	return obj.GetMemory()
+}
+
+/*
+	在原地修改了key对应的value之后，重新估算value占用的内存，并更新数据库的内存统计。
+	由于估算是基于采样的，所以每次更新的代价都是常数级别的。
+*/
+func (db *Database) UpdateKeyMemory(key string) {
+	if obj := db.LookupKeyNoTouch(key); obj != nil && !isSharedObject(obj) {
+		memory := keyMemoryUsage(key, obj)
+		atomic.AddInt64(&db.usedMemory, memory-obj.GetMemory())
+		obj.SetMemory(memory)
+	}
+}
+
+// 重新统计数据库中所有key占用的内存，在从磁盘加载数据之后调用
+func (db *Database) RebuildMemoryUsage() {
+	var total int64
+	for key, value := range db.dict.KeyValueSet() {
+		if obj, ok := value.(TBase); ok {
+			memory := keyMemoryUsage(key.(string), obj)
+			if !isSharedObject(obj) {
+				obj.SetMemory(memory)
+			}
+			total += memory
+		}
+	}
+	atomic.StoreInt64(&db.usedMemory, total)
+}
+
+// 返回数据库中所有key和value占用的内存
+func (db *Database) UsedMemory() int64 {
+	return atomic.LoadInt64(&db.usedMemory)
+}
+
 // 获取数据库中所有的key
 func (db *Database) GetAllKeys() []string {
 	ret := make([]string, 0)
@@ -71,10 +203,89 @@ func (db *Database) GetAllKeys() []string {
 	return ret
 }
 
+/*
+	随机采样数据库中最多count个key，用于maxmemory的近似淘汰算法。
+	volatileOnly为true时只返回设置了过期时间的key。
+*/
+func (db *Database) SampleKeys(count int, volatileOnly bool) map[string]TBase {
+	ret := make(map[string]TBase)
+	for key, value := range db.dict.GetSomeKeys(count) {
+		tBase, ok := value.(TBase)
+		if !ok || (volatileOnly && tBase.GetExpireTime().IsZero()) {
+			continue
+		}
+		ret[key.(string)] = tBase
+	}
+	return ret
+}
+
+/*
+	随机采样count个设置了过期时间的key, 删除其中已经过期的key。返回采样到的key的个数以及删除的key的个数。
+	和redis一样, 用没有过期的key的剩余ttl更新数据库的平均ttl, 每次采样的结果只占2%的权重。
+*/
+func (db *Database) ActiveExpireSample(count int) (int, int) {
+	samples := db.SampleKeys(count, true)
+	expired := 0
+	var ttlSum, ttlSamples int64
+	now := time.Now()
+	for key, obj := range samples {
+		if obj.IsExpired() {
+			if db.expireKey(key) {
+				expired++
+			}
+			continue
+		}
+		ttlSum += int64(obj.GetExpireTime().Sub(now) / time.Millisecond)
+		ttlSamples++
+	}
+	if ttlSamples > 0 {
+		avgTTL := ttlSum / ttlSamples
+		if old := atomic.LoadInt64(&db.avgTTL); old != 0 {
+			avgTTL = (old/50)*49 + avgTTL/50
+		}
+		atomic.StoreInt64(&db.avgTTL, avgTTL)
+	}
+	return len(samples), expired
+}
+
+// 获取Key在数据库中对应的Value，不更新对象的访问时间
+func (db *Database) LookupKeyNoTouch(key string) TBase {
+	if obj := db.dict.Get(key); obj != nil {
+		if tBase, ok := obj.(TBase); ok {
+			return tBase
+		}
+	}
+	return nil
+}
+
 func (db *Database) FlushDB() {
 	db.dict.Clear()
+	atomic.StoreInt64(&db.usedMemory, 0)
+	atomic.StoreInt64(&db.expires, 0)
+	atomic.StoreInt64(&db.avgTTL, 0)
 }
 
 func (db *Database) DBSize() int {
 	return db.dict.Size()
 }
+
+// 返回数据库中设置了过期时间的key的个数以及采样估算的平均ttl(毫秒)，用于INFO keyspace
+func (db *Database) ExpiresStats() (int, int64) {
+	expires := atomic.LoadInt64(&db.expires)
+	if expires == 0 {
+		return 0, 0
+	}
+	return int(expires), atomic.LoadInt64(&db.avgTTL)
+}
+
+// 返回数据库查找key命中、未命中以及过期删除的次数
+func (db *Database) GetStats() (hits int64, misses int64, expired int64) {
+	return atomic.LoadInt64(&db.statHits), atomic.LoadInt64(&db.statMisses), atomic.LoadInt64(&db.statExpiredKey)
+}
+
+// 重置数据库的统计信息
+func (db *Database) ResetStats() {
+	atomic.StoreInt64(&db.statHits, 0)
+	atomic.StoreInt64(&db.statMisses, 0)
+	atomic.StoreInt64(&db.statExpiredKey, 0)
+}
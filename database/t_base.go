@@ -1,6 +1,10 @@
 package database
 
-import "github.com/SwanSpouse/redis_go/encodings"
+import (
+	"time"
+
+	"github.com/SwanSpouse/redis_go/encodings"
+)
 
 var (
 	_ TBase = (*encodings.StringRaw)(nil)
@@ -8,6 +12,7 @@ var (
 	_ TBase = (*encodings.StringEmb)(nil)
 
 	_ TBase = (*encodings.ListLinkedList)(nil)
+	_ TBase = (*encodings.ListQuickList)(nil)
 
 	_ TBase = (*encodings.HashDict)(nil)
 
@@ -31,5 +36,12 @@ type TBase interface {
 	GetValue() interface{}
 	SetValue(interface{})
 	IsExpired() bool
+	GetExpireTime() time.Time
+	UpdateLRU()
+	LFUDecrAndReturn() int
+	EstimateIdleTime() int64
+	GetMemory() int64
+	SetMemory(int64)
+	MemoryUsage(int) int64
 	String() string
 }
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"time"
+
 	"github.com/SwanSpouse/redis_go/encodings"
 )
 
@@ -25,6 +27,13 @@ type THash interface {
 	GetValue() interface{}
 	SetValue(interface{})
 	IsExpired() bool
+	GetExpireTime() time.Time
+	UpdateLRU()
+	LFUDecrAndReturn() int
+	EstimateIdleTime() int64
+	GetMemory() int64
+	SetMemory(int64)
+	MemoryUsage(int) int64
 
 	// hash command operation
 	HSet(string, string) int
@@ -43,5 +52,5 @@ func NewRedisHashObject() TBase {
 }
 
 func NewRedisHashObjectWithTTL(ttl int) TBase {
-	return encodings.NewHashDict(ttl)
+	return encodings.NewHashListPack(ttl)
 }
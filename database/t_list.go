@@ -1,10 +1,15 @@
 package database
 
-import "github.com/SwanSpouse/redis_go/encodings"
+import (
+	"time"
+
+	"github.com/SwanSpouse/redis_go/encodings"
+)
 
 var (
 	// list对象的实现方式
 	_ TList = (*encodings.ListLinkedList)(nil)
+	_ TList = (*encodings.ListQuickList)(nil)
 )
 
 type TList interface {
@@ -23,12 +28,19 @@ type TList interface {
 	GetValue() interface{}
 	SetValue(interface{})
 	IsExpired() bool
+	GetExpireTime() time.Time
+	UpdateLRU()
+	LFUDecrAndReturn() int
+	EstimateIdleTime() int64
+	GetMemory() int64
+	SetMemory(int64)
+	MemoryUsage(int) int64
 
 	// list command operation
 	LPush([]string) int
 	RPush([]string) int
-	LPop() string
-	RPop() string
+	LPop(int) []string
+	RPop(int) []string
 	LRange(int, int) []string
 	LIndex(int) (string, error)
 	LLen() int
@@ -36,7 +48,9 @@ type TList interface {
 	LRem(int, string) int
 	LTrim(int, int) error
 	LSet(int, string) error
+	LPos(string, int, int, int) []int
 	GetAllMembers() []string
+	QuickListNodes() [][]string
 	Debug()
 }
 
@@ -44,6 +58,10 @@ func NewRedisListWithEncodingLinkedList(ttl int) TBase {
 	return encodings.NewListLinkedList(ttl)
 }
 
+func NewRedisListWithEncodingQuickList(ttl int) TBase {
+	return encodings.NewListQuickList(ttl)
+}
+
 // 创建一个新的redis list object
 func NewRedisListObject() TBase {
 	return NewRedisListObjectWithTTL(-1)
@@ -51,5 +69,5 @@ func NewRedisListObject() TBase {
 
 // 创建一个新的带有ttl的redis list object
 func NewRedisListObjectWithTTL(ttl int) TBase {
-	return NewRedisListWithEncodingLinkedList(ttl)
+	return NewRedisListWithEncodingQuickList(ttl)
 }
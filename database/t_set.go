@@ -1,6 +1,10 @@
 package database
 
-import "github.com/SwanSpouse/redis_go/encodings"
+import (
+	"time"
+
+	"github.com/SwanSpouse/redis_go/encodings"
+)
 
 var (
 	// set对象的实现方式
@@ -23,6 +27,13 @@ type TSet interface {
 	GetValue() interface{}
 	SetValue(interface{})
 	IsExpired() bool
+	GetExpireTime() time.Time
+	UpdateLRU()
+	LFUDecrAndReturn() int
+	EstimateIdleTime() int64
+	GetMemory() int64
+	SetMemory(int64)
+	MemoryUsage(int) int64
 	String() string
 
 	// set command operation
@@ -41,5 +52,5 @@ func NewRedisSetObject() TBase {
 }
 
 func NewRedisSetObjectWithTTL(ttl int) TBase {
-	return encodings.NewHasSet(ttl)
+	return encodings.NewSetIntSet(ttl)
 }
@@ -1,7 +1,10 @@
 package database
 
 import (
+	"time"
+
 	"github.com/SwanSpouse/redis_go/encodings"
+	"github.com/SwanSpouse/redis_go/util"
 )
 
 var (
@@ -27,6 +30,15 @@ type TString interface {
 	GetValue() interface{}
 	SetValue(interface{})
 	IsExpired() bool
+	GetExpireTime() time.Time
+	SetExpireTime(time.Time)
+	IsShared() bool
+	UpdateLRU()
+	LFUDecrAndReturn() int
+	EstimateIdleTime() int64
+	GetMemory() int64
+	SetMemory(int64)
+	MemoryUsage(int) int64
 
 	// string command operation
 	Append(string) int
@@ -36,6 +48,7 @@ type TString interface {
 	DecrBy(string) (int64, error)
 	IncrByFloat(string) (string, error)
 	Strlen() int
+	SetRange(int, string) int
 	String() string
 }
 
@@ -47,10 +60,33 @@ func NewRedisStringWithEncodingStringInt(value int64, ttl int) TString {
 	return encodings.NewStringInt(ttl, value)
 }
 
-func NewRedisStringWithEncodingStringEmb(value int, ttl int) TString {
+func NewRedisStringWithEncodingStringEmb(value string, ttl int) TString {
 	return encodings.NewStringEmb(ttl, value)
 }
 
+/**
+字符串对象的编码:
+	能够表示成long long的字符串使用int编码, 0 ~ 9999之间并且没有设置过期时间的时候使用共享对象;
+	长度不超过44字节的字符串使用embstr编码, 其他的字符串使用raw编码。
+*/
+func newRedisStringObject(value string, ttl int, tryInt bool) TString {
+	// 长度超过20的字符串不可能是long long
+	if tryInt && len(value) <= 20 {
+		if valueInt, ok := util.StringToInt64(value); ok {
+			if ttl <= 0 {
+				if shared, ok := encodings.GetSharedInteger(valueInt); ok {
+					return shared
+				}
+			}
+			return NewRedisStringWithEncodingStringInt(valueInt, ttl)
+		}
+	}
+	if len(value) <= encodings.RedisEncodingEmbStrSize {
+		return NewRedisStringWithEncodingStringEmb(value, ttl)
+	}
+	return NewRedisStringWithEncodingRawString(value, ttl)
+}
+
 // 创建一个新的redis string object
 func NewRedisStringObject(value string) TBase {
 	return NewRedisStringObjectWithTTL(value, -1)
@@ -58,9 +94,26 @@ func NewRedisStringObject(value string) TBase {
 
 // 创建一个新的带有ttl的redis string object
 func NewRedisStringObjectWithTTL(value string, ttl int) TBase {
-	// 先默认都是raw string
-	//if valueInt, err := strconv.ParseInt(value, 10, 64); err == nil {
-	//	return NewRedisStringWithEncodingStringInt(valueInt, ttl)
-	//}
-	return NewRedisStringWithEncodingRawString(value, ttl)
+	return newRedisStringObject(value, ttl, true)
+}
+
+// 用value创建一个新的字符串对象替换old, 保留old的过期时间。tryInt为false的时候不使用int编码, 例如INCRBYFLOAT的结果
+func NewRedisStringObjectFrom(value string, old TString, tryInt bool) TString {
+	if old == nil || old.GetExpireTime().IsZero() {
+		return newRedisStringObject(value, -1, tryInt)
+	}
+	obj := newRedisStringObject(value, old.GetTTL(), tryInt)
+	if obj.IsShared() {
+		// 设置了过期时间的对象不能使用共享对象
+		obj = NewRedisStringWithEncodingStringInt(obj.GetValue().(int64), old.GetTTL())
+	}
+	obj.SetExpireTime(old.GetExpireTime())
+	return obj
+}
+
+// 复制一个raw编码的字符串对象, 保留old的过期时间。用于APPEND、SETRANGE等原地修改字符串的命令
+func NewRedisStringRawFrom(old TString) TString {
+	obj := NewRedisStringWithEncodingRawString(old.String(), old.GetTTL())
+	obj.SetExpireTime(old.GetExpireTime())
+	return obj
 }
@@ -1,6 +1,10 @@
 package database
 
-import "github.com/SwanSpouse/redis_go/encodings"
+import (
+	"time"
+
+	"github.com/SwanSpouse/redis_go/encodings"
+)
 
 var (
 	// zset对象的实现方式
@@ -23,6 +27,13 @@ type TZSet interface {
 	GetValue() interface{}
 	SetValue(interface{})
 	IsExpired() bool
+	GetExpireTime() time.Time
+	UpdateLRU()
+	LFUDecrAndReturn() int
+	EstimateIdleTime() int64
+	GetMemory() int64
+	SetMemory(int64)
+	MemoryUsage(int) int64
 	String() string
 
 	// sorted set command operation
@@ -40,6 +51,7 @@ type TZSet interface {
 	ZRemRangeByRank(string, string) (int, error)
 	ZRemRangeByScore(string, string) (int, error)
 	ZScore(string) (float64, error)
+	ZMembers() ([]string, []float64)
 	//ZUnionStore()
 	//ZInterStore()
 	//ZScan()
@@ -50,5 +62,5 @@ func NewRedisSortedSetObject() TZSet {
 }
 
 func NewRedisSortedSetObjectWithTTL(ttl int) TZSet {
-	return encodings.NewSortedSet(ttl)
+	return encodings.NewSortedSetListPack(ttl)
 }
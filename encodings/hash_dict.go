@@ -26,12 +26,18 @@ func NewHashDict(ttl int) *HashDict {
 			encoding:   RedisEncodingHT,
 			ttl:        ttl,
 			value:      raw_type.NewDict(),
+			lru:        initialLRU(),
+			refCount:   1,
 			expireTime: expireTime,
 		},
 	}
 }
 
 func (hd *HashDict) HSet(key string, value string) int {
+	hd.tryConvertListPack(key, value)
+	if hd.isListPack() {
+		return hd.listPackHSet(key, value)
+	}
 	dict := hd.GetValue().(*raw_type.Dict)
 	if dict.Put(key, value) == nil {
 		return 1
@@ -40,6 +46,12 @@ func (hd *HashDict) HSet(key string, value string) int {
 }
 
 func (hd *HashDict) HGet(key string) (string, error) {
+	if hd.isListPack() {
+		if ret, ok := hd.listPackHGet(key); ok {
+			return ret, nil
+		}
+		return "", re.ErrNilValue
+	}
 	dict := hd.GetValue().(*raw_type.Dict)
 	if ret := dict.Get(key); ret == nil {
 		return "", re.ErrNilValue
@@ -49,6 +61,12 @@ func (hd *HashDict) HGet(key string) (string, error) {
 }
 
 func (hd *HashDict) HExists(key string) int {
+	if hd.isListPack() {
+		if hd.listPackFind(key) >= 0 {
+			return 1
+		}
+		return 0
+	}
 	dict := hd.GetValue().(*raw_type.Dict)
 	if dict.ContainsKey(key) {
 		return 1
@@ -57,8 +75,16 @@ func (hd *HashDict) HExists(key string) int {
 }
 
 func (hd *HashDict) HDel(keys []string) int {
-	dict := hd.GetValue().(*raw_type.Dict)
 	succCount := 0
+	if hd.isListPack() {
+		for _, key := range keys {
+			if hd.listPackHDel(key) {
+				succCount++
+			}
+		}
+		return succCount
+	}
+	dict := hd.GetValue().(*raw_type.Dict)
 	for _, key := range keys {
 		if dict.RemoveKey(key) != nil {
 			succCount++
@@ -68,11 +94,17 @@ func (hd *HashDict) HDel(keys []string) int {
 }
 
 func (hd *HashDict) HLen() int {
+	if hd.isListPack() {
+		return hd.GetValue().(*raw_type.ListPack).Len() / 2
+	}
 	dict := hd.GetValue().(*raw_type.Dict)
 	return dict.Size()
 }
 
 func (hd *HashDict) HGetAll() []string {
+	if hd.isListPack() {
+		return hd.GetValue().(*raw_type.ListPack).Entries()
+	}
 	dict := hd.GetValue().(*raw_type.Dict)
 	keyValues := dict.KeyValueSet()
 	ret := make([]string, 0)
@@ -84,10 +116,9 @@ func (hd *HashDict) HGetAll() []string {
 }
 
 func (hd *HashDict) HIncrBy(key string, increment string) (string, error) {
-	dict := hd.GetValue().(*raw_type.Dict)
 	originVal := "0"
-	if dict.Get(key) != nil {
-		originVal = dict.Get(key).(string)
+	if value, err := hd.HGet(key); err == nil {
+		originVal = value
 	}
 	var incrementInt, originValInt int64
 	var err error
@@ -99,15 +130,14 @@ func (hd *HashDict) HIncrBy(key string, increment string) (string, error) {
 	}
 	ret := incrementInt + originValInt
 	retStr := strconv.FormatInt(ret, 10)
-	dict.Put(key, retStr)
+	hd.HSet(key, retStr)
 	return retStr, nil
 }
 
 func (hd *HashDict) HIncrByFloat(key string, increment string) (string, error) {
-	dict := hd.GetValue().(*raw_type.Dict)
 	originVal := "0"
-	if dict.Get(key) != nil {
-		originVal = dict.Get(key).(string)
+	if value, err := hd.HGet(key); err == nil {
+		originVal = value
 	}
 	var incrementFloat, originValFloat float64
 	var err error
@@ -122,7 +152,7 @@ func (hd *HashDict) HIncrByFloat(key string, increment string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	dict.Put(key, retStr)
+	hd.HSet(key, retStr)
 	return retStr, nil
 }
 
@@ -131,11 +161,10 @@ func (hd *HashDict) HDebug() {
 }
 
 func (hd *HashDict) String() string {
-	dict := hd.GetValue().(*raw_type.Dict)
-	keyValues := dict.KeyValueSet()
-	msg := "current dict is"
-	for key, value := range keyValues {
-		msg += fmt.Sprintf("[%s=%s]", key, value)
+	keyValues := hd.HGetAll()
+	msg := "current dict(" + hd.GetEncoding() + ") is"
+	for i := 0; i < len(keyValues); i += 2 {
+		msg += fmt.Sprintf("[%s=%s]", keyValues[i], keyValues[i+1])
 	}
 	return msg
 }
@@ -0,0 +1,107 @@
+package encodings
+
+import (
+	"time"
+
+	"github.com/SwanSpouse/redis_go/raw_type"
+)
+
+/**
+哈希对象的编码:
+	field和value的个数不超过hash-max-listpack-entries, 并且长度都不超过hash-max-listpack-value的时候使用listpack编码,
+	field和value依次保存在listpack中: <field1> <value1> <field2> <value2> ...
+	任意一个条件不满足之后转换成hashtable编码, 转换之后不会再转换回listpack。
+*/
+var (
+	HashMaxListPackEntries = 128 /* hash-max-listpack-entries */
+	HashMaxListPackValue   = 64  /* hash-max-listpack-value */
+)
+
+// 创建listpack编码的哈希对象
+func NewHashListPack(ttl int) *HashDict {
+	var expireTime time.Time
+	if ttl > 0 {
+		expireTime = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+	return &HashDict{
+		RedisObject: RedisObject{
+			objectType: RedisTypeHash,
+			encoding:   RedisEncodingListPack,
+			ttl:        ttl,
+			value:      raw_type.NewListPack(),
+			lru:        initialLRU(),
+			refCount:   1,
+			expireTime: expireTime,
+		},
+	}
+}
+
+func (hd *HashDict) isListPack() bool {
+	return hd.GetEncoding() == RedisEncodingListPack
+}
+
+// 转换成hashtable编码
+func (hd *HashDict) convertToDict() {
+	lp := hd.GetValue().(*raw_type.ListPack)
+	dict := raw_type.NewDict()
+	for p := lp.First(); p >= 0; p = lp.Next(lp.Next(p)) {
+		dict.Put(lp.Get(p), lp.Get(lp.Next(p)))
+	}
+	hd.SetValue(dict)
+	hd.SetEncoding(RedisEncodingHT)
+}
+
+// 设置field之前检查是否需要转换成hashtable
+func (hd *HashDict) tryConvertListPack(key string, value string) {
+	if !hd.isListPack() {
+		return
+	}
+	if len(key) > HashMaxListPackValue || len(value) > HashMaxListPackValue {
+		hd.convertToDict()
+		return
+	}
+	if hd.listPackFind(key) < 0 && hd.HLen()+1 > HashMaxListPackEntries {
+		hd.convertToDict()
+	}
+}
+
+// 返回field在listpack中的位置, 不存在的时候返回-1
+func (hd *HashDict) listPackFind(key string) int {
+	lp := hd.GetValue().(*raw_type.ListPack)
+	for p := lp.First(); p >= 0; p = lp.Next(lp.Next(p)) {
+		if lp.Get(p) == key {
+			return p
+		}
+	}
+	return -1
+}
+
+func (hd *HashDict) listPackHSet(key string, value string) int {
+	lp := hd.GetValue().(*raw_type.ListPack)
+	if p := hd.listPackFind(key); p >= 0 {
+		lp.Replace(lp.Next(p), value)
+		return 0
+	}
+	lp.Append(key)
+	lp.Append(value)
+	return 1
+}
+
+func (hd *HashDict) listPackHGet(key string) (string, bool) {
+	lp := hd.GetValue().(*raw_type.ListPack)
+	if p := hd.listPackFind(key); p >= 0 {
+		return lp.Get(lp.Next(p)), true
+	}
+	return "", false
+}
+
+func (hd *HashDict) listPackHDel(key string) bool {
+	lp := hd.GetValue().(*raw_type.ListPack)
+	p := hd.listPackFind(key)
+	if p < 0 {
+		return false
+	}
+	// 依次删除field和value
+	lp.Delete(lp.Delete(p))
+	return true
+}
@@ -2,6 +2,7 @@ package encodings
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	re "github.com/SwanSpouse/redis_go/error"
@@ -28,15 +29,25 @@ func NewHasSet(ttl int) *HashSet {
 			encoding:   RedisEncodingHT,
 			ttl:        ttl,
 			value:      raw_type.NewDict(),
+			lru:        initialLRU(),
+			refCount:   1,
 			expireTime: expireTime,
 		},
 	}
 }
 
 func (hs *HashSet) SAdd(values []string) int {
-	set := hs.GetValue().(*raw_type.Dict)
 	succCount := 0
 	for _, value := range values {
+		if hs.isIntSet() {
+			if added, ok := hs.intSetAdd(value); ok {
+				if added {
+					succCount += 1
+				}
+				continue
+			}
+		}
+		set := hs.GetValue().(*raw_type.Dict)
 		if set.Put(value, RedisHashSetDefaultValueInDict) == nil {
 			succCount += 1
 		}
@@ -45,11 +56,20 @@ func (hs *HashSet) SAdd(values []string) int {
 }
 
 func (hs *HashSet) SCard() int {
+	if hs.isIntSet() {
+		return hs.GetValue().(*raw_type.IntSet).Len()
+	}
 	set := hs.GetValue().(*raw_type.Dict)
 	return set.Size()
 }
 
 func (hs *HashSet) SIsMember(value string) int {
+	if hs.isIntSet() {
+		if hs.intSetFind(value) {
+			return 1
+		}
+		return 0
+	}
 	set := hs.GetValue().(*raw_type.Dict)
 	if set.ContainsKey(value) {
 		return 1
@@ -58,6 +78,9 @@ func (hs *HashSet) SIsMember(value string) int {
 }
 
 func (hs *HashSet) SMembers() []string {
+	if hs.isIntSet() {
+		return hs.intSetMembers()
+	}
 	set := hs.GetValue().(*raw_type.Dict)
 	ret := make([]string, 0)
 	for item := range set.KeySet() {
@@ -67,6 +90,15 @@ func (hs *HashSet) SMembers() []string {
 }
 
 func (hs *HashSet) SPop() (string, error) {
+	if hs.isIntSet() {
+		is := hs.GetValue().(*raw_type.IntSet)
+		if is.Len() == 0 {
+			return "", re.ErrNilValue
+		}
+		value := is.Random()
+		is.Remove(value)
+		return strconv.FormatInt(value, 10), nil
+	}
 	set := hs.GetValue().(*raw_type.Dict)
 	if key := set.RandomKey(); key == nil {
 		return "", re.ErrNilValue
@@ -77,6 +109,13 @@ func (hs *HashSet) SPop() (string, error) {
 }
 
 func (hs *HashSet) SRandMember() (string, error) {
+	if hs.isIntSet() {
+		is := hs.GetValue().(*raw_type.IntSet)
+		if is.Len() == 0 {
+			return "", re.ErrNilValue
+		}
+		return strconv.FormatInt(is.Random(), 10), nil
+	}
 	set := hs.GetValue().(*raw_type.Dict)
 	if key := set.RandomKey(); key == nil {
 		return "", re.ErrNilValue
@@ -87,8 +126,16 @@ func (hs *HashSet) SRandMember() (string, error) {
 }
 
 func (hs *HashSet) SRem(values []string) int {
-	set := hs.GetValue().(*raw_type.Dict)
 	succCount := 0
+	if hs.isIntSet() {
+		for _, value := range values {
+			if hs.intSetRemove(value) {
+				succCount += 1
+			}
+		}
+		return succCount
+	}
+	set := hs.GetValue().(*raw_type.Dict)
 	for _, value := range values {
 		if set.RemoveKey(value) != nil {
 			succCount += 1
@@ -98,19 +145,16 @@ func (hs *HashSet) SRem(values []string) int {
 }
 
 func (hs *HashSet) SDebug() {
-	set := hs.GetValue().(*raw_type.Dict)
 	msg := ""
-	for key := range set.KeySet() {
-		msg += fmt.Sprintf("[%s]=>", key.(string))
+	for _, key := range hs.SMembers() {
+		msg += fmt.Sprintf("[%s]=>", key)
 	}
 	loggers.Info("Set Debug Info: %s", msg)
 }
 
 func (hs *HashSet) String() string {
-	set := hs.GetValue().(*raw_type.Dict)
-	keys := set.KeySet()
-	msg := "current set is"
-	for key := range keys {
+	msg := "current set(" + hs.GetEncoding() + ") is"
+	for _, key := range hs.SMembers() {
 		msg += fmt.Sprintf("[%s]", key)
 	}
 	return msg
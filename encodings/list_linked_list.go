@@ -28,6 +28,8 @@ func NewListLinkedList(ttl int) *ListLinkedList {
 			encoding:   RedisEncodingLinkedList,
 			ttl:        ttl,
 			value:      raw_type.ListCreate(),
+			lru:        initialLRU(),
+			refCount:   1,
 			expireTime: expireTime,
 		},
 	}
@@ -53,18 +55,30 @@ func (ll *ListLinkedList) RPush(values []string) int {
 	return list.ListLength()
 }
 
-func (ll *ListLinkedList) LPop() string {
+// 从表头最多弹出count个元素
+func (ll *ListLinkedList) LPop(count int) []string {
 	list := ll.GetValue().(*raw_type.List)
-	value := list.ListFirst().NodeValue()
-	ll.SetValue(list.ListRemoveNode(list.ListFirst()))
-	return value.(string)
+	ret := make([]string, 0)
+	for len(ret) < count && list.ListLength() > 0 {
+		node := list.ListFirst()
+		ret = append(ret, node.NodeValue().(string))
+		list = list.ListRemoveNode(node)
+	}
+	ll.SetValue(list)
+	return ret
 }
 
-func (ll *ListLinkedList) RPop() string {
+// 从表尾最多弹出count个元素
+func (ll *ListLinkedList) RPop(count int) []string {
 	list := ll.GetValue().(*raw_type.List)
-	value := list.ListLast().NodeValue()
-	ll.SetValue(list.ListRemoveNode(list.ListLast()))
-	return value.(string)
+	ret := make([]string, 0)
+	for len(ret) < count && list.ListLength() > 0 {
+		node := list.ListLast()
+		ret = append(ret, node.NodeValue().(string))
+		list = list.ListRemoveNode(node)
+	}
+	ll.SetValue(list)
+	return ret
 }
 
 func (ll *ListLinkedList) LIndex(index int) (string, error) {
@@ -131,20 +145,36 @@ func (ll *ListLinkedList) LRem(count int, key string) int {
 	return succCount
 }
 
-//TODO lmj
+// 只保留[start, stop]之间的元素, start和stop可以是负数, 表示从表尾开始计算
 func (ll *ListLinkedList) LTrim(start int, stop int) error {
-	//list := ll.GetValue().(*raw_type.List)
-	//
-	//if start >= list.ListLength() || -start >= list.ListLength() {
-	//	return re.ErrNotIntegerOrOutOfRange
-	//}
-	//if stop >= list.ListLength() {
-	//	stop = list.ListLength() - 1
-	//} else if -stop >= list.ListLength() {
-	//	stop = -(list.ListLength() - 1)
-	//}
-	//startNode := list.ListIndex(start)
-	//stopNode := list.ListIndex(stop)
+	list := ll.GetValue().(*raw_type.List)
+	length := list.ListLength()
+	if start < 0 {
+		start = length + start
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	if start < 0 {
+		start = 0
+	}
+	var leftTrim, rightTrim int
+	if start > stop || start >= length {
+		// 范围为空的时候删除所有的元素
+		leftTrim, rightTrim = length, 0
+	} else {
+		if stop >= length {
+			stop = length - 1
+		}
+		leftTrim, rightTrim = start, length-stop-1
+	}
+	for i := 0; i < leftTrim; i++ {
+		list = list.ListRemoveNode(list.ListFirst())
+	}
+	for i := 0; i < rightTrim; i++ {
+		list = list.ListRemoveNode(list.ListLast())
+	}
+	ll.SetValue(list)
 	return nil
 }
 
@@ -179,6 +209,41 @@ func (ll *ListLinkedList) LRange(start int, stop int) []string {
 	return ret
 }
 
+/**
+返回和element相等的元素的下标, 下标总是从表头开始计算。
+rank > 0 : 从表头开始向表尾搜索, 跳过前rank-1个匹配的元素。
+rank < 0 : 从表尾开始向表头搜索, 跳过前-rank-1个匹配的元素。
+count为0的时候返回所有匹配的元素, maxLen为0的时候不限制比较的元素的个数。
+*/
+func (ll *ListLinkedList) LPos(element string, rank int, count int, maxLen int) []int {
+	list := ll.GetValue().(*raw_type.List)
+	ret := make([]int, 0)
+	skip := rank - 1
+	node, index := list.ListFirst(), 0
+	if rank < 0 {
+		skip = -rank - 1
+		node, index = list.ListLast(), list.ListLength()-1
+	}
+	for compared := 0; node != nil && (maxLen == 0 || compared < maxLen); compared++ {
+		if node.NodeValue() == element {
+			if skip > 0 {
+				skip -= 1
+			} else {
+				ret = append(ret, index)
+				if count != 0 && len(ret) >= count {
+					break
+				}
+			}
+		}
+		if rank > 0 {
+			node, index = node.NodeNext(), index+1
+		} else {
+			node, index = node.NodePrev(), index-1
+		}
+	}
+	return ret
+}
+
 func (ll *ListLinkedList) String() string {
 	ret := "CURRENT_LIST:"
 	if linkedList, ok := ll.GetValue().(*raw_type.List); !ok {
@@ -203,6 +268,15 @@ func (ll *ListLinkedList) GetAllMembers() []string {
 	}
 }
 
+// 按照list-max-listpack-size的限制把所有的元素分成多个quicklist节点
+func (ll *ListLinkedList) QuickListNodes() [][]string {
+	ql := raw_type.NewQuickList(ListMaxListPackSize, 0)
+	for _, member := range ll.GetAllMembers() {
+		ql.PushTail(member)
+	}
+	return ql.NodeEntries()
+}
+
 func (ll *ListLinkedList) Debug() {
 	loggers.Info(ll.String())
 }
@@ -0,0 +1,367 @@
+package encodings
+
+import (
+	"time"
+
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/raw_type"
+)
+
+/**
+列表对象的编码:
+	元素比较少的时候使用listpack编码, 所有的元素保存在一块连续的内存中;
+	listpack超过list-max-listpack-size的限制之后转换成quicklist编码, 由多个listpack节点组成, 中间的节点可以被压缩;
+	quicklist只剩下一个节点, 并且不到限制的一半的时候再转换回listpack, 避免在边界上反复转换。
+*/
+var (
+	ListMaxListPackSize = raw_type.QuickListDefaultFill     /* list-max-listpack-size */
+	ListCompressDepth   = raw_type.QuickListDefaultCompress /* list-compress-depth */
+)
+
+type ListQuickList struct {
+	RedisObject
+}
+
+// listpack和quicklist的迭代器
+type listIterator interface {
+	Next() (string, bool)
+	Delete()
+	Release()
+}
+
+func NewListQuickList(ttl int) *ListQuickList {
+	var expireTime time.Time
+	if ttl > 0 {
+		expireTime = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+	return &ListQuickList{
+		RedisObject: RedisObject{
+			objectType: RedisTypeList,
+			encoding:   RedisEncodingListPack,
+			ttl:        ttl,
+			value:      raw_type.NewListPack(),
+			lru:        initialLRU(),
+			refCount:   1,
+			expireTime: expireTime,
+		},
+	}
+}
+
+func (lq *ListQuickList) isListPack() bool {
+	return lq.GetEncoding() == RedisEncodingListPack
+}
+
+func (lq *ListQuickList) iterator(index int, forward bool) listIterator {
+	if lq.isListPack() {
+		return lq.GetValue().(*raw_type.ListPack).Iterator(index, forward)
+	}
+	return lq.GetValue().(*raw_type.QuickList).Iterator(index, forward)
+}
+
+// 添加values之后listpack会超过限制的时候转换成quicklist
+func (lq *ListQuickList) tryConvertListPack(values []string) {
+	if !lq.isListPack() {
+		return
+	}
+	lp := lq.GetValue().(*raw_type.ListPack)
+	size := lp.Bytes()
+	for _, value := range values {
+		size += raw_type.ListPackEntrySize(value)
+	}
+	if raw_type.QuickListNodeExceedsLimit(ListMaxListPackSize, size, lp.Len()+len(values)) {
+		ql := raw_type.NewQuickList(ListMaxListPackSize, ListCompressDepth)
+		ql.AppendListPack(lp)
+		lq.SetValue(ql)
+		lq.SetEncoding(RedisEncodingQuickList)
+	}
+}
+
+// 删除元素之后, quicklist只有一个节点并且不到限制的一半的时候转换回listpack
+func (lq *ListQuickList) tryConvertQuickList() {
+	if lq.isListPack() {
+		return
+	}
+	ql := lq.GetValue().(*raw_type.QuickList)
+	if ql.NodeCount() > 1 {
+		return
+	}
+	// 先用节点中记录的大小判断, 确定要转换的时候才取出listpack
+	sizeLimit, countLimit := raw_type.QuickListNodeLimit(ListMaxListPackSize)
+	if size, count := ql.HeadNodeStat(); size > sizeLimit/2 || count > countLimit/2 {
+		return
+	}
+	lq.SetValue(ql.HeadListPack())
+	lq.SetEncoding(RedisEncodingListPack)
+}
+
+// head为true的时候从表头添加, 否则从表尾添加
+func (lq *ListQuickList) push(values []string, head bool) int {
+	lq.tryConvertListPack(values)
+	for _, value := range values {
+		if lq.isListPack() {
+			lp := lq.GetValue().(*raw_type.ListPack)
+			if head {
+				lp.Prepend(value)
+			} else {
+				lp.Append(value)
+			}
+		} else {
+			ql := lq.GetValue().(*raw_type.QuickList)
+			if head {
+				ql.PushHead(value)
+			} else {
+				ql.PushTail(value)
+			}
+		}
+	}
+	return lq.LLen()
+}
+
+func (lq *ListQuickList) LPush(values []string) int {
+	return lq.push(values, true)
+}
+
+func (lq *ListQuickList) RPush(values []string) int {
+	return lq.push(values, false)
+}
+
+// 从头部或者尾部最多弹出count个元素
+func (lq *ListQuickList) pop(count int, head bool) []string {
+	ret := make([]string, 0)
+	it := lq.iterator(0, true)
+	if !head {
+		it = lq.iterator(-1, false)
+	}
+	for len(ret) < count {
+		value, ok := it.Next()
+		if !ok {
+			break
+		}
+		ret = append(ret, value)
+		it.Delete()
+	}
+	it.Release()
+	lq.tryConvertQuickList()
+	return ret
+}
+
+func (lq *ListQuickList) LPop(count int) []string {
+	return lq.pop(count, true)
+}
+
+func (lq *ListQuickList) RPop(count int) []string {
+	return lq.pop(count, false)
+}
+
+func (lq *ListQuickList) LIndex(index int) (string, error) {
+	it := lq.iterator(index, true)
+	defer it.Release()
+	if value, ok := it.Next(); ok {
+		return value, nil
+	}
+	return "", re.ErrNilValue
+}
+
+func (lq *ListQuickList) LLen() int {
+	if lq.isListPack() {
+		return lq.GetValue().(*raw_type.ListPack).Len()
+	}
+	return lq.GetValue().(*raw_type.QuickList).Len()
+}
+
+// 在第一个和val相等的元素之前或者之后插入values, 没有找到val的时候返回-1
+func (lq *ListQuickList) LInsert(insertFlag int, val string, values ...string) (int, error) {
+	index := -1
+	it := lq.iterator(0, true)
+	for i := 0; ; i++ {
+		value, ok := it.Next()
+		if !ok {
+			break
+		}
+		if value == val {
+			index = i
+			break
+		}
+	}
+	it.Release()
+	if index < 0 {
+		return -1, nil
+	}
+	lq.tryConvertListPack(values)
+	after := insertFlag == RedisTypeListInsertAfter
+	for i, value := range values {
+		// 插入之后的元素排在前一个插入的元素之后
+		pos := index + i
+		if lq.isListPack() {
+			lp := lq.GetValue().(*raw_type.ListPack)
+			lp.Insert(lp.Seek(pos), value, after)
+		} else {
+			lq.GetValue().(*raw_type.QuickList).Insert(pos, value, after)
+		}
+	}
+	return lq.LLen(), nil
+}
+
+/**
+count > 0 : 从表头开始向表尾搜索，移除与 value 相等的元素，数量为 count 。
+count < 0 : 从表尾开始向表头搜索，移除与 value 相等的元素，数量为 count 的绝对值。
+count = 0 : 移除表中所有与 value 相等的值。
+*/
+func (lq *ListQuickList) LRem(count int, key string) int {
+	it := lq.iterator(0, true)
+	if count < 0 {
+		it = lq.iterator(-1, false)
+		count = -count
+	}
+	removed := 0
+	for count == 0 || removed < count {
+		value, ok := it.Next()
+		if !ok {
+			break
+		}
+		if value == key {
+			it.Delete()
+			removed++
+		}
+	}
+	it.Release()
+	lq.tryConvertQuickList()
+	return removed
+}
+
+// 只保留[start, stop]之间的元素, start和stop可以是负数, 表示从表尾开始计算
+func (lq *ListQuickList) LTrim(start int, stop int) error {
+	length := lq.LLen()
+	if start < 0 {
+		start = length + start
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	if start < 0 {
+		start = 0
+	}
+	var leftTrim, rightTrim int
+	if start > stop || start >= length {
+		// 范围为空的时候删除所有的元素
+		leftTrim, rightTrim = length, 0
+	} else {
+		if stop >= length {
+			stop = length - 1
+		}
+		leftTrim, rightTrim = start, length-stop-1
+	}
+	if lq.isListPack() {
+		lp := lq.GetValue().(*raw_type.ListPack)
+		lp.DeleteRange(0, leftTrim)
+		lp.DeleteRange(-rightTrim, rightTrim)
+	} else {
+		ql := lq.GetValue().(*raw_type.QuickList)
+		ql.DelRange(0, leftTrim)
+		ql.DelRange(-rightTrim, rightTrim)
+	}
+	lq.tryConvertQuickList()
+	return nil
+}
+
+func (lq *ListQuickList) LSet(index int, val string) error {
+	length := lq.LLen()
+	if index >= length || -index > length {
+		return re.ErrNotIntegerOrOutOfRange
+	}
+	lq.tryConvertListPack([]string{val})
+	if lq.isListPack() {
+		lp := lq.GetValue().(*raw_type.ListPack)
+		lp.Replace(lp.Seek(index), val)
+	} else {
+		lq.GetValue().(*raw_type.QuickList).Replace(index, val)
+	}
+	return nil
+}
+
+func (lq *ListQuickList) LRange(start int, stop int) []string {
+	ret := make([]string, 0)
+	length := lq.LLen()
+	if start < 0 {
+		start = length + start
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= length {
+		return ret
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	it := lq.iterator(start, true)
+	for i := start; i <= stop; i++ {
+		value, _ := it.Next()
+		ret = append(ret, value)
+	}
+	it.Release()
+	return ret
+}
+
+/**
+返回和element相等的元素的下标, 下标总是从表头开始计算。
+rank > 0 : 从表头开始向表尾搜索, 跳过前rank-1个匹配的元素。
+rank < 0 : 从表尾开始向表头搜索, 跳过前-rank-1个匹配的元素。
+count为0的时候返回所有匹配的元素, maxLen为0的时候不限制比较的元素的个数。
+*/
+func (lq *ListQuickList) LPos(element string, rank int, count int, maxLen int) []int {
+	ret := make([]int, 0)
+	skip, index, step := rank-1, 0, 1
+	it := lq.iterator(0, true)
+	if rank < 0 {
+		skip, index, step = -rank-1, lq.LLen()-1, -1
+		it = lq.iterator(-1, false)
+	}
+	defer it.Release()
+	for compared := 0; maxLen == 0 || compared < maxLen; compared++ {
+		value, ok := it.Next()
+		if !ok {
+			break
+		}
+		if value == element {
+			if skip > 0 {
+				skip -= 1
+			} else {
+				ret = append(ret, index)
+				if count != 0 && len(ret) >= count {
+					break
+				}
+			}
+		}
+		index += step
+	}
+	return ret
+}
+
+func (lq *ListQuickList) GetAllMembers() []string {
+	return lq.LRange(0, -1)
+}
+
+// 按照quicklist的节点返回所有的元素, listpack编码的时候只有一个节点
+func (lq *ListQuickList) QuickListNodes() [][]string {
+	if lq.isListPack() {
+		return [][]string{lq.GetValue().(*raw_type.ListPack).Entries()}
+	}
+	return lq.GetValue().(*raw_type.QuickList).NodeEntries()
+}
+
+func (lq *ListQuickList) String() string {
+	ret := "CURRENT_LIST(" + lq.GetEncoding() + "):"
+	for _, member := range lq.GetAllMembers() {
+		ret += "{" + member + "}"
+	}
+	return ret
+}
+
+func (lq *ListQuickList) Debug() {
+	loggers.Info(lq.String())
+}
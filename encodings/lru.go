@@ -0,0 +1,107 @@
+package encodings
+
+import (
+	"math/rand"
+	"time"
+)
+
+/**
+RedisObject.lru 字段在不同的maxmemory-policy下有不同的含义:
+	LRU: 低24位保存对象最后一次被访问时的LRU时钟
+	LFU: 高16位保存最后一次递减计数的时间(分钟), 低8位保存对数访问计数
+*/
+const (
+	LRUClockMax        = 1<<24 - 1 /* Max value of obj->lru */
+	LRUClockResolution = 1000      /* LRU clock resolution in ms */
+
+	LFUInitVal    = 5   /* 新对象的初始访问计数, 避免新对象被马上淘汰 */
+	LFUCounterMax = 255 /* 8 bit 访问计数的最大值 */
+)
+
+var (
+	// 由server根据maxmemory-policy设置，决定访问对象时更新LRU时钟还是LFU计数
+	MaxMemoryPolicyLFU bool
+	LFULogFactor       = 10
+	LFUDecayTime       = 1
+)
+
+// 返回当前的LRU时钟
+func GetLRUClock() int {
+	return int((time.Now().UnixNano() / int64(time.Millisecond) / LRUClockResolution) & LRUClockMax)
+}
+
+// 返回新对象lru字段的初始值
+func initialLRU() int {
+	if MaxMemoryPolicyLFU {
+		return (lfuGetTimeInMinutes() << 8) | LFUInitVal
+	}
+	return GetLRUClock()
+}
+
+// 返回当前时间的分钟数，只保留低16位
+func lfuGetTimeInMinutes() int {
+	return int((time.Now().Unix() / 60) & 65535)
+}
+
+// 返回从ldt到现在经过的分钟数，考虑了16位时间的回绕
+func lfuTimeElapsed(ldt int) int {
+	now := lfuGetTimeInMinutes()
+	if now >= ldt {
+		return now - ldt
+	}
+	return 65535 - ldt + now
+}
+
+// 以对数的方式增加访问计数，计数越大，增加的概率越小
+func lfuLogIncr(counter int) int {
+	if counter == LFUCounterMax {
+		return LFUCounterMax
+	}
+	baseVal := float64(counter - LFUInitVal)
+	if baseVal < 0 {
+		baseVal = 0
+	}
+	p := 1.0 / (baseVal*float64(LFULogFactor) + 1)
+	if rand.Float64() < p {
+		counter++
+	}
+	return counter
+}
+
+// 对象被访问时更新lru字段
+func (obj *RedisObject) UpdateLRU() {
+	if MaxMemoryPolicyLFU {
+		counter := obj.LFUDecrAndReturn()
+		counter = lfuLogIncr(counter)
+		obj.lru = (lfuGetTimeInMinutes() << 8) | counter
+	} else {
+		obj.lru = GetLRUClock()
+	}
+}
+
+// 按照经过的时间对访问计数进行衰减，返回衰减后的计数，并不修改对象
+func (obj *RedisObject) LFUDecrAndReturn() int {
+	ldt := obj.lru >> 8
+	counter := obj.lru & 255
+	var numPeriods int
+	if LFUDecayTime > 0 {
+		numPeriods = lfuTimeElapsed(ldt) / LFUDecayTime
+	}
+	if numPeriods > 0 {
+		if numPeriods > counter {
+			counter = 0
+		} else {
+			counter -= numPeriods
+		}
+	}
+	return counter
+}
+
+// 估算对象的空闲时间, 单位毫秒
+func (obj *RedisObject) EstimateIdleTime() int64 {
+	lruClock := GetLRUClock()
+	if lruClock >= obj.lru {
+		return int64(lruClock-obj.lru) * LRUClockResolution
+	}
+	return int64(lruClock+(LRUClockMax-obj.lru)) * LRUClockResolution
+}
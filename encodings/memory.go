@@ -0,0 +1,132 @@
+package encodings
+
+import (
+	"unsafe"
+
+	"github.com/SwanSpouse/redis_go/raw_type"
+)
+
+/**
+对象内存占用的估算:
+	这里并不追求精确的内存占用，而是按照各种数据结构在64位机器上的大小进行估算。
+	对于元素较多的对象，只采样samples个元素计算平均大小，再乘以元素个数得到估算值；samples <= 0时遍历所有元素。
+*/
+const (
+	ObjectMemoryDefaultSamples = 5 /* MEMORY USAGE 默认的采样个数 */
+
+	StringHeaderSize  = int64(unsafe.Sizeof(""))                   /* string 头部大小 */
+	DictEntrySize     = 48                                         /* raw_type.dictEntry 的大小: Key(16) + Value(16) + next(8) + hash(8) */
+	DictOverhead      = 512                                        /* raw_type.Dict 中segment等结构的固定开销 */
+	ListNodeSize      = int64(unsafe.Sizeof(raw_type.ListNode{}))  /* 链表节点的大小 */
+	ListOverhead      = int64(unsafe.Sizeof(raw_type.List{}))      /* 链表的固定开销 */
+	ListPackOverhead  = int64(unsafe.Sizeof(raw_type.ListPack{}))  /* listpack的固定开销 */
+	QuickListNodeSize = 64                                         /* quicklist节点的大小: prev(8) + next(8) + lp(8) + compressed(24) + count(8) + size(8) */
+	QuickListOverhead = int64(unsafe.Sizeof(raw_type.QuickList{})) /* quicklist的固定开销 */
+	IntSetOverhead    = int64(unsafe.Sizeof(raw_type.IntSet{}))    /* intset的固定开销 */
+	SkipNodeSize      = int64(unsafe.Sizeof(raw_type.SkipNode{}))  /* 跳跃表节点的大小 */
+	SkipLevelSize     = int64(unsafe.Sizeof(raw_type.SkipLevel{})) /* 跳跃表节点每一层的大小 */
+	SkipListOverhead  = int64(unsafe.Sizeof(raw_type.SkipList{}))  /* 跳跃表的固定开销 */
+	MapEntrySize      = 8 + StringHeaderSize                       /* SortedSet.dict 中每个元素的开销 */
+	objectMemoryEmpty = int64(unsafe.Sizeof(RedisObject{})) + 8    /* 对象本身以及外层结构体的开销 */
+	int64MemorySize   = int64(unsafe.Sizeof(int64(0))) + int64(unsafe.Sizeof(interface{}(nil)))
+)
+
+// 估算字符串占用的内存
+func StringMemory(s string) int64 {
+	return StringHeaderSize + int64(len(s))
+}
+
+// 根据采样结果估算所有元素占用的内存
+func sampledMemory(total int, sampled int, sampledSize int64) int64 {
+	if sampled == 0 || sampled >= total {
+		return sampledSize
+	}
+	return sampledSize / int64(sampled) * int64(total)
+}
+
+// 估算dict中所有元素占用的内存, valueSize 用于计算每个value的大小
+func dictMemory(dict *raw_type.Dict, samples int, valueSize func(interface{}) int64) int64 {
+	var keyValues map[interface{}]interface{}
+	if samples <= 0 {
+		keyValues = dict.KeyValueSet()
+	} else {
+		keyValues = dict.GetSomeKeys(samples)
+	}
+	var size int64
+	for key, value := range keyValues {
+		size += DictEntrySize + StringMemory(key.(string)) + valueSize(value)
+	}
+	return DictOverhead + sampledMemory(dict.Size(), len(keyValues), size)
+}
+
+func (sr *StringRaw) MemoryUsage(samples int) int64 {
+	return objectMemoryEmpty + StringMemory(sr.GetValue().(string))
+}
+
+func (si *StringInt) MemoryUsage(samples int) int64 {
+	return objectMemoryEmpty + int64MemorySize
+}
+
+func (se *StringEmb) MemoryUsage(samples int) int64 {
+	return objectMemoryEmpty + StringMemory(se.GetValue().(string))
+}
+
+func (ll *ListLinkedList) MemoryUsage(samples int) int64 {
+	list := ll.GetValue().(*raw_type.List)
+	var size int64
+	sampled := 0
+	for node := list.ListFirst(); node != nil && (samples <= 0 || sampled < samples); node = node.NodeNext() {
+		size += ListNodeSize + StringMemory(node.NodeValue().(string))
+		sampled++
+	}
+	return objectMemoryEmpty + ListOverhead + sampledMemory(list.ListLength(), sampled, size)
+}
+
+// listpack和quicklist中的元素保存在连续的内存中, 直接按照字节数计算, 不需要采样
+func (lq *ListQuickList) MemoryUsage(samples int) int64 {
+	if lq.isListPack() {
+		return objectMemoryEmpty + ListPackOverhead + int64(lq.GetValue().(*raw_type.ListPack).Bytes())
+	}
+	ql := lq.GetValue().(*raw_type.QuickList)
+	nodes := int64(ql.NodeCount()) * (QuickListNodeSize + ListPackOverhead)
+	return objectMemoryEmpty + QuickListOverhead + nodes + int64(ql.Bytes())
+}
+
+func (hd *HashDict) MemoryUsage(samples int) int64 {
+	if hd.isListPack() {
+		return objectMemoryEmpty + ListPackOverhead + int64(hd.GetValue().(*raw_type.ListPack).Bytes())
+	}
+	dict := hd.GetValue().(*raw_type.Dict)
+	return objectMemoryEmpty + dictMemory(dict, samples, func(value interface{}) int64 {
+		return StringMemory(value.(string))
+	})
+}
+
+func (hs *HashSet) MemoryUsage(samples int) int64 {
+	if hs.isIntSet() {
+		return objectMemoryEmpty + IntSetOverhead + int64(hs.GetValue().(*raw_type.IntSet).Bytes())
+	}
+	set := hs.GetValue().(*raw_type.Dict)
+	return objectMemoryEmpty + dictMemory(set, samples, func(value interface{}) int64 {
+		// set中的value都是同一个bool值
+		return 0
+	})
+}
+
+func (ss *SortedSet) MemoryUsage(samples int) int64 {
+	if ss.isListPack() {
+		return objectMemoryEmpty + ListPackOverhead + int64(ss.GetValue().(*raw_type.ListPack).Bytes())
+	}
+	skipList := ss.GetValue().(*raw_type.SkipList)
+	var size int64
+	sampled := 0
+	for key, node := range ss.dict {
+		if samples > 0 && sampled >= samples {
+			break
+		}
+		// 跳跃表节点和dict中的元素共享同一个字符串
+		size += SkipNodeSize + int64(node.GetLevel())*SkipLevelSize + StringMemory(key) + MapEntrySize
+		sampled++
+	}
+	return objectMemoryEmpty + SkipListOverhead + sampledMemory(skipList.Length(), sampled, size)
+}
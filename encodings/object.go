@@ -9,13 +9,14 @@ const (
 	RedisTypeString  ->  RedisEncodingInt	 	: 使用整数值实现的字符串对象
 	RedisTypeString  ->  RedisEncodingEmbStr        : 使用embstr编码的简单动态字符串实现的字符串对象
 	RedisTypeString  ->  RedisEncodingRaw		: 使用简单动态字符串实现的字符串对象
-	RedisTypeList    ->  RedisEncodingZipList	: 使用压缩列表实现的列表对象
+	RedisTypeList    ->  RedisEncodingListPack	: 使用listpack实现的列表对象
+	RedisTypeList    ->  RedisEncodingQuickList	: 使用由listpack组成的双端链表实现的列表对象
 	RedisTypeList    ->  RedisEncodingLinkedList	: 使用双端链表实现的列表对象
-	RedisTypeHash    ->  RedisEncodingZipList	: 使用压缩链表实现的哈希对象
+	RedisTypeHash    ->  RedisEncodingListPack	: 使用listpack实现的哈希对象
 	RedisTypeHash    ->  RedisEncodingHT		: 使用字典实现的哈希对象
 	RedisTypeSet     ->  RedisEncodingIntSet	: 使用整数集合实现的集合对象
 	RedisTypeSet     ->  RedisEncodingHT		: 使用字典实现的集合对象
-	RedisTypeZSet    ->  RedisEncodingZipList	: 使用压缩链表实现的有序集合对象
+	RedisTypeZSet    ->  RedisEncodingListPack	: 使用listpack实现的有序集合对象
 	RedisTypeZSet    ->  RedisEncodingSkipList	: 使用跳跃表和字典实现的有序集合对象
 	*/
 
@@ -35,6 +36,8 @@ const (
 	RedisEncodingZipList    = "ziplist"
 	RedisEncodingIntSet     = "intset"
 	RedisEncodingSkipList   = "skiplist"
+	RedisEncodingListPack   = "listpack"
+	RedisEncodingQuickList  = "quicklist"
 )
 
 type RedisObject struct {
@@ -44,6 +47,7 @@ type RedisObject struct {
 	refCount   int         // 引用计数
 	ttl        int         // ttl
 	expireTime time.Time   // 过期时间
+	memory     int64       // 在数据库中统计的内存占用
 	value      interface{} // 指向的对象
 }
 
@@ -75,16 +79,25 @@ func (obj *RedisObject) GetRefCount() int {
 	return obj.refCount
 }
 
+// 共享对象的引用计数固定为RedisSharedRefCount, 不会被修改
 func (obj *RedisObject) IncrRefCount() int {
-	obj.refCount += 1
+	if obj.refCount != RedisSharedRefCount {
+		obj.refCount += 1
+	}
 	return obj.refCount
 }
 
 func (obj *RedisObject) DecrRefCount() int {
-	obj.refCount -= 1
+	if obj.refCount != RedisSharedRefCount && obj.refCount > 0 {
+		obj.refCount -= 1
+	}
 	return obj.refCount
 }
 
+func (obj *RedisObject) IsShared() bool {
+	return obj.refCount == RedisSharedRefCount
+}
+
 func (obj *RedisObject) GetTTL() int {
 	return obj.ttl
 }
@@ -101,6 +114,22 @@ func (obj *RedisObject) SetValue(value interface{}) {
 	obj.value = value
 }
 
+func (obj *RedisObject) GetMemory() int64 {
+	return obj.memory
+}
+
+func (obj *RedisObject) SetMemory(memory int64) {
+	obj.memory = memory
+}
+
+func (obj *RedisObject) GetExpireTime() time.Time {
+	return obj.expireTime
+}
+
+func (obj *RedisObject) SetExpireTime(expireTime time.Time) {
+	obj.expireTime = expireTime
+}
+
 func (obj *RedisObject) IsExpired() bool {
 	// 如果过期时间是有效值，并且当前时间在过期时间之后，说明已经过期。
 	if !obj.expireTime.IsZero() && time.Now().After(obj.expireTime) {
@@ -0,0 +1,89 @@
+package encodings
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/raw_type"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/**
+集合对象的编码:
+	所有的元素都是整数, 并且元素个数不超过set-max-intset-entries的时候使用intset编码;
+	添加第一个不是整数的元素, 或者元素个数超过限制之后转换成hashtable编码, 转换之后不会再转换回intset。
+*/
+var (
+	SetMaxIntSetEntries = 512 /* set-max-intset-entries */
+)
+
+// 创建intset编码的集合对象
+func NewSetIntSet(ttl int) *HashSet {
+	var expireTime time.Time
+	if ttl > 0 {
+		expireTime = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+	return &HashSet{
+		RedisObject: RedisObject{
+			objectType: RedisTypeSet,
+			encoding:   RedisEncodingIntSet,
+			ttl:        ttl,
+			value:      raw_type.NewIntSet(),
+			lru:        initialLRU(),
+			refCount:   1,
+			expireTime: expireTime,
+		},
+	}
+}
+
+func (hs *HashSet) isIntSet() bool {
+	return hs.GetEncoding() == RedisEncodingIntSet
+}
+
+// 转换成hashtable编码
+func (hs *HashSet) convertToDict() {
+	is := hs.GetValue().(*raw_type.IntSet)
+	set := raw_type.NewDict()
+	for i := 0; i < is.Len(); i++ {
+		set.Put(strconv.FormatInt(is.Get(i), 10), RedisHashSetDefaultValueInDict)
+	}
+	hs.SetValue(set)
+	hs.SetEncoding(RedisEncodingHT)
+}
+
+// 向intset中添加value, value不是整数或者超过元素个数的限制的时候转换成hashtable, ok返回false, 需要再添加到hashtable中
+func (hs *HashSet) intSetAdd(value string) (added bool, ok bool) {
+	is := hs.GetValue().(*raw_type.IntSet)
+	n, isInt := util.StringToInt64(value)
+	if !isInt {
+		hs.convertToDict()
+		return false, false
+	}
+	if is.Find(n) {
+		return false, true
+	}
+	if is.Len()+1 > SetMaxIntSetEntries {
+		hs.convertToDict()
+		return false, false
+	}
+	return is.Add(n), true
+}
+
+func (hs *HashSet) intSetFind(value string) bool {
+	n, isInt := util.StringToInt64(value)
+	return isInt && hs.GetValue().(*raw_type.IntSet).Find(n)
+}
+
+func (hs *HashSet) intSetRemove(value string) bool {
+	n, isInt := util.StringToInt64(value)
+	return isInt && hs.GetValue().(*raw_type.IntSet).Remove(n)
+}
+
+func (hs *HashSet) intSetMembers() []string {
+	is := hs.GetValue().(*raw_type.IntSet)
+	ret := make([]string, 0, is.Len())
+	for i := 0; i < is.Len(); i++ {
+		ret = append(ret, strconv.FormatInt(is.Get(i), 10))
+	}
+	return ret
+}
@@ -26,6 +26,8 @@ func NewSortedSet(ttl int) *SortedSet {
 			encoding:   RedisEncodingSkipList,
 			ttl:        ttl,
 			value:      raw_type.NewSkipList(),
+			lru:        initialLRU(),
+			refCount:   1,
 			expireTime: expireTime,
 		},
 		dict: make(map[string]*raw_type.SkipNode),
@@ -42,6 +44,16 @@ func (ss *SortedSet) ZAdd(inputs []string) (int, error) {
 			return 0, re.ErrValueIsNotFloat
 		}
 	}
+	if ss.isListPack() {
+		scores := make([]float64, 0, len(inputs)/2)
+		members := make([]string, 0, len(inputs)/2)
+		for i := 0; i < len(inputs); i += 2 {
+			score, _ := strconv.ParseFloat(inputs[i], 64)
+			scores = append(scores, score)
+			members = append(members, inputs[i+1])
+		}
+		return ss.listPackZAdd(scores, members), nil
+	}
 	skipList := ss.GetValue().(*raw_type.SkipList)
 	count := 0
 	for i := 0; i < len(inputs); i += 2 {
@@ -49,7 +61,9 @@ func (ss *SortedSet) ZAdd(inputs []string) (int, error) {
 		key := inputs[i+1]
 		if obj, exists := ss.dict[key]; exists {
 			if obj.GetScore() != score {
-				obj.SetScore(score)
+				// score变化之后需要重新插入, 保证跳跃表有序
+				skipList.Delete(key, obj.GetScore())
+				ss.dict[key] = skipList.Insert(key, score)
 				count += 1
 			}
 		} else {
@@ -62,6 +76,9 @@ func (ss *SortedSet) ZAdd(inputs []string) (int, error) {
 }
 
 func (ss *SortedSet) ZCard() int {
+	if ss.isListPack() {
+		return ss.GetValue().(*raw_type.ListPack).Len() / 2
+	}
 	skipList := ss.GetValue().(*raw_type.SkipList)
 	return skipList.Length()
 }
@@ -72,6 +89,10 @@ func (ss *SortedSet) ZCount(lower, upper string) (int, error) {
 	if err1 != nil || err2 != nil {
 		return 0, re.ErrValueIsNotFloat
 	}
+	if ss.isListPack() {
+		start, end := zsetScoreRange(ss.listPackEntries(), fLower, fUpper)
+		return end - start, nil
+	}
 	skipList := ss.GetValue().(*raw_type.SkipList)
 
 	firstNode := skipList.FirstInRange(raw_type.RangeSpec{
@@ -99,6 +120,9 @@ func (ss *SortedSet) ZIncrBy(key string, increment string) (float64, error) {
 	if err != nil {
 		return 0, re.ErrValueIsNotFloat
 	}
+	if ss.isListPack() {
+		return ss.listPackZIncrBy(key, fIncrement), nil
+	}
 	if obj, exists := ss.dict[key]; !exists {
 		skipList := ss.GetValue().(*raw_type.SkipList)
 		newNode := skipList.Insert(key, fIncrement)
@@ -106,7 +130,9 @@ func (ss *SortedSet) ZIncrBy(key string, increment string) (float64, error) {
 		return fIncrement, nil
 	} else {
 		newScore := obj.GetScore() + fIncrement
-		obj.SetScore(newScore)
+		skipList := ss.GetValue().(*raw_type.SkipList)
+		skipList.Delete(key, obj.GetScore())
+		ss.dict[key] = skipList.Insert(key, newScore)
 		return newScore, nil
 	}
 }
@@ -117,6 +143,14 @@ func (ss *SortedSet) ZRange(lower, upper string) ([]string, error) {
 	if err1 != nil || err2 != nil {
 		return nil, re.ErrNotIntegerOrOutOfRange
 	}
+	if ss.isListPack() {
+		entries := ss.listPackEntries()
+		start, end, err := zsetRankRange(iLower, iUpper, len(entries), false)
+		if err != nil {
+			return make([]string, 0), err
+		}
+		return zsetRangeReply(entries[start:end]), nil
+	}
 	skipList := ss.GetValue().(*raw_type.SkipList)
 	ret := make([]string, 0)
 
@@ -150,6 +184,11 @@ func (ss *SortedSet) ZRangeByScore(lower, upper string) ([]string, error) {
 	if err1 != nil || err2 != nil {
 		return nil, re.ErrValueIsNotFloat
 	}
+	if ss.isListPack() {
+		entries := ss.listPackEntries()
+		start, end := zsetScoreRange(entries, fLower, fUpper)
+		return zsetRangeReply(entries[start:end]), nil
+	}
 	skipList := ss.GetValue().(*raw_type.SkipList)
 	ret := make([]string, 0)
 
@@ -197,6 +236,12 @@ func (ss *SortedSet) ZRevRangeByScore(lower, upper string) ([]string, error) {
 }
 
 func (ss *SortedSet) ZRank(key string) (int, error) {
+	if ss.isListPack() {
+		if index := zsetFind(ss.listPackEntries(), key); index >= 0 {
+			return index, nil
+		}
+		return 0, re.ErrNoSuchKey
+	}
 	if obj, exits := ss.dict[key]; !exits {
 		return 0, re.ErrNoSuchKey
 	} else {
@@ -206,6 +251,13 @@ func (ss *SortedSet) ZRank(key string) (int, error) {
 }
 
 func (ss *SortedSet) ZRevRank(key string) (int, error) {
+	if ss.isListPack() {
+		entries := ss.listPackEntries()
+		if index := zsetFind(entries, key); index >= 0 {
+			return len(entries) - 1 - index, nil
+		}
+		return 0, re.ErrNoSuchKey
+	}
 	if obj, exits := ss.dict[key]; !exits {
 		return 0, re.ErrNoSuchKey
 	} else {
@@ -216,6 +268,9 @@ func (ss *SortedSet) ZRevRank(key string) (int, error) {
 }
 
 func (ss *SortedSet) ZRem(inputs []string) int {
+	if ss.isListPack() {
+		return ss.listPackZRem(inputs)
+	}
 	skipList := ss.GetValue().(*raw_type.SkipList)
 	count := 0
 	for _, key := range inputs {
@@ -235,20 +290,27 @@ func (ss *SortedSet) ZRemRangeByRank(lower, upper string) (int, error) {
 	if err1 != nil || err2 != nil {
 		return 0, re.ErrNotIntegerOrOutOfRange
 	}
-	skipList := ss.GetValue().(*raw_type.SkipList)
-	if iLower > skipList.Length() || -iLower > skipList.Length() {
-		return 0, re.ErrEmptyListOrSet
-	}
-	if ((iLower > 0 && iUpper > 0) || (iLower < 0 && iUpper < 0)) && iLower > iUpper {
-		return 0, re.ErrEmptyListOrSet
+	if ss.isListPack() {
+		entries := ss.listPackEntries()
+		start, end, err := zsetRankRange(iLower, iUpper, len(entries), true)
+		if err != nil {
+			return 0, err
+		}
+		ss.listPackStore(append(entries[:start], entries[end:]...))
+		return end - start, nil
 	}
-	if iLower < 0 {
-		iLower = skipList.Length() - 1 + iLower
+	skipList := ss.GetValue().(*raw_type.SkipList)
+	start, end, err := zsetRankRange(iLower, iUpper, skipList.Length(), true)
+	if err != nil {
+		return 0, err
 	}
-	if iUpper < 0 {
-		iUpper = skipList.Length() - 1 + iUpper
+	// 先从dict中删除, 再从跳跃表中删除
+	cur := skipList.GetElementByRank(start + 1)
+	for i := start; cur != nil && i < end; i++ {
+		delete(ss.dict, cur.GetValue())
+		cur = cur.GetNextNode()
 	}
-	return skipList.DeleteRangeByRank(iLower+1, iUpper+1), nil
+	return skipList.DeleteRangeByRank(start+1, end), nil
 }
 
 func (ss *SortedSet) ZRemRangeByScore(lower, upper string) (int, error) {
@@ -257,13 +319,35 @@ func (ss *SortedSet) ZRemRangeByScore(lower, upper string) (int, error) {
 	if err1 != nil || err2 != nil {
 		return 0, re.ErrValueIsNotFloat
 	}
+	if ss.isListPack() {
+		entries := ss.listPackEntries()
+		start, end := zsetScoreRange(entries, fLower, fUpper)
+		ss.listPackStore(append(entries[:start], entries[end:]...))
+		return end - start, nil
+	}
 	skipList := ss.GetValue().(*raw_type.SkipList)
-	return skipList.DeleteRangeByScore(raw_type.RangeSpec{
+	spec := raw_type.RangeSpec{
 		Min: fLower, Max: fUpper, MinEx: false, MaxEx: false,
-	}), nil
+	}
+	// 先从dict中删除, 再从跳跃表中删除
+	endNode := skipList.LastInRange(spec)
+	for cur := skipList.FirstInRange(spec); cur != nil; cur = cur.GetNextNode() {
+		delete(ss.dict, cur.GetValue())
+		if cur == endNode {
+			break
+		}
+	}
+	return skipList.DeleteRangeByScore(spec), nil
 }
 
 func (ss *SortedSet) ZScore(key string) (float64, error) {
+	if ss.isListPack() {
+		entries := ss.listPackEntries()
+		if index := zsetFind(entries, key); index >= 0 {
+			return entries[index].score, nil
+		}
+		return 0.0, re.ErrNoSuchKey
+	}
 	if obj, exists := ss.dict[key]; !exists {
 		return 0.0, re.ErrNoSuchKey
 	} else {
@@ -271,6 +355,31 @@ func (ss *SortedSet) ZScore(key string) (float64, error) {
 	}
 }
 
+// 按照score从小到大返回所有的member和score, 用于持久化
+func (ss *SortedSet) ZMembers() ([]string, []float64) {
+	if ss.isListPack() {
+		entries := ss.listPackEntries()
+		members := make([]string, 0, len(entries))
+		scores := make([]float64, 0, len(entries))
+		for _, entry := range entries {
+			members = append(members, entry.member)
+			scores = append(scores, entry.score)
+		}
+		return members, scores
+	}
+	skipList := ss.GetValue().(*raw_type.SkipList)
+	members := make([]string, 0, skipList.Length())
+	scores := make([]float64, 0, skipList.Length())
+	for cur := skipList.GetElementByRank(1); cur != nil; cur = cur.GetNextNode() {
+		members = append(members, cur.GetValue())
+		scores = append(scores, cur.GetScore())
+	}
+	return members, scores
+}
+
 func (ss *SortedSet) String() string {
+	if ss.isListPack() {
+		return ss.listPackString()
+	}
 	return fmt.Sprintf("SortedSet:%+v", ss.dict)
 }
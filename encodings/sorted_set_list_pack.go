@@ -0,0 +1,205 @@
+package encodings
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/raw_type"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/**
+有序集合对象的编码:
+	元素个数不超过zset-max-listpack-entries, 并且member的长度不超过zset-max-listpack-value的时候使用listpack编码,
+	member和score按照score从小到大(score相同的时候按照member的字典序)依次保存在listpack中:
+		<member1> <score1> <member2> <score2> ...
+	任意一个条件不满足之后转换成skiplist编码, 转换之后不会再转换回listpack。
+	listpack中的元素不多, 每次操作都把所有的元素读出来, 修改之后再写回去。
+*/
+var (
+	ZSetMaxListPackEntries = 128 /* zset-max-listpack-entries */
+	ZSetMaxListPackValue   = 64  /* zset-max-listpack-value */
+)
+
+type zsetEntry struct {
+	member string
+	score  float64
+}
+
+// 创建listpack编码的有序集合对象
+func NewSortedSetListPack(ttl int) *SortedSet {
+	var expireTime time.Time
+	if ttl > 0 {
+		expireTime = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+	return &SortedSet{
+		RedisObject: RedisObject{
+			objectType: RedisTypeZSet,
+			encoding:   RedisEncodingListPack,
+			ttl:        ttl,
+			value:      raw_type.NewListPack(),
+			lru:        initialLRU(),
+			refCount:   1,
+			expireTime: expireTime,
+		},
+	}
+}
+
+func (ss *SortedSet) isListPack() bool {
+	return ss.GetEncoding() == RedisEncodingListPack
+}
+
+// score使用能够精确还原的最短的字符串保存, 整数的score会被listpack按照整数编码
+func zsetFormatScore(score float64) string {
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
+
+// 读出listpack中所有的元素
+func (ss *SortedSet) listPackEntries() []zsetEntry {
+	values := ss.GetValue().(*raw_type.ListPack).Entries()
+	entries := make([]zsetEntry, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		score, _ := strconv.ParseFloat(values[i+1], 64)
+		entries = append(entries, zsetEntry{member: values[i], score: score})
+	}
+	return entries
+}
+
+// 排序之后写回listpack, 超过限制的时候转换成skiplist
+func (ss *SortedSet) listPackStore(entries []zsetEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].score != entries[j].score {
+			return entries[i].score < entries[j].score
+		}
+		return entries[i].member < entries[j].member
+	})
+	convert := len(entries) > ZSetMaxListPackEntries
+	for _, entry := range entries {
+		if len(entry.member) > ZSetMaxListPackValue {
+			convert = true
+		}
+	}
+	if convert {
+		skipList := raw_type.NewSkipList()
+		ss.dict = make(map[string]*raw_type.SkipNode, len(entries))
+		for _, entry := range entries {
+			ss.dict[entry.member] = skipList.Insert(entry.member, entry.score)
+		}
+		ss.SetValue(skipList)
+		ss.SetEncoding(RedisEncodingSkipList)
+		return
+	}
+	lp := raw_type.NewListPack()
+	for _, entry := range entries {
+		lp.Append(entry.member)
+		lp.Append(zsetFormatScore(entry.score))
+	}
+	ss.SetValue(lp)
+}
+
+// 返回member的下标, 不存在的时候返回-1
+func zsetFind(entries []zsetEntry, member string) int {
+	for i, entry := range entries {
+		if entry.member == member {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ss *SortedSet) listPackZAdd(scores []float64, members []string) int {
+	entries := ss.listPackEntries()
+	count := 0
+	for i, member := range members {
+		if index := zsetFind(entries, member); index >= 0 {
+			if entries[index].score != scores[i] {
+				entries[index].score = scores[i]
+				count += 1
+			}
+		} else {
+			entries = append(entries, zsetEntry{member: member, score: scores[i]})
+			count += 1
+		}
+	}
+	ss.listPackStore(entries)
+	return count
+}
+
+func (ss *SortedSet) listPackZIncrBy(member string, increment float64) float64 {
+	entries := ss.listPackEntries()
+	score := increment
+	if index := zsetFind(entries, member); index >= 0 {
+		score += entries[index].score
+		entries[index].score = score
+	} else {
+		entries = append(entries, zsetEntry{member: member, score: score})
+	}
+	ss.listPackStore(entries)
+	return score
+}
+
+// score在[lower, upper]之间的元素的下标范围[start, end)
+func zsetScoreRange(entries []zsetEntry, lower, upper float64) (int, int) {
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].score >= lower })
+	end := sort.Search(len(entries), func(i int) bool { return entries[i].score > upper })
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+func zsetRangeReply(entries []zsetEntry) []string {
+	ret := make([]string, 0, len(entries)*2)
+	for _, entry := range entries {
+		ret = append(ret, entry.member)
+		ret = append(ret, util.FloatToSimpleString(entry.score))
+	}
+	return ret
+}
+
+func (ss *SortedSet) listPackZRem(members []string) int {
+	entries := ss.listPackEntries()
+	count := 0
+	for _, member := range members {
+		if index := zsetFind(entries, member); index >= 0 {
+			entries = append(entries[:index], entries[index+1:]...)
+			count += 1
+		}
+	}
+	ss.listPackStore(entries)
+	return count
+}
+
+func (ss *SortedSet) listPackString() string {
+	msg := "SortedSet(" + RedisEncodingListPack + "):"
+	for _, entry := range ss.listPackEntries() {
+		msg += fmt.Sprintf("[%s=%s]", entry.member, zsetFormatScore(entry.score))
+	}
+	return msg
+}
+
+// 把ZRANGE、ZREMRANGEBYRANK的下标转换成[start, end)
+func zsetRankRange(lower, upper int, length int, allowFull bool) (int, int, error) {
+	if lower > length || -lower > length || (!allowFull && -lower == length) {
+		return 0, 0, re.ErrEmptyListOrSet
+	}
+	if ((lower > 0 && upper > 0) || (lower < 0 && upper < 0)) && lower > upper {
+		return 0, 0, re.ErrEmptyListOrSet
+	}
+	if lower < 0 {
+		lower = length + lower
+	}
+	if upper < 0 {
+		upper = length + upper
+	}
+	if upper >= length {
+		upper = length - 1
+	}
+	if lower > upper {
+		return lower, lower, nil
+	}
+	return lower, upper + 1, nil
+}
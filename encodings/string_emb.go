@@ -1,6 +1,10 @@
 package encodings
 
-import "time"
+import (
+	"time"
+
+	re "github.com/SwanSpouse/redis_go/error"
+)
 
 type StringEmb struct {
 	RedisObject
@@ -17,13 +21,18 @@ func NewStringEmb(ttl int, value interface{}) *StringEmb {
 			encoding:   RedisEncodingEmbStr,
 			ttl:        ttl,
 			value:      value,
+			lru:        initialLRU(),
+			refCount:   1,
 			expireTime: expireTime,
 		},
 	}
 }
 
+/**
+embstr编码的字符串是只读的, APPEND、INCRBYFLOAT等修改字符串的命令需要先转换成raw编码
+*/
 func (se *StringEmb) String() string {
-	return ""
+	return se.GetValue().(string)
 }
 
 func (se *StringEmb) Append(val string) int {
@@ -31,25 +40,29 @@ func (se *StringEmb) Append(val string) int {
 }
 
 func (se *StringEmb) Incr() (int64, error) {
-	return 0, nil
+	return 0, re.ErrWrongTypeOrEncoding
 }
 
 func (se *StringEmb) Decr() (int64, error) {
-	return 0, nil
+	return 0, re.ErrWrongTypeOrEncoding
 }
 
 func (se *StringEmb) IncrBy(val string) (int64, error) {
-	return 0, nil
+	return 0, re.ErrWrongTypeOrEncoding
 }
 
 func (se *StringEmb) DecrBy(val string) (int64, error) {
-	return 0, nil
+	return 0, re.ErrWrongTypeOrEncoding
+}
+
+func (se *StringEmb) SetRange(offset int, val string) int {
+	return 0
 }
 
 func (se *StringEmb) IncrByFloat(val string) (string, error) {
-	return "", nil
+	return "", re.ErrWrongTypeOrEncoding
 }
 
 func (se *StringEmb) Strlen() int {
-	return 0
+	return len(se.GetValue().(string))
 }
@@ -24,6 +24,8 @@ func NewStringInt(ttl int, value interface{}) *StringInt {
 			encoding:   RedisEncodingInt,
 			ttl:        ttl,
 			value:      value,
+			lru:        initialLRU(),
+			refCount:   1,
 			expireTime: expireTime,
 		},
 	}
@@ -86,6 +88,10 @@ func (si *StringInt) DecrBy(val string) (int64, error) {
 	return si.IncrBy(strconv.FormatInt(-1*decrValInt, 10))
 }
 
+func (si *StringInt) SetRange(offset int, val string) int {
+	return 0
+}
+
 func (si *StringInt) IncrByFloat(val string) (string, error) {
 	return "", re.ErrWrongTypeOrEncoding
 }
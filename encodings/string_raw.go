@@ -24,6 +24,8 @@ func NewStringRaw(ttl int, value interface{}) *StringRaw {
 			encoding:   RedisEncodingRaw,
 			ttl:        ttl,
 			value:      value,
+			lru:        initialLRU(),
+			refCount:   1,
 			expireTime: expireTime,
 		},
 	}
@@ -90,3 +92,18 @@ func (sr *StringRaw) IncrByFloat(val string) (string, error) {
 func (sr *StringRaw) Strlen() int {
 	return len(sr.GetValue().(string))
 }
+
+// 从offset开始用val覆盖字符串, 字符串长度不够的时候用0补齐, 返回修改之后的长度
+func (sr *StringRaw) SetRange(offset int, val string) int {
+	value := sr.GetValue().(string)
+	if len(val) == 0 {
+		return len(value)
+	}
+	buf := []byte(value)
+	if offset+len(val) > len(buf) {
+		buf = append(buf, make([]byte, offset+len(val)-len(buf))...)
+	}
+	copy(buf[offset:], val)
+	sr.SetValue(string(buf))
+	return len(buf)
+}
@@ -0,0 +1,47 @@
+package encodings
+
+import "math"
+
+/**
+共享整数对象:
+	0 ~ RedisSharedIntegers-1 之间的整数字符串对象在启动的时候创建, 所有值相同并且没有设置过期时间的key共享同一个对象,
+	共享对象的引用计数固定为RedisSharedRefCount。共享对象不能被原地修改, 修改之前需要先复制一个新的对象。
+	maxmemory-policy 使用LRU或者LFU的时候每个对象需要记录自己的访问时间, 这时不使用共享对象。
+*/
+const (
+	RedisSharedIntegers     = 10000         /* 共享整数对象的个数 */
+	RedisSharedRefCount     = math.MaxInt32 /* 共享对象的引用计数 */
+	RedisEncodingEmbStrSize = 44            /* 长度不超过44字节的字符串使用embstr编码 */
+)
+
+var (
+	// 由server根据maxmemory和maxmemory-policy设置
+	SharedIntegersEnabled = true
+
+	sharedIntegers [RedisSharedIntegers]*StringInt
+)
+
+func init() {
+	for i := 0; i < RedisSharedIntegers; i++ {
+		sharedIntegers[i] = &StringInt{
+			RedisObject: RedisObject{
+				objectType: RedisTypeString,
+				encoding:   RedisEncodingInt,
+				ttl:        -1,
+				value:      int64(i),
+				lru:        GetLRUClock(),
+				refCount:   RedisSharedRefCount,
+			},
+		}
+	}
+}
+
+// value可以使用共享对象的时候返回共享对象
+func GetSharedInteger(value int64) (*StringInt, bool) {
+	if !SharedIntegersEnabled || value < 0 || value >= RedisSharedIntegers {
+		return nil, false
+	}
+	shared := sharedIntegers[value]
+	shared.IncrRefCount()
+	return shared, true
+}
@@ -25,6 +25,11 @@ const (
 	ErrInvalidBulkLength      = ProtoError("ERR Protocol error: invalid bulk length")
 	ErrBlankBulkLength        = ProtoError("ERR Protocol error: expected '$', got ' '")
 	ErrInlineRequestTooLong   = ProtoError("ERR Protocol error: too big inline request")
+	ErrMultiBulkCountTooBig   = ProtoError("ERR Protocol error: too big mbulk count string")
+	ErrBulkCountTooBig        = ProtoError("ERR Protocol error: too big bulk count string")
+	ErrBulkWithoutCRLF        = ProtoError("ERR Protocol error: expected '\\r\\n' after bulk string")
+	ErrQueryBufferLimit       = ProtoError("ERR Protocol error: max query buffer length reached")
+	ErrUnbalancedQuotes       = ProtoError("ERR Protocol error: unbalanced quotes in request")
 	ErrNotANumber             = ProtoError("ERR Protocol error: expected a number")
 	ErrNotANilMessage         = ProtoError("ERR Protocol error: expected a nil")
 	ErrBadResponseType        = ProtoError("ERR Protocol error: bad response type")
@@ -49,5 +54,56 @@ const (
 	ErrRedisRdbSaveInProcess  = ProtoError("ERR redis rdb save is in process")
 	ErrAofFormat              = ProtoError("Bad file format reading the append only file: make a backup of your AOF file, then use ./redis-check-aof --fix <filename>")
 	ErrPubSubCommand          = ProtoError("ERR Unknown PUBSUB subcommand or wrong number of arguments for %s")
-	ErrClientCommand          = ProtoError("ERR Syntax error, try CLIENT (LIST | KILL | GETNAME | SETNAME | PAUSE | REPLY)")
+	ErrClientCommand          = ProtoError("ERR Unknown CLIENT subcommand or wrong number of arguments for %s")
+	ErrClientNameInvalid      = ProtoError("ERR Client names cannot contain spaces, newlines or special characters.")
+	ErrClientUnknownType      = ProtoError("ERR Unknown client type '%s'")
+	ErrClientInvalidID        = ProtoError("ERR client-id should be greater than 0")
+	ErrClientNoSuchUser       = ProtoError("ERR No such user '%s'")
+	ErrClientNoSuchClient     = ProtoError("ERR No such client")
+	ErrClientPauseTimeout     = ProtoError("ERR timeout is not an integer or out of range")
+	ErrClientPauseNegative    = ProtoError("ERR timeout is negative")
+	ErrClientPauseType        = ProtoError("ERR CLIENT PAUSE mode must be WRITE or ALL")
+	ErrMaxClientsReached      = ProtoError("ERR max number of clients reached")
+	ErrOOMCommandNotAllowed   = ProtoError("OOM command not allowed when used memory > 'maxmemory'.")
+	ErrMemoryCommand          = ProtoError("ERR Unknown MEMORY subcommand or wrong number of arguments for %s")
+	ErrConfigCommand          = ProtoError("ERR Unknown CONFIG subcommand or wrong number of arguments for %s")
+	ErrConfigSetUnknownOption = ProtoError("ERR Unknown option or number of arguments for CONFIG SET - '%s'")
+	ErrConfigSetFailed        = ProtoError("ERR CONFIG SET failed (possibly related to argument '%s') - %s")
+	ErrConfigNoConfigFile     = ProtoError("ERR The server is running without a config file")
+	ErrConfigRewriteFailed    = ProtoError("ERR Rewriting config file: %s")
+	ErrSlowLogCommand         = ProtoError("ERR Unknown SLOWLOG subcommand or wrong number of arguments for %s")
+	ErrLatencyCommand         = ProtoError("ERR Unknown LATENCY subcommand or wrong number of arguments for %s")
+	ErrLatencyNoSamples       = ProtoError("ERR No samples available for event '%s'")
+	ErrDebugCommand           = ProtoError("ERR Unknown DEBUG subcommand or wrong number of arguments for %s")
+	ErrShutdownFailed         = ProtoError("ERR Errors trying to SHUTDOWN. Check logs.")
+	ErrShutdownInProgress     = ProtoError("ERR Server is shutting down")
+	ErrNoShutdownInProgress   = ProtoError("ERR No shutdown in progress.")
+	ErrHelloProtocolVersion   = ProtoError("ERR Protocol version is not an integer or out of range")
+	ErrHelloSyntax            = ProtoError("ERR Syntax error in HELLO option '%s'")
+	ErrNoProto                = ProtoError("NOPROTO unsupported protocol version")
+	ErrWrongPass              = ProtoError("WRONGPASS invalid username-password pair or user is disabled.")
+	ErrTrackingRedirMulti     = ProtoError("ERR A client can only redirect to a single other client")
+	ErrTrackingRedirNotExist  = ProtoError("ERR The client ID you want redirect to does not exist")
+	ErrTrackingPrefixNoBcast  = ProtoError("ERR PREFIX option requires BCAST mode to be enabled")
+	ErrTrackingSwitchBcast    = ProtoError("ERR You can't switch BCAST mode on/off before disabling tracking for this client, and then re-enabling it with a different mode.")
+	ErrTrackingOptInOptOut    = ProtoError("ERR You can't use both OPTIN and OPTOUT")
+	ErrTrackingOptBcast       = ProtoError("ERR OPTIN and OPTOUT are not compatible with BCAST")
+	ErrTrackingPrefixExisting = ProtoError("ERR Prefix '%s' overlaps with an existing prefix '%s'. Prefixes for a single client must not overlap.")
+	ErrTrackingPrefixProvided = ProtoError("ERR Prefix '%s' overlaps with another provided prefix '%s'. Prefixes for a single client must not overlap.")
+	ErrTrackingCachingMode    = ProtoError("ERR CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")
+	ErrTrackingCachingYes     = ProtoError("ERR CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode.")
+	ErrTrackingCachingNo      = ProtoError("ERR CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode.")
+	ErrTimeoutNotFloat        = ProtoError("ERR timeout is not a float or out of range")
+	ErrTimeoutNegative        = ProtoError("ERR timeout is negative")
+	ErrNumKeysNotPositive     = ProtoError("ERR numkeys should be greater than 0")
+	ErrCountNotPositive       = ProtoError("ERR count should be greater than 0")
+	ErrValueMustBePositive    = ProtoError("ERR value is out of range, must be positive")
+	ErrLPosRankZero           = ProtoError("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+	ErrLPosCountNegative      = ProtoError("ERR COUNT can't be negative")
+	ErrLPosMaxLenNegative     = ProtoError("ERR MAXLEN can't be negative")
+	ErrOffsetOutOfRange       = ProtoError("ERR offset is out of range")
+	ErrStringExceedsMaxSize   = ProtoError("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	ErrObjectCommand          = ProtoError("ERR Unknown OBJECT subcommand or wrong number of arguments for %s")
+	ErrObjectFreqNotTracked   = ProtoError("ERR An LFU maxmemory policy is not selected, access frequency not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+	ErrObjectIdleNotTracked   = ProtoError("ERR An LFU maxmemory policy is selected, idle time not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
 )
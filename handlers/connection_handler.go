@@ -11,6 +11,7 @@ const (
 	RedisConnectionCommandSelect = "SELECT"
 	RedisConnectionCommandEcho   = "ECHO"
 	RedisConnectionCommandQuit   = "QUIT"
+	RedisConnectionCommandHello  = "HELLO"
 )
 
 type ConnectionHandler struct {
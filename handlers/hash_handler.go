@@ -6,6 +6,8 @@ import (
 	"github.com/SwanSpouse/redis_go/encodings"
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
 )
 
 const (
@@ -27,9 +29,10 @@ const (
 	RedisHashCommandHDebug       = "HDEBUG"
 )
 
-// HashHandler可以处理的一种rawType
+// HashHandler可以处理的rawType
 var hashEncodingTypeDict = map[string]bool{
-	encodings.RedisEncodingHT: true,
+	encodings.RedisEncodingHT:       true,
+	encodings.RedisEncodingListPack: true,
 }
 
 type HashHandler struct {
@@ -61,30 +64,30 @@ func createHashIfNotExists(cli *client.Client, key string) error {
 }
 
 func (handler *HashHandler) HDel(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(th.HDel(cli.Argv[2:]))
+		cli.Response(th.HDel(util.BytesToStrings(cli.Argv[2:])))
 		cli.Dirty += 1
 	}
 }
 
 func (handler *HashHandler) HExists(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(th.HExists(cli.Argv[2]))
+		cli.Response(th.HExists(string(cli.Argv[2])))
 	}
 }
 
 func (handler *HashHandler) HGet(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := th.HGet(cli.Argv[2]); err != nil {
+		if ret, err := th.HGet(string(cli.Argv[2])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -93,16 +96,16 @@ func (handler *HashHandler) HGet(cli *client.Client) {
 }
 
 func (handler *HashHandler) HGetAll(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(th.HGetAll())
+		cli.Response(tcp.NewStringMapReply(th.HGetAll()))
 	}
 }
 
 func (handler *HashHandler) HKeys(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
@@ -116,7 +119,7 @@ func (handler *HashHandler) HKeys(cli *client.Client) {
 }
 
 func (handler *HashHandler) HLen(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
@@ -125,12 +128,12 @@ func (handler *HashHandler) HLen(cli *client.Client) {
 }
 
 func (handler *HashHandler) HMGet(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
 		ret := make([]string, 0)
-		for _, item := range cli.Argv[2:] {
+		for _, item := range util.BytesToStrings(cli.Argv[2:]) {
 			value, _ := th.HGet(item)
 			ret = append(ret, value)
 		}
@@ -139,7 +142,7 @@ func (handler *HashHandler) HMGet(cli *client.Client) {
 }
 
 func (handler *HashHandler) HMSet(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if len(cli.Argv)%2 == 1 {
 		cli.ResponseReError(re.ErrWrongNumberOfArgs, cli.Cmd.GetOriginName())
 		return
@@ -152,7 +155,7 @@ func (handler *HashHandler) HMSet(cli *client.Client) {
 		cli.ResponseReError(err)
 	} else {
 		for i := 2; i < len(cli.Argv); i += 2 {
-			th.HSet(cli.Argv[i], cli.Argv[i+1])
+			th.HSet(string(cli.Argv[i]), string(cli.Argv[i+1]))
 		}
 		cli.ResponseOK()
 		cli.Dirty += 1
@@ -160,7 +163,7 @@ func (handler *HashHandler) HMSet(cli *client.Client) {
 }
 
 func (handler *HashHandler) HSet(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createHashIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -168,13 +171,13 @@ func (handler *HashHandler) HSet(cli *client.Client) {
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(th.HSet(cli.Argv[2], cli.Argv[3]))
+		cli.Response(th.HSet(string(cli.Argv[2]), string(cli.Argv[3])))
 		cli.Dirty += 1
 	}
 }
 
 func (handler *HashHandler) HSetNX(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createHashIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -182,8 +185,8 @@ func (handler *HashHandler) HSetNX(cli *client.Client) {
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if val, _ := th.HGet(cli.Argv[2]); val == "" {
-			cli.Response(th.HSet(cli.Argv[2], cli.Argv[3]))
+		if val, _ := th.HGet(string(cli.Argv[2])); val == "" {
+			cli.Response(th.HSet(string(cli.Argv[2]), string(cli.Argv[3])))
 			cli.Dirty += 1
 		} else {
 			cli.Response(0)
@@ -192,7 +195,7 @@ func (handler *HashHandler) HSetNX(cli *client.Client) {
 }
 
 func (handler *HashHandler) HVals(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
@@ -206,11 +209,11 @@ func (handler *HashHandler) HVals(cli *client.Client) {
 }
 
 func (handler *HashHandler) HStrLen(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := th.HGet(cli.Argv[2]); err != nil {
+		if ret, err := th.HGet(string(cli.Argv[2])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(len(ret))
@@ -219,7 +222,7 @@ func (handler *HashHandler) HStrLen(cli *client.Client) {
 }
 
 func (handler *HashHandler) HIncrBy(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createHashIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -227,7 +230,7 @@ func (handler *HashHandler) HIncrBy(cli *client.Client) {
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := th.HIncrBy(cli.Argv[2], cli.Argv[3]); err != nil {
+		if ret, err := th.HIncrBy(string(cli.Argv[2]), string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -237,7 +240,7 @@ func (handler *HashHandler) HIncrBy(cli *client.Client) {
 }
 
 func (handler *HashHandler) HIncrByFloat(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createHashIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -245,17 +248,17 @@ func (handler *HashHandler) HIncrByFloat(cli *client.Client) {
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := th.HIncrByFloat(cli.Argv[2], cli.Argv[3]); err != nil {
+		if ret, err := th.HIncrByFloat(string(cli.Argv[2]), string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
-			cli.Response(ret)
+			cli.Response(tcp.DoubleReply(ret))
 			cli.Dirty += 1
 		}
 	}
 }
 
 func (handler *HashHandler) HDebug(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if th, err := getTHashValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/encodings"
 	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/util"
 )
 
 const (
@@ -37,23 +39,24 @@ const (
 	CommandObjectSubTypeRefCount  = "REFCOUNT"
 	CommandObjectSubTypeEncodings = "ENCODING"
 	CommandObjectSubTypeIdleTime  = "IDLETIME"
+	CommandObjectSubTypeFreq      = "FREQ"
 )
 
 type KeyHandler struct{}
 
 func (handler *KeyHandler) Del(cli *client.Client) {
-	successCount := cli.SelectedDatabase().RemoveKeyInDB(cli.Argv)
+	successCount := cli.SelectedDatabase().RemoveKeyInDB(util.BytesToStrings(cli.Argv[1:]))
 	cli.Dirty += successCount
 	cli.Response(successCount)
 }
 
 func (handler *KeyHandler) Exists(cli *client.Client) {
-	successCount, _ := cli.SelectedDatabase().SearchKeysInDB(cli.Argv)
+	successCount, _ := cli.SelectedDatabase().SearchKeysInDB(util.BytesToStrings(cli.Argv[1:]))
 	cli.Response(len(successCount))
 }
 
 func (handler *KeyHandler) Type(cli *client.Client) {
-	if tb := cli.SelectedDatabase().SearchKeyInDB(cli.Argv[1]); tb == nil {
+	if tb := cli.SelectedDatabase().SearchKeyInDB(string(cli.Argv[1])); tb == nil {
 		cli.Response(nil)
 	} else {
 		cli.Response(tb.GetObjectType())
@@ -71,31 +74,55 @@ func (handler *KeyHandler) RandomKey(cli *client.Client) {
 }
 
 func (handler *KeyHandler) Rename(cli *client.Client) {
-	if tb := cli.SelectedDatabase().SearchKeyInDB(cli.Argv[1]); tb == nil {
+	if tb := cli.SelectedDatabase().SearchKeyInDB(string(cli.Argv[1])); tb == nil {
 		cli.ResponseReError(re.ErrNoSuchKey)
 	} else {
-		cli.SelectedDatabase().RemoveKeyInDB([]string{cli.Argv[1]})
-		cli.SelectedDatabase().SetKeyInDB(cli.Argv[2], tb)
+		// RemoveKeyInDB会减少对象的引用计数, 先增加引用计数
+		tb.IncrRefCount()
+		cli.SelectedDatabase().RemoveKeyInDB([]string{string(cli.Argv[1])})
+		cli.SelectedDatabase().SetKeyInDB(string(cli.Argv[2]), tb)
 		cli.Dirty += 1
 		cli.ResponseOK()
 	}
 }
 
+/**
+OBJECT REFCOUNT|ENCODING|IDLETIME|FREQ key
+	查找key的时候不更新对象的访问时间。IDLETIME只在LRU模式下有效, FREQ只在LFU模式下有效。
+*/
 func (handler *KeyHandler) Object(cli *client.Client) {
-	encodings := cli.Argv[1]
-	key := cli.Argv[2]
-	switch strings.ToUpper(encodings) {
-	case CommandObjectSubTypeEncodings:
-		if tb := cli.SelectedDatabase().SearchKeyInDB(key); tb == nil {
-			cli.Response(nil)
-		} else {
-			cli.Response(tb.GetEncoding())
-		}
+	if len(cli.Argv) != 3 {
+		cli.ResponseReError(re.ErrObjectCommand, string(cli.Argv[1]))
+		return
+	}
+	subCommand := strings.ToUpper(string(cli.Argv[1]))
+	switch subCommand {
+	case CommandObjectSubTypeRefCount, CommandObjectSubTypeEncodings, CommandObjectSubTypeIdleTime, CommandObjectSubTypeFreq:
+	default:
+		cli.ResponseReError(re.ErrObjectCommand, string(cli.Argv[1]))
+		return
+	}
+	tb := cli.SelectedDatabase().LookupKeyNoTouch(string(cli.Argv[2]))
+	if tb == nil || tb.IsExpired() {
+		cli.Response(nil)
+		return
+	}
+	switch subCommand {
 	case CommandObjectSubTypeRefCount:
-		cli.ResponseReError(re.ErrFunctionNotImplement)
+		cli.Response(tb.GetRefCount())
+	case CommandObjectSubTypeEncodings:
+		cli.Response(tb.GetEncoding())
 	case CommandObjectSubTypeIdleTime:
-		cli.ResponseReError(re.ErrFunctionNotImplement)
-	default:
-		cli.ResponseReError(re.ErrFunctionNotImplement)
+		if encodings.MaxMemoryPolicyLFU {
+			cli.ResponseReError(re.ErrObjectIdleNotTracked)
+			return
+		}
+		cli.Response(tb.EstimateIdleTime() / 1000)
+	case CommandObjectSubTypeFreq:
+		if !encodings.MaxMemoryPolicyLFU {
+			cli.ResponseReError(re.ErrObjectFreqNotTracked)
+			return
+		}
+		cli.Response(tb.LFUDecrAndReturn())
 	}
 }
@@ -9,6 +9,8 @@ import (
 	"github.com/SwanSpouse/redis_go/encodings"
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
 )
 
 const (
@@ -27,19 +29,39 @@ const (
 	RedisListCommandRPush     = "RPUSH"
 	RedisListCommandRpushX    = "RPUSHX"
 	RedisListCommandLDebug    = "LDEBUG"
+	RedisListCommandLMove     = "LMOVE"
+	RedisListCommandLPos      = "LPOS"
+	RedisListCommandLMPop     = "LMPOP"
+
+	// 阻塞的列表命令由server处理
+	RedisListCommandBLPop      = "BLPOP"
+	RedisListCommandBRPop      = "BRPOP"
+	RedisListCommandBRPopLPush = "BRPOPLPUSH"
+	RedisListCommandBLMove     = "BLMOVE"
+	RedisListCommandBLMPop     = "BLMPOP"
 )
 
-// ListHandler可以处理的两种rawType
+const (
+	RedisListHead = 0 /* 列表的头部, 对应LEFT */
+	RedisListTail = 1 /* 列表的尾部, 对应RIGHT */
+)
+
+// ListHandler可以处理的rawType
 var listEncodingTypeDict = map[string]bool{
 	encodings.RedisEncodingLinkedList: true,
-	encodings.RedisEncodingZipList:    true,
+	encodings.RedisEncodingListPack:   true,
+	encodings.RedisEncodingQuickList:  true,
 }
 
 type ListHandler struct {
 }
 
 func getTListValueByKey(cli *client.Client, key string) (database.TList, error) {
-	baseType := cli.SelectedDatabase().SearchKeyInDB(key)
+	return getTListFromDB(cli.SelectedDatabase(), key)
+}
+
+func getTListFromDB(db *database.Database, key string) (database.TList, error) {
+	baseType := db.SearchKeyInDB(key)
 	if baseType == nil {
 		return nil, re.ErrNoSuchKey
 	}
@@ -63,17 +85,28 @@ func createListIfNotExists(cli *client.Client, key string) error {
 	return nil
 }
 
+// 列表中没有元素之后从数据库中删除key
+func removeListIfEmpty(db *database.Database, key string, tl database.TList) {
+	if tl.LLen() == 0 {
+		db.RemoveKeyInDB([]string{key})
+	}
+}
+
 func (handler *ListHandler) LIndex(cli *client.Client) {
-	key := cli.Argv[1]
-	if ts, err := getTListValueByKey(cli, key); err != nil {
+	key := string(cli.Argv[1])
+	if ts, err := getTListValueByKey(cli, key); err != nil && err != re.ErrNoSuchKey {
 		cli.ResponseReError(err)
+	} else if err == re.ErrNoSuchKey {
+		cli.Response(nil)
 	} else {
-		index, err := strconv.Atoi(cli.Argv[2])
+		index, err := strconv.Atoi(string(cli.Argv[2]))
 		if err != nil {
 			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
 			return
 		}
-		if ret, err := ts.LIndex(index); err != nil {
+		if ret, err := ts.LIndex(index); err == re.ErrNilValue {
+			cli.Response(nil)
+		} else if err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -82,13 +115,14 @@ func (handler *ListHandler) LIndex(cli *client.Client) {
 }
 
 func (handler *ListHandler) LInsert(cli *client.Client) {
-	key := cli.Argv[1]
-	if ts, err := getTListValueByKey(cli, key); err != nil {
+	key := string(cli.Argv[1])
+	if ts, err := getTListValueByKey(cli, key); err != nil && err != re.ErrNoSuchKey {
 		cli.ResponseReError(err)
-		cli.Response(nil)
+	} else if err == re.ErrNoSuchKey {
+		cli.Response(0)
 	} else {
 		var insertFlag int
-		switch strings.ToUpper(cli.Argv[2]) {
+		switch strings.ToUpper(string(cli.Argv[2])) {
 		case "BEFORE":
 			insertFlag = encodings.RedisTypeListInsertBefore
 		case "AFTER":
@@ -97,7 +131,7 @@ func (handler *ListHandler) LInsert(cli *client.Client) {
 			cli.ResponseReError(re.ErrSyntaxError)
 			return
 		}
-		if ret, err := ts.LInsert(insertFlag, cli.Argv[3], cli.Argv[4:]...); err != nil {
+		if ret, err := ts.LInsert(insertFlag, string(cli.Argv[3]), util.BytesToStrings(cli.Argv[4:])...); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -107,10 +141,10 @@ func (handler *ListHandler) LInsert(cli *client.Client) {
 }
 
 func (handler *ListHandler) LLen(cli *client.Client) {
-	key := cli.Argv[1]
-	if ts, err := getTListValueByKey(cli, key); err != nil && err != re.ErrNilValue {
+	key := string(cli.Argv[1])
+	if ts, err := getTListValueByKey(cli, key); err != nil && err != re.ErrNoSuchKey {
 		cli.ResponseReError(err)
-	} else if err == re.ErrNilValue {
+	} else if err == re.ErrNoSuchKey {
 		cli.Response(0)
 	} else {
 		cli.Response(ts.LLen())
@@ -118,17 +152,53 @@ func (handler *ListHandler) LLen(cli *client.Client) {
 }
 
 func (handler *ListHandler) LPop(cli *client.Client) {
-	key := cli.Argv[1]
-	if tl, err := getTListValueByKey(cli, key); err != nil {
+	handler.popGeneric(cli, RedisListHead)
+}
+
+// LPOP、RPOP key [count], 没有count参数的时候回复一个元素, 有count参数的时候回复数组
+func (handler *ListHandler) popGeneric(cli *client.Client, where int) {
+	if cli.Argc > 3 {
+		cli.ResponseReError(re.ErrWrongNumberOfArgs, cli.Cmd.GetOriginName())
+		return
+	}
+	count, hasCount := 1, cli.Argc == 3
+	if hasCount {
+		n, err := strconv.ParseInt(string(cli.Argv[2]), 10, 64)
+		if err != nil || n < 0 {
+			cli.ResponseReError(re.ErrValueMustBePositive)
+			return
+		}
+		count = int(n)
+	}
+	key := string(cli.Argv[1])
+	if n, err := ListLen(cli.SelectedDatabase(), key); err != nil {
+		cli.ResponseReError(err)
+		return
+	} else if n == 0 {
+		if hasCount {
+			cli.Response(tcp.NullArrayReply{})
+		} else {
+			cli.Response(nil)
+		}
+		return
+	}
+	values, err := ListPop(cli.SelectedDatabase(), key, where, count)
+	if err != nil {
 		cli.ResponseReError(err)
+		return
+	}
+	if hasCount {
+		cli.Response(tcp.NewStringArrayReply(values))
 	} else {
-		cli.Response(tl.LPop())
+		cli.Response(values[0])
+	}
+	if len(values) > 0 {
 		cli.Dirty += 1
 	}
 }
 
 func (handler *ListHandler) LPush(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createListIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -136,20 +206,41 @@ func (handler *ListHandler) LPush(cli *client.Client) {
 	if tl, err := getTListValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(tl.LPush(cli.Argv[2:]))
+		cli.Response(tl.LPush(util.BytesToStrings(cli.Argv[2:])))
+		cli.Dirty += 1
+	}
+}
+
+// LPUSHX key element [element ...], 只有key存在的时候才添加元素
+func (handler *ListHandler) LPushX(cli *client.Client) {
+	handler.pushxGeneric(cli, RedisListHead)
+}
+
+func (handler *ListHandler) pushxGeneric(cli *client.Client, where int) {
+	key := string(cli.Argv[1])
+	if tl, err := getTListValueByKey(cli, key); err != nil && err != re.ErrNoSuchKey {
+		cli.ResponseReError(err)
+	} else if err == re.ErrNoSuchKey {
+		cli.Response(0)
+	} else {
+		if where == RedisListHead {
+			cli.Response(tl.LPush(util.BytesToStrings(cli.Argv[2:])))
+		} else {
+			cli.Response(tl.RPush(util.BytesToStrings(cli.Argv[2:])))
+		}
 		cli.Dirty += 1
 	}
 }
 
 func (handler *ListHandler) LRange(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTListValueByKey(cli, key); err != nil && err != re.ErrNoSuchKey {
 		cli.ResponseReError(err)
 	} else if err == re.ErrNoSuchKey {
 		cli.ResponseReError(re.ErrEmptyListOrSet)
 	} else {
-		start, startErr := strconv.Atoi(cli.Argv[2])
-		stop, stopErr := strconv.Atoi(cli.Argv[3])
+		start, startErr := strconv.Atoi(string(cli.Argv[2]))
+		stop, stopErr := strconv.Atoi(string(cli.Argv[3]))
 		if startErr != nil || stopErr != nil {
 			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
 			return
@@ -159,31 +250,37 @@ func (handler *ListHandler) LRange(cli *client.Client) {
 }
 
 func (handler *ListHandler) LRem(cli *client.Client) {
-	key := cli.Argv[1]
-	if ts, err := getTListValueByKey(cli, key); err != nil {
+	key := string(cli.Argv[1])
+	if ts, err := getTListValueByKey(cli, key); err != nil && err != re.ErrNoSuchKey {
 		cli.ResponseReError(err)
+	} else if err == re.ErrNoSuchKey {
+		cli.Response(0)
 	} else {
-		index, err := strconv.Atoi(cli.Argv[2])
+		index, err := strconv.Atoi(string(cli.Argv[2]))
 		if err != nil {
 			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
 			return
 		}
-		cli.Response(ts.LRem(index, cli.Argv[3]))
-		cli.Dirty += 1
+		removed := ts.LRem(index, string(cli.Argv[3]))
+		removeListIfEmpty(cli.SelectedDatabase(), key, ts)
+		cli.Response(removed)
+		if removed > 0 {
+			cli.Dirty += 1
+		}
 	}
 }
 
 func (handler *ListHandler) LSet(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTListValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		index, err := strconv.Atoi(cli.Argv[2])
+		index, err := strconv.Atoi(string(cli.Argv[2]))
 		if err != nil {
 			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
 			return
 		}
-		if err := ts.LSet(index, cli.Argv[3]); err != nil {
+		if err := ts.LSet(index, string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.ResponseOK()
@@ -193,14 +290,14 @@ func (handler *ListHandler) LSet(cli *client.Client) {
 }
 
 func (handler *ListHandler) LTrim(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTListValueByKey(cli, key); err != nil && err != re.ErrNoSuchKey {
 		cli.ResponseReError(err)
 	} else if err == re.ErrNoSuchKey {
 		cli.ResponseOK()
 	} else {
-		startPos, startPosErr := strconv.Atoi(cli.Argv[2])
-		endPos, endPosErr := strconv.Atoi(cli.Argv[3])
+		startPos, startPosErr := strconv.Atoi(string(cli.Argv[2]))
+		endPos, endPosErr := strconv.Atoi(string(cli.Argv[3]))
 		if startPosErr != nil || endPosErr != nil {
 			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
 			return
@@ -208,6 +305,7 @@ func (handler *ListHandler) LTrim(cli *client.Client) {
 		if err := ts.LTrim(startPos, endPos); err != nil {
 			cli.ResponseReError(err)
 		} else {
+			removeListIfEmpty(cli.SelectedDatabase(), key, ts)
 			cli.ResponseOK()
 			cli.Dirty += 1
 		}
@@ -215,17 +313,38 @@ func (handler *ListHandler) LTrim(cli *client.Client) {
 }
 
 func (handler *ListHandler) RPop(cli *client.Client) {
-	key := cli.Argv[1]
-	if tl, err := getTListValueByKey(cli, key); err != nil {
+	handler.popGeneric(cli, RedisListTail)
+}
+
+// RPOPLPUSH source destination
+func (handler *ListHandler) RPopLPush(cli *client.Client) {
+	handler.moveGeneric(cli, RedisListTail, RedisListHead)
+}
+
+// LMOVE source destination LEFT|RIGHT LEFT|RIGHT
+func (handler *ListHandler) LMove(cli *client.Client) {
+	from, fromOk := ParseListWhere(string(cli.Argv[3]))
+	to, toOk := ParseListWhere(string(cli.Argv[4]))
+	if !fromOk || !toOk {
+		cli.ResponseReError(re.ErrSyntaxError)
+		return
+	}
+	handler.moveGeneric(cli, from, to)
+}
+
+func (handler *ListHandler) moveGeneric(cli *client.Client, from, to int) {
+	if value, ok, err := ListMove(cli.SelectedDatabase(), string(cli.Argv[1]), string(cli.Argv[2]), from, to); err != nil {
 		cli.ResponseReError(err)
+	} else if !ok {
+		cli.Response(nil)
 	} else {
-		cli.Response(tl.RPop())
+		cli.Response(value)
 		cli.Dirty += 1
 	}
 }
 
 func (handler *ListHandler) RPush(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createListIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -233,13 +352,97 @@ func (handler *ListHandler) RPush(cli *client.Client) {
 	if tl, err := getTListValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(tl.RPush(cli.Argv[2:]))
+		cli.Response(tl.RPush(util.BytesToStrings(cli.Argv[2:])))
 		cli.Dirty += 1
 	}
 }
 
+// RPUSHX key element [element ...], 只有key存在的时候才添加元素
+func (handler *ListHandler) RPushX(cli *client.Client) {
+	handler.pushxGeneric(cli, RedisListTail)
+}
+
+// LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func (handler *ListHandler) LPos(cli *client.Client) {
+	rank, count, maxLen := 1, 1, 0
+	hasCount := false
+	for i := 3; i < cli.Argc; i += 2 {
+		option := strings.ToUpper(string(cli.Argv[i]))
+		if (option != "RANK" && option != "COUNT" && option != "MAXLEN") || i+1 >= cli.Argc {
+			cli.ResponseReError(re.ErrSyntaxError)
+			return
+		}
+		n, err := strconv.Atoi(string(cli.Argv[i+1]))
+		if err != nil {
+			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
+			return
+		}
+		switch option {
+		case "RANK":
+			if n == 0 {
+				cli.ResponseReError(re.ErrLPosRankZero)
+				return
+			}
+			rank = n
+		case "COUNT":
+			if n < 0 {
+				cli.ResponseReError(re.ErrLPosCountNegative)
+				return
+			}
+			count, hasCount = n, true
+		case "MAXLEN":
+			if n < 0 {
+				cli.ResponseReError(re.ErrLPosMaxLenNegative)
+				return
+			}
+			maxLen = n
+		}
+	}
+	tl, err := getTListValueByKey(cli, string(cli.Argv[1]))
+	if err != nil && err != re.ErrNoSuchKey {
+		cli.ResponseReError(err)
+		return
+	}
+	positions := make([]int, 0)
+	if err == nil {
+		positions = tl.LPos(string(cli.Argv[2]), rank, count, maxLen)
+	}
+	if hasCount {
+		ret := make(tcp.ArrayReply, len(positions))
+		for i, pos := range positions {
+			ret[i] = pos
+		}
+		cli.Response(ret)
+	} else if len(positions) == 0 {
+		cli.Response(nil)
+	} else {
+		cli.Response(positions[0])
+	}
+}
+
+// LMPOP numkeys key [key ...] LEFT|RIGHT [COUNT count], 从第一个不为空的列表中弹出元素
+func (handler *ListHandler) LMPop(cli *client.Client) {
+	keys, where, count, ok := ParseMPopArgs(cli, 1)
+	if !ok {
+		return
+	}
+	for _, key := range keys {
+		values, err := ListPop(cli.SelectedDatabase(), key, where, count)
+		if err != nil {
+			cli.ResponseReError(err)
+			return
+		}
+		if len(values) > 0 {
+			cli.Response(tcp.ArrayReply{key, tcp.NewStringArrayReply(values)})
+			cli.Dirty += 1
+			return
+		}
+	}
+	cli.Response(tcp.NullArrayReply{})
+}
+
 func (handler *ListHandler) Debug(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tl, err := getTListValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
@@ -247,3 +450,128 @@ func (handler *ListHandler) Debug(cli *client.Client) {
 		cli.ResponseOK()
 	}
 }
+
+// 解析LEFT|RIGHT参数
+func ParseListWhere(arg string) (int, bool) {
+	switch strings.ToUpper(arg) {
+	case "LEFT":
+		return RedisListHead, true
+	case "RIGHT":
+		return RedisListTail, true
+	}
+	return 0, false
+}
+
+// 列表的长度, key不存在的时候返回0
+func ListLen(db *database.Database, key string) (int, error) {
+	tl, err := getTListFromDB(db, key)
+	if err == re.ErrNoSuchKey {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return tl.LLen(), nil
+}
+
+/**
+从列表的头部或者尾部最多弹出count个元素, 列表为空之后删除key。
+key不存在的时候返回空的slice, key不是列表的时候返回WRONGTYPE。
+*/
+func ListPop(db *database.Database, key string, where int, count int) ([]string, error) {
+	tl, err := getTListFromDB(db, key)
+	if err == re.ErrNoSuchKey {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var values []string
+	if where == RedisListHead {
+		values = tl.LPop(count)
+	} else {
+		values = tl.RPop(count)
+	}
+	removeListIfEmpty(db, key, tl)
+	return values, nil
+}
+
+// 把value添加到列表的头部或者尾部, key不存在的时候创建列表
+func ListPush(db *database.Database, key string, where int, value string) error {
+	tl, err := getTListFromDB(db, key)
+	if err == re.ErrNoSuchKey {
+		obj := database.NewRedisListObject()
+		db.SetKeyInDB(key, obj)
+		tl = obj.(database.TList)
+	} else if err != nil {
+		return err
+	}
+	if where == RedisListHead {
+		tl.LPush([]string{value})
+	} else {
+		tl.RPush([]string{value})
+	}
+	return nil
+}
+
+/**
+从source的from端弹出一个元素添加到destination的to端, 返回弹出的元素。
+source为空的时候ok为false, destination不是列表的时候不会弹出元素并返回WRONGTYPE。
+*/
+func ListMove(db *database.Database, source, destination string, from, to int) (value string, ok bool, err error) {
+	if n, err := ListLen(db, source); err != nil || n == 0 {
+		return "", false, err
+	}
+	if _, err := ListLen(db, destination); err != nil {
+		return "", false, err
+	}
+	values, err := ListPop(db, source, from, 1)
+	if err != nil || len(values) == 0 {
+		return "", false, err
+	}
+	if err := ListPush(db, destination, to, values[0]); err != nil {
+		return "", false, err
+	}
+	return values[0], true, nil
+}
+
+/**
+解析LMPOP、BLMPOP的参数: numkeys key [key ...] LEFT|RIGHT [COUNT count], numKeysIndex是numkeys参数的位置。
+参数不合法的时候直接回复错误并返回false。
+*/
+func ParseMPopArgs(cli *client.Client, numKeysIndex int) (keys []string, where int, count int, ok bool) {
+	numKeys, err := strconv.ParseInt(string(cli.Argv[numKeysIndex]), 10, 64)
+	if err != nil {
+		cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
+		return nil, 0, 0, false
+	}
+	if numKeys <= 0 {
+		cli.ResponseReError(re.ErrNumKeysNotPositive)
+		return nil, 0, 0, false
+	}
+	whereIndex := numKeysIndex + 1 + int(numKeys)
+	if numKeys > int64(cli.Argc) || whereIndex >= cli.Argc {
+		cli.ResponseReError(re.ErrSyntaxError)
+		return nil, 0, 0, false
+	}
+	if where, ok = ParseListWhere(string(cli.Argv[whereIndex])); !ok {
+		cli.ResponseReError(re.ErrSyntaxError)
+		return nil, 0, 0, false
+	}
+	count = 1
+	hasCount := false
+	for i := whereIndex + 1; i < cli.Argc; i++ {
+		if strings.ToUpper(string(cli.Argv[i])) == "COUNT" && i+1 < cli.Argc && !hasCount {
+			n, err := strconv.ParseInt(string(cli.Argv[i+1]), 10, 64)
+			if err != nil || n <= 0 {
+				cli.ResponseReError(re.ErrCountNotPositive)
+				return nil, 0, 0, false
+			}
+			count = int(n)
+			hasCount = true
+			i++
+		} else {
+			cli.ResponseReError(re.ErrSyntaxError)
+			return nil, 0, 0, false
+		}
+	}
+	return util.BytesToStrings(cli.Argv[numKeysIndex+1 : whereIndex]), where, count, true
+}
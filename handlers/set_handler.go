@@ -6,6 +6,8 @@ import (
 	"github.com/SwanSpouse/redis_go/encodings"
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
 )
 
 const (
@@ -26,9 +28,10 @@ const (
 	RedisSetCommandSSCAN       = "SSCAN"
 )
 
-// SetHandler可以处理的一种rawType
+// SetHandler可以处理的rawType
 var setEncodingTypeDict = map[string]bool{
-	encodings.RedisEncodingHT: true,
+	encodings.RedisEncodingHT:     true,
+	encodings.RedisEncodingIntSet: true,
 }
 
 type SetHandler struct{}
@@ -59,7 +62,7 @@ func createSetIfNotExists(cli *client.Client, key string) error {
 }
 
 func (handler *SetHandler) SAdd(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createSetIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -67,7 +70,7 @@ func (handler *SetHandler) SAdd(cli *client.Client) {
 	if ts, err := getTSetValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		ret := ts.SAdd(cli.Argv[2:])
+		ret := ts.SAdd(util.BytesToStrings(cli.Argv[2:]))
 		cli.Response(ret)
 		if ret != 0 {
 			cli.Dirty += 1
@@ -76,7 +79,7 @@ func (handler *SetHandler) SAdd(cli *client.Client) {
 }
 
 func (handler *SetHandler) SCard(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.Response(0)
 	} else if err != nil {
@@ -103,20 +106,20 @@ func (handler *SetHandler) SInterStore(cli *client.Client) {
 }
 
 func (handler *SetHandler) SIsMember(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTSetValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(ts.SIsMember(cli.Argv[2]))
+		cli.Response(ts.SIsMember(string(cli.Argv[2])))
 	}
 }
 
 func (handler *SetHandler) SMembers(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTSetValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(ts.SMembers())
+		cli.Response(tcp.NewStringSetReply(ts.SMembers()))
 	}
 }
 
@@ -125,7 +128,7 @@ func (handler *SetHandler) SMove(cli *client.Client) {
 }
 
 func (handler *SetHandler) SPop(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTSetValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
@@ -139,11 +142,11 @@ func (handler *SetHandler) SPop(cli *client.Client) {
 }
 
 func (handler *SetHandler) SRem(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTSetValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		ret := ts.SRem(cli.Argv[2:])
+		ret := ts.SRem(util.BytesToStrings(cli.Argv[2:]))
 		cli.Response(ret)
 		if ret != 0 {
 			cli.Dirty += 1
@@ -152,7 +155,7 @@ func (handler *SetHandler) SRem(cli *client.Client) {
 }
 
 func (handler *SetHandler) SRandMember(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTSetValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
@@ -165,7 +168,7 @@ func (handler *SetHandler) SRandMember(cli *client.Client) {
 }
 
 func (handler *SetHandler) SDebug(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if ts, err := getTSetValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"strconv"
+
 	"github.com/SwanSpouse/redis_go/client"
 	"github.com/SwanSpouse/redis_go/database"
 	"github.com/SwanSpouse/redis_go/encodings"
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/tcp"
 	"github.com/SwanSpouse/redis_go/util"
 )
 
@@ -32,6 +35,7 @@ const (
 // SetHandler可以处理的一种rawType
 var setEncodingTypeSortedSet = map[string]bool{
 	encodings.RedisEncodingSkipList: true,
+	encodings.RedisEncodingListPack: true,
 }
 
 type SortedSetHandler struct {
@@ -63,7 +67,7 @@ func createZSetIfNotExists(cli *client.Client, key string) error {
 }
 
 func (handler *SortedSetHandler) ZAdd(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createZSetIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -71,7 +75,7 @@ func (handler *SortedSetHandler) ZAdd(cli *client.Client) {
 	if tss, err := getTZSetValueByKey(cli, key); err != nil {
 		cli.ResponseReError(err)
 	} else {
-		count, err := tss.ZAdd(cli.Argv[2:])
+		count, err := tss.ZAdd(util.BytesToStrings(cli.Argv[2:]))
 		if err != nil {
 			cli.ResponseReError(err)
 		} else {
@@ -84,7 +88,7 @@ func (handler *SortedSetHandler) ZAdd(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZCard(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.Response(0)
 	} else if err != nil {
@@ -95,13 +99,13 @@ func (handler *SortedSetHandler) ZCard(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZCount(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.Response(0)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		count, err := tss.ZCount(cli.Argv[2], cli.Argv[3])
+		count, err := tss.ZCount(string(cli.Argv[2]), string(cli.Argv[3]))
 		if err != nil {
 			cli.ResponseReError(err)
 		} else {
@@ -111,7 +115,7 @@ func (handler *SortedSetHandler) ZCount(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZIncrBy(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if err := createZSetIfNotExists(cli, key); err != nil {
 		cli.ResponseReError(err)
 		return
@@ -121,24 +125,24 @@ func (handler *SortedSetHandler) ZIncrBy(cli *client.Client) {
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		ret, err := tss.ZIncrBy(cli.Argv[2], cli.Argv[3])
+		ret, err := tss.ZIncrBy(string(cli.Argv[2]), string(cli.Argv[3]))
 		if err != nil {
 			cli.ResponseReError(err)
 		} else {
-			cli.Response(ret)
+			cli.Response(tcp.DoubleReply(strconv.FormatFloat(ret, 'f', -1, 64)))
 			cli.Dirty += 1
 		}
 	}
 }
 
 func (handler *SortedSetHandler) ZRange(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.ResponseReError(re.ErrEmptyListOrSet)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := tss.ZRange(cli.Argv[2], cli.Argv[3]); err != nil {
+		if ret, err := tss.ZRange(string(cli.Argv[2]), string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -147,13 +151,13 @@ func (handler *SortedSetHandler) ZRange(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZRangeByScore(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.ResponseReError(re.ErrEmptyListOrSet)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := tss.ZRangeByScore(cli.Argv[2], cli.Argv[3]); err != nil {
+		if ret, err := tss.ZRangeByScore(string(cli.Argv[2]), string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -162,13 +166,13 @@ func (handler *SortedSetHandler) ZRangeByScore(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZRank(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.Response(nil)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if rank, err := tss.ZRank(cli.Argv[2]); err != nil && err == re.ErrNoSuchKey {
+		if rank, err := tss.ZRank(string(cli.Argv[2])); err != nil && err == re.ErrNoSuchKey {
 			cli.Response(nil)
 		} else {
 			cli.Response(rank)
@@ -177,24 +181,24 @@ func (handler *SortedSetHandler) ZRank(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZRem(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.Response(0)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		cli.Response(tss.ZRem(cli.Argv[2:]))
+		cli.Response(tss.ZRem(util.BytesToStrings(cli.Argv[2:])))
 	}
 }
 
 func (handler *SortedSetHandler) ZRemRangeByRank(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.ResponseReError(re.ErrEmptyListOrSet)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := tss.ZRemRangeByRank(cli.Argv[2], cli.Argv[3]); err != nil {
+		if ret, err := tss.ZRemRangeByRank(string(cli.Argv[2]), string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -204,13 +208,13 @@ func (handler *SortedSetHandler) ZRemRangeByRank(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZRemRangeByScore(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.ResponseReError(re.ErrEmptyListOrSet)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := tss.ZRemRangeByScore(cli.Argv[2], cli.Argv[3]); err != nil {
+		if ret, err := tss.ZRemRangeByScore(string(cli.Argv[2]), string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -220,13 +224,13 @@ func (handler *SortedSetHandler) ZRemRangeByScore(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZRevRange(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.ResponseReError(re.ErrEmptyListOrSet)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := tss.ZRevRange(cli.Argv[2], cli.Argv[3]); err != nil {
+		if ret, err := tss.ZRevRange(string(cli.Argv[2]), string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -235,13 +239,13 @@ func (handler *SortedSetHandler) ZRevRange(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZRevRangeByScore(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.ResponseReError(re.ErrEmptyListOrSet)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := tss.ZRevRangeByScore(cli.Argv[2], cli.Argv[3]); err != nil {
+		if ret, err := tss.ZRevRangeByScore(string(cli.Argv[2]), string(cli.Argv[3])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -250,13 +254,13 @@ func (handler *SortedSetHandler) ZRevRangeByScore(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZRevRank(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.ResponseReError(re.ErrEmptyListOrSet)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if ret, err := tss.ZRevRank(cli.Argv[2]); err != nil {
+		if ret, err := tss.ZRevRank(string(cli.Argv[2])); err != nil {
 			cli.ResponseReError(err)
 		} else {
 			cli.Response(ret)
@@ -265,16 +269,16 @@ func (handler *SortedSetHandler) ZRevRank(cli *client.Client) {
 }
 
 func (handler *SortedSetHandler) ZScore(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if tss, err := getTZSetValueByKey(cli, key); err != nil && err == re.ErrNoSuchKey {
 		cli.Response(nil)
 	} else if err != nil {
 		cli.ResponseReError(err)
 	} else {
-		if score, err := tss.ZScore(cli.Argv[2]); err == re.ErrNoSuchKey {
+		if score, err := tss.ZScore(string(cli.Argv[2])); err == re.ErrNoSuchKey {
 			cli.Response(nil)
 		} else {
-			cli.Response(util.FloatToSimpleString(score))
+			cli.Response(tcp.DoubleReply(util.FloatToSimpleString(score)))
 		}
 	}
 }
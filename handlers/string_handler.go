@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/SwanSpouse/redis_go/client"
@@ -9,6 +9,8 @@ import (
 	"github.com/SwanSpouse/redis_go/encodings"
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
 )
 
 const (
@@ -34,6 +36,8 @@ const (
 	RedisStringCommandSetEX       = "SETEX"
 	RedisStringCommandSetRange    = "SETRANGE"
 	RedisStringCommandStrLen      = "STRLEN"
+
+	RedisStringMaxSize = 512 * 1024 * 1024 /* 字符串的最大长度, 和proto-max-bulk-len的默认值一致 */
 )
 
 // StringHandler可以处理的三种rawType
@@ -66,39 +70,97 @@ func getTStringValueByKey(cli *client.Client, key string) (database.TString, err
 	return nil, re.ErrWrongType
 }
 
+/**
+原地修改字符串之前调用: 共享对象以及int、embstr编码的对象不能被修改, 需要先复制成raw编码的对象再写回数据库
+*/
+func unshareTStringValue(cli *client.Client, key string, ts database.TString) database.TString {
+	if ts.IsShared() || ts.GetEncoding() != encodings.RedisEncodingRaw {
+		ts = database.NewRedisStringRawFrom(ts)
+		cli.SelectedDatabase().SetKeyInDB(key, ts)
+	}
+	return ts
+}
+
 func (handler *StringHandler) Append(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	ts, err := getTStringValueByKey(cli, key)
+	if err != nil && err != re.ErrNilValue {
+		cli.ResponseReError(err)
+		return
+	}
+	if err == re.ErrNilValue {
+		// key不存在的时候和SET一样创建新的字符串对象
+		cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(string(cli.Argv[2])))
+		cli.Response(len(cli.Argv[2]))
+		cli.Dirty += 1
+		return
+	}
+	if ts.Strlen()+len(cli.Argv[2]) > RedisStringMaxSize {
+		cli.ResponseReError(re.ErrStringExceedsMaxSize)
+		return
+	}
+	ts = unshareTStringValue(cli, key, ts)
+	cli.Response(ts.Append(string(cli.Argv[2])))
+	cli.SelectedDatabase().UpdateKeyMemory(key)
+	cli.Dirty += 1
+}
+
+func (handler *StringHandler) SetRange(cli *client.Client) {
+	key := string(cli.Argv[1])
+	offset, err := strconv.Atoi(string(cli.Argv[2]))
 	if err != nil {
+		cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
+		return
+	}
+	if offset < 0 {
+		cli.ResponseReError(re.ErrOffsetOutOfRange)
+		return
+	}
+	value := string(cli.Argv[3])
+	ts, err := getTStringValueByKey(cli, key)
+	if err != nil && err != re.ErrNilValue {
 		cli.ResponseReError(err)
 		return
 	}
-	// 如果TString的编码类型是int,转换成StringRaw再进行处理
-	if ts.GetEncoding() == encodings.RedisEncodingInt {
-		if valueInt, ok := ts.GetValue().(int64); !ok {
-			cli.ResponseReError(re.ErrWrongTypeOrEncoding)
+	if err == re.ErrNilValue {
+		// key不存在并且value为空的时候不创建key
+		if len(value) == 0 {
+			cli.Response(0)
+			return
+		}
+		if offset+len(value) > RedisStringMaxSize {
+			cli.ResponseReError(re.ErrStringExceedsMaxSize)
 			return
-		} else {
-			rs := database.NewRedisStringWithEncodingRawString(fmt.Sprintf("%d", valueInt), -1)
-			cli.SelectedDatabase().SetKeyInDB(key, rs)
-			ts = rs
 		}
+		ts = database.NewRedisStringWithEncodingRawString("", -1)
+		cli.SelectedDatabase().SetKeyInDB(key, ts)
+	} else {
+		if len(value) == 0 {
+			cli.Response(ts.Strlen())
+			return
+		}
+		if offset+len(value) > RedisStringMaxSize {
+			cli.ResponseReError(re.ErrStringExceedsMaxSize)
+			return
+		}
+		ts = unshareTStringValue(cli, key, ts)
 	}
-	cli.Response(ts.Append(cli.Argv[2]))
+	cli.Response(ts.SetRange(offset, value))
+	cli.SelectedDatabase().UpdateKeyMemory(key)
 	cli.Dirty += 1
 }
 
 func (handler *StringHandler) Set(cli *client.Client) {
-	key := cli.Argv[1]
-	cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(cli.Argv[2]))
+	key := string(cli.Argv[1])
+	cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(string(cli.Argv[2])))
 	cli.ResponseOK()
 	cli.Dirty += 1
 }
 
 func (handler *StringHandler) SetNx(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	if cli.SelectedDatabase().SearchKeyInDB(key) == nil {
-		cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(cli.Argv[2]))
+		cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(string(cli.Argv[2])))
 		cli.Response(1)
 		cli.Dirty += 1
 	} else {
@@ -114,7 +176,7 @@ func (handler *StringHandler) MSetNx(cli *client.Client) {
 	}
 	var containsKey bool
 	for i := 2; i < len(cli.Argv); i += 2 {
-		if cli.SelectedDatabase().SearchKeyInDB(cli.Argv[i]) != nil {
+		if cli.SelectedDatabase().SearchKeyInDB(string(cli.Argv[i])) != nil {
 			containsKey = true
 			break
 		}
@@ -123,7 +185,7 @@ func (handler *StringHandler) MSetNx(cli *client.Client) {
 		cli.Response(0)
 	} else {
 		for i := 2; i < len(cli.Argv); i += 2 {
-			cli.SelectedDatabase().SetKeyInDB(cli.Argv[i], database.NewRedisStringObject(cli.Argv[i+1]))
+			cli.SelectedDatabase().SetKeyInDB(string(cli.Argv[i]), database.NewRedisStringObject(string(cli.Argv[i+1])))
 		}
 		cli.Response(1)
 		cli.Dirty += 1
@@ -131,7 +193,7 @@ func (handler *StringHandler) MSetNx(cli *client.Client) {
 }
 
 func (handler *StringHandler) Get(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	ts, err := getTStringValueByKey(cli, key)
 	if err != nil {
 		cli.ResponseReError(err)
@@ -141,14 +203,14 @@ func (handler *StringHandler) Get(cli *client.Client) {
 }
 
 func (handler *StringHandler) GetSet(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	ts, err := getTStringValueByKey(cli, key)
 
 	if err != nil && err != re.ErrNilValue {
 		cli.ResponseReError(err)
 		return
 	}
-	cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(cli.Argv[2]))
+	cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(string(cli.Argv[2])))
 
 	if ts == nil {
 		cli.ResponseReError(re.ErrNilValue)
@@ -160,7 +222,7 @@ func (handler *StringHandler) GetSet(cli *client.Client) {
 
 func (handler *StringHandler) MGet(cli *client.Client) {
 	ret := make([]interface{}, 0)
-	for _, key := range cli.Argv[1:] {
+	for _, key := range util.BytesToStrings(cli.Argv[1:]) {
 		ts, err := getTStringValueByKey(cli, key)
 		if err != nil {
 			ret = append(ret, nil)
@@ -177,125 +239,98 @@ func (handler *StringHandler) MSet(cli *client.Client) {
 		return
 	}
 	for i := 1; i < len(cli.Argv); i += 2 {
-		cli.SelectedDatabase().SetKeyInDB(cli.Argv[i], database.NewRedisStringObject(cli.Argv[i+1]))
+		cli.SelectedDatabase().SetKeyInDB(string(cli.Argv[i]), database.NewRedisStringObject(string(cli.Argv[i+1])))
 	}
 	cli.Dirty += int64((len(cli.Argv) - 1) / 2)
 	cli.ResponseOK()
 }
 
-func (handler *StringHandler) Incr(cli *client.Client) {
-	key := cli.Argv[1]
+/**
+INCR、DECR、INCRBY、DECRBY:
+	只有不是共享对象的int编码对象可以原地修改, 并且修改之后的值不能使用共享对象;
+	其他情况下用修改之后的值创建一个新的对象(可能是共享对象)替换原来的对象, 保留原来的过期时间。
+*/
+func incrDecr(cli *client.Client, key string, incr int64) {
 	ts, err := getTStringValueByKey(cli, key)
 	if err != nil && err != re.ErrNilValue {
 		cli.ResponseReError(err)
-	} else if err == re.ErrNilValue {
-		cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject("1"))
-		cli.Response(1)
-	} else {
-		if ret, err := ts.Incr(); err != nil {
-			cli.ResponseReError(err)
-		} else {
-			cli.Response(ret)
-			cli.Dirty += 1
-		}
+		return
 	}
-}
-
-func (handler *StringHandler) IncrBy(cli *client.Client) {
-	key := cli.Argv[1]
-	ts, err := getTStringValueByKey(cli, key)
-	if err != nil && err != re.ErrNilValue {
-		cli.ResponseReError(err)
-	} else if err == re.ErrNilValue {
-		if _, err := strconv.ParseInt(cli.Argv[1], 10, 64); err != nil {
+	var value int64
+	if ts != nil {
+		var ok bool
+		if value, ok = util.StringToInt64(ts.String()); !ok {
 			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
-		} else {
-			cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(cli.Argv[2]))
-			cli.Response(cli.Argv[1])
+			return
 		}
-	} else if ret, err := ts.IncrBy(cli.Argv[2]); err != nil {
-		cli.ResponseReError(err)
+	}
+	if (incr < 0 && value < 0 && incr < math.MinInt64-value) ||
+		(incr > 0 && value > 0 && incr > math.MaxInt64-value) {
+		cli.ResponseReError(re.ErrIncrOrDecrOverflow)
+		return
+	}
+	value += incr
+	newValue := strconv.FormatInt(value, 10)
+	if ts != nil && !ts.IsShared() && ts.GetEncoding() == encodings.RedisEncodingInt &&
+		(value < 0 || value >= encodings.RedisSharedIntegers || !encodings.SharedIntegersEnabled) {
+		ts.SetValue(value)
 	} else {
-		cli.Response(ret)
-		cli.Dirty += 1
+		cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObjectFrom(newValue, ts, true))
 	}
+	cli.Response(value)
+	cli.Dirty += 1
 }
 
-func (handler *StringHandler) IncrByFloat(cli *client.Client) {
-	key := cli.Argv[1]
-	ts, err := getTStringValueByKey(cli, key)
-	if err != nil && err != re.ErrNilValue {
-		cli.ResponseReError(err)
-	} else if err == re.ErrNilValue {
-		if _, err := strconv.ParseFloat(cli.Argv[2], 64); err != nil {
-			cli.ResponseReError(re.ErrValueIsNotFloat)
-		} else {
-			cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(cli.Argv[2]))
-			cli.Response(cli.Argv[2])
-		}
+func (handler *StringHandler) Incr(cli *client.Client) {
+	incrDecr(cli, string(cli.Argv[1]), 1)
+}
+
+func (handler *StringHandler) IncrBy(cli *client.Client) {
+	if incr, err := strconv.ParseInt(string(cli.Argv[2]), 10, 64); err != nil {
+		cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
 	} else {
-		// 如果TString的编码类型是int,转换成StringRaw再进行处理
-		if ts.GetEncoding() == encodings.RedisEncodingInt {
-			if valueInt, ok := ts.GetValue().(int64); !ok {
-				cli.ResponseReError(re.ErrWrongTypeOrEncoding)
-				return
-			} else {
-				rs := database.NewRedisStringWithEncodingRawString(fmt.Sprintf("%d", valueInt), -1)
-				cli.SelectedDatabase().SetKeyInDB(key, rs)
-				ts = rs
-			}
-		}
-		if ret, err := ts.IncrByFloat(cli.Argv[2]); err != nil {
-			cli.ResponseReError(err)
-		} else {
-			cli.Response(ret)
-			cli.Dirty += 1
-		}
+		incrDecr(cli, string(cli.Argv[1]), incr)
 	}
 }
 
 func (handler *StringHandler) Decr(cli *client.Client) {
-	key := cli.Argv[1]
-	ts, err := getTStringValueByKey(cli, key)
-	if err != nil && err != re.ErrNilValue {
-		cli.ResponseReError(err)
-	} else if err == re.ErrNilValue {
-		cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject("-1"))
-		cli.Response("-1")
+	incrDecr(cli, string(cli.Argv[1]), -1)
+}
+
+func (handler *StringHandler) DecrBy(cli *client.Client) {
+	if decr, err := strconv.ParseInt(string(cli.Argv[2]), 10, 64); err != nil {
+		cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
+	} else if decr == math.MinInt64 {
+		cli.ResponseReError(re.ErrIncrOrDecrOverflow)
 	} else {
-		if ret, err := ts.Decr(); err != nil {
-			cli.ResponseReError(err)
-		} else {
-			cli.Response(ret)
-			cli.Dirty += 1
-		}
+		incrDecr(cli, string(cli.Argv[1]), -decr)
 	}
 }
 
-func (handler *StringHandler) DecrBy(cli *client.Client) {
-	key := cli.Argv[1]
+func (handler *StringHandler) IncrByFloat(cli *client.Client) {
+	key := string(cli.Argv[1])
 	ts, err := getTStringValueByKey(cli, key)
 	if err != nil && err != re.ErrNilValue {
 		cli.ResponseReError(err)
-	} else if err == re.ErrNilValue {
-		if _, err := strconv.ParseInt(cli.Argv[2], 10, 64); err != nil {
-			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
-		} else {
-			cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObject(cli.Argv[2]))
-			cli.Response(cli.Argv[2])
-		}
+		return
+	}
+	value := "0"
+	if ts != nil {
+		value = ts.String()
+	}
+	// 在raw编码的临时对象上计算, 结果作为新的字符串对象写回数据库, 不使用int编码
+	rs := database.NewRedisStringWithEncodingRawString(value, -1)
+	if ret, err := rs.IncrByFloat(string(cli.Argv[2])); err != nil {
+		cli.ResponseReError(err)
 	} else {
-		if ret, err := ts.DecrBy(cli.Argv[2]); err != nil {
-			cli.ResponseReError(err)
-		} else {
-			cli.Response(ret)
-			cli.Dirty += 1
-		}
+		cli.SelectedDatabase().SetKeyInDB(key, database.NewRedisStringObjectFrom(ret, ts, false))
+		cli.Response(tcp.DoubleReply(ret))
+		cli.Dirty += 1
 	}
 }
 
 func (handler *StringHandler) Strlen(cli *client.Client) {
-	key := cli.Argv[1]
+	key := string(cli.Argv[1])
 	ts, err := getTStringValueByKey(cli, key)
 	if err != nil {
 		cli.ResponseReError(err)
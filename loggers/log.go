@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/kusora/raven-go"
 	"os"
+	"sync/atomic"
 )
 
 const (
@@ -18,6 +19,15 @@ const (
 
 var Level int64 = INFO
 
+// CONFIG SET loglevel的时候其他goroutine可能正在打印日志, 需要原子的读写Level
+func SetLevel(level int64) {
+	atomic.StoreInt64(&Level, level)
+}
+
+func GetLevel() int64 {
+	return atomic.LoadInt64(&Level)
+}
+
 var lg *Logger
 
 func init() {
@@ -25,32 +35,32 @@ func init() {
 }
 
 func Info(format string, v ...interface{}) {
-	if Level >= INFO {
+	if GetLevel() >= INFO {
 		lg.Output(2, fmt.Sprintf("%c[1;40;32m%s%c[0m\n", 0x1B, fmt.Sprintf("[INFO] "+format, v...), 0x1B))
 	}
 }
 
 func Warn(format string, v ...interface{}) {
-	if Level >= WARN {
+	if GetLevel() >= WARN {
 		lg.Output(2, fmt.Sprintf("%c[1;40;33m%s%c[0m\n", 0x1B, fmt.Sprintf("[WARN] "+format, v...), 0x1B))
 	}
 }
 
 func Errorf(format string, v ...interface{}) {
-	if Level >= ERROR {
+	if GetLevel() >= ERROR {
 		msg := fmt.Sprintf("%c[1;40;31m%s%c[0m\n", 0x1B, fmt.Sprintf("[ERROR] "+format, v...), 0x1B)
 		lg.Output(2, msg)
 	}
 }
 
 func Debug(format string, v ...interface{}) {
-	if Level >= DEBUG {
+	if GetLevel() >= DEBUG {
 		lg.Output(2, fmt.Sprintf("%c[1;40;44m%s%c[0m\n", 0x1B, fmt.Sprintf("[DEBUG] "+format, v...), 0x1B))
 	}
 }
 
 func Fatal(format string, v ...interface{}) {
-	if Level >= FATAL {
+	if GetLevel() >= FATAL {
 		msg := fmt.Sprintf(format+"\n", v...)
 		lg.Output(2, "[FATAL] "+msg)
 		raven.CaptureError(errors.New(msg), map[string]string{"level": "fatal"})
@@ -59,7 +69,7 @@ func Fatal(format string, v ...interface{}) {
 }
 
 func Panic(format string, v ...interface{}) {
-	if Level >= PANIC {
+	if GetLevel() >= PANIC {
 		msg := fmt.Sprintf("[PANIC] "+format+"\n", v...)
 		lg.Output(2, msg)
 		panic(msg)
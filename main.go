@@ -25,7 +25,7 @@ import (
 )
 
 // Run runs your Service.
-// Run will block until one of the signals specified is received.
+// Run will block until one of the signals specified is received or SHUTDOWN is executed.
 func main() {
 	prg := &server.Program{}
 	prg.Init()
@@ -34,7 +34,15 @@ func main() {
 	sig := []os.Signal{syscall.SIGINT, syscall.SIGTERM}
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, sig...)
-	<-signalChan
+	// 收到信号或者执行了SHUTDOWN命令之后退出
+	for shutdown := false; !shutdown; {
+		select {
+		case <-signalChan:
+			shutdown = prg.Terminate() == nil
+		case <-prg.ShutdownChan:
+			shutdown = true
+		}
+	}
 
 	prg.Stop()
 }
@@ -0,0 +1,268 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestClientCommand", func() {
+	var cn net.Conn
+	var w *RequestWriter
+	var r *ResponseReader
+
+	BeforeEach(func() {
+		var err error
+		cn, err = net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+	})
+
+	AfterEach(func() {
+		cn.Close()
+	})
+
+	It("test client setname getname", func() {
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandGetName)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("NIL"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandSetName, "my client")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR Client names cannot contain spaces, newlines or special characters."))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandSetName, "my-client")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandGetName)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("my-client"))
+	})
+
+	It("test client id info list", func() {
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandID)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		id := ret[0]
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandInfo)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HavePrefix(fmt.Sprintf("id=%s addr=%s ", id, cn.LocalAddr())))
+		Expect(ret[0]).To(ContainSubstring(" cmd=client "))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandList, "ID", id)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(strings.Count(ret[0], "\n")).To(Equal(1))
+		Expect(ret[0]).To(HavePrefix(fmt.Sprintf("id=%s ", id)))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandList, "TYPE", "unknown")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR Unknown client type 'unknown'"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, "unknown")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR Unknown CLIENT subcommand or wrong number of arguments for unknown"))
+	})
+
+	It("test client kill", func() {
+		other, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		defer other.Close()
+		ow := NewRequestWriter(other)
+		or := NewResponseReader(other)
+		ow.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandID)
+		ow.Flush()
+		ret, err := or.Read()
+		Expect(err).To(BeNil())
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandKill, "ID", ret[0])
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("1"))
+
+		other.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = or.Read()
+		Expect(err).NotTo(BeNil())
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandKill, "127.0.0.1:1")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR No such client"))
+
+		// 默认不会关闭自己
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandKill, "ADDR", cn.LocalAddr().String())
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("0"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandKill, "ADDR", cn.LocalAddr().String(), "SKIPME", "no")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("1"))
+		cn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = r.Read()
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("test client reply", func() {
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandReply, "SKIP")
+		w.WriteCmdString("SET", "client-reply-key", "skip")
+		w.WriteCmdString("GET", "client-reply-key")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandReply, "OFF")
+		w.WriteCmdString("SET", "client-reply-key", "off")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandReply, "ON")
+		w.WriteCmdString("GET", "client-reply-key")
+		w.Flush()
+
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("skip"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("off"))
+	})
+
+	It("test client pause unpause", func() {
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandPause, "-1")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR timeout is negative"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandPause, "100", "READ")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR CLIENT PAUSE mode must be WRITE or ALL"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandPause, "200", "WRITE")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		// 读命令不会被暂停，写命令要等到暂停结束之后才会执行
+		w.WriteCmdString("GET", "client-pause-key")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("NIL"))
+
+		start := time.Now()
+		w.WriteCmdString("SET", "client-pause-key", "value")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+		Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandPause, "100000")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandUnpause)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		w.WriteCmdString("DEL", "client-pause-key")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("1"))
+	})
+
+	It("test client no-evict", func() {
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandNoEvict, "ON")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandInfo)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring(" flags=e "))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandNoEvict, "maybe")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR syntax error"))
+	})
+
+	It("test client output buffer limit", func() {
+		w.WriteCmdString("SET", "client-obuf-key", strings.Repeat("a", 4096))
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "client-output-buffer-limit", "normal 1kb 0 0")
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandGet, "client-output-buffer-limit")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[1]).To(Equal("normal 1024 0 0 replica 268435456 67108864 60 pubsub 33554432 8388608 60"))
+
+		// 回复超过了hard limit, 连接会被关闭
+		w.WriteCmdString("GET", "client-obuf-key")
+		w.Flush()
+		cn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = r.Read()
+		Expect(err).NotTo(BeNil())
+
+		other, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		defer other.Close()
+		ow := NewRequestWriter(other)
+		or := NewResponseReader(other)
+		ow.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "client-output-buffer-limit", "normal 0 0 0")
+		ow.WriteCmdString("DEL", "client-obuf-key")
+		ow.Flush()
+		ret, err = or.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+		ret, err = or.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("1"))
+	})
+})
@@ -3,7 +3,10 @@ package mock
 import (
 	"fmt"
 	"net"
+	"strings"
+	"time"
 
+	"github.com/SwanSpouse/redis_go/client"
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/server"
 	"github.com/SwanSpouse/redis_go/tcp"
@@ -79,4 +82,130 @@ var _ = Describe("MockRedisClient", func() {
 		Expect(err).To(BeNil())
 		Expect(ret[0]).To(Equal(re.ErrInvalidBulkLength.Error()))
 	})
+
+	It("test connection closed after protocol error", func() {
+		w.WriteRawString("*2147483648\r\n*1\r\n$4\r\nPING\r\n")
+		err := w.Flush()
+		Expect(err).To(BeNil())
+
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal(re.ErrInvalidMultiBulkLength.Error()))
+
+		// 协议错误之后不再处理后面的请求
+		cn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = r.Read()
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("test input err too big bulk length", func() {
+		w.WriteRawString("*2\r\n$3\r\nget\r\n$1073741824\r\n")
+		err := w.Flush()
+		Expect(err).To(BeNil())
+
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal(re.ErrInvalidBulkLength.Error()))
+	})
+
+	It("test inline command", func() {
+		w.WriteRawString("PING\r\n\r\n  \n")
+		w.WriteRawString("SET inline-key \"my \\x41\\tvalue\"\n")
+		w.WriteRawString("GET 'inline-key'\r\n")
+		w.WriteRawString("DEL inline-key\r\n")
+		err := w.Flush()
+		Expect(err).To(BeNil())
+
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("PONG"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("my A\tvalue"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("1"))
+	})
+
+	It("test inline command unbalanced quotes", func() {
+		w.WriteRawString("SET \"inline-key value\r\nPING\r\n")
+		err := w.Flush()
+		Expect(err).To(BeNil())
+
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal(re.ErrUnbalancedQuotes.Error()))
+
+		cn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = r.Read()
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("test inline command too long", func() {
+		w.WriteRawString("SET key " + strings.Repeat("x", tcp.MaxInlineSize))
+		err := w.Flush()
+		Expect(err).To(BeNil())
+
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal(re.ErrInlineRequestTooLong.Error()))
+	})
+
+	It("test empty and binary arguments", func() {
+		value := string([]byte{0, 1, '\r', '\n', 0xff, '$', '*'}) + strings.Repeat("b", client.RedisMBulkBigArg)
+		w.WriteCmdString("SET", "", "")
+		w.WriteCmdString("GET", "")
+		w.WriteCmdString("SET", "binary-key\x00", value)
+		w.WriteCmdString("APPEND", "binary-key\x00", "")
+		w.WriteCmdString("GET", "binary-key\x00")
+		w.WriteCmdString("DEL", "", "binary-key\x00")
+		err := w.Flush()
+		Expect(err).To(BeNil())
+
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal(""))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal(fmt.Sprintf("%d", len(value))))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal(value))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("2"))
+	})
+	It("test stored values do not share the reused argument buffer", func() {
+		w.WriteCmdString("SET", "argbuf-key", "value-1")
+		w.WriteCmdString("RPUSH", "argbuf-list", "item-1", "item-2")
+		// 后面的请求会覆盖复用的参数缓冲区
+		w.WriteCmdString("SET", "argbuf-other", "xxxxxxx")
+		w.WriteCmdString("RPUSH", "argbuf-other-list", "yyyyyy", "zzzzzz")
+		w.WriteCmdString("GET", "argbuf-key")
+		w.WriteCmdString("LRANGE", "argbuf-list", "0", "-1")
+		w.WriteCmdString("DEL", "argbuf-key", "argbuf-list", "argbuf-other", "argbuf-other-list")
+		err := w.Flush()
+		Expect(err).To(BeNil())
+
+		for _, expected := range []string{"OK", "2", "OK", "2", "value-1"} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret[0]).To(Equal(expected))
+		}
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(Equal([]string{"item-1", "item-2"}))
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("4"))
+	})
 })
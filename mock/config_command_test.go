@@ -0,0 +1,109 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestConfigCommand", func() {
+	var w *RequestWriter
+	var r *ResponseReader
+
+	BeforeEach(func() {
+		cn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+	})
+
+	It("test config get", func() {
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandGet, "maxmemory*")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(Equal([]string{"maxmemory", "0", "maxmemory-policy", "noeviction", "maxmemory-samples", "5"}))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandGet, "PORT", "port")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(Equal([]string{"port", fmt.Sprintf("%d", MockPort)}))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandGet, "no-such-config")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("(empty list or set)"))
+	})
+
+	It("test config set", func() {
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "slowlog-max-len", "64", "maxmemory-policy", "allkeys-lru")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandGet, "slowlog-max-len", "maxmemory-policy")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(ConsistOf("slowlog-max-len", "64", "maxmemory-policy", "allkeys-lru"))
+
+		// 校验失败的时候所有的配置项都不会被修改
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "slowlog-max-len", "128", "maxmemory-policy", "unknown")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HavePrefix("ERR CONFIG SET failed (possibly related to argument 'maxmemory-policy')"))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandGet, "slowlog-max-len")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(Equal([]string{"slowlog-max-len", "64"}))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "port", "6379")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("can't set immutable config"))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "no-such-config", "1")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HavePrefix("ERR Unknown option or number of arguments for CONFIG SET"))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "save", "60")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("Invalid save parameters"))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "rdb-filename", "appendonly.aof")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR CONFIG SET failed (possibly related to argument 'aof-filename') - can not be the same file as 'rdb-filename'"))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "slowlog-max-len", "128", "maxmemory-policy", "noeviction")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+	})
+
+	It("test config rewrite without config file", func() {
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandRewrite)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR The server is running without a config file"))
+	})
+})
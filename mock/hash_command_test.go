@@ -3,6 +3,7 @@ package mock
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/SwanSpouse/redis_go/handlers"
 	"github.com/SwanSpouse/redis_go/server"
@@ -194,4 +195,31 @@ var _ = Describe("TestRedisHashCommand", func() {
 		Expect(err).To(BeNil())
 		Expect(ret[0]).To(Equal("0.999"))
 	})
+
+	It("Test redis hash encoding listpack and hashtable", func() {
+		key, bigKey := "hash_encoding_key", "hash_encoding_big_key"
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "hash-max-listpack-entries", "2")
+		w.WriteCmdString(handlers.RedisHashCommandHSet, key, "f1", "v1")
+		w.WriteCmdString(handlers.RedisHashCommandHSet, key, "f2", "v2")
+		w.WriteCmdString(handlers.RedisHashCommandHSet, key, "f1", "v3")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisHashCommandHGetAll, key)
+		w.WriteCmdString(handlers.RedisHashCommandHDel, key, "f2", "f3")
+		w.WriteCmdString(handlers.RedisHashCommandHIncrBy, key, "counter", "5")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisHashCommandHSet, key, "f4", "v4")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisHashCommandHMGet, key, "f1", "counter", "f4")
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "hash-max-listpack-entries", "128")
+		// value超过hash-max-listpack-value之后转换成hashtable
+		w.WriteCmdString(handlers.RedisHashCommandHSet, bigKey, "f", strings.Repeat("v", 65))
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", bigKey)
+		w.Flush()
+		for _, expected := range [][]string{{"OK"}, {"1"}, {"1"}, {"0"}, {"listpack"}, {"f1", "v3", "f2", "v2"},
+			{"1"}, {"5"}, {"listpack"}, {"1"}, {"hashtable"}, {"v3", "5", "v4"}, {"OK"}, {"1"}, {"hashtable"}} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+	})
 })
@@ -0,0 +1,119 @@
+package mock
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestHelloCommand", func() {
+	var cn net.Conn
+	var w *RequestWriter
+	var br *bufio.Reader
+
+	// RESP3的回复直接按照原始的字节进行比较
+	expectRaw := func(expected string) {
+		buf := make([]byte, len(expected))
+		cn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err := io.ReadFull(br, buf)
+		Expect(err).To(BeNil())
+		Expect(string(buf)).To(Equal(expected))
+	}
+	helloReply := func(aggregate string, proto int, id string) string {
+		return fmt.Sprintf("%s\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$%d\r\n%s\r\n$5\r\nproto\r\n:%d\r\n$2\r\nid\r\n:%s\r\n"+
+			"$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n",
+			aggregate, len(conf.RedisVersion), conf.RedisVersion, proto, id)
+	}
+
+	BeforeEach(func() {
+		var err error
+		cn, err = net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		w = NewRequestWriter(cn)
+		br = bufio.NewReader(cn)
+	})
+
+	AfterEach(func() {
+		cn.Close()
+	})
+
+	It("test hello errors", func() {
+		w.WriteCmdString(handlers.RedisConnectionCommandHello, "4")
+		w.WriteCmdString(handlers.RedisConnectionCommandHello, "three")
+		w.WriteCmdString(handlers.RedisConnectionCommandHello, "3", "AUTH", "someone", "password")
+		w.WriteCmdString(handlers.RedisConnectionCommandHello, "3", "SETNAME")
+		w.WriteCmdString(handlers.RedisConnectionCommandHello, "3", "SETNAME", "bad name")
+		w.Flush()
+		expectRaw("-NOPROTO unsupported protocol version\r\n")
+		expectRaw("-ERR Protocol version is not an integer or out of range\r\n")
+		expectRaw("-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+		expectRaw("-ERR Syntax error in HELLO option 'SETNAME'\r\n")
+		expectRaw("-ERR Client names cannot contain spaces, newlines or special characters.\r\n")
+	})
+
+	It("test hello switch protocol", func() {
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandID)
+		w.Flush()
+		line, err := br.ReadString('\n')
+		Expect(err).To(BeNil())
+		id := line[1 : len(line)-2]
+
+		w.WriteCmdString(handlers.RedisConnectionCommandHello)
+		w.Flush()
+		expectRaw(helloReply("*14", 2, id))
+
+		w.WriteCmdString(handlers.RedisConnectionCommandHello, "3", "AUTH", "default", "password", "SETNAME", "hello-client")
+		w.Flush()
+		expectRaw(helloReply("%7", 3, id))
+
+		w.WriteCmdString(handlers.RedisStringCommandGet, "hello-no-such-key")
+		w.WriteCmdString(handlers.RedisHashCommandHSet, "hello-hash", "field", "value")
+		w.WriteCmdString(handlers.RedisHashCommandHGetAll, "hello-hash")
+		w.WriteCmdString(handlers.RedisSetCommandSADD, "hello-set", "member")
+		w.WriteCmdString(handlers.RedisSetCommandSMEMBERS, "hello-set")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZAdd, "hello-zset", "1.5", "member")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZScore, "hello-zset", "member")
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandGet, "no-such-config")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandGetName)
+		w.Flush()
+		expectRaw("_\r\n")
+		expectRaw(":1\r\n")
+		expectRaw("%1\r\n$5\r\nfield\r\n$5\r\nvalue\r\n")
+		expectRaw(":1\r\n")
+		expectRaw("~1\r\n$6\r\nmember\r\n")
+		expectRaw(":1\r\n")
+		expectRaw(",1.5\r\n")
+		expectRaw("%0\r\n")
+		expectRaw("$12\r\nhello-client\r\n")
+
+		w.WriteCmdString(server.RedisServerCommandInfo, "server")
+		w.Flush()
+		line, err = br.ReadString('\n')
+		Expect(err).To(BeNil())
+		Expect(line[0]).To(Equal(byte('=')))
+		var size int
+		fmt.Sscanf(line[1:], "%d", &size)
+		expectRaw("txt:")
+		_, err = io.ReadFull(br, make([]byte, size-4+2))
+		Expect(err).To(BeNil())
+
+		w.WriteCmdString(handlers.RedisConnectionCommandHello, "2")
+		w.WriteCmdString(handlers.RedisStringCommandGet, "hello-no-such-key")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZScore, "hello-zset", "member")
+		w.WriteCmdString(handlers.RedisKeyCommandDel, "hello-hash", "hello-set", "hello-zset")
+		w.Flush()
+		expectRaw(helloReply("*14", 2, id))
+		expectRaw("$-1\r\n")
+		expectRaw("$3\r\n1.5\r\n")
+		expectRaw(":3\r\n")
+	})
+})
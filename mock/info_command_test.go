@@ -0,0 +1,149 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestInfoCommand", func() {
+	var w *RequestWriter
+	var r *ResponseReader
+
+	var stringKey = "redis_info_command_key_string"
+
+	BeforeEach(func() {
+		cn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+
+		// first truncate all DB
+		w.WriteCmdString(server.RedisServerCommandFlushAll)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+	})
+
+	It("test info default sections", func() {
+		w.WriteCmdString(handlers.RedisStringCommandSet, stringKey, "value")
+		w.Flush()
+		_, err := r.Read()
+		Expect(err).To(BeNil())
+
+		w.WriteCmdString(server.RedisServerCommandInfo)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("# Server\r\n"))
+		Expect(ret[0]).To(ContainSubstring("redis_version:"))
+		Expect(ret[0]).To(ContainSubstring("# Clients\r\n"))
+		Expect(ret[0]).To(ContainSubstring("# Stats\r\n"))
+		Expect(ret[0]).To(ContainSubstring("total_commands_processed:"))
+		Expect(ret[0]).To(ContainSubstring("# Keyspace\r\n"))
+		Expect(ret[0]).To(ContainSubstring("db0:keys=1,expires=0"))
+		Expect(ret[0]).NotTo(ContainSubstring("# Commandstats"))
+	})
+
+	It("test info multiple sections", func() {
+		w.WriteCmdString(server.RedisServerCommandInfo, server.RedisInfoSectionServer, server.RedisInfoSectionCPU)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("# Server\r\n"))
+		Expect(ret[0]).To(ContainSubstring("# CPU\r\n"))
+		Expect(ret[0]).NotTo(ContainSubstring("# Keyspace"))
+
+		w.WriteCmdString(server.RedisServerCommandInfo, "unknown_section")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal(""))
+	})
+
+	It("test info errorstats", func() {
+		w.WriteCmdString(handlers.RedisStringCommandSet, stringKey, "value")
+		w.Flush()
+		_, err := r.Read()
+		Expect(err).To(BeNil())
+
+		w.WriteCmdString(handlers.RedisListCommandLPush, stringKey, "value")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HavePrefix("WRONGTYPE"))
+
+		w.WriteCmdString(server.RedisServerCommandInfo, server.RedisInfoSectionErrorStats)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("# Errorstats\r\n"))
+		Expect(ret[0]).To(ContainSubstring("errorstat_WRONGTYPE:count="))
+	})
+	It("test info commandstats & latencystats", func() {
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandResetStat)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		w.WriteCmdString(handlers.RedisStringCommandSet, stringKey, "value")
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+
+		// 参数个数错误的命令会被记录为rejected_calls
+		w.WriteCmdString(handlers.RedisStringCommandGet)
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+
+		// 执行过程中返回错误的命令会被记录为failed_calls
+		w.WriteCmdString(handlers.RedisListCommandLPush, stringKey, "value")
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+
+		w.WriteCmdString(server.RedisServerCommandInfo, server.RedisInfoSectionCommandStats)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("# Commandstats\r\n"))
+		Expect(ret[0]).To(MatchRegexp(`cmdstat_set:calls=1,usec=\d+,usec_per_call=[\d.]+,rejected_calls=0,failed_calls=0`))
+		Expect(ret[0]).To(ContainSubstring("cmdstat_get:calls=0,usec=0,usec_per_call=0.00,rejected_calls=1,failed_calls=0"))
+		Expect(ret[0]).To(MatchRegexp(`cmdstat_lpush:calls=1,usec=\d+,usec_per_call=[\d.]+,rejected_calls=0,failed_calls=1`))
+
+		w.WriteCmdString(server.RedisServerCommandInfo, server.RedisInfoSectionLatencyStats)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("# Latencystats\r\n"))
+		Expect(ret[0]).To(MatchRegexp(`latency_percentiles_usec_set:p50=[\d.]+,p99=[\d.]+,p99.9=[\d.]+`))
+		Expect(ret[0]).NotTo(ContainSubstring("latency_percentiles_usec_get:"))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandResetStat)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		w.WriteCmdString(server.RedisServerCommandInfo, server.RedisInfoSectionCommandStats)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).NotTo(ContainSubstring("cmdstat_set:"))
+
+		w.WriteCmdString(server.RedisServerCommandConfig, "unknown")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HavePrefix("ERR Unknown CONFIG subcommand"))
+	})
+})
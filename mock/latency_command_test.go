@@ -0,0 +1,68 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestLatencyCommand", func() {
+	var w *RequestWriter
+	var r *ResponseReader
+
+	BeforeEach(func() {
+		cn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+
+		w.WriteCmdString(server.RedisServerCommandLatency, server.RedisLatencySubCommandReset)
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+	})
+
+	It("test latency & debug sleep", func() {
+		w.WriteCmdString(server.RedisServerCommandDebug, server.RedisDebugSubCommandSleep, "0.01")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		// 默认关闭latency monitor, 不会记录任何事件
+		w.WriteCmdString(server.RedisServerCommandLatency, server.RedisLatencySubCommandLatest)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(BeEmpty())
+
+		w.WriteCmdString(server.RedisServerCommandLatency, server.RedisLatencySubCommandDoctor)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("Latency monitoring is disabled"))
+
+		w.WriteCmdString(server.RedisServerCommandLatency, server.RedisLatencySubCommandGraph, server.LatencyEventCommand)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR No samples available for event 'command'"))
+
+		w.WriteCmdString(server.RedisServerCommandLatency, server.RedisLatencySubCommandReset, server.LatencyEventCommand)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("0"))
+
+		w.WriteCmdString(server.RedisServerCommandDebug, server.RedisDebugSubCommandSleep, "abc")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HavePrefix("ERR"))
+	})
+})
@@ -0,0 +1,191 @@
+package mock
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestListBlockingCommand", func() {
+	var cn, first, second net.Conn
+	var w, fw, sw *RequestWriter
+	var r *ResponseReader
+	var fbr, sbr *bufio.Reader
+
+	expectRaw := func(cn net.Conn, br *bufio.Reader, expected string) {
+		buf := make([]byte, len(expected))
+		cn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err := io.ReadFull(br, buf)
+		Expect(err).To(BeNil())
+		Expect(string(buf)).To(Equal(expected))
+	}
+	// 等待阻塞的客户端的个数达到n
+	waitBlocked := func(n int) {
+		for i := 0; i < 100; i++ {
+			w.WriteCmdString(server.RedisServerCommandInfo, "clients")
+			w.Flush()
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			if strings.Contains(ret[0], fmt.Sprintf("blocked_clients:%d\r\n", n)) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		Fail(fmt.Sprintf("blocked clients should be %d", n))
+	}
+
+	BeforeEach(func() {
+		var err error
+		cn, err = net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+
+		first, err = net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		fw = NewRequestWriter(first)
+		fbr = bufio.NewReader(first)
+
+		second, err = net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		sw = NewRequestWriter(second)
+		sbr = bufio.NewReader(second)
+	})
+
+	AfterEach(func() {
+		cn.Close()
+		first.Close()
+		second.Close()
+	})
+
+	It("test blocking pop without blocking", func() {
+		fw.WriteCmdString(handlers.RedisListCommandRPush, "bl-list", "a", "b", "c")
+		fw.WriteCmdString(handlers.RedisListCommandBLPop, "bl-empty", "bl-list", "0")
+		fw.WriteCmdString(handlers.RedisListCommandBRPop, "bl-list", "0")
+		fw.WriteCmdString(handlers.RedisListCommandBLMPop, "0", "2", "bl-empty", "bl-list", "LEFT", "COUNT", "5")
+		fw.WriteCmdString(handlers.RedisKeyCommandExists, "bl-list")
+		fw.Flush()
+		expectRaw(first, fbr, ":3\r\n")
+		expectRaw(first, fbr, "*2\r\n$7\r\nbl-list\r\n$1\r\na\r\n")
+		expectRaw(first, fbr, "*2\r\n$7\r\nbl-list\r\n$1\r\nc\r\n")
+		expectRaw(first, fbr, "*2\r\n$7\r\nbl-list\r\n*1\r\n$1\r\nb\r\n")
+		expectRaw(first, fbr, ":0\r\n")
+	})
+
+	It("test blocking errors and timeout", func() {
+		fw.WriteCmdString(handlers.RedisStringCommandSet, "bl-string", "value")
+		fw.WriteCmdString(handlers.RedisListCommandBLPop, "bl-string", "0")
+		fw.WriteCmdString(handlers.RedisListCommandBLPop, "bl-key", "-1")
+		fw.WriteCmdString(handlers.RedisListCommandBLPop, "bl-key", "abc")
+		fw.WriteCmdString(handlers.RedisListCommandBLMove, "bl-src", "bl-dst", "UP", "LEFT", "0")
+		fw.WriteCmdString(handlers.RedisListCommandBLMPop, "0", "0", "bl-key", "LEFT")
+		fw.WriteCmdString(handlers.RedisListCommandBLMPop, "0", "1", "bl-key", "LEFT", "COUNT", "0")
+		fw.WriteCmdString(handlers.RedisKeyCommandDel, "bl-string")
+		fw.Flush()
+		expectRaw(first, fbr, "+OK\r\n")
+		expectRaw(first, fbr, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n")
+		expectRaw(first, fbr, "-ERR timeout is negative\r\n")
+		expectRaw(first, fbr, "-ERR timeout is not a float or out of range\r\n")
+		expectRaw(first, fbr, "-ERR syntax error\r\n")
+		expectRaw(first, fbr, "-ERR numkeys should be greater than 0\r\n")
+		expectRaw(first, fbr, "-ERR count should be greater than 0\r\n")
+		expectRaw(first, fbr, ":1\r\n")
+
+		start := time.Now()
+		fw.WriteCmdString(handlers.RedisListCommandBLPop, "bl-key", "0.1")
+		fw.WriteCmdString(handlers.RedisListCommandBRPopLPush, "bl-key", "bl-dst", "0.1")
+		fw.Flush()
+		expectRaw(first, fbr, "*-1\r\n")
+		expectRaw(first, fbr, "$-1\r\n")
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+	})
+
+	It("test blocking clients are served in order", func() {
+		fw.WriteCmdString(handlers.RedisListCommandBLPop, "bl-fifo", "0")
+		fw.Flush()
+		waitBlocked(1)
+		sw.WriteCmdString(handlers.RedisListCommandBRPop, "bl-other", "bl-fifo", "0")
+		sw.Flush()
+		waitBlocked(2)
+
+		w.WriteCmdString(handlers.RedisListCommandRPush, "bl-fifo", "x", "y", "z")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("3"))
+		expectRaw(first, fbr, "*2\r\n$7\r\nbl-fifo\r\n$1\r\nx\r\n")
+		expectRaw(second, sbr, "*2\r\n$7\r\nbl-fifo\r\n$1\r\nz\r\n")
+
+		w.WriteCmdString(handlers.RedisListCommandLRange, "bl-fifo", "0", "-1")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(Equal([]string{"y"}))
+		waitBlocked(0)
+	})
+
+	It("test brpoplpush and blmove", func() {
+		// 第二个客户端阻塞在destination上, 元素被移动之后也会被唤醒
+		sw.WriteCmdString(handlers.RedisListCommandBLPop, "bl-processing", "0")
+		sw.Flush()
+		waitBlocked(1)
+		fw.WriteCmdString(handlers.RedisListCommandBRPopLPush, "bl-jobs", "bl-processing", "0")
+		fw.Flush()
+		waitBlocked(2)
+
+		w.WriteCmdString(handlers.RedisListCommandLPush, "bl-jobs", "job")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("1"))
+		expectRaw(first, fbr, "$3\r\njob\r\n")
+		expectRaw(second, sbr, "*2\r\n$13\r\nbl-processing\r\n$3\r\njob\r\n")
+
+		fw.WriteCmdString(handlers.RedisListCommandBLMove, "bl-jobs", "bl-done", "LEFT", "RIGHT", "0")
+		fw.Flush()
+		waitBlocked(1)
+		w.WriteCmdString(handlers.RedisListCommandRPush, "bl-jobs", "a", "b")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("2"))
+		expectRaw(first, fbr, "$1\r\na\r\n")
+
+		w.WriteCmdString(handlers.RedisKeyCommandDel, "bl-jobs", "bl-done", "bl-processing")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("2"))
+	})
+
+	It("test client kill unblocks client", func() {
+		fw.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandID)
+		fw.Flush()
+		first.SetReadDeadline(time.Now().Add(time.Second))
+		line, err := fbr.ReadString('\n')
+		Expect(err).To(BeNil())
+		id := line[1 : len(line)-2]
+
+		fw.WriteCmdString(handlers.RedisListCommandBLPop, "bl-kill", "0")
+		fw.Flush()
+		waitBlocked(1)
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandKill, "ID", id)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("1"))
+		waitBlocked(0)
+		first.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = fbr.ReadString('\n')
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -3,6 +3,7 @@ package mock
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/SwanSpouse/redis_go/handlers"
 	"github.com/SwanSpouse/redis_go/server"
@@ -211,4 +212,197 @@ var _ = Describe("TestRedisListCommand", func() {
 		w.WriteCmdString(handlers.RedisKeyCommandDel, curKey)
 		w.Flush()
 	})
+
+	It("test redis list command LPop RPop with count and empty key deletion", func() {
+		key := "redis_list_test_pop_count_key"
+		w.WriteCmdString(handlers.RedisListCommandRPush, key, "a", "b", "c", "d")
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("4"))
+
+		w.WriteCmdString(handlers.RedisListCommandLPop, key, "2")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(Equal([]string{"a", "b"}))
+
+		w.WriteCmdString(handlers.RedisListCommandRPop, key, "0")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(len(ret)).To(Equal(0))
+
+		w.WriteCmdString(handlers.RedisListCommandRPop, key, "5")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(Equal([]string{"d", "c"}))
+
+		// 列表为空之后key被删除
+		w.WriteCmdString(handlers.RedisKeyCommandExists, key)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("0"))
+
+		w.WriteCmdString(handlers.RedisListCommandLPop, key)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("NIL"))
+
+		w.WriteCmdString(handlers.RedisListCommandLLen, key)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("0"))
+
+		w.WriteCmdString(handlers.RedisListCommandLPop, key, "-1")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR value is out of range, must be positive"))
+
+		// LREM和LTRIM删除所有元素之后key也会被删除
+		w.WriteCmdString(handlers.RedisListCommandRPush, key, "a", "a")
+		w.WriteCmdString(handlers.RedisListCommandLRem, key, "0", "a")
+		w.WriteCmdString(handlers.RedisListCommandRPush, key, "a", "b", "c")
+		w.WriteCmdString(handlers.RedisListCommandLTrim, key, "1", "-1")
+		w.WriteCmdString(handlers.RedisListCommandLRange, key, "0", "-1")
+		w.WriteCmdString(handlers.RedisListCommandLTrim, key, "5", "10")
+		w.WriteCmdString(handlers.RedisKeyCommandExists, key)
+		w.Flush()
+		for _, expected := range [][]string{{"2"}, {"2"}, {"3"}, {"OK"}, {"b", "c"}, {"OK"}, {"0"}} {
+			ret, err = r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+	})
+
+	It("test redis list command LPushX RPushX", func() {
+		key := "redis_list_test_pushx_key"
+		w.WriteCmdString(handlers.RedisListCommandLPushX, key, "a")
+		w.WriteCmdString(handlers.RedisKeyCommandExists, key)
+		w.WriteCmdString(handlers.RedisListCommandRpushX, commonKey, "x", "y")
+		w.WriteCmdString(handlers.RedisListCommandLPushX, commonKey, "w")
+		w.WriteCmdString(handlers.RedisListCommandLIndex, commonKey, "0")
+		w.WriteCmdString(handlers.RedisListCommandLIndex, commonKey, "-1")
+		w.Flush()
+		for _, expected := range []string{"0", "0", "12", "13", "w", "y"} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret[0]).To(Equal(expected))
+		}
+	})
+
+	It("test redis list command RPopLPush LMove", func() {
+		src, dst := "redis_list_test_move_src", "redis_list_test_move_dst"
+		w.WriteCmdString(handlers.RedisListCommandRPush, src, "a", "b", "c")
+		w.WriteCmdString(handlers.RedisListCommandRPopLPush, src, dst)
+		w.WriteCmdString(handlers.RedisListCommandLMove, src, dst, "LEFT", "RIGHT")
+		w.WriteCmdString(handlers.RedisListCommandLMove, src, src, "RIGHT", "LEFT")
+		w.WriteCmdString(handlers.RedisListCommandLMove, src, dst, "UP", "LEFT")
+		w.WriteCmdString(handlers.RedisListCommandLRange, dst, "0", "-1")
+		w.WriteCmdString(handlers.RedisListCommandLMove, src, dst, "LEFT", "LEFT")
+		w.WriteCmdString(handlers.RedisListCommandRPopLPush, src, dst)
+		w.WriteCmdString(handlers.RedisKeyCommandExists, src)
+		w.WriteCmdString(handlers.RedisListCommandLMove, dst, commonKey, "LEFT", "LEFT")
+		w.WriteCmdString(handlers.RedisListCommandLIndex, commonKey, "0")
+		w.Flush()
+		for _, expected := range [][]string{{"3"}, {"c"}, {"a"}, {"b"}, {"ERR syntax error"}, {"c", "a"}, {"b"}, {"NIL"}, {"0"}, {"b"}, {"b"}} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+
+		w.WriteCmdString(handlers.RedisKeyCommandDel, dst)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("1"))
+	})
+
+	It("test redis list command LPos", func() {
+		key := "redis_list_test_lpos_key"
+		w.WriteCmdString(handlers.RedisListCommandRPush, key, "a", "b", "c", "1", "2", "3", "c", "c")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "RANK", "2")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "RANK", "-1")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "COUNT", "2")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "COUNT", "0")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "RANK", "-1", "COUNT", "2")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "COUNT", "0", "MAXLEN", "7")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "x")
+		w.WriteCmdString(handlers.RedisListCommandLPos, "redis_list_test_lpos_missing", "x", "COUNT", "1")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "RANK", "0")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "COUNT", "-1")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "MAXLEN", "-1")
+		w.WriteCmdString(handlers.RedisListCommandLPos, key, "c", "RANK")
+		w.WriteCmdString(handlers.RedisKeyCommandDel, key)
+		w.Flush()
+		for _, expected := range [][]string{{"8"}, {"2"}, {"6"}, {"7"}, {"2", "6"}, {"2", "6", "7"}, {"7", "6"}, {"2", "6"}, {"NIL"}, {},
+			{"ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list"},
+			{"ERR COUNT can't be negative"}, {"ERR MAXLEN can't be negative"}, {"ERR syntax error"}, {"1"}} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+	})
+
+	It("test redis list command LMPop", func() {
+		key := "redis_list_test_lmpop_key"
+		w.WriteCmdString(handlers.RedisListCommandRPush, key, "a", "b", "c")
+		w.WriteCmdString(handlers.RedisListCommandLMPop, "2", "redis_list_test_lmpop_empty", key, "LEFT")
+		w.WriteCmdString(handlers.RedisListCommandLMPop, "1", key, "RIGHT", "COUNT", "10")
+		w.WriteCmdString(handlers.RedisKeyCommandExists, key)
+		w.WriteCmdString(handlers.RedisListCommandLMPop, "0", key, "RIGHT")
+		w.WriteCmdString(handlers.RedisListCommandLMPop, "1", key, "RIGHT", "COUNT", "0")
+		w.WriteCmdString(handlers.RedisListCommandLMPop, "2", key, "RIGHT")
+		w.WriteCmdString(handlers.RedisListCommandLMPop, "1", commonKey, "LEFT", "COUNT", "2")
+		w.Flush()
+		for _, expected := range [][]string{{"3"}, {key, "a"}, {key, "c", "b"}, {"0"},
+			{"ERR numkeys should be greater than 0"}, {"ERR count should be greater than 0"}, {"ERR syntax error"},
+			{commonKey, "value0", "value1"}} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+	})
+
+	It("test redis list encoding listpack and quicklist", func() {
+		key, bigKey := "redis_list_test_encoding_key", "redis_list_test_encoding_big_key"
+		w.WriteCmdString(handlers.RedisListCommandRPush, key, "a", "b", "c")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "list-max-listpack-size", "4")
+		w.WriteCmdString(handlers.RedisListCommandRPush, key, "d", "e")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisListCommandLInsert, key, "BEFORE", "c", "x")
+		w.WriteCmdString(handlers.RedisListCommandLRange, key, "0", "-1")
+		w.WriteCmdString(handlers.RedisListCommandRPop, key, "5")
+		// 只剩下一个元素, 不到限制的一半, 转换回listpack
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "list-max-listpack-size", "-2")
+		w.Flush()
+		for _, expected := range [][]string{{"3"}, {"listpack"}, {"OK"}, {"5"}, {"quicklist"}, {"6"},
+			{"a", "b", "x", "c", "d", "e"}, {"e", "d", "c", "x", "b"}, {"listpack"}, {"OK"}} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+
+		// 超过8KB之后转换成quicklist
+		w.WriteCmdString(handlers.RedisListCommandRPush, bigKey, strings.Repeat("x", 9000))
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", bigKey)
+		w.WriteCmdString(handlers.RedisListCommandRPush, bigKey, "y")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", bigKey)
+		w.WriteCmdString(handlers.RedisListCommandLIndex, bigKey, "-1")
+		w.WriteCmdString(handlers.RedisKeyCommandDel, key, bigKey)
+		w.Flush()
+		for _, expected := range [][]string{{"1"}, {"listpack"}, {"2"}, {"quicklist"}, {"y"}, {"2"}} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+	})
 })
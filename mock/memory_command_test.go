@@ -0,0 +1,125 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestMemoryCommand", func() {
+	var w *RequestWriter
+	var r *ResponseReader
+
+	var stringKey = "redis_memory_command_key_string"
+	var listKey = "redis_memory_command_key_list"
+
+	BeforeEach(func() {
+		cn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+
+		// first truncate all DB
+		w.WriteCmdString(server.RedisServerCommandFlushAll)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+	})
+
+	It("test memory usage", func() {
+		w.WriteCmdString(server.RedisServerCommandMemory, server.RedisMemorySubCommandUsage, stringKey)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("NIL"))
+
+		w.WriteCmdString(handlers.RedisStringCommandSet, stringKey, strings.Repeat("a", 100))
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+
+		w.WriteCmdString(server.RedisServerCommandMemory, server.RedisMemorySubCommandUsage, stringKey)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		small, err := strconv.Atoi(ret[0])
+		Expect(err).To(BeNil())
+		Expect(small > 100).To(BeTrue())
+
+		// list 中的元素越多，占用的内存越多
+		w.WriteCmdString(handlers.RedisListCommandRPush, listKey, "a")
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+		w.WriteCmdString(server.RedisServerCommandMemory, server.RedisMemorySubCommandUsage, listKey, server.RedisMemorySubCommandSamples, "0")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		before, _ := strconv.Atoi(ret[0])
+
+		args := []string{listKey}
+		for i := 0; i < 100; i++ {
+			args = append(args, strings.Repeat("b", 10))
+		}
+		w.WriteCmdString(handlers.RedisListCommandRPush, args...)
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+		w.WriteCmdString(server.RedisServerCommandMemory, server.RedisMemorySubCommandUsage, listKey, server.RedisMemorySubCommandSamples, "0")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		after, _ := strconv.Atoi(ret[0])
+		Expect(after > before+1000).To(BeTrue())
+
+		w.WriteCmdString(server.RedisServerCommandMemory, server.RedisMemorySubCommandUsage, listKey, "LIMIT", "0")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR syntax error"))
+	})
+
+	It("test memory stats & doctor", func() {
+		w.WriteCmdString(handlers.RedisStringCommandSet, stringKey, "value")
+		w.Flush()
+		_, err := r.Read()
+		Expect(err).To(BeNil())
+
+		w.WriteCmdString(server.RedisServerCommandMemory, server.RedisMemorySubCommandStats)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(ContainElement("peak.allocated"))
+		Expect(ret).To(ContainElement("dataset.bytes"))
+		Expect(ret).To(ContainElement("db.0"))
+
+		w.WriteCmdString(server.RedisServerCommandMemory, server.RedisMemorySubCommandDoctor)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(strings.HasPrefix(ret[0], "Hi")).To(BeTrue())
+
+		w.WriteCmdString(server.RedisServerCommandInfo, server.RedisInfoSectionMemory)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("used_memory:"))
+		Expect(ret[0]).To(ContainSubstring("used_memory_dataset:"))
+
+		w.WriteCmdString(server.RedisServerCommandMemory, "unknown")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HavePrefix("ERR Unknown MEMORY subcommand"))
+	})
+})
@@ -0,0 +1,79 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestMonitorCommand", func() {
+	var w *RequestWriter
+	var r *ResponseReader
+	var monitorConn net.Conn
+	var mw *RequestWriter
+	var mr *ResponseReader
+
+	var stringKey = "redis_monitor_command_key_string"
+
+	BeforeEach(func() {
+		cn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+
+		monitorConn, err = net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		mw = NewRequestWriter(monitorConn)
+		mr = NewResponseReader(monitorConn)
+
+		mw.WriteCmdString(server.RedisServerCommandMonitor)
+		mw.Flush()
+		ret, err := mr.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+	})
+
+	AfterEach(func() {
+		monitorConn.Close()
+	})
+
+	It("test monitor receives executed commands", func() {
+		w.WriteCmdString(handlers.RedisStringCommandSet, stringKey, "value")
+		w.Flush()
+		_, err := r.Read()
+		Expect(err).To(BeNil())
+
+		ret, err := mr.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(MatchRegexp(`^\d+\.\d{6} \[0 127\.0\.0\.1:\d+\] "SET" "` + stringKey + `" "value"$`))
+
+		w.WriteCmdString(handlers.RedisConnectionCommandEcho, "hello\r\nworld")
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+
+		ret, err = mr.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HaveSuffix(`"ECHO" "hello\r\nworld"`))
+
+		// 管理命令不会发送给MONITOR客户端
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandResetStat)
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+
+		w.WriteCmdString(handlers.RedisStringCommandGet, stringKey)
+		w.Flush()
+		_, err = r.Read()
+		Expect(err).To(BeNil())
+
+		ret, err = mr.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HaveSuffix(`"GET" "` + stringKey + `"`))
+	})
+})
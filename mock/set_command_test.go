@@ -143,4 +143,30 @@ var _ = Describe("TestRedisSetCommand", func() {
 		ret, _ = r.Read()
 		Expect(ret[0]).To(Equal("8"))
 	})
+
+	It("Test redis set encoding intset and hashtable", func() {
+		key := "redis_set_command_test_intset_key"
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "set-max-intset-entries", "4")
+		w.WriteCmdString(handlers.RedisSetCommandSADD, key, "3", "-1", "70000", "3")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisSetCommandSMEMBERS, key)
+		w.WriteCmdString(handlers.RedisSetCommandSISMEMBER, key, "70000")
+		w.WriteCmdString(handlers.RedisSetCommandSISMEMBER, key, "07")
+		w.WriteCmdString(handlers.RedisSetCommandSREM, key, "-1", "abc")
+		w.WriteCmdString(handlers.RedisSetCommandSADD, key, "5", "6")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisSetCommandSADD, key, "7")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisSetCommandSCARD, key)
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "set-max-intset-entries", "512")
+		w.WriteCmdString(handlers.RedisSetCommandSADD, key+"2", "1", "a")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key+"2")
+		w.Flush()
+		for _, expected := range [][]string{{"OK"}, {"3"}, {"intset"}, {"-1", "3", "70000"}, {"1"}, {"0"}, {"1"},
+			{"2"}, {"intset"}, {"1"}, {"hashtable"}, {"5"}, {"OK"}, {"2"}, {"hashtable"}} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+	})
 })
@@ -0,0 +1,52 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestShutdownCommand", func() {
+	var w *RequestWriter
+	var r *ResponseReader
+
+	BeforeEach(func() {
+		cn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+	})
+
+	It("test shutdown with invalid arguments", func() {
+		w.WriteCmdString(server.RedisServerCommandShutDown, server.RedisShutdownSubCommandSave, server.RedisShutdownSubCommandNoSave)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR syntax error"))
+
+		w.WriteCmdString(server.RedisServerCommandShutDown, server.RedisShutdownSubCommandAbort, server.RedisShutdownSubCommandNow)
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR syntax error"))
+
+		w.WriteCmdString(server.RedisServerCommandShutDown, "unknown")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR syntax error"))
+	})
+
+	It("test shutdown abort without shutdown in progress", func() {
+		w.WriteCmdString(server.RedisServerCommandShutDown, server.RedisShutdownSubCommandAbort)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("ERR No shutdown in progress."))
+	})
+})
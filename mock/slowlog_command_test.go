@@ -0,0 +1,56 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestSlowLogCommand", func() {
+	var w *RequestWriter
+	var r *ResponseReader
+
+	BeforeEach(func() {
+		cn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+
+		w = NewRequestWriter(cn)
+		r = NewResponseReader(cn)
+
+		w.WriteCmdString(server.RedisServerCommandSlowLog, server.RedisSlowLogSubCommandReset)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("OK"))
+	})
+
+	It("test slowlog len & get & reset", func() {
+		w.WriteCmdString(server.RedisServerCommandSlowLog, server.RedisSlowLogSubCommandLen)
+		w.Flush()
+		ret, err := r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(Equal("0"))
+
+		w.WriteCmdString(server.RedisServerCommandSlowLog, server.RedisSlowLogSubCommandGet, "5")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret).To(BeEmpty())
+
+		w.WriteCmdString(server.RedisServerCommandSlowLog, server.RedisSlowLogSubCommandGet, "abc")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(ContainSubstring("not an integer"))
+
+		w.WriteCmdString(server.RedisServerCommandSlowLog, "unknown")
+		w.Flush()
+		ret, err = r.Read()
+		Expect(err).To(BeNil())
+		Expect(ret[0]).To(HavePrefix("ERR Unknown SLOWLOG subcommand"))
+	})
+})
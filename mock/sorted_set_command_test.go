@@ -3,6 +3,7 @@ package mock
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/SwanSpouse/redis_go/handlers"
 	"github.com/SwanSpouse/redis_go/server"
@@ -162,4 +163,38 @@ var _ = Describe("TestRedisSortedSetCommands", func() {
 		Expect(err).To(BeNil())
 		Expect(ret[0]).To(Equal("8"))
 	})
+
+	It("Test redis sorted set encoding listpack and skiplist", func() {
+		key := "sorted_set_command_test_listpack_key"
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "zset-max-listpack-entries", "4")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZAdd, key, "3", "c", "1", "a", "2", "b")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisSortedSetCommandZRange, key, "0", "-1")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZAdd, key, "0", "c")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZRank, key, "c")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZRevRank, key, "a")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZIncrBy, key, "a", "5")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZCount, key, "1", "6")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZRangeByScore, key, "1", "6")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZRemRangeByScore, key, "0", "1")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZAdd, key, "4", "d", "5", "e")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisSortedSetCommandZAdd, key, "7", "f")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key)
+		w.WriteCmdString(handlers.RedisSortedSetCommandZRange, key, "0", "-1")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZRemRangeByRank, key, "-2", "-1")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZRem, key, "b", "a")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZCard, key)
+		w.WriteCmdString(server.RedisServerCommandConfig, server.RedisConfigSubCommandSet, "zset-max-listpack-entries", "128")
+		w.WriteCmdString(handlers.RedisSortedSetCommandZAdd, key+"2", "1", strings.Repeat("m", 65))
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", key+"2")
+		w.Flush()
+		for _, expected := range [][]string{{"OK"}, {"3"}, {"listpack"}, {"a", "1.0", "b", "2.0", "c", "3.0"}, {"1"}, {"0"}, {"1"},
+			{"6"}, {"2"}, {"b", "2.0", "a", "6.0"}, {"1"}, {"2"}, {"listpack"}, {"1"}, {"skiplist"},
+			{"b", "2.0", "d", "4.0", "e", "5.0", "a", "6.0", "f", "7.0"}, {"2"}, {"1"}, {"2"}, {"OK"}, {"1"}, {"skiplist"}} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret).To(Equal(expected))
+		}
+	})
 })
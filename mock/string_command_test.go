@@ -3,7 +3,10 @@ package mock
 import (
 	"fmt"
 	"net"
+	"strings"
 
+	"github.com/SwanSpouse/redis_go/encodings"
+	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/handlers"
 	"github.com/SwanSpouse/redis_go/server"
 
@@ -73,40 +76,47 @@ var _ = Describe("TestRedisStringCommand", func() {
 		}
 	})
 
-	// TODO RDB暂时没有考虑编码的问题。所以暂时关闭这个测试
-	//It("test redis string type and encodings", func() {
-	//	key := "number"
-	//	value := "123"
-	//	w.WriteCmd(handlers.RedisStringCommandSet, []byte(key), []byte(value))
-	//	w.Flush()
-	//	ret, err := r.Read()
-	//	Expect(err).To(BeNil())
-	//	Expect(ret[0]).To(Equal("OK"))
-	//
-	//	w.WriteCmd(handlers.RedisKeyCommandType, []byte(key))
-	//	w.Flush()
-	//	ret, err = r.Read()
-	//	Expect(err).To(BeNil())
-	//	Expect(ret[0]).To(Equal(encodings.RedisTypeString))
-	//
-	//	w.WriteCmd(handlers.RedisKeyCommandObject, []byte("encoding"), []byte(key))
-	//	w.Flush()
-	//	ret, err = r.Read()
-	//	Expect(err).To(BeNil())
-	//	Expect(ret[0]).To(Equal(encodings.RedisEncodingInt))
-	//
-	//	w.WriteCmd(handlers.RedisStringCommandAppend, []byte(key), []byte(" "))
-	//	w.Flush()
-	//	ret, err = r.Read()
-	//	Expect(err).To(BeNil())
-	//	Expect(ret[0]).To(Equal(fmt.Sprintf("%d", len(value+"x"))))
-	//
-	//	w.WriteCmd(handlers.RedisKeyCommandObject, []byte("encoding"), []byte(key))
-	//	w.Flush()
-	//	ret, err = r.Read()
-	//	Expect(err).To(BeNil())
-	//	Expect(ret[0]).To(Equal(encodings.RedisEncodingRaw))
-	//})
+	It("test redis string type and encodings", func() {
+		embStr := strings.Repeat("a", encodings.RedisEncodingEmbStrSize)
+		w.WriteCmdString(handlers.RedisStringCommandSet, "number", "123")
+		w.WriteCmdString(handlers.RedisKeyCommandType, "number")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", "number")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "refcount", "number")
+		w.WriteCmdString(handlers.RedisStringCommandAppend, "number", " ")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", "number")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "refcount", "number")
+		w.WriteCmdString(handlers.RedisStringCommandSet, "big_number", "10000")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", "big_number")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "refcount", "big_number")
+		w.WriteCmdString(handlers.RedisStringCommandDecr, "big_number")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "refcount", "big_number")
+		w.WriteCmdString(handlers.RedisStringCommandSet, "emb", embStr)
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", "emb")
+		w.WriteCmdString(handlers.RedisStringCommandSet, "raw", embStr+"a")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", "raw")
+		w.WriteCmdString(handlers.RedisStringCommandSetRange, "emb", "1", "bc")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", "emb")
+		w.WriteCmdString(handlers.RedisStringCommandGet, "emb")
+		w.WriteCmdString(handlers.RedisStringCommandSetRange, "new", "3", "x")
+		w.WriteCmdString(handlers.RedisStringCommandGet, "new")
+		w.WriteCmdString(handlers.RedisStringCommandIncrByFloat, "float", "1.5")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "encoding", "float")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "idletime", "number")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "freq", "number")
+		w.WriteCmdString(handlers.RedisKeyCommandObject, "unknown", "number")
+		w.Flush()
+		for _, expected := range []string{"OK", encodings.RedisTypeString, encodings.RedisEncodingInt, "2147483647",
+			"4", encodings.RedisEncodingRaw, "1",
+			"OK", encodings.RedisEncodingInt, "1", "9999", "2147483647",
+			"OK", encodings.RedisEncodingEmbStr, "OK", encodings.RedisEncodingRaw,
+			fmt.Sprintf("%d", len(embStr)), encodings.RedisEncodingRaw, "abc" + embStr[3:],
+			"4", "\x00\x00\x00x", "1.50", encodings.RedisEncodingEmbStr, "0",
+			string(re.ErrObjectFreqNotTracked), fmt.Sprintf(string(re.ErrObjectCommand), "unknown")} {
+			ret, err := r.Read()
+			Expect(err).To(BeNil())
+			Expect(ret[0]).To(Equal(expected))
+		}
+	})
 
 	It("test redis string incr and decr", func() {
 		key := "number"
@@ -0,0 +1,165 @@
+package mock
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/server"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestTrackingCommand", func() {
+	var cn, other net.Conn
+	var w, ow *RequestWriter
+	var br, obr *bufio.Reader
+
+	expectRaw := func(cn net.Conn, br *bufio.Reader, expected string) {
+		buf := make([]byte, len(expected))
+		cn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err := io.ReadFull(br, buf)
+		Expect(err).To(BeNil())
+		Expect(string(buf)).To(Equal(expected))
+	}
+	invalidate := func(key string) string {
+		return fmt.Sprintf(">2\r\n$10\r\ninvalidate\r\n*1\r\n$%d\r\n%s\r\n", len(key), key)
+	}
+	// 切换到RESP3协议, 跳过HELLO的回复
+	hello3 := func() {
+		w.WriteCmdString(handlers.RedisConnectionCommandHello, "3")
+		w.Flush()
+		cn.SetReadDeadline(time.Now().Add(time.Second))
+		var reply string
+		for !strings.HasSuffix(reply, "$7\r\nmodules\r\n*0\r\n") {
+			line, err := br.ReadString('\n')
+			Expect(err).To(BeNil())
+			reply += line
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		cn, err = net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		w = NewRequestWriter(cn)
+		br = bufio.NewReader(cn)
+
+		other, err = net.Dial("tcp", fmt.Sprintf("%s:%d", MockAddr, MockPort))
+		Expect(err).To(BeNil())
+		ow = NewRequestWriter(other)
+		obr = bufio.NewReader(other)
+	})
+
+	AfterEach(func() {
+		cn.Close()
+		other.Close()
+	})
+
+	It("test tracking errors", func() {
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON", "PREFIX", "trk-")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON", "OPTIN", "OPTOUT")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON", "BCAST", "OPTIN")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON", "REDIRECT", "999999")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON", "BCAST", "PREFIX", "a", "PREFIX", "ab")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandCaching, "YES")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandGetRedir)
+		w.Flush()
+		expectRaw(cn, br, "-ERR PREFIX option requires BCAST mode to be enabled\r\n")
+		expectRaw(cn, br, "-ERR You can't use both OPTIN and OPTOUT\r\n")
+		expectRaw(cn, br, "-ERR OPTIN and OPTOUT are not compatible with BCAST\r\n")
+		expectRaw(cn, br, "-ERR The client ID you want redirect to does not exist\r\n")
+		expectRaw(cn, br, "-ERR Prefix 'a' overlaps with another provided prefix 'ab'. Prefixes for a single client must not overlap.\r\n")
+		expectRaw(cn, br, "-ERR CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled\r\n")
+		expectRaw(cn, br, ":-1\r\n")
+	})
+
+	It("test tracking default mode", func() {
+		hello3()
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON")
+		w.WriteCmdString(handlers.RedisStringCommandGet, "trk-key")
+		w.Flush()
+		expectRaw(cn, br, "+OK\r\n")
+		expectRaw(cn, br, "_\r\n")
+
+		// 其他客户端修改了key之后收到失效消息, 没有再次读取之前不会再收到
+		ow.WriteCmdString(handlers.RedisStringCommandSet, "trk-key", "1")
+		ow.WriteCmdString(handlers.RedisStringCommandSet, "trk-key", "2")
+		ow.Flush()
+		expectRaw(other, obr, "+OK\r\n+OK\r\n")
+		expectRaw(cn, br, invalidate("trk-key"))
+
+		// 自己修改key的时候也会收到失效消息
+		w.WriteCmdString(handlers.RedisStringCommandGet, "trk-key")
+		w.WriteCmdString(handlers.RedisKeyCommandDel, "trk-key")
+		w.Flush()
+		expectRaw(cn, br, "$1\r\n2\r\n")
+		expectRaw(cn, br, ":1\r\n")
+		expectRaw(cn, br, invalidate("trk-key"))
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "OFF")
+		w.Flush()
+		expectRaw(cn, br, "+OK\r\n")
+	})
+
+	It("test tracking optin", func() {
+		hello3()
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON", "OPTIN")
+		w.WriteCmdString(handlers.RedisStringCommandGet, "trk-optin-1")
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandCaching, "YES")
+		w.WriteCmdString(handlers.RedisStringCommandGet, "trk-optin-2")
+		w.Flush()
+		expectRaw(cn, br, "+OK\r\n_\r\n+OK\r\n_\r\n")
+
+		ow.WriteCmdString(handlers.RedisStringCommandSet, "trk-optin-1", "1")
+		ow.WriteCmdString(handlers.RedisStringCommandSet, "trk-optin-2", "2")
+		ow.WriteCmdString(handlers.RedisKeyCommandDel, "trk-optin-1", "trk-optin-2")
+		ow.Flush()
+		expectRaw(other, obr, "+OK\r\n+OK\r\n:2\r\n")
+		expectRaw(cn, br, invalidate("trk-optin-2"))
+	})
+
+	It("test tracking bcast and noloop", func() {
+		hello3()
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON", "BCAST", "PREFIX", "trk-bcast:", "NOLOOP")
+		w.WriteCmdString(handlers.RedisStringCommandSet, "trk-bcast:1", "1")
+		w.Flush()
+		expectRaw(cn, br, "+OK\r\n+OK\r\n")
+
+		ow.WriteCmdString(handlers.RedisStringCommandSet, "trk-other", "1")
+		ow.WriteCmdString(handlers.RedisStringCommandSet, "trk-bcast:2", "2")
+		ow.WriteCmdString(handlers.RedisKeyCommandDel, "trk-other", "trk-bcast:1", "trk-bcast:2")
+		ow.Flush()
+		expectRaw(other, obr, "+OK\r\n+OK\r\n:3\r\n")
+		expectRaw(cn, br, invalidate("trk-bcast:2"))
+		expectRaw(cn, br, invalidate("trk-bcast:1"))
+		expectRaw(cn, br, invalidate("trk-bcast:2"))
+	})
+
+	It("test tracking redirect", func() {
+		// RESP2的连接通过订阅__redis__:invalidate接收失效消息
+		ow.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandID)
+		ow.Flush()
+		other.SetReadDeadline(time.Now().Add(time.Second))
+		line, err := obr.ReadString('\n')
+		Expect(err).To(BeNil())
+		id := line[1 : len(line)-2]
+		ow.WriteCmdString("SUBSCRIBE", server.RedisTrackingInvalidateChannel)
+		ow.Flush()
+		expectRaw(other, obr, "*3\r\n$9\r\nsubscribe\r\n$20\r\n__redis__:invalidate\r\n:1\r\n")
+
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandTracking, "ON", "REDIRECT", id)
+		w.WriteCmdString(server.RedisServerCommandClient, server.RedisClientSubCommandGetRedir)
+		w.WriteCmdString(handlers.RedisStringCommandGet, "trk-redir")
+		w.WriteCmdString(handlers.RedisStringCommandSet, "trk-redir", "1")
+		w.WriteCmdString(handlers.RedisKeyCommandDel, "trk-redir")
+		w.Flush()
+		expectRaw(cn, br, fmt.Sprintf("+OK\r\n:%s\r\n$-1\r\n+OK\r\n:1\r\n", id))
+		expectRaw(other, obr, "*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n*1\r\n$9\r\ntrk-redir\r\n")
+	})
+})
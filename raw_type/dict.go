@@ -2,6 +2,7 @@ package raw_type
 
 import (
 	"fmt"
+	"hash/maphash"
 	"math"
 	"math/rand"
 	"reflect"
@@ -111,7 +112,7 @@ func (seg *segment) put(hashCode int, key, value interface{}) interface{} {
 	for true {
 		if e != nil {
 			// 如果找到相同元素，先记录oldValue，再覆盖
-			if hashCode == e.hash && reflect.DeepEqual(key, e.Key) {
+			if hashCode == e.hash && keysEqual(key, e.Key) {
 				oldValue = e.Value
 				e.Value = value
 				seg.modCount += 1
@@ -194,7 +195,7 @@ func (seg *segment) remove(hashCode int, key, value interface{}) interface{} {
 	}
 	var pre *dictEntry
 	for cur := seg.table[idx]; cur != nil; cur = cur.next {
-		if cur.hash == hashCode && reflect.DeepEqual(key, cur.Key) &&
+		if cur.hash == hashCode && keysEqual(key, cur.Key) &&
 			(value == nil || reflect.DeepEqual(value, cur.Value)) {
 			if pre == nil {
 				seg.table[idx] = cur.next
@@ -225,7 +226,7 @@ func (seg *segment) replace(hashCode int, key, oldValue, newValue interface{}) b
 	index := hashCode & seg.sizeMask
 	for e := seg.table[index]; e != nil; e = e.next {
 		// 查找目标元素
-		if hashCode == e.hash && reflect.DeepEqual(key, e.Key) && reflect.DeepEqual(oldValue, e.Value) {
+		if hashCode == e.hash && keysEqual(key, e.Key) && reflect.DeepEqual(oldValue, e.Value) {
 			e.Value = newValue
 			seg.modCount += 1
 			return true
@@ -382,7 +383,7 @@ func (dict *Dict) Get(key interface{}) interface{} {
 	idx := hashCode & dict.segments[segmentIdx].sizeMask
 	if dict.segments[segmentIdx].table[idx] != nil {
 		for e := dict.segments[segmentIdx].table[idx]; e != nil; e = e.next {
-			if e.hash == hashCode && reflect.DeepEqual(e.Key, key) {
+			if e.hash == hashCode && keysEqual(key, e.Key) {
 				return e.Value
 			}
 		}
@@ -430,6 +431,10 @@ func (dict *Dict) Put(key, value interface{}) interface{} {
 	if key == nil || value == nil {
 		panic("PUT key or value null pointer exception")
 	}
+	if b, ok := key.([]byte); ok {
+		// []byte类型的key保存一份字符串的拷贝, 避免引用调用方会继续修改的内存
+		key = string(b)
+	}
 	hashCode := hash(key)
 	segmentIdx := (hashCode >> dict.segmentShift) & dict.segmentMask
 	return dict.segments[segmentIdx].put(hashCode, key, value)
@@ -485,10 +490,9 @@ func (dict *Dict) KeySet() map[interface{}]bool {
 	for index := 0; index < len(dict.segments); index++ {
 		dict.segments[index].locker.Lock()
 		for i := 0; i < len(dict.segments[index].table); i++ {
-			if dict.segments[index].table[i] == nil {
-				continue
+			for node := dict.segments[index].table[i]; node != nil; node = node.next {
+				ret[node.Key] = true
 			}
-			ret[dict.segments[index].table[i].Key] = true
 		}
 		dict.segments[index].locker.Unlock()
 	}
@@ -500,10 +504,9 @@ func (dict *Dict) KeyValueSet() map[interface{}]interface{} {
 	for index := 0; index < len(dict.segments); index++ {
 		dict.segments[index].locker.Lock()
 		for i := 0; i < len(dict.segments[index].table); i++ {
-			if dict.segments[index].table[i] == nil {
-				continue
+			for node := dict.segments[index].table[i]; node != nil; node = node.next {
+				ret[node.Key] = node.Value
 			}
-			ret[dict.segments[index].table[i].Key] = dict.segments[index].table[i].Value
 		}
 		dict.segments[index].locker.Unlock()
 	}
@@ -537,6 +540,35 @@ func (dict *Dict) RandomKey() interface{} {
 	return keyList[rand.Intn(len(keySet)-1)]
 }
 
+/*
+	随机返回Dict中最多count个k-v，用于maxmemory淘汰策略中的近似采样。
+	从随机的segment和随机的槽位开始，依次遍历相邻的槽位，直到取到count个元素或者达到最大步数。
+	返回的元素个数可能少于count，也不保证每个元素被选中的概率完全相同。
+*/
+func (dict *Dict) GetSomeKeys(count int) map[interface{}]interface{} {
+	ret := make(map[interface{}]interface{}, count)
+	if count <= 0 {
+		return ret
+	}
+	maxSteps := count * 10
+	segmentStart := rand.Intn(len(dict.segments))
+	for i := 0; i < len(dict.segments) && len(ret) < count && maxSteps > 0; i++ {
+		seg := dict.segments[(segmentStart+i)%len(dict.segments)]
+		seg.locker.RLock()
+		if seg.count > 0 {
+			slotStart := rand.Intn(len(seg.table))
+			for j := 0; j < len(seg.table) && len(ret) < count && maxSteps > 0; j++ {
+				for e := seg.table[(slotStart+j)&seg.sizeMask]; e != nil && len(ret) < count; e = e.next {
+					ret[e.Key] = e.Value
+				}
+				maxSteps -= 1
+			}
+		}
+		seg.locker.RUnlock()
+	}
+	return ret
+}
+
 func (dict *Dict) printDictForDebug() {
 	fmt.Printf("dict has %d segment and %d entries\n", len(dict.segments), dict.Size())
 	for i := 0; i < len(dict.segments); i++ {
@@ -549,7 +581,40 @@ func (dict *Dict) printDictForDebug() {
 }
 
 /************************************     common   **************************************/
+var stringHashSeed = maphash.MakeSeed()
+
+// 字符串以及[]byte类型的key直接按照字节计算哈希值, 避免hashstructure通过反射计算带来的开销。
+// 内容相同的string和[]byte的哈希值相同, 所以可以用[]byte查找以string保存的key
 func hash(value interface{}) int {
-	hashCode, _ := hashstructure.Hash(value, nil)
+	var hashCode uint64
+	switch v := value.(type) {
+	case string:
+		hashCode = maphash.String(stringHashSeed, v)
+	case []byte:
+		hashCode = maphash.Bytes(stringHashSeed, v)
+	default:
+		hashCode, _ = hashstructure.Hash(value, nil)
+	}
 	return int(hashCode & uint64(math.MaxInt32))
 }
+
+// 比较两个key是否相同, string和[]byte按照内容比较, 其他类型的key使用reflect.DeepEqual
+func keysEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case string:
+		switch y := b.(type) {
+		case string:
+			return x == y
+		case []byte:
+			return x == string(y)
+		}
+	case []byte:
+		switch y := b.(type) {
+		case string:
+			return string(x) == y
+		case []byte:
+			return string(x) == string(y)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -93,3 +93,48 @@ var _ = Describe("test dict base operations", func() {
 		}
 	})
 })
+
+var _ = Describe("test dict iteration with collided keys", func() {
+	It("test dict operation KeySet and KeyValueSet return every entry in a bucket chain", func() {
+		// 只有一个segment并且容量很小, 大部分key都会落在同一个槽位的链表上
+		dict := NewDictWithCapacityAndConcurrencyLevel(MinSegmentTableCapacity, 1)
+		inputSize := 1000
+		for i := 0; i < inputSize; i++ {
+			dict.Put(i, i+1)
+		}
+		Expect(dict.Size()).To(Equal(inputSize))
+
+		keySet := dict.KeySet()
+		Expect(keySet).To(HaveLen(inputSize))
+		kvSet := dict.KeyValueSet()
+		Expect(kvSet).To(HaveLen(inputSize))
+		for i := 0; i < inputSize; i++ {
+			Expect(keySet[i]).To(BeTrue())
+			Expect(kvSet[i]).To(Equal(i + 1))
+		}
+	})
+})
+
+var _ = Describe("test dict with binary keys", func() {
+	It("test dict operation []byte key is the same as string key", func() {
+		dict := NewDict()
+		key := []byte("key\x00\r\n")
+		Expect(dict.Put(key, 1)).To(BeNil())
+		// 保存的是key的拷贝, 修改原来的内存不会影响dict中的key
+		key[0] = 'K'
+		Expect(dict.Get("key\x00\r\n")).To(Equal(1))
+		Expect(dict.Get([]byte("key\x00\r\n"))).To(Equal(1))
+		Expect(dict.Get(key)).To(BeNil())
+
+		Expect(dict.Put("key\x00\r\n", 2)).To(Equal(1))
+		Expect(dict.Size()).To(Equal(1))
+		for k := range dict.KeySet() {
+			Expect(k).To(Equal("key\x00\r\n"))
+		}
+		Expect(dict.RemoveKey([]byte("key\x00\r\n"))).To(Equal(2))
+		Expect(dict.IsEmpty()).To(BeTrue())
+
+		Expect(dict.Put([]byte{}, 3)).To(BeNil())
+		Expect(dict.Get("")).To(Equal(3))
+	})
+})
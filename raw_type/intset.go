@@ -0,0 +1,220 @@
+package raw_type
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+)
+
+/**
+INTSET:
+	<encoding uint32> <length uint32> <contents>
+	整数集合中的元素按照从小到大的顺序保存在contents中, 每个元素占用encoding个字节, 使用小端序。
+	encoding可以是int16、int32、int64, 添加的元素超过当前encoding的范围时, 所有的元素都会升级到更大的encoding,
+	升级之后不会再降级。查找元素的时候使用二分查找。
+*/
+const (
+	IntSetEncInt16 = 2
+	IntSetEncInt32 = 4
+	IntSetEncInt64 = 8
+
+	intSetHeaderSize = 8
+)
+
+var errIntSetCorrupted = errors.New("intset is corrupted")
+
+type IntSet struct {
+	encoding int
+	length   int
+	contents []byte
+}
+
+func NewIntSet() *IntSet {
+	return &IntSet{encoding: IntSetEncInt16, contents: make([]byte, 0)}
+}
+
+// 能够保存value的最小的encoding
+func intSetValueEncoding(value int64) int {
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return IntSetEncInt64
+	} else if value < math.MinInt16 || value > math.MaxInt16 {
+		return IntSetEncInt32
+	}
+	return IntSetEncInt16
+}
+
+func (is *IntSet) getEncoded(pos int, encoding int) int64 {
+	switch encoding {
+	case IntSetEncInt64:
+		return int64(binary.LittleEndian.Uint64(is.contents[pos*8:]))
+	case IntSetEncInt32:
+		return int64(int32(binary.LittleEndian.Uint32(is.contents[pos*4:])))
+	default:
+		return int64(int16(binary.LittleEndian.Uint16(is.contents[pos*2:])))
+	}
+}
+
+func (is *IntSet) set(pos int, value int64) {
+	switch is.encoding {
+	case IntSetEncInt64:
+		binary.LittleEndian.PutUint64(is.contents[pos*8:], uint64(value))
+	case IntSetEncInt32:
+		binary.LittleEndian.PutUint32(is.contents[pos*4:], uint32(int32(value)))
+	default:
+		binary.LittleEndian.PutUint16(is.contents[pos*2:], uint16(int16(value)))
+	}
+}
+
+// 二分查找value, 找到的时候返回value的位置, 否则返回value应该插入的位置
+func (is *IntSet) search(value int64) (int, bool) {
+	low, high := 0, is.length-1
+	if is.length == 0 {
+		return 0, false
+	}
+	// 比最大的元素大或者比最小的元素小的时候不需要查找
+	if value > is.Get(high) {
+		return is.length, false
+	} else if value < is.Get(0) {
+		return 0, false
+	}
+	for low <= high {
+		mid := int(uint(low+high) >> 1)
+		cur := is.Get(mid)
+		if value > cur {
+			low = mid + 1
+		} else if value < cur {
+			high = mid - 1
+		} else {
+			return mid, true
+		}
+	}
+	return low, false
+}
+
+func (is *IntSet) resize(length int) {
+	size := length * is.encoding
+	if size > cap(is.contents) {
+		contents := make([]byte, size, size+size/2)
+		copy(contents, is.contents)
+		is.contents = contents
+	}
+	is.contents = is.contents[:size]
+}
+
+// 升级encoding之后添加value, value一定比所有的元素都大或者都小
+func (is *IntSet) upgradeAndAdd(value int64) {
+	oldEncoding := is.encoding
+	length := is.length
+	prepend := 0
+	if value < 0 {
+		prepend = 1
+	}
+	is.encoding = intSetValueEncoding(value)
+	is.resize(length + 1)
+	// 从后往前移动, 避免覆盖还没有移动的元素
+	for i := length - 1; i >= 0; i-- {
+		is.set(i+prepend, is.getEncoded(i, oldEncoding))
+	}
+	if prepend == 1 {
+		is.set(0, value)
+	} else {
+		is.set(length, value)
+	}
+	is.length++
+}
+
+// 添加value, value已经存在的时候返回false
+func (is *IntSet) Add(value int64) bool {
+	if intSetValueEncoding(value) > is.encoding {
+		is.upgradeAndAdd(value)
+		return true
+	}
+	pos, found := is.search(value)
+	if found {
+		return false
+	}
+	is.resize(is.length + 1)
+	copy(is.contents[(pos+1)*is.encoding:], is.contents[pos*is.encoding:is.length*is.encoding])
+	is.set(pos, value)
+	is.length++
+	return true
+}
+
+// 删除value, value不存在的时候返回false
+func (is *IntSet) Remove(value int64) bool {
+	if intSetValueEncoding(value) > is.encoding {
+		return false
+	}
+	pos, found := is.search(value)
+	if !found {
+		return false
+	}
+	copy(is.contents[pos*is.encoding:], is.contents[(pos+1)*is.encoding:])
+	is.length--
+	is.resize(is.length)
+	return true
+}
+
+func (is *IntSet) Find(value int64) bool {
+	if intSetValueEncoding(value) > is.encoding {
+		return false
+	}
+	_, found := is.search(value)
+	return found
+}
+
+// 返回第pos个元素
+func (is *IntSet) Get(pos int) int64 {
+	return is.getEncoded(pos, is.encoding)
+}
+
+func (is *IntSet) Random() int64 {
+	return is.Get(rand.Intn(is.length))
+}
+
+func (is *IntSet) Len() int {
+	return is.length
+}
+
+func (is *IntSet) Encoding() int {
+	return is.encoding
+}
+
+// 整数集合占用的字节数
+func (is *IntSet) Bytes() int {
+	return intSetHeaderSize + len(is.contents)
+}
+
+// 编码成redis的intset格式, 用于持久化
+func (is *IntSet) RawBytes() []byte {
+	buf := make([]byte, intSetHeaderSize, is.Bytes())
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(is.encoding))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(is.length))
+	return append(buf, is.contents...)
+}
+
+// 从redis的intset格式创建整数集合
+func NewIntSetFromBytes(buf []byte) (*IntSet, error) {
+	if len(buf) < intSetHeaderSize {
+		return nil, errIntSetCorrupted
+	}
+	encoding := int(binary.LittleEndian.Uint32(buf[0:4]))
+	length := int(binary.LittleEndian.Uint32(buf[4:8]))
+	if encoding != IntSetEncInt16 && encoding != IntSetEncInt32 && encoding != IntSetEncInt64 {
+		return nil, errIntSetCorrupted
+	}
+	if len(buf)-intSetHeaderSize != length*encoding {
+		return nil, errIntSetCorrupted
+	}
+	contents := make([]byte, length*encoding)
+	copy(contents, buf[intSetHeaderSize:])
+	is := &IntSet{encoding: encoding, length: length, contents: contents}
+	// 元素必须是严格递增的
+	for i := 1; i < length; i++ {
+		if is.Get(i-1) >= is.Get(i) {
+			return nil, errIntSetCorrupted
+		}
+	}
+	return is, nil
+}
@@ -0,0 +1,82 @@
+package raw_type
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func checkIntSet(is *IntSet, expected map[int64]bool) {
+	values := make([]int64, 0, len(expected))
+	for value := range expected {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	Expect(is.Len()).To(Equal(len(values)))
+	for i, value := range values {
+		Expect(is.Get(i)).To(Equal(value))
+	}
+}
+
+var _ = Describe("test intset", func() {
+	It("test intset upgrade", func() {
+		is := NewIntSet()
+		is.Add(32)
+		is.Add(-5)
+		Expect(is.Encoding()).To(Equal(IntSetEncInt16))
+		Expect(is.Bytes()).To(Equal(intSetHeaderSize + 2*2))
+		is.Add(65535)
+		Expect(is.Encoding()).To(Equal(IntSetEncInt32))
+		is.Add(math.MinInt64)
+		Expect(is.Encoding()).To(Equal(IntSetEncInt64))
+		checkIntSet(is, map[int64]bool{32: true, -5: true, 65535: true, math.MinInt64: true})
+
+		// 删除元素之后不会降级
+		is.Remove(math.MinInt64)
+		Expect(is.Encoding()).To(Equal(IntSetEncInt64))
+		Expect(is.Find(math.MinInt64)).To(BeFalse())
+		Expect(is.Find(65535)).To(BeTrue())
+
+		// 添加已经存在的元素或者删除不存在的元素都会失败
+		Expect(is.Add(32)).To(BeFalse())
+		Expect(is.Remove(100)).To(BeFalse())
+		Expect(is.Remove(math.MaxInt64)).To(BeFalse())
+	})
+
+	It("test intset random add and remove", func() {
+		r := rand.New(rand.NewSource(1))
+		is := NewIntSet()
+		expected := make(map[int64]bool)
+		ranges := []int64{100, math.MaxInt16 * 2, math.MaxInt32 * 2, math.MaxInt64}
+		for i := 0; i < 5000; i++ {
+			// 逐渐扩大元素的范围, 覆盖每一种encoding的升级
+			max := ranges[i*len(ranges)/5000]
+			value := r.Int63n(max)
+			if r.Intn(2) == 0 {
+				value = -value
+			}
+			if r.Intn(3) == 0 {
+				Expect(is.Remove(value)).To(Equal(expected[value]))
+				delete(expected, value)
+			} else {
+				Expect(is.Add(value)).To(Equal(!expected[value]))
+				expected[value] = true
+			}
+			Expect(is.Find(value)).To(Equal(expected[value]))
+		}
+		checkIntSet(is, expected)
+
+		loaded, err := NewIntSetFromBytes(is.RawBytes())
+		Expect(err).To(BeNil())
+		Expect(loaded.Encoding()).To(Equal(IntSetEncInt64))
+		checkIntSet(loaded, expected)
+	})
+
+	It("test load intset with duplicated values", func() {
+		_, err := NewIntSetFromBytes([]byte{2, 0, 0, 0, 2, 0, 0, 0, 1, 0, 1, 0})
+		Expect(err).NotTo(BeNil())
+	})
+})
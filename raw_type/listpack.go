@@ -0,0 +1,405 @@
+package raw_type
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+/**
+LISTPACK:
+	listpack是一块连续的内存, 用来紧凑地保存元素个数比较少的列表、哈希和有序集合:
+		<total-bytes uint32> <num-elements uint16> <entry> ... <entry> <end 0xFF>
+	每个entry由<encoding><data><backlen>三部分组成:
+		encoding: 元素的编码, 整数使用尽量少的字节保存, 其他的值按照字符串保存;
+		backlen: encoding和data的总长度, 从右向左读取, 每个字节保存7位, 最高位为1表示左边还有字节, 用于从后向前遍历。
+	元素的个数超过65535的时候num-elements保存65535, 需要遍历才能得到元素的个数。
+	entry通过在listpack中的偏移量访问, -1表示没有对应的元素。
+*/
+const (
+	ListPackHeaderSize = 6    /* total-bytes + num-elements */
+	listPackEOF        = 0xFF /* listpack的结束标记 */
+	listPackNumUnknown = 65535
+
+	listPackEncoding7BitUint   = 0x00 /* 0xxxxxxx */
+	listPackEncoding6BitStr    = 0x80 /* 10xxxxxx */
+	listPackEncoding13BitInt   = 0xC0 /* 110xxxxx yyyyyyyy */
+	listPackEncoding12BitStr   = 0xE0 /* 1110xxxx yyyyyyyy */
+	listPackEncoding32BitStr   = 0xF0 /* 11110000 4字节的长度 */
+	listPackEncoding16BitInt   = 0xF1
+	listPackEncoding24BitInt   = 0xF2
+	listPackEncoding32BitInt   = 0xF3
+	listPackEncoding64BitInt   = 0xF4
+	listPackEncoding7BitUintMk = 0x80
+	listPackEncoding6BitStrMk  = 0xC0
+	listPackEncoding13BitIntMk = 0xE0
+	listPackEncoding12BitStrMk = 0xF0
+)
+
+type ListPack struct {
+	buf []byte
+}
+
+func NewListPack() *ListPack {
+	buf := make([]byte, ListPackHeaderSize+1)
+	buf[ListPackHeaderSize] = listPackEOF
+	lp := &ListPack{buf: buf}
+	lp.setTotalBytes()
+	return lp
+}
+
+// 字符串能否按照整数保存, 只有转换回字符串之后和原来相同的时候才可以
+func listPackStringToInt(value string) (int64, bool) {
+	if len(value) == 0 || len(value) > 20 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != value {
+		return 0, false
+	}
+	return n, true
+}
+
+// 对元素进行编码, 返回encoding和data
+func listPackEncode(value string) []byte {
+	if n, ok := listPackStringToInt(value); ok {
+		switch {
+		case n >= 0 && n <= 127:
+			return []byte{byte(n)}
+		case n >= -4096 && n <= 4095:
+			u := uint16(n) & 0x1FFF
+			return []byte{listPackEncoding13BitInt | byte(u>>8), byte(u)}
+		case n >= -32768 && n <= 32767:
+			buf := []byte{listPackEncoding16BitInt, 0, 0}
+			binary.LittleEndian.PutUint16(buf[1:], uint16(n))
+			return buf
+		case n >= -8388608 && n <= 8388607:
+			u := uint32(n)
+			return []byte{listPackEncoding24BitInt, byte(u), byte(u >> 8), byte(u >> 16)}
+		case n >= -2147483648 && n <= 2147483647:
+			buf := []byte{listPackEncoding32BitInt, 0, 0, 0, 0}
+			binary.LittleEndian.PutUint32(buf[1:], uint32(n))
+			return buf
+		default:
+			buf := []byte{listPackEncoding64BitInt, 0, 0, 0, 0, 0, 0, 0, 0}
+			binary.LittleEndian.PutUint64(buf[1:], uint64(n))
+			return buf
+		}
+	}
+	var buf []byte
+	switch length := len(value); {
+	case length < 64:
+		buf = append(make([]byte, 0, 1+length), listPackEncoding6BitStr|byte(length))
+	case length < 4096:
+		buf = append(make([]byte, 0, 2+length), listPackEncoding12BitStr|byte(length>>8), byte(length))
+	default:
+		buf = make([]byte, 5, 5+length)
+		buf[0] = listPackEncoding32BitStr
+		binary.LittleEndian.PutUint32(buf[1:], uint32(length))
+	}
+	return append(buf, value...)
+}
+
+// backlen从右向左每个字节保存7位, 除了最左边的字节之外最高位都是1
+func listPackEncodeBacklen(length int) []byte {
+	size := listPackBacklenSize(length)
+	buf := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		buf[i] = byte(length & 127)
+		if i != 0 {
+			buf[i] |= 128
+		}
+		length >>= 7
+	}
+	return buf
+}
+
+func listPackBacklenSize(length int) int {
+	size := 1
+	for length > 127 {
+		length >>= 7
+		size++
+	}
+	return size
+}
+
+// 计算一个元素编码之后在listpack中占用的字节数
+func ListPackEntrySize(value string) int {
+	length := len(listPackEncode(value))
+	return length + listPackBacklenSize(length)
+}
+
+// 返回p处entry的encoding和data的长度, 以及data的起始位置
+func (lp *ListPack) entryLength(p int) (length int, dataStart int) {
+	b := lp.buf[p]
+	switch {
+	case b&listPackEncoding7BitUintMk == listPackEncoding7BitUint:
+		return 1, p
+	case b&listPackEncoding6BitStrMk == listPackEncoding6BitStr:
+		return 1 + int(b&0x3F), p + 1
+	case b&listPackEncoding13BitIntMk == listPackEncoding13BitInt:
+		return 2, p
+	case b&listPackEncoding12BitStrMk == listPackEncoding12BitStr:
+		return 2 + (int(b&0x0F)<<8 | int(lp.buf[p+1])), p + 2
+	case b == listPackEncoding32BitStr:
+		return 5 + int(binary.LittleEndian.Uint32(lp.buf[p+1:])), p + 5
+	case b == listPackEncoding16BitInt:
+		return 3, p
+	case b == listPackEncoding24BitInt:
+		return 4, p
+	case b == listPackEncoding32BitInt:
+		return 5, p
+	case b == listPackEncoding64BitInt:
+		return 9, p
+	}
+	panic("listpack: invalid entry encoding")
+}
+
+func (lp *ListPack) setTotalBytes() {
+	binary.LittleEndian.PutUint32(lp.buf[0:], uint32(len(lp.buf)))
+}
+
+func (lp *ListPack) setLength(length int) {
+	if length >= listPackNumUnknown {
+		length = listPackNumUnknown
+	}
+	binary.LittleEndian.PutUint16(lp.buf[4:], uint16(length))
+}
+
+// listpack中元素的个数
+func (lp *ListPack) Len() int {
+	length := int(binary.LittleEndian.Uint16(lp.buf[4:]))
+	if length != listPackNumUnknown {
+		return length
+	}
+	length = 0
+	for p := lp.First(); p >= 0; p = lp.Next(p) {
+		length++
+	}
+	return length
+}
+
+// listpack占用的字节数
+func (lp *ListPack) Bytes() int {
+	return len(lp.buf)
+}
+
+// 返回listpack的原始数据, 用于压缩和持久化
+func (lp *ListPack) RawBytes() []byte {
+	return lp.buf
+}
+
+// 使用原始数据创建listpack
+func NewListPackFromBytes(buf []byte) *ListPack {
+	return &ListPack{buf: buf}
+}
+
+func (lp *ListPack) First() int {
+	if lp.buf[ListPackHeaderSize] == listPackEOF {
+		return -1
+	}
+	return ListPackHeaderSize
+}
+
+func (lp *ListPack) Last() int {
+	if lp.buf[ListPackHeaderSize] == listPackEOF {
+		return -1
+	}
+	return lp.Prev(len(lp.buf) - 1)
+}
+
+// p之后的entry, p是最后一个entry的时候返回-1
+func (lp *ListPack) Next(p int) int {
+	length, _ := lp.entryLength(p)
+	p += length + listPackBacklenSize(length)
+	if lp.buf[p] == listPackEOF {
+		return -1
+	}
+	return p
+}
+
+// p之前的entry, p是第一个entry的时候返回-1
+func (lp *ListPack) Prev(p int) int {
+	if p <= ListPackHeaderSize {
+		return -1
+	}
+	q := p - 1
+	length, shift := 0, 0
+	for {
+		length |= int(lp.buf[q]&127) << shift
+		if lp.buf[q]&128 == 0 {
+			break
+		}
+		shift += 7
+		q--
+	}
+	return q - length
+}
+
+// 读取p处的元素, 整数会被转换成字符串
+func (lp *ListPack) Get(p int) string {
+	b := lp.buf[p]
+	switch {
+	case b&listPackEncoding7BitUintMk == listPackEncoding7BitUint:
+		return strconv.FormatInt(int64(b), 10)
+	case b&listPackEncoding13BitIntMk == listPackEncoding13BitInt:
+		n := int64(b&0x1F)<<8 | int64(lp.buf[p+1])
+		if n >= 1<<12 {
+			n -= 1 << 13
+		}
+		return strconv.FormatInt(n, 10)
+	case b == listPackEncoding16BitInt:
+		return strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(lp.buf[p+1:]))), 10)
+	case b == listPackEncoding24BitInt:
+		u := uint32(lp.buf[p+1]) | uint32(lp.buf[p+2])<<8 | uint32(lp.buf[p+3])<<16
+		return strconv.FormatInt(int64(int32(u<<8)>>8), 10)
+	case b == listPackEncoding32BitInt:
+		return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(lp.buf[p+1:]))), 10)
+	case b == listPackEncoding64BitInt:
+		return strconv.FormatInt(int64(binary.LittleEndian.Uint64(lp.buf[p+1:])), 10)
+	}
+	length, dataStart := lp.entryLength(p)
+	return string(lp.buf[dataStart : p+length])
+}
+
+// 根据下标查找entry, 下标可以是负数, 表示从尾部开始计算
+func (lp *ListPack) Seek(index int) int {
+	length := lp.Len()
+	if index < 0 {
+		index += length
+	}
+	if index < 0 || index >= length {
+		return -1
+	}
+	if index < length/2 {
+		p := lp.First()
+		for ; index > 0; index-- {
+			p = lp.Next(p)
+		}
+		return p
+	}
+	p := lp.Last()
+	for index = length - 1 - index; index > 0; index-- {
+		p = lp.Prev(p)
+	}
+	return p
+}
+
+// 在p处插入数据, 原来p处以及之后的entry向后移动, length是插入之后元素的个数
+func (lp *ListPack) insertAt(p int, value string, length int) {
+	encoded := listPackEncode(value)
+	entry := append(encoded, listPackEncodeBacklen(len(encoded))...)
+	lp.buf = append(lp.buf, entry...)
+	copy(lp.buf[p+len(entry):], lp.buf[p:len(lp.buf)-len(entry)])
+	copy(lp.buf[p:], entry)
+	lp.setTotalBytes()
+	lp.setLength(length)
+}
+
+// 在p之前或者之后插入元素, 返回新元素的位置
+func (lp *ListPack) Insert(p int, value string, after bool) int {
+	if after {
+		if next := lp.Next(p); next >= 0 {
+			p = next
+		} else {
+			p = len(lp.buf) - 1
+		}
+	}
+	lp.insertAt(p, value, lp.Len()+1)
+	return p
+}
+
+func (lp *ListPack) Append(value string) {
+	lp.insertAt(len(lp.buf)-1, value, lp.Len()+1)
+}
+
+func (lp *ListPack) Prepend(value string) {
+	lp.insertAt(ListPackHeaderSize, value, lp.Len()+1)
+}
+
+// 删除p处的元素, 返回被删除的元素之后的entry
+func (lp *ListPack) Delete(p int) int {
+	count := lp.Len()
+	length, _ := lp.entryLength(p)
+	size := length + listPackBacklenSize(length)
+	lp.buf = append(lp.buf[:p], lp.buf[p+size:]...)
+	lp.setTotalBytes()
+	lp.setLength(count - 1)
+	if lp.buf[p] == listPackEOF {
+		return -1
+	}
+	return p
+}
+
+// 从下标index开始删除count个元素
+func (lp *ListPack) DeleteRange(index int, count int) {
+	start := lp.Seek(index)
+	if start < 0 || count <= 0 {
+		return
+	}
+	length := lp.Len()
+	end, deleted := start, 0
+	for ; deleted < count && lp.buf[end] != listPackEOF; deleted++ {
+		length, _ := lp.entryLength(end)
+		end += length + listPackBacklenSize(length)
+	}
+	lp.buf = append(lp.buf[:start], lp.buf[end:]...)
+	lp.setTotalBytes()
+	lp.setLength(length - deleted)
+}
+
+// 替换p处的元素, 返回新元素的位置
+func (lp *ListPack) Replace(p int, value string) int {
+	count := lp.Len()
+	length, _ := lp.entryLength(p)
+	size := length + listPackBacklenSize(length)
+	lp.buf = append(lp.buf[:p], lp.buf[p+size:]...)
+	lp.insertAt(p, value, count)
+	return p
+}
+
+// 返回listpack中的所有元素
+func (lp *ListPack) Entries() []string {
+	ret := make([]string, 0, lp.Len())
+	for p := lp.First(); p >= 0; p = lp.Next(p) {
+		ret = append(ret, lp.Get(p))
+	}
+	return ret
+}
+
+// 从下标index开始, 按照方向遍历listpack
+type ListPackIterator struct {
+	lp      *ListPack
+	pos     int  // 下一个元素的位置
+	current int  // 上一次Next返回的元素的位置
+	forward bool // 是否从头部向尾部遍历
+}
+
+func (lp *ListPack) Iterator(index int, forward bool) *ListPackIterator {
+	return &ListPackIterator{lp: lp, pos: lp.Seek(index), current: -1, forward: forward}
+}
+
+func (it *ListPackIterator) Next() (string, bool) {
+	if it.pos < 0 {
+		return "", false
+	}
+	it.current = it.pos
+	if it.forward {
+		it.pos = it.lp.Next(it.pos)
+	} else {
+		it.pos = it.lp.Prev(it.pos)
+	}
+	return it.lp.Get(it.current), true
+}
+
+// 删除上一次Next返回的元素, 之后可以继续遍历
+func (it *ListPackIterator) Delete() {
+	next := it.lp.Delete(it.current)
+	if it.forward {
+		// 后面的元素向前移动到了被删除的元素的位置
+		it.pos = next
+	}
+	it.current = -1
+}
+
+func (it *ListPackIterator) Release() {
+}
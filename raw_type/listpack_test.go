@@ -0,0 +1,93 @@
+package raw_type
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("test listpack", func() {
+	It("test listpack encoding", func() {
+		values := []string{"0", "127", "128", "-1", "-4096", "4095", "4096", "-4097", "32767", "-32768", "32768",
+			"8388607", "-8388608", "8388608", "2147483647", "-2147483648", "2147483648",
+			"9223372036854775807", "-9223372036854775808", "9223372036854775808", "", "abc", "007", "+1", "-0", "1.5",
+			strings.Repeat("a", 63), strings.Repeat("b", 64), strings.Repeat("c", 4095), strings.Repeat("d", 4096)}
+		lp := NewListPack()
+		for _, value := range values {
+			lp.Append(value)
+		}
+		Expect(lp.Len()).To(Equal(len(values)))
+		Expect(lp.Bytes()).To(Equal(len(lp.RawBytes())))
+
+		// 从前向后和从后向前遍历的结果都和写入的相同
+		i := 0
+		for p := lp.First(); p >= 0; p = lp.Next(p) {
+			Expect(lp.Get(p)).To(Equal(values[i]))
+			i++
+		}
+		Expect(i).To(Equal(len(values)))
+		i = len(values) - 1
+		for p := lp.Last(); p >= 0; p = lp.Prev(p) {
+			Expect(lp.Get(p)).To(Equal(values[i]))
+			i--
+		}
+		Expect(i).To(Equal(-1))
+
+		// 小整数只占用一个字节
+		Expect(ListPackEntrySize("100")).To(Equal(2))
+		Expect(ListPackEntrySize("-100")).To(Equal(3))
+	})
+
+	It("test listpack operations", func() {
+		lp := NewListPack()
+		for i := 0; i < 10; i++ {
+			lp.Append(fmt.Sprintf("%d", i))
+		}
+		lp.Prepend("head")
+		lp.Insert(lp.Seek(5), "before5", false)
+		lp.Insert(lp.Seek(-1), "tail", true)
+		lp.Replace(lp.Seek(1), strings.Repeat("x", 100))
+		lp.Delete(lp.Seek(2))
+		Expect(lp.Entries()).To(Equal([]string{"head", strings.Repeat("x", 100), "2", "3", "before5", "4", "5", "6", "7", "8", "9", "tail"}))
+
+		lp.DeleteRange(-3, 10)
+		lp.DeleteRange(0, 1)
+		expected := []string{strings.Repeat("x", 100), "2", "3", "before5", "4", "5", "6", "7"}
+		Expect(lp.Entries()).To(Equal(expected))
+		Expect(lp.Len()).To(Equal(len(expected)))
+		Expect(lp.Seek(len(expected))).To(Equal(-1))
+		Expect(lp.Seek(-len(expected) - 1)).To(Equal(-1))
+	})
+
+	It("test delete elements while iterating", func() {
+		lp := NewListPack()
+		for _, value := range []string{strings.Repeat("x", 100), "2", "3", "before5", "4", "5", "6", "7"} {
+			lp.Append(value)
+		}
+		it := lp.Iterator(-1, false)
+		for value, ok := it.Next(); ok; value, ok = it.Next() {
+			if len(value) == 1 && value[0]%2 == 1 {
+				it.Delete()
+			}
+		}
+		it = lp.Iterator(0, true)
+		for value, ok := it.Next(); ok; value, ok = it.Next() {
+			if len(value) > 1 {
+				it.Delete()
+			}
+		}
+		Expect(lp.Entries()).To(Equal([]string{"2", "4", "6"}))
+	})
+
+	It("test listpack with many elements", func() {
+		lp := NewListPack()
+		for i := 0; i < 66000; i++ {
+			lp.Append("v")
+		}
+		Expect(lp.Len()).To(Equal(66000))
+		lp.DeleteRange(0, 10000)
+		Expect(lp.Len()).To(Equal(56000))
+	})
+})
@@ -0,0 +1,504 @@
+package raw_type
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+	"math"
+)
+
+/**
+QUICKLIST:
+	quicklist是由listpack组成的双端链表, 每个节点保存多个元素, 兼顾了内存占用和插入删除的效率。
+	fill限制每个节点的大小:
+		fill > 0 : 每个节点最多保存fill个元素;
+		fill < 0 : 每个节点最多占用的字节数, -1:4KB -2:8KB -3:16KB -4:32KB -5:64KB。
+	compress是两端不压缩的节点个数, 中间的节点会被压缩, 访问的时候再解压。0表示不压缩。
+	两端的节点访问最频繁, 所以总是保持解压的状态。
+*/
+const (
+	QuickListDefaultFill     = -2
+	QuickListDefaultCompress = 0
+
+	quickListMinCompressBytes   = 48 /* 小于这个大小的节点不压缩 */
+	quickListMinCompressImprove = 8  /* 压缩之后至少要减少的字节数 */
+	quickListMaxFill            = 1 << 15
+	quickListSizeSafetyLimit    = 8192 /* fill为正数的时候节点的大小限制 */
+)
+
+// 负数的fill对应的节点大小限制
+var quickListOptimizationLevel = []int{4096, 8192, 16384, 32768, 65536}
+
+type quickListNode struct {
+	prev       *quickListNode
+	next       *quickListNode
+	lp         *ListPack // 节点解压之后的listpack, 被压缩的时候为nil
+	compressed []byte    // 压缩之后的数据, 节点被修改之后失效
+	count      int       // 节点中元素的个数
+	size       int       // listpack的字节数
+}
+
+type QuickList struct {
+	head     *quickListNode
+	tail     *quickListNode
+	count    int // 所有节点中元素的总数
+	length   int // 节点的个数
+	fill     int
+	compress int
+}
+
+func NewQuickList(fill int, compress int) *QuickList {
+	if fill > quickListMaxFill {
+		fill = quickListMaxFill
+	}
+	if compress < 0 {
+		compress = 0
+	}
+	return &QuickList{fill: fill, compress: compress}
+}
+
+// fill对应的每个节点的字节数和元素个数的限制
+func QuickListNodeLimit(fill int) (size int, count int) {
+	if fill >= 0 {
+		return quickListSizeSafetyLimit, fill
+	}
+	level := -fill - 1
+	if level >= len(quickListOptimizationLevel) {
+		level = len(quickListOptimizationLevel) - 1
+	}
+	return quickListOptimizationLevel[level], math.MaxInt32
+}
+
+/**
+节点中的元素个数和字节数是否超过了fill的限制, listpack编码的列表也通过这个函数判断是否需要转换成quicklist。
+*/
+func QuickListNodeExceedsLimit(fill int, size int, count int) bool {
+	if count <= 1 {
+		// 单个节点至少保存一个元素
+		return false
+	}
+	sizeLimit, countLimit := QuickListNodeLimit(fill)
+	return count > countLimit || size > sizeLimit
+}
+
+func newQuickListNode(lp *ListPack) *quickListNode {
+	return &quickListNode{lp: lp, count: lp.Len(), size: lp.Bytes()}
+}
+
+// 返回节点的listpack, 被压缩的节点会先解压
+func (node *quickListNode) listPack() *ListPack {
+	if node.lp == nil {
+		reader := flate.NewReader(bytes.NewReader(node.compressed))
+		buf, err := ioutil.ReadAll(reader)
+		if err != nil {
+			panic("quicklist: decompress node failed " + err.Error())
+		}
+		node.lp = NewListPackFromBytes(buf)
+	}
+	return node.lp
+}
+
+// 节点被修改之后更新元素个数和大小, 之前压缩的数据失效
+func (node *quickListNode) update() {
+	node.count = node.lp.Len()
+	node.size = node.lp.Bytes()
+	node.compressed = nil
+}
+
+func (node *quickListNode) compressNode() {
+	if node.lp == nil {
+		return
+	}
+	if node.compressed == nil {
+		if node.size < quickListMinCompressBytes {
+			return
+		}
+		var buf bytes.Buffer
+		writer, _ := flate.NewWriter(&buf, flate.BestSpeed)
+		writer.Write(node.lp.RawBytes())
+		writer.Close()
+		if buf.Len()+quickListMinCompressImprove >= node.size {
+			return
+		}
+		node.compressed = buf.Bytes()
+	}
+	node.lp = nil
+}
+
+// 节点中新增size个字节、count个元素之后是否仍然满足fill的限制
+func (ql *QuickList) nodeAllowInsert(node *quickListNode, size int, count int) bool {
+	if node == nil {
+		return false
+	}
+	return !QuickListNodeExceedsLimit(ql.fill, node.size+size, node.count+count)
+}
+
+/**
+保证两端compress个节点没有被压缩, 然后压缩刚好超出范围的节点以及node。
+其他节点的压缩状态在之前的操作中已经维护好了。
+*/
+func (ql *QuickList) compressAround(node *quickListNode) {
+	if ql.compress == 0 || ql.head == nil {
+		return
+	}
+	forward, reverse := ql.head, ql.tail
+	inDepth := false
+	for i := 0; i < ql.compress && forward != nil; i++ {
+		forward.listPack()
+		reverse.listPack()
+		if forward == node || reverse == node {
+			inDepth = true
+		}
+		if forward == reverse || forward.next == reverse {
+			// 所有节点都在不压缩的范围内
+			return
+		}
+		forward, reverse = forward.next, reverse.prev
+	}
+	forward.compressNode()
+	reverse.compressNode()
+	if node != nil && !inDepth {
+		node.compressNode()
+	}
+}
+
+func (ql *QuickList) insertNode(old *quickListNode, node *quickListNode, after bool) {
+	if old == nil {
+		ql.head, ql.tail = node, node
+	} else if after {
+		node.prev, node.next = old, old.next
+		if old.next != nil {
+			old.next.prev = node
+		} else {
+			ql.tail = node
+		}
+		old.next = node
+	} else {
+		node.prev, node.next = old.prev, old
+		if old.prev != nil {
+			old.prev.next = node
+		} else {
+			ql.head = node
+		}
+		old.prev = node
+	}
+	ql.length++
+}
+
+func (ql *QuickList) deleteNode(node *quickListNode) {
+	if node.prev != nil {
+		node.prev.next = node.next
+	} else {
+		ql.head = node.next
+	}
+	if node.next != nil {
+		node.next.prev = node.prev
+	} else {
+		ql.tail = node.prev
+	}
+	ql.length--
+	ql.count -= node.count
+	node.prev, node.next = nil, nil
+}
+
+// quicklist中元素的个数
+func (ql *QuickList) Len() int {
+	return ql.count
+}
+
+// quicklist中节点的个数
+func (ql *QuickList) NodeCount() int {
+	return ql.length
+}
+
+// 头节点中listpack解压之后的字节数和元素个数, quicklist为空的时候返回0
+func (ql *QuickList) HeadNodeStat() (size int, count int) {
+	if ql.head == nil {
+		return 0, 0
+	}
+	return ql.head.size, ql.head.count
+}
+
+// 返回头节点的listpack, 被压缩的节点会先解压, quicklist为空的时候返回一个空的listpack
+func (ql *QuickList) HeadListPack() *ListPack {
+	if ql.head == nil {
+		return NewListPack()
+	}
+	return ql.head.listPack()
+}
+
+// 所有节点占用的字节数, 被压缩的节点按照压缩之后的大小计算
+func (ql *QuickList) Bytes() int {
+	size := 0
+	for node := ql.head; node != nil; node = node.next {
+		if node.lp == nil {
+			size += len(node.compressed)
+		} else {
+			size += node.size
+		}
+	}
+	return size
+}
+
+func (ql *QuickList) PushHead(value string) {
+	if ql.nodeAllowInsert(ql.head, ListPackEntrySize(value), 1) {
+		ql.head.listPack().Prepend(value)
+		ql.head.update()
+	} else {
+		lp := NewListPack()
+		lp.Prepend(value)
+		ql.insertNode(ql.head, newQuickListNode(lp), false)
+	}
+	ql.count++
+	ql.compressAround(ql.head)
+}
+
+func (ql *QuickList) PushTail(value string) {
+	if ql.nodeAllowInsert(ql.tail, ListPackEntrySize(value), 1) {
+		ql.tail.listPack().Append(value)
+		ql.tail.update()
+	} else {
+		lp := NewListPack()
+		lp.Append(value)
+		ql.insertNode(ql.tail, newQuickListNode(lp), true)
+	}
+	ql.count++
+	ql.compressAround(ql.tail)
+}
+
+// 把一个listpack作为节点添加到尾部, 用于把listpack编码的列表转换成quicklist
+func (ql *QuickList) AppendListPack(lp *ListPack) {
+	if lp.Len() == 0 {
+		return
+	}
+	node := newQuickListNode(lp)
+	ql.insertNode(ql.tail, node, true)
+	ql.count += node.count
+	ql.compressAround(node)
+}
+
+// 查找下标对应的节点以及元素在节点中的下标, 下标可以是负数
+func (ql *QuickList) seek(index int) (*quickListNode, int) {
+	if index < 0 {
+		index += ql.count
+	}
+	if index < 0 || index >= ql.count {
+		return nil, 0
+	}
+	if index < ql.count/2 {
+		for node := ql.head; node != nil; node = node.next {
+			if index < node.count {
+				return node, index
+			}
+			index -= node.count
+		}
+	} else {
+		index = ql.count - 1 - index
+		for node := ql.tail; node != nil; node = node.prev {
+			if index < node.count {
+				return node, node.count - 1 - index
+			}
+			index -= node.count
+		}
+	}
+	return nil, 0
+}
+
+// 返回下标对应的元素
+func (ql *QuickList) Index(index int) (string, bool) {
+	node, offset := ql.seek(index)
+	if node == nil {
+		return "", false
+	}
+	lp := node.listPack()
+	value := lp.Get(lp.Seek(offset))
+	ql.compressAround(node)
+	return value, true
+}
+
+// 替换下标对应的元素, 下标超出范围的时候返回false
+func (ql *QuickList) Replace(index int, value string) bool {
+	node, offset := ql.seek(index)
+	if node == nil {
+		return false
+	}
+	lp := node.listPack()
+	lp.Replace(lp.Seek(offset), value)
+	node.update()
+	ql.compressAround(node)
+	return true
+}
+
+/**
+在下标对应的元素之前或者之后插入元素。
+节点已经满了的时候, 如果插入的位置在节点的边缘, 尝试插入到相邻的节点中, 否则把节点从插入的位置分裂成两个节点。
+*/
+func (ql *QuickList) Insert(index int, value string, after bool) bool {
+	node, offset := ql.seek(index)
+	if node == nil {
+		return false
+	}
+	size := ListPackEntrySize(value)
+	lp := node.listPack()
+	switch {
+	case ql.nodeAllowInsert(node, size, 1):
+		lp.Insert(lp.Seek(offset), value, after)
+		node.update()
+	case after && offset == node.count-1 && ql.nodeAllowInsert(node.next, size, 1):
+		node = node.next
+		node.listPack().Prepend(value)
+		node.update()
+	case !after && offset == 0 && ql.nodeAllowInsert(node.prev, size, 1):
+		node = node.prev
+		node.listPack().Append(value)
+		node.update()
+	default:
+		if after {
+			offset++
+		}
+		newLp := NewListPack()
+		newLp.Append(value)
+		newNode := newQuickListNode(newLp)
+		switch offset {
+		case 0:
+			ql.insertNode(node, newNode, false)
+		case node.count:
+			ql.insertNode(node, newNode, true)
+		default:
+			// 把offset以及之后的元素移动到新的节点中, 新元素插入到两个节点之间
+			right := ql.splitNode(node, offset)
+			ql.insertNode(node, newNode, true)
+			ql.compressAround(right)
+		}
+		ql.compressAround(node)
+		node = newNode
+	}
+	ql.count++
+	ql.compressAround(node)
+	return true
+}
+
+// 把node中从offset开始的元素移动到node之后的新节点中, 返回新节点, offset需要在(0, node.count)之间
+func (ql *QuickList) splitNode(node *quickListNode, offset int) *quickListNode {
+	lp := node.listPack()
+	right := NewListPack()
+	for p := lp.Seek(offset); p >= 0; p = lp.Next(p) {
+		right.Append(lp.Get(p))
+	}
+	lp.DeleteRange(offset, node.count-offset)
+	node.update()
+	rightNode := newQuickListNode(right)
+	ql.insertNode(node, rightNode, true)
+	return rightNode
+}
+
+// 从下标start开始删除count个元素, 整个节点都在范围内的时候直接删除节点
+func (ql *QuickList) DelRange(start int, count int) {
+	node, offset := ql.seek(start)
+	for node != nil && count > 0 {
+		next := node.next
+		if offset == 0 && count >= node.count {
+			count -= node.count
+			ql.deleteNode(node)
+		} else {
+			deleted := node.count - offset
+			if deleted > count {
+				deleted = count
+			}
+			node.listPack().DeleteRange(offset, deleted)
+			node.update()
+			ql.count -= deleted
+			count -= deleted
+			ql.compressAround(node)
+		}
+		node, offset = next, 0
+	}
+	ql.compressAround(nil)
+}
+
+// 返回每个节点中的元素, 用于持久化
+func (ql *QuickList) NodeEntries() [][]string {
+	ret := make([][]string, 0, ql.length)
+	for node := ql.head; node != nil; node = node.next {
+		ret = append(ret, node.listPack().Entries())
+		ql.compressAround(node)
+	}
+	return ret
+}
+
+// 从下标index开始, 按照方向遍历quicklist, 遍历结束之后需要调用Release重新压缩访问过的节点
+type QuickListIterator struct {
+	ql      *QuickList
+	node    *quickListNode // 下一个元素所在的节点
+	pos     int            // 下一个元素在节点的listpack中的位置
+	curNode *quickListNode // 上一次Next返回的元素所在的节点
+	curPos  int
+	forward bool
+}
+
+func (ql *QuickList) Iterator(index int, forward bool) *QuickListIterator {
+	it := &QuickListIterator{ql: ql, pos: -1, curPos: -1, forward: forward}
+	if node, offset := ql.seek(index); node != nil {
+		it.node, it.pos = node, node.listPack().Seek(offset)
+	}
+	return it
+}
+
+func (it *QuickListIterator) Next() (string, bool) {
+	if it.curNode != nil && it.curNode != it.node {
+		it.ql.compressAround(it.curNode)
+	}
+	if it.node == nil {
+		return "", false
+	}
+	lp := it.node.listPack()
+	it.curNode, it.curPos = it.node, it.pos
+	value := lp.Get(it.pos)
+	if it.forward {
+		it.pos = lp.Next(it.pos)
+	} else {
+		it.pos = lp.Prev(it.pos)
+	}
+	// 当前节点遍历完成之后进入下一个节点
+	for it.pos < 0 && it.node != nil {
+		if it.forward {
+			it.node = it.node.next
+		} else {
+			it.node = it.node.prev
+		}
+		if it.node != nil {
+			if it.forward {
+				it.pos = it.node.listPack().First()
+			} else {
+				it.pos = it.node.listPack().Last()
+			}
+		}
+	}
+	return value, true
+}
+
+// 删除上一次Next返回的元素, 之后可以继续遍历
+func (it *QuickListIterator) Delete() {
+	node := it.curNode
+	next := node.listPack().Delete(it.curPos)
+	node.update()
+	it.ql.count--
+	if it.forward && it.node == node {
+		// 后面的元素向前移动到了被删除的元素的位置
+		it.pos = next
+	}
+	if node.count == 0 {
+		it.ql.deleteNode(node)
+	}
+	it.curNode, it.curPos = nil, -1
+}
+
+func (it *QuickListIterator) Release() {
+	if it.curNode != nil {
+		it.ql.compressAround(it.curNode)
+	}
+	if it.node != nil {
+		it.ql.compressAround(it.node)
+	}
+	it.curNode, it.node = nil, nil
+}
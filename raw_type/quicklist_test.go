@@ -0,0 +1,141 @@
+package raw_type
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func checkQuickList(ql *QuickList, expected []string) {
+	entries := make([]string, 0)
+	for _, node := range ql.NodeEntries() {
+		entries = append(entries, node...)
+	}
+	Expect(ql.Len()).To(Equal(len(expected)))
+	Expect(entries).To(Equal(expected))
+	// 每个节点记录的元素个数和listpack中的一致
+	for node := ql.head; node != nil; node = node.next {
+		Expect(node.count).NotTo(Equal(0))
+		Expect(node.count).To(Equal(node.listPack().Len()))
+	}
+	// 两端compress个节点没有被压缩
+	node := ql.head
+	for i := 0; i < ql.compress && node != nil; i, node = i+1, node.next {
+		Expect(node.lp).NotTo(BeNil())
+	}
+}
+
+var _ = Describe("test quicklist", func() {
+	for _, fill := range []int{1, 4, -1} {
+		for _, compress := range []int{0, 1, 2} {
+			fill, compress := fill, compress
+			It(fmt.Sprintf("test random operations with fill %d compress %d", fill, compress), func() {
+				r := rand.New(rand.NewSource(int64(fill*10 + compress)))
+				ql := NewQuickList(fill, compress)
+				expected := make([]string, 0)
+				for i := 0; i < 2000; i++ {
+					value := fmt.Sprintf("%d", r.Intn(1000))
+					if r.Intn(5) == 0 {
+						value = strings.Repeat(value, 50)
+					}
+					switch op := r.Intn(10); {
+					case op < 3:
+						ql.PushHead(value)
+						expected = append([]string{value}, expected...)
+					case op < 6:
+						ql.PushTail(value)
+						expected = append(expected, value)
+					case op < 8 && len(expected) > 0:
+						index := r.Intn(len(expected))
+						after := r.Intn(2) == 0
+						ql.Insert(index, value, after)
+						if after {
+							index++
+						}
+						expected = append(expected[:index], append([]string{value}, expected[index:]...)...)
+					case op < 9 && len(expected) > 0:
+						index := r.Intn(len(expected))
+						ql.Replace(index-len(expected), value)
+						expected[index] = value
+					case len(expected) > 0:
+						start, count := r.Intn(len(expected)), r.Intn(10)
+						ql.DelRange(start, count)
+						if start+count > len(expected) {
+							count = len(expected) - start
+						}
+						expected = append(expected[:start], expected[start+count:]...)
+					}
+					if i%100 == 0 {
+						checkQuickList(ql, expected)
+					}
+				}
+				checkQuickList(ql, expected)
+				for i := 0; i < 10 && len(expected) > 0; i++ {
+					index := r.Intn(len(expected))
+					value, ok := ql.Index(index)
+					Expect(ok).To(BeTrue())
+					Expect(value).To(Equal(expected[index]))
+				}
+
+				// 遍历的时候删除元素
+				it := ql.Iterator(-1, false)
+				remain := make([]string, 0)
+				for value, ok := it.Next(); ok; value, ok = it.Next() {
+					if len(value) > 2 {
+						it.Delete()
+					} else {
+						remain = append([]string{value}, remain...)
+					}
+				}
+				it.Release()
+				checkQuickList(ql, remain)
+				it = ql.Iterator(0, true)
+				for _, ok := it.Next(); ok; _, ok = it.Next() {
+					it.Delete()
+				}
+				it.Release()
+				Expect(ql.Len()).To(Equal(0))
+				Expect(ql.NodeCount()).To(Equal(0))
+			})
+		}
+	}
+
+	It("test quicklist compress", func() {
+		ql := NewQuickList(16, 1)
+		for i := 0; i < 16*10; i++ {
+			ql.PushTail(strings.Repeat("value", 10))
+		}
+		compressed := 0
+		for node := ql.head; node != nil; node = node.next {
+			if node.lp == nil {
+				compressed++
+			}
+		}
+		Expect(ql.NodeCount()).To(Equal(10))
+		Expect(compressed).To(Equal(8))
+		Expect(ql.Bytes()).To(BeNumerically("<", ListPackHeaderSize*10+16*10*ListPackEntrySize(strings.Repeat("value", 10))))
+		value, ok := ql.Index(80)
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(strings.Repeat("value", 10)))
+	})
+
+	It("test head node stat of compressed node", func() {
+		ql := NewQuickList(-1, 0)
+		Expect(ql.HeadListPack().Len()).To(Equal(0))
+		for i := 0; i < 10; i++ {
+			ql.PushTail(strings.Repeat("value", 10))
+		}
+		ql.head.compressNode()
+		Expect(ql.head.lp).To(BeNil())
+
+		// 被压缩的节点仍然返回解压之后的大小
+		size, count := ql.HeadNodeStat()
+		lp := ql.HeadListPack()
+		Expect(count).To(Equal(10))
+		Expect(lp.Len()).To(Equal(count))
+		Expect(lp.Bytes()).To(Equal(size))
+	})
+})
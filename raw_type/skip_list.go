@@ -59,6 +59,10 @@ func (sn *SkipNode) GetNextNode() *SkipNode {
 	return sn.level[0].forward
 }
 
+func (sn *SkipNode) GetLevel() int {
+	return len(sn.level)
+}
+
 func createSkipNode(obj string, score float64) *SkipNode {
 	return &SkipNode{
 		obj:   obj,
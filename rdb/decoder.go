@@ -12,6 +12,7 @@ import (
 	"strconv"
 
 	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/raw_type"
 )
 
 type ValueType byte
@@ -247,6 +248,24 @@ func (d *Decoder) readObject(key []byte, typo ValueType, expiry int64) error {
 			d.server.Rpush(key, value)
 		}
 		d.server.EndList(key)
+	case TypeListZiplist:
+		d.server.StartList(key, -1, expiry)
+		if err := d.readZiplist(key); err != nil {
+			return err
+		}
+		d.server.EndList(key)
+	case TypeListQuicklist:
+		length, _, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		d.server.StartList(key, -1, expiry)
+		for i := uint32(0); i < length; i++ {
+			if err := d.readZiplist(key); err != nil {
+				return err
+			}
+		}
+		d.server.EndList(key)
 	case TypeSet:
 		cardinality, _, err := d.readLength()
 		if err != nil {
@@ -262,6 +281,20 @@ func (d *Decoder) readObject(key []byte, typo ValueType, expiry int64) error {
 			d.server.Sadd(key, member)
 		}
 		d.server.EndSet(key)
+	case TypeSetIntset:
+		buf, err := d.readString()
+		if err != nil {
+			return err
+		}
+		is, err := raw_type.NewIntSetFromBytes(buf)
+		if err != nil {
+			return err
+		}
+		d.server.StartSet(key, int64(is.Len()), expiry)
+		for i := 0; i < is.Len(); i++ {
+			d.server.Sadd(key, []byte(strconv.FormatInt(is.Get(i), 10)))
+		}
+		d.server.EndSet(key)
 	case TypeHash:
 		length, _, err := d.readLength()
 		if err != nil {
@@ -281,6 +314,19 @@ func (d *Decoder) readObject(key []byte, typo ValueType, expiry int64) error {
 			d.server.Hset(key, field, value)
 		}
 		d.server.EndHash(key)
+	case TypeHashZiplist:
+		entries, err := d.readZiplistEntries()
+		if err != nil {
+			return err
+		}
+		if len(entries)%2 != 0 {
+			return errZiplistCorrupted
+		}
+		d.server.StartHash(key, int64(len(entries)/2), expiry)
+		for i := 0; i < len(entries); i += 2 {
+			d.server.Hset(key, entries[i], entries[i+1])
+		}
+		d.server.EndHash(key)
 	case TypeZSet:
 		cardinality, _, err := d.readLength()
 		if err != nil {
@@ -300,12 +346,50 @@ func (d *Decoder) readObject(key []byte, typo ValueType, expiry int64) error {
 			d.server.Zadd(key, score, member)
 		}
 		d.server.EndZSet(key)
+	case TypeZSetZiplist:
+		entries, err := d.readZiplistEntries()
+		if err != nil {
+			return err
+		}
+		if len(entries)%2 != 0 {
+			return errZiplistCorrupted
+		}
+		d.server.StartZSet(key, int64(len(entries)/2), expiry)
+		for i := 0; i < len(entries); i += 2 {
+			score, err := strconv.ParseFloat(string(entries[i+1]), 64)
+			if err != nil {
+				return errZiplistCorrupted
+			}
+			d.server.Zadd(key, score, entries[i])
+		}
+		d.server.EndZSet(key)
 	default:
 		return fmt.Errorf("rdb: unknown object type %d for key %s", typo, key)
 	}
 	return nil
 }
 
+// 读取一个ziplist, 把其中所有的元素添加到列表中
+func (d *Decoder) readZiplist(key []byte) error {
+	entries, err := d.readZiplistEntries()
+	if err != nil {
+		return err
+	}
+	for _, value := range entries {
+		d.server.Rpush(key, value)
+	}
+	return nil
+}
+
+// 读取一个ziplist中所有的entry
+func (d *Decoder) readZiplistEntries() ([][]byte, error) {
+	buf, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	return decodeZiplist(buf)
+}
+
 /*
 长度编码用于存储流中接下来对象的长度。长度编码是一个可变字节编码，为尽可能少用字节而设计:
 从流中读取一个字节，最高 2 bit 被读取。
@@ -333,8 +417,8 @@ func (d *Decoder) readLength() (uint32, bool, error) {
 		return (uint32(b&0x3f) << 8) | uint32(nextByte), false, nil
 	case rdb32bitLen:
 		// when the first two bits are 10, the next 6 bits are discarded.
-		// The next 4 bytes are the length
-		length, err := d.readUint32()
+		// The next 4 bytes are the length, stored in big endian
+		length, err := d.readUint32Big()
 		return length, false, err
 	case rdbEncodingVal:
 		// when the first two bits are 11, the next object is encoded.
@@ -367,6 +451,16 @@ func (d *Decoder) readUint32() (uint32, error) {
 	return binary.LittleEndian.Uint32(buf), nil
 }
 
+// 长度编码中的4个字节和EncodeLength一样是大端序, 编码成整数的字符串是小端序
+func (d *Decoder) readUint32Big() (uint32, error) {
+	buf := make([]byte, 4)
+	_, err := io.ReadFull(d.r, buf)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(buf), nil
+}
+
 func (d *Decoder) readUint64() (uint64, error) {
 	buf := make([]byte, 8)
 	_, err := io.ReadFull(d.r, buf)
@@ -12,7 +12,7 @@ import (
 	"github.com/cupcake/rdb/crc64"
 )
 
-const Version = 6
+const Version = 7
 
 type Encoder struct {
 	w   io.Writer
@@ -81,6 +81,43 @@ func (e *Encoder) EncodeString(s []byte) error {
 	return err
 }
 
+// 把entries编码成ziplist, 作为一个字符串写入
+func (e *Encoder) EncodeZiplist(entries []string) error {
+	return e.EncodeString(encodeZiplist(entries))
+}
+
+// quicklist的每个节点编码成一个ziplist
+func (e *Encoder) EncodeQuickList(nodes [][]string) error {
+	if err := e.EncodeLength(uint32(len(nodes))); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := e.EncodeZiplist(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// score的编码方式和readFloat64对应: 长度占1个字节, 253、254、255分别表示NaN、+inf、-inf
+func (e *Encoder) EncodeFloat(f float64) error {
+	switch {
+	case math.IsNaN(f):
+		_, err := e.w.Write([]byte{253})
+		return err
+	case math.IsInf(f, 1):
+		_, err := e.w.Write([]byte{254})
+		return err
+	case math.IsInf(f, -1):
+		_, err := e.w.Write([]byte{255})
+		return err
+	}
+	b := []byte(strconv.FormatFloat(f, 'g', 17, 64))
+	e.w.Write([]byte{byte(len(b))})
+	_, err := e.w.Write(b)
+	return err
+}
+
 func (e *Encoder) encodeIntString(b []byte) (written bool, err error) {
 	s := string(b)
 	i, err := strconv.ParseInt(s, 10, 32)
@@ -0,0 +1,185 @@
+package rdb
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SwanSpouse/redis_go/raw_type"
+)
+
+// 记录解析出来的所有对象
+type testDecoder struct {
+	lists  map[string][]string
+	hashes map[string]map[string]string
+	sets   map[string]map[string]bool
+	zsets  map[string]map[string]float64
+}
+
+func newTestDecoder() *testDecoder {
+	return &testDecoder{
+		lists:  make(map[string][]string),
+		hashes: make(map[string]map[string]string),
+		sets:   make(map[string]map[string]bool),
+		zsets:  make(map[string]map[string]float64),
+	}
+}
+
+func (d *testDecoder) StartRDB()                                 {}
+func (d *testDecoder) StartDatabase(n int)                       {}
+func (d *testDecoder) Aux(key, value []byte)                     {}
+func (d *testDecoder) ResizeDatabase(dbSize, expiresSize uint32) {}
+func (d *testDecoder) Set(key, value []byte, expiry int64)       {}
+func (d *testDecoder) StartHash(key []byte, length, expiry int64) {
+	d.hashes[string(key)] = make(map[string]string)
+}
+func (d *testDecoder) Hset(key, field, value []byte) {
+	d.hashes[string(key)][string(field)] = string(value)
+}
+func (d *testDecoder) EndHash(key []byte) {}
+func (d *testDecoder) StartSet(key []byte, cardinality, expiry int64) {
+	d.sets[string(key)] = make(map[string]bool)
+}
+func (d *testDecoder) Sadd(key, member []byte) { d.sets[string(key)][string(member)] = true }
+func (d *testDecoder) EndSet(key []byte)       {}
+func (d *testDecoder) StartList(key []byte, length, expiry int64) {
+	d.lists[string(key)] = make([]string, 0)
+}
+func (d *testDecoder) Rpush(key, value []byte) {
+	d.lists[string(key)] = append(d.lists[string(key)], string(value))
+}
+func (d *testDecoder) EndList(key []byte) {}
+func (d *testDecoder) StartZSet(key []byte, cardinality, expiry int64) {
+	d.zsets[string(key)] = make(map[string]float64)
+}
+func (d *testDecoder) Zadd(key []byte, score float64, member []byte) {
+	d.zsets[string(key)][string(member)] = score
+}
+func (d *testDecoder) EndZSet(key []byte) {}
+func (d *testDecoder) EndDatabase(n int)  {}
+func (d *testDecoder) EndRDB()            {}
+
+// 使用encode写入一个rdb文件之后再解析出来
+func roundTrip(t *testing.T, encode func(e *Encoder)) *testDecoder {
+	dir, err := ioutil.TempDir("", "redis-go-rdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "dump.rdb")
+
+	e, err := NewEncoder(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.EncodeHeader()
+	e.EncodeDatabase(0)
+	encode(e)
+	e.EncodeFooter()
+
+	ret := newTestDecoder()
+	d, err := NewDecoder(filename, ret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Decode(); err != nil {
+		t.Fatalf("decode rdb error %s", err)
+	}
+	return ret
+}
+
+// 覆盖ziplist中每一种整数编码以及超过16KB的字符串
+func testZiplistValues() []string {
+	return []string{"0", "12", "13", "-1", "127", "-128", "128", "32767", "-32768", "32768",
+		"8388607", "-8388608", "8388608", "2147483647", "-2147483648", "2147483648",
+		strconv.FormatInt(math.MaxInt64, 10), strconv.FormatInt(math.MinInt64, 10), "9223372036854775808",
+		"", "abc", strings.Repeat("a", 63), strings.Repeat("b", 16383), strings.Repeat("c", 16384), strings.Repeat("d", 17000)}
+}
+
+func TestQuickListRoundTrip(t *testing.T) {
+	values := testZiplistValues()
+	nodes := [][]string{values[:10], values[10:], {strings.Repeat("e", 20000)}}
+	ret := roundTrip(t, func(e *Encoder) {
+		e.EncodeType(TypeListQuicklist)
+		e.EncodeRawString("list")
+		e.EncodeQuickList(nodes)
+	})
+	expected := append(append([]string{}, values...), strings.Repeat("e", 20000))
+	if !reflect.DeepEqual(ret.lists["list"], expected) {
+		t.Fatalf("unexpected list after reload, got %d entries", len(ret.lists["list"]))
+	}
+}
+
+func TestHashZiplistRoundTrip(t *testing.T) {
+	values := testZiplistValues()
+	entries := make([]string, 0, len(values)*2)
+	expected := make(map[string]string)
+	for i, value := range values {
+		field := "field" + strconv.Itoa(i)
+		entries = append(entries, field, value)
+		expected[field] = value
+	}
+	// 超过16KB的field
+	entries = append(entries, strings.Repeat("f", 17000), "1")
+	expected[strings.Repeat("f", 17000)] = "1"
+	ret := roundTrip(t, func(e *Encoder) {
+		e.EncodeType(TypeHashZiplist)
+		e.EncodeRawString("hash")
+		e.EncodeZiplist(entries)
+	})
+	if !reflect.DeepEqual(ret.hashes["hash"], expected) {
+		t.Fatalf("unexpected hash after reload, got %d fields", len(ret.hashes["hash"]))
+	}
+}
+
+func TestZSetZiplistRoundTrip(t *testing.T) {
+	scores := []float64{0, 12, -1, 127, -128, 32767, -32768, 8388607, -8388608, 2147483647, -2147483648,
+		1 << 40, -(1 << 40), 1.5, -0.25, math.Inf(1), math.Inf(-1)}
+	entries := make([]string, 0, len(scores)*2)
+	expected := make(map[string]float64)
+	for i, score := range scores {
+		member := strings.Repeat(strconv.Itoa(i), 1+i*1000)
+		entries = append(entries, member, strconv.FormatFloat(score, 'g', -1, 64))
+		expected[member] = score
+	}
+	ret := roundTrip(t, func(e *Encoder) {
+		e.EncodeType(TypeZSetZiplist)
+		e.EncodeRawString("zset")
+		e.EncodeZiplist(entries)
+	})
+	if !reflect.DeepEqual(ret.zsets["zset"], expected) {
+		t.Fatalf("unexpected zset after reload, got %d members", len(ret.zsets["zset"]))
+	}
+}
+
+func TestIntSetRoundTrip(t *testing.T) {
+	// 每一种encoding都写入超过16KB的数据
+	for _, max := range []int64{math.MaxInt16, math.MaxInt32, math.MaxInt64} {
+		is := raw_type.NewIntSet()
+		expected := make(map[string]bool)
+		for i := int64(0); i < 9000; i++ {
+			value := max - i*3
+			if i%2 == 1 {
+				value = -value
+			}
+			is.Add(value)
+			expected[strconv.FormatInt(value, 10)] = true
+		}
+		if is.Bytes() <= 16*1024 {
+			t.Fatalf("intset should be larger than 16KB, got %d", is.Bytes())
+		}
+		ret := roundTrip(t, func(e *Encoder) {
+			e.EncodeType(TypeSetIntset)
+			e.EncodeRawString("set")
+			e.EncodeString(is.RawBytes())
+		})
+		if !reflect.DeepEqual(ret.sets["set"], expected) {
+			t.Fatalf("unexpected set with encoding %d after reload, got %d members", is.Encoding(), len(ret.sets["set"]))
+		}
+	}
+}
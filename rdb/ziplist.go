@@ -0,0 +1,186 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+)
+
+/**
+ZIPLIST:
+	<zlbytes uint32> <zltail uint32> <zllen uint16> <entry> ... <entry> <zlend 0xFF>
+	每个entry由 <prevlen> <encoding> <data> 组成:
+		prevlen: 前一个entry的长度, 小于254的时候使用1个字节, 否则使用0xFE加上4个字节;
+		encoding: 以00、01、10开头的时候是字符串, 分别使用6bit、14bit、32bit保存长度, 长度是大端序;
+		          以11开头的时候是整数, 整数使用小端序保存。
+	RDB中的TypeListQuicklist由多个ziplist组成, 每个quicklist节点对应一个ziplist。
+*/
+const (
+	ziplistHeaderSize = 10
+	ziplistEnd        = 0xff
+	ziplistBigPrevLen = 0xfe
+)
+
+var errZiplistCorrupted = errors.New("rdb: ziplist is corrupted")
+
+// 把entries编码成ziplist
+func encodeZiplist(entries []string) []byte {
+	buf := make([]byte, ziplistHeaderSize)
+	prevLen, tail := 0, ziplistHeaderSize
+	for _, entry := range entries {
+		tail = len(buf)
+		if prevLen < ziplistBigPrevLen {
+			buf = append(buf, byte(prevLen))
+		} else {
+			buf = append(buf, ziplistBigPrevLen, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(buf[len(buf)-4:], uint32(prevLen))
+		}
+		buf = appendZiplistValue(buf, entry)
+		prevLen = len(buf) - tail
+	}
+	buf = append(buf, ziplistEnd)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(tail))
+	if len(entries) < math.MaxUint16 {
+		binary.LittleEndian.PutUint16(buf[8:10], uint16(len(entries)))
+	} else {
+		binary.LittleEndian.PutUint16(buf[8:10], math.MaxUint16)
+	}
+	return buf
+}
+
+// 添加entry的encoding和data, 能够表示成整数的字符串使用整数编码
+func appendZiplistValue(buf []byte, entry string) []byte {
+	if i, err := strconv.ParseInt(entry, 10, 64); err == nil && strconv.FormatInt(i, 10) == entry {
+		switch {
+		case i >= 0 && i <= 12:
+			return append(buf, byte(0xf1+i))
+		case i >= math.MinInt8 && i <= math.MaxInt8:
+			return append(buf, rdbZiplistInt8, byte(int8(i)))
+		case i >= math.MinInt16 && i <= math.MaxInt16:
+			buf = append(buf, rdbZiplistInt16, 0, 0)
+			binary.LittleEndian.PutUint16(buf[len(buf)-2:], uint16(int16(i)))
+			return buf
+		case i >= -(1<<23) && i < 1<<23:
+			return append(buf, rdbZiplistInt24, byte(i), byte(i>>8), byte(i>>16))
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			buf = append(buf, rdbZiplistInt32, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(buf[len(buf)-4:], uint32(int32(i)))
+			return buf
+		default:
+			buf = append(buf, rdbZiplistInt64, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint64(buf[len(buf)-8:], uint64(i))
+			return buf
+		}
+	}
+	length := len(entry)
+	switch {
+	case length < 1<<6:
+		buf = append(buf, byte(length))
+	case length < 1<<14:
+		buf = append(buf, byte(length>>8)|rdbZiplist14bitlenString<<6, byte(length))
+	default:
+		buf = append(buf, rdbZiplist32bitlenString<<6, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(length))
+	}
+	return append(buf, entry...)
+}
+
+// 解析ziplist中所有的entry
+func decodeZiplist(buf []byte) ([][]byte, error) {
+	if len(buf) < ziplistHeaderSize+1 {
+		return nil, errZiplistCorrupted
+	}
+	entries := make([][]byte, 0)
+	p := ziplistHeaderSize
+	for {
+		if p >= len(buf) {
+			return nil, errZiplistCorrupted
+		}
+		if buf[p] == ziplistEnd {
+			return entries, nil
+		}
+		// 跳过prevlen
+		if buf[p] == ziplistBigPrevLen {
+			p += 5
+		} else {
+			p += 1
+		}
+		if p >= len(buf) {
+			return nil, errZiplistCorrupted
+		}
+		entry, next, err := decodeZiplistEntry(buf, p)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		p = next
+	}
+}
+
+// 解析从p开始的entry的encoding和data, 返回entry的值以及下一个entry的位置
+func decodeZiplistEntry(buf []byte, p int) ([]byte, int, error) {
+	b := buf[p]
+	var length, start int
+	switch b >> 6 {
+	case rdbZiplist6bitlenString:
+		length, start = int(b&0x3f), p+1
+	case rdbZiplist14bitlenString:
+		if p+2 > len(buf) {
+			return nil, 0, errZiplistCorrupted
+		}
+		length, start = int(b&0x3f)<<8|int(buf[p+1]), p+2
+	case rdbZiplist32bitlenString:
+		if p+5 > len(buf) {
+			return nil, 0, errZiplistCorrupted
+		}
+		length, start = int(binary.BigEndian.Uint32(buf[p+1:p+5])), p+5
+	default:
+		return decodeZiplistInt(buf, p)
+	}
+	if start+length > len(buf) {
+		return nil, 0, errZiplistCorrupted
+	}
+	return buf[start : start+length], start + length, nil
+}
+
+func decodeZiplistInt(buf []byte, p int) ([]byte, int, error) {
+	b := buf[p]
+	var size int
+	switch {
+	case b == rdbZiplistInt8:
+		size = 1
+	case b == rdbZiplistInt16:
+		size = 2
+	case b == rdbZiplistInt24:
+		size = 3
+	case b == rdbZiplistInt32:
+		size = 4
+	case b == rdbZiplistInt64:
+		size = 8
+	case b>>4 == rdbZiplistInt4 && b&0x0f >= 1 && b&0x0f <= 13:
+		// 1111xxxx, xxxx的取值范围是0001到1101, 表示0到12
+		return []byte(strconv.Itoa(int(b&0x0f) - 1)), p + 1, nil
+	default:
+		return nil, 0, errZiplistCorrupted
+	}
+	data := buf[p+1:]
+	if len(data) < size {
+		return nil, 0, errZiplistCorrupted
+	}
+	var i int64
+	switch size {
+	case 1:
+		i = int64(int8(data[0]))
+	case 2:
+		i = int64(int16(binary.LittleEndian.Uint16(data)))
+	case 3:
+		i = int64(int32(uint32(data[0])<<8|uint32(data[1])<<16|uint32(data[2])<<24) >> 8)
+	case 4:
+		i = int64(int32(binary.LittleEndian.Uint32(data)))
+	case 8:
+		i = int64(binary.LittleEndian.Uint64(data))
+	}
+	return []byte(strconv.FormatInt(i, 10)), p + 1 + size, nil
+}
@@ -3,7 +3,6 @@ package server
 import (
 	"fmt"
 	"io"
-	"strings"
 	"time"
 
 	"github.com/SwanSpouse/redis_go/aof"
@@ -20,13 +19,15 @@ func appendStrToByteArr(input []byte, args ...string) []byte {
 	return input
 }
 
-func catAppendOnlyGenericCommand(buf []byte, argc int, argv []string) []byte {
+func catAppendOnlyGenericCommand(buf []byte, argc int, argv [][]byte) []byte {
 	// 先处理参数个数
 	buf = appendStrToByteArr(buf, "*")
 	buf = appendStrToByteArr(buf, fmt.Sprintf("%d\r\n", argc))
 
 	for _, arg := range argv {
-		buf = appendStrToByteArr(buf, fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg))
+		buf = appendStrToByteArr(buf, fmt.Sprintf("$%d\r\n", len(arg)))
+		buf = append(buf, arg...)
+		buf = appendStrToByteArr(buf, "\r\n")
 	}
 	return buf
 }
@@ -34,35 +35,39 @@ func catAppendOnlyGenericCommand(buf []byte, argc int, argv []string) []byte {
 func (srv *Server) propagate(c *client.Client) {
 	loggers.Debug("propagate cmd to server aof buf")
 
-	outBuf := make([]byte, 0)
-	dbId := c.SelectedDatabase().GetID()
-	if dbId != srv.aofSelectDBId {
-		dbStr := fmt.Sprintf("%d", dbId)
-		outBuf = appendStrToByteArr(outBuf, fmt.Sprintf("*2\r\n$6\r\nSELECT\r\n$%d\r\n%d\r\n", len(dbStr), dbId))
-		srv.aofSelectDBId = c.SelectedDatabase().GetID()
-	}
-
 	if c.Cmd.GetName() == handlers.RedisKeyCommandPExpire {
 		// TODO lmj
 	} else if c.Cmd.GetName() == handlers.RedisStringCommandSetEX || c.Cmd.GetName() == handlers.RedisStringCommandPSetEx {
 		// TODO lmj
 	} else {
-		outBuf = catAppendOnlyGenericCommand(outBuf, c.Argc, c.Argv)
+		srv.feedAppendOnlyFile(c.SelectedDatabase().GetID(), c.Argc, c.Argv)
 	}
+}
 
-	// 将命令写入srv的 aof_buf，下次同步到aof文件的时候这些数据就会被刷新到文件中。
+// 将命令写入srv的 aof_buf，下次同步到aof文件的时候这些数据就会被刷新到文件中。
+func (srv *Server) feedAppendOnlyFile(dbId int, argc int, argv [][]byte) {
+	srv.aofLock.Lock()
+	defer srv.aofLock.Unlock()
+
+	outBuf := make([]byte, 0)
+	if dbId != srv.aofSelectDBId {
+		dbStr := fmt.Sprintf("%d", dbId)
+		outBuf = appendStrToByteArr(outBuf, fmt.Sprintf("*2\r\n$6\r\nSELECT\r\n$%d\r\n%d\r\n", len(dbStr), dbId))
+		srv.aofSelectDBId = dbId
+	}
+	outBuf = catAppendOnlyGenericCommand(outBuf, argc, argv)
 	srv.aofBuf = append(srv.aofBuf, outBuf...)
 	loggers.Debug("current aof debug:%s", string(srv.aofBuf))
 }
 
-// 把aofBuf中的数据刷写到文件中
-func (srv *Server) flushAppendOnlyFile() {
+// 把aofBuf中的数据刷写到文件中, force为true的时候忽略aof-fsync的配置，立即写入并fsync
+func (srv *Server) flushAppendOnlyFile(force bool) {
 	if len(srv.aofBuf) == 0 {
 		return
 	}
 	srv.aofLock.Lock()
 	defer srv.aofLock.Unlock()
-	if srv.Config.AofFSync == conf.RedisAofFSyncEverySec {
+	if srv.Config.AofFSync == conf.RedisAofFSyncEverySec && !force {
 		// TODO 这里有策略可以进行延迟写
 		loggers.Info("Hi~ You have a todo here. ")
 		return
@@ -74,11 +79,23 @@ func (srv *Server) flushAppendOnlyFile() {
 		loggers.Errorf("new aof encoder error:%+v", err)
 		return
 	}
-	if n, err := encoder.Write(srv.aofBuf); err != nil {
+	defer encoder.Close()
+
+	start := time.Now()
+	n, err := encoder.Write(srv.aofBuf)
+	srv.latencyAddSampleIfNeeded(LatencyEventAofWrite, time.Since(start))
+	if err != nil {
 		loggers.Errorf("flush aof data to file error:%+v", err)
 	} else if n != len(srv.aofBuf) {
 		loggers.Errorf("number:%d of written data is not equal with aof buf length:%d", n, len(srv.aofBuf))
 	} else {
+		if srv.Config.AofFSync == conf.RedisAofFSyncAlways || force {
+			start = time.Now()
+			if err := encoder.Sync(); err != nil {
+				loggers.Errorf("fsync aof file error:%+v", err)
+			}
+			srv.latencyAddSampleIfNeeded(LatencyEventAofFsyncAlways, time.Since(start))
+		}
 		srv.aofLastSave = time.Now()
 		srv.aofBuf = make([]byte, 0)
 		loggers.Debug("flush aof file end")
@@ -104,20 +121,21 @@ func (srv *Server) loadAppendOnlyFile() {
 			loggers.Errorf("load append only file error:%+v", err)
 			return
 		}
-		cmd, ok := srv.commandTable[strings.ToUpper(out.Argv[0])]
+		cmd, ok := srv.lookupCommand(out.Argv[0])
 		if !ok {
 			loggers.Errorf("Unknown command '%s' reading the append only file", out.Argv[0])
 			return
 		}
 		if (cmd.Arity > 0 && out.Argc != cmd.Arity) || (out.Argc < -cmd.Arity) {
-			loggers.Errorf("wrong number of args %+v", out.Argv[0])
+			loggers.Errorf("wrong number of args %s", out.Argv[0])
 			return
 		}
-		loggers.Debug("current cmd we receive in aof argv:%+v", out.Argv)
+		loggers.Debug("current cmd we receive in aof argv:%q", out.Argv)
 		srv.FakeClient.LastCmd = srv.FakeClient.Cmd
 		srv.FakeClient.Cmd = cmd
 		srv.FakeClient.Argc = out.Argc
 		srv.FakeClient.Argv = out.Argv
+		srv.feedMonitors(srv.FakeClient, cmd)
 		// process command
 		cmd.Proc(srv.FakeClient)
 		srv.FakeClient.Argc = 0
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/database"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/raw_type"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/**
+BLOCKING:
+	BLPOP、BRPOP、BRPOPLPUSH、BLMOVE、BLMPOP在所有的key都没有数据的时候阻塞客户端, 直到有数据或者超时。
+	每个客户端在自己的IOLoop中执行命令, 所以阻塞的命令直接在handler中等待被唤醒, 等待期间不计入inflightCommands。
+	阻塞的客户端按照(db, key)记录在blockingKeys中, 每个key对应一个按照阻塞的先后顺序排列的客户端链表。
+	LPUSH、RPUSH、LINSERT等写命令执行之后, 如果修改的key上有阻塞的客户端, 由执行写命令的客户端按照FIFO的顺序
+	替阻塞的客户端弹出元素并写入回复, 然后再唤醒阻塞的客户端, 这样元素不会在唤醒的过程中被其他客户端抢走。
+	检查key和阻塞客户端都在blockingLock中进行, 不会错过在两者之间写入的数据。
+	阻塞的超时在serverCron中检查, 精度和serverCron的执行间隔相同。
+	阻塞期间继续读取客户端的连接, 连接关闭之后马上解除阻塞, 不会再替已经断开的客户端弹出元素。
+	读取出错(例如输入缓冲区超过了限制)的时候同样解除阻塞, 不写入超时的回复, 由IOLoop处理读取的错误并关闭客户端。
+	阻塞等待的时间不计入命令的执行时间, 不会影响SLOWLOG、LATENCY以及commandstats。
+	AOF载入时的fake client不会阻塞, 没有数据的时候直接返回null。还没有实现MULTI/EXEC, 不需要处理事务中的阻塞命令。
+*/
+
+type blockingKey struct {
+	db  int
+	key string
+}
+
+// 尝试用key中的数据完成阻塞的命令, 成功的时候写入回复并返回true
+type blockedServeProc func(c *client.Client, key string) (bool, error)
+
+type blockedClient struct {
+	c        *client.Client
+	keys     []string
+	timeout  time.Time        // 超时的时间, 零值表示一直阻塞
+	serve    blockedServeProc // 有数据之后完成命令
+	timedOut bool             // 是否因为超时或者连接关闭被唤醒
+	closed   int32            // 连接已经关闭, 不能再替这个客户端执行命令
+	done     chan struct{}    // 被唤醒之后关闭
+}
+
+// BLPOP key [key ...] timeout
+func (srv *Server) BLPop(cli *client.Client) {
+	srv.blockingPopGeneric(cli, handlers.RedisListHead)
+}
+
+// BRPOP key [key ...] timeout
+func (srv *Server) BRPop(cli *client.Client) {
+	srv.blockingPopGeneric(cli, handlers.RedisListTail)
+}
+
+func (srv *Server) blockingPopGeneric(cli *client.Client, where int) {
+	timeout, ok := getBlockingTimeout(cli, string(cli.Argv[cli.Argc-1]))
+	if !ok {
+		return
+	}
+	serve := func(c *client.Client, key string) (bool, error) {
+		values, err := handlers.ListPop(c.SelectedDatabase(), key, where, 1)
+		if err != nil || len(values) == 0 {
+			return false, err
+		}
+		c.Response(tcp.ArrayReply{key, values[0]})
+		c.Dirty += 1
+		return true, nil
+	}
+	srv.blockForKeys(cli, util.BytesToStrings(cli.Argv[1:cli.Argc-1]), timeout, serve, tcp.NullArrayReply{})
+}
+
+// BRPOPLPUSH source destination timeout
+func (srv *Server) BRPopLPush(cli *client.Client) {
+	timeout, ok := getBlockingTimeout(cli, string(cli.Argv[3]))
+	if !ok {
+		return
+	}
+	srv.blockingMoveGeneric(cli, string(cli.Argv[1]), string(cli.Argv[2]), handlers.RedisListTail, handlers.RedisListHead, timeout)
+}
+
+// BLMOVE source destination LEFT|RIGHT LEFT|RIGHT timeout
+func (srv *Server) BLMove(cli *client.Client) {
+	from, fromOk := handlers.ParseListWhere(string(cli.Argv[3]))
+	to, toOk := handlers.ParseListWhere(string(cli.Argv[4]))
+	if !fromOk || !toOk {
+		cli.ResponseReError(re.ErrSyntaxError)
+		return
+	}
+	timeout, ok := getBlockingTimeout(cli, string(cli.Argv[5]))
+	if !ok {
+		return
+	}
+	srv.blockingMoveGeneric(cli, string(cli.Argv[1]), string(cli.Argv[2]), from, to, timeout)
+}
+
+func (srv *Server) blockingMoveGeneric(cli *client.Client, source, destination string, from, to int, timeout time.Duration) {
+	serve := func(c *client.Client, key string) (bool, error) {
+		value, ok, err := handlers.ListMove(c.SelectedDatabase(), key, destination, from, to)
+		if err != nil || !ok {
+			return false, err
+		}
+		c.Response(value)
+		c.Dirty += 1
+		return true, nil
+	}
+	srv.blockForKeys(cli, []string{source}, timeout, serve, nil)
+}
+
+// BLMPOP timeout numkeys key [key ...] LEFT|RIGHT [COUNT count]
+func (srv *Server) BLMPop(cli *client.Client) {
+	timeout, ok := getBlockingTimeout(cli, string(cli.Argv[1]))
+	if !ok {
+		return
+	}
+	keys, where, count, ok := handlers.ParseMPopArgs(cli, 2)
+	if !ok {
+		return
+	}
+	serve := func(c *client.Client, key string) (bool, error) {
+		values, err := handlers.ListPop(c.SelectedDatabase(), key, where, count)
+		if err != nil || len(values) == 0 {
+			return false, err
+		}
+		c.Response(tcp.ArrayReply{key, tcp.NewStringArrayReply(values)})
+		c.Dirty += 1
+		return true, nil
+	}
+	srv.blockForKeys(cli, keys, timeout, serve, tcp.NullArrayReply{})
+}
+
+// 解析阻塞命令的timeout参数, 单位是秒, 可以是小数, 0表示一直阻塞
+func getBlockingTimeout(cli *client.Client, arg string) (time.Duration, bool) {
+	timeout, err := strconv.ParseFloat(arg, 64)
+	if err != nil || math.IsNaN(timeout) || math.IsInf(timeout, 0) {
+		cli.ResponseReError(re.ErrTimeoutNotFloat)
+		return 0, false
+	}
+	if timeout < 0 {
+		cli.ResponseReError(re.ErrTimeoutNegative)
+		return 0, false
+	}
+	return time.Duration(timeout * float64(time.Second)), true
+}
+
+/**
+按照顺序尝试每个key, 都没有数据的时候阻塞客户端直到被唤醒或者超时, 超时之后回复timeoutReply。
+key不是列表的时候直接回复WRONGTYPE。
+*/
+func (srv *Server) blockForKeys(cli *client.Client, keys []string, timeout time.Duration, serve blockedServeProc, timeoutReply interface{}) {
+	srv.blockingLock.Lock()
+	for _, key := range keys {
+		served, err := serve(cli, key)
+		if err != nil || served {
+			srv.blockingLock.Unlock()
+			if err != nil {
+				cli.ResponseReError(err)
+			}
+			return
+		}
+	}
+	if cli.IsFakeClient() {
+		srv.blockingLock.Unlock()
+		cli.Response(timeoutReply)
+		return
+	}
+
+	bc := &blockedClient{c: cli, serve: serve, done: make(chan struct{})}
+	if timeout > 0 {
+		bc.timeout = time.Now().Add(timeout)
+	}
+	dbID := cli.SelectedDatabase().GetID()
+	for _, key := range keys {
+		bk := blockingKey{db: dbID, key: key}
+		clients, ok := srv.blockingKeys[bk]
+		if !ok {
+			clients = raw_type.ListCreate()
+			srv.blockingKeys[bk] = clients
+		}
+		// 同一个key出现多次的时候只记录一次
+		if clients.ListSearchKey(bc) == nil {
+			clients.ListAddNodeTail(bc)
+			bc.keys = append(bc.keys, key)
+		}
+	}
+	srv.blockedClients[cli.ID()] = bc
+	cli.Flags |= client.RedisClientBlocked
+	srv.blockingLock.Unlock()
+
+	// 阻塞期间不计入正在执行的命令, 不会影响SHUTDOWN
+	srv.endCommand()
+	blockStart := time.Now()
+	stopWatch := cli.WatchConnWhileBlocked(func() {
+		atomic.StoreInt32(&bc.closed, 1)
+		srv.unblockClient(cli)
+	})
+	<-bc.done
+	stopWatch()
+	cli.BlockedTime += time.Since(blockStart)
+	atomic.AddInt64(&srv.inflightCommands, 1)
+	// 连接已经关闭或者读取出错的客户端即将被释放, 不需要回复
+	if bc.timedOut && atomic.LoadInt32(&bc.closed) == 0 {
+		cli.Response(timeoutReply)
+	}
+}
+
+// 写命令修改了key之后, 按照阻塞的先后顺序唤醒阻塞在这些key上的客户端
+func (srv *Server) handleClientsBlockedOnKeys(db *database.Database, keys []string) {
+	srv.blockingLock.Lock()
+	defer srv.blockingLock.Unlock()
+	if len(srv.blockedClients) == 0 {
+		return
+	}
+	for _, key := range keys {
+		bk := blockingKey{db: db.GetID(), key: key}
+		for {
+			clients, ok := srv.blockingKeys[bk]
+			if !ok {
+				break
+			}
+			// key被删除或者不是列表的时候客户端继续阻塞
+			if n, err := handlers.ListLen(db, key); err != nil || n == 0 {
+				break
+			}
+			bc := clients.ListFirst().NodeValue().(*blockedClient)
+			if atomic.LoadInt32(&bc.closed) != 0 {
+				// 连接已经关闭, 元素留给后面的客户端
+				srv.unblockClientLocked(bc, true)
+				continue
+			}
+			served, err := bc.serve(bc.c, key)
+			if err != nil {
+				// 例如BLMOVE的destination不是列表
+				bc.c.ResponseReError(err)
+			} else if !served {
+				break
+			} else {
+				// 替阻塞的客户端执行命令同样修改了source和destination, 马上重新估算它们的内存
+				srv.updateKeysMemory(bc.c)
+			}
+			srv.unblockClientLocked(bc, false)
+		}
+	}
+}
+
+// 超时或者连接被关闭的时候唤醒阻塞的客户端
+func (srv *Server) unblockClient(c *client.Client) bool {
+	srv.blockingLock.Lock()
+	defer srv.blockingLock.Unlock()
+	bc, ok := srv.blockedClients[c.ID()]
+	if !ok {
+		return false
+	}
+	srv.unblockClientLocked(bc, true)
+	return true
+}
+
+func (srv *Server) unblockClientLocked(bc *blockedClient, timedOut bool) {
+	dbID := bc.c.SelectedDatabase().GetID()
+	for _, key := range bc.keys {
+		bk := blockingKey{db: dbID, key: key}
+		if clients, ok := srv.blockingKeys[bk]; ok {
+			clients.ListRemoveNode(clients.ListSearchKey(bc))
+			if clients.ListLength() == 0 {
+				delete(srv.blockingKeys, bk)
+			}
+		}
+	}
+	delete(srv.blockedClients, bc.c.ID())
+	bc.c.Flags &^= client.RedisClientBlocked
+	bc.timedOut = timedOut
+	close(bc.done)
+}
+
+// 在serverCron中唤醒阻塞超时的客户端
+func (srv *Server) blockedClientsCron() {
+	now := time.Now()
+	srv.blockingLock.Lock()
+	defer srv.blockingLock.Unlock()
+	for _, bc := range srv.blockedClients {
+		if !bc.timeout.IsZero() && now.After(bc.timeout) {
+			srv.unblockClientLocked(bc, true)
+		}
+	}
+}
+
+// 阻塞在列表上的客户端的个数
+func (srv *Server) blockedClientsCount() int {
+	srv.blockingLock.Lock()
+	defer srv.blockingLock.Unlock()
+	return len(srv.blockedClients)
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/database"
+	"github.com/SwanSpouse/redis_go/handlers"
+)
+
+// 等待阻塞的客户端个数变成n
+func waitBlockedClients(t *testing.T, srv *Server, n int) {
+	for i := 0; i < 500 && srv.blockedClientsCount() != n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := srv.blockedClientsCount(); count != n {
+		t.Fatalf("expect %d blocked clients, got %d", n, count)
+	}
+}
+
+func execTestCommand(srv *Server, c *client.Client, args ...string) {
+	c.Argv, c.Argc = newTestArgv(args...), len(args)
+	c.Cmd = srv.commandTable[args[0]]
+	c.Cmd.Proc(c)
+}
+
+// LPUSH之后和IOLoop一样唤醒阻塞在key上的客户端
+func pushAndServeBlocked(srv *Server, db *database.Database, key string, values ...string) {
+	pusher, peer := newTestClient(100)
+	defer peer.Close()
+	pusher.SetDatabase(db)
+	execTestCommand(srv, pusher, append([]string{handlers.RedisListCommandLPush, key}, values...)...)
+	srv.handleClientsBlockedOnKeys(pusher.SelectedDatabase(), []string{key})
+}
+
+func TestBlockedClientDisconnected(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	c, peer := newTestClient(1)
+	done := make(chan struct{})
+	go func() {
+		execTestCommand(srv, c, handlers.RedisListCommandBLPop, "blocked-list", "0")
+		close(done)
+	}()
+	waitBlockedClients(t, srv, 1)
+
+	// 阻塞期间断开连接, 客户端马上被唤醒
+	peer.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client should be unblocked after the connection is closed")
+	}
+	waitBlockedClients(t, srv, 0)
+
+	// 之后写入的元素不能被已经断开的客户端弹出
+	pushAndServeBlocked(srv, c.SelectedDatabase(), "blocked-list", "a")
+	if n, _ := handlers.ListLen(c.SelectedDatabase(), "blocked-list"); n != 1 {
+		t.Fatalf("element should not be popped for a disconnected client, list length %d", n)
+	}
+}
+
+func TestBlockedClientKeepsPipelinedCommands(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	go io.Copy(io.Discard, peer)
+	done := make(chan struct{})
+	go func() {
+		execTestCommand(srv, c, handlers.RedisListCommandBLPop, "pipeline-list", "0")
+		close(done)
+	}()
+	waitBlockedClients(t, srv, 1)
+
+	// 阻塞期间收到的命令保留在输入缓冲区中
+	if _, err := peer.Write([]byte("*1\r\n$4\r\nPING\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	pushAndServeBlocked(srv, c.SelectedDatabase(), "pipeline-list", "a")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client should be served after LPUSH")
+	}
+	if n, _ := handlers.ListLen(c.SelectedDatabase(), "pipeline-list"); n != 0 {
+		t.Fatalf("element should be popped for the blocked client, list length %d", n)
+	}
+	if err := c.ProcessInputBuffer(); err != nil || c.Argc != 1 || string(c.Argv[0]) != "PING" {
+		t.Fatalf("pipelined command should be kept, got %q %v", c.Argv, err)
+	}
+}
+
+func TestBlockedClientQueryBufferLimit(t *testing.T) {
+	config := conf.NewServerConfig()
+	config.ClientMaxQueryBufLen = 64 * 1024
+	srv := NewServer(config)
+	cn, peer := net.Pipe()
+	defer peer.Close()
+	go srv.IOLoop(cn)
+	peer.Write([]byte("*3\r\n$5\r\nBLPOP\r\n$10\r\nlimit-list\r\n$1\r\n0\r\n"))
+	waitBlockedClients(t, srv, 1)
+
+	// 阻塞期间输入缓冲区超过限制, 客户端被关闭, 不会收到超时的回复
+	go peer.Write(bytes.Repeat([]byte("a"), 1024*1024))
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if reply, err := ioutil.ReadAll(peer); err != nil || len(reply) != 0 {
+		t.Fatalf("client should be closed without reply, got %q %v", reply, err)
+	}
+	if n := atomic.LoadInt64(&srv.statQbufLimitDisconns); n != 1 {
+		t.Fatalf("expect 1 query buffer limit disconnection, got %d", n)
+	}
+	waitBlockedClients(t, srv, 0)
+	waitClientsRemoved(t, srv)
+}
+
+func TestBlockedTimeNotRecorded(t *testing.T) {
+	config := conf.NewServerConfig()
+	config.SlowLogLogSlowerThan = 50 * 1000
+	srv := NewServer(config)
+
+	blocked, blockedPeer := net.Pipe()
+	defer blockedPeer.Close()
+	go srv.IOLoop(blocked)
+	blockedPeer.Write([]byte("*3\r\n$5\r\nBLPOP\r\n$10\r\ntimed-list\r\n$1\r\n0\r\n"))
+	waitBlockedClients(t, srv, 1)
+	time.Sleep(200 * time.Millisecond)
+
+	pusher, pusherPeer := net.Pipe()
+	defer pusherPeer.Close()
+	go srv.IOLoop(pusher)
+	pusherPeer.Write([]byte("*3\r\n$5\r\nLPUSH\r\n$10\r\ntimed-list\r\n$1\r\na\r\n"))
+	if line, _ := bufio.NewReader(pusherPeer).ReadString('\n'); line != ":1\r\n" {
+		t.Fatalf("unexpected LPUSH reply %q", line)
+	}
+	if line, _ := bufio.NewReader(blockedPeer).ReadString('\n'); line != "*2\r\n" {
+		t.Fatalf("unexpected BLPOP reply %q", line)
+	}
+
+	// 阻塞等待的200ms不计入命令的执行时间
+	cmd := srv.commandTable[handlers.RedisListCommandBLPop]
+	if cmd.GetCalls() != 1 || cmd.GetMicrosecond() >= 50*1000 {
+		t.Fatalf("blocked time should not be recorded, calls %d usec %d", cmd.GetCalls(), cmd.GetMicrosecond())
+	}
+	srv.slowLogLock.Lock()
+	length := srv.slowLog.length
+	srv.slowLogLock.Unlock()
+	if length != 0 {
+		t.Fatalf("blocked command should not be logged in slowlog, got %d entries", length)
+	}
+	blockedPeer.Close()
+	pusherPeer.Close()
+	waitClientsRemoved(t, srv)
+}
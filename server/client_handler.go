@@ -0,0 +1,501 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/**
+CLIENT:
+	客户端都保存在Server.clients中，所以CLIENT命令由Server来处理。
+	CLIENT LIST、CLIENT INFO的输出格式和redis保持一致:
+		id=3 addr=127.0.0.1:59954 laddr=127.0.0.1:6379 fd=8 name= age=0 idle=0 flags=N db=0 sub=0 psub=0 multi=-1
+		qbuf=26 qbuf-free=40928 argv-mem=10 obl=0 oll=0 omem=0 tot-mem=61466 events=r cmd=client user=default redir=-1 resp=2
+*/
+const (
+	RedisClientSubCommandID      = "ID"
+	RedisClientSubCommandInfo    = "INFO"
+	RedisClientSubCommandGetName = "GETNAME"
+	RedisClientSubCommandSetName = "SETNAME"
+	RedisClientSubCommandList    = "LIST"
+	RedisClientSubCommandKill    = "KILL"
+	RedisClientSubCommandReply   = "REPLY"
+	RedisClientSubCommandPause   = "PAUSE"
+	RedisClientSubCommandUnpause = "UNPAUSE"
+	RedisClientSubCommandNoEvict = "NO-EVICT"
+
+	RedisClientTypeNormal  = "normal"
+	RedisClientTypeSlave   = "slave"
+	RedisClientTypeReplica = "replica"
+	RedisClientTypeMaster  = "master"
+	RedisClientTypePubSub  = "pubsub"
+
+	RedisClientPauseOff   = 0 /* Pause no commands */
+	RedisClientPauseWrite = 1 /* Pause write commands */
+	RedisClientPauseAll   = 2 /* Pause all commands */
+
+	RedisClientDefaultUser = "default"
+)
+
+// CLIENT subcommand [arguments]
+func (srv *Server) ClientCommand(cli *client.Client) {
+	switch strings.ToUpper(string(cli.Argv[1])) {
+	case RedisClientSubCommandID:
+		srv.clientID(cli)
+	case RedisClientSubCommandInfo:
+		srv.clientInfo(cli)
+	case RedisClientSubCommandGetName:
+		srv.clientGetName(cli)
+	case RedisClientSubCommandSetName:
+		srv.clientSetName(cli)
+	case RedisClientSubCommandList:
+		srv.clientList(cli)
+	case RedisClientSubCommandKill:
+		srv.clientKill(cli)
+	case RedisClientSubCommandReply:
+		srv.clientReply(cli)
+	case RedisClientSubCommandPause:
+		srv.clientPause(cli)
+	case RedisClientSubCommandUnpause:
+		srv.clientUnpause(cli)
+	case RedisClientSubCommandNoEvict:
+		srv.clientNoEvict(cli)
+	case RedisClientSubCommandTracking:
+		srv.clientTracking(cli)
+	case RedisClientSubCommandTrackingInfo:
+		srv.clientTrackingInfo(cli)
+	case RedisClientSubCommandCaching:
+		srv.clientCaching(cli)
+	case RedisClientSubCommandGetRedir:
+		srv.clientGetRedir(cli)
+	default:
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+	}
+}
+
+// CLIENT ID
+func (srv *Server) clientID(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	cli.Response(cli.ID())
+}
+
+// CLIENT INFO
+func (srv *Server) clientInfo(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	cli.Response(tcp.VerbatimReply{Format: "txt", Text: catClientInfoString(cli, time.Now()) + "\n"})
+}
+
+// CLIENT GETNAME
+func (srv *Server) clientGetName(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	if cli.Name == "" {
+		cli.Response(nil)
+		return
+	}
+	cli.Response(cli.Name)
+}
+
+// CLIENT SETNAME connection-name
+func (srv *Server) clientSetName(cli *client.Client) {
+	if cli.Argc != 3 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	if !isValidClientName(string(cli.Argv[2])) {
+		cli.ResponseReError(re.ErrClientNameInvalid)
+		return
+	}
+	cli.Name = string(cli.Argv[2])
+	cli.ResponseOK()
+}
+
+// 名字中不能包含空格以及不可见字符, 否则CLIENT LIST的输出没办法解析
+func isValidClientName(name string) bool {
+	for _, ch := range []byte(name) {
+		if ch < '!' || ch > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// CLIENT LIST [TYPE normal|master|replica|pubsub] [ID client-id [client-id ...]]
+func (srv *Server) clientList(cli *client.Client) {
+	var clientType string
+	var ids map[int64]bool
+	if cli.Argc == 4 && strings.ToUpper(string(cli.Argv[2])) == "TYPE" {
+		clientType = strings.ToLower(string(cli.Argv[3]))
+		if !isValidClientType(clientType) {
+			cli.ResponseReError(re.ErrClientUnknownType, string(cli.Argv[3]))
+			return
+		}
+	} else if cli.Argc > 3 && strings.ToUpper(string(cli.Argv[2])) == "ID" {
+		ids = make(map[int64]bool)
+		for _, arg := range util.BytesToStrings(cli.Argv[3:]) {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || id <= 0 {
+				cli.ResponseReError(re.ErrClientInvalidID)
+				return
+			}
+			ids[id] = true
+		}
+	} else if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrSyntaxError)
+		return
+	}
+
+	now := time.Now()
+	var builder strings.Builder
+	for _, c := range srv.getSortedClients() {
+		if clientType != "" && getClientType(c) != normalizeClientType(clientType) {
+			continue
+		}
+		if ids != nil && !ids[c.ID()] {
+			continue
+		}
+		builder.WriteString(catClientInfoString(c, now))
+		builder.WriteByte('\n')
+	}
+	cli.Response(tcp.VerbatimReply{Format: "txt", Text: builder.String()})
+}
+
+/**
+CLIENT KILL ip:port
+CLIENT KILL [ID client-id] [TYPE normal|master|replica|pubsub] [USER username] [ADDR ip:port] [LADDR ip:port] [SKIPME yes/no]
+	旧的格式返回OK或者错误, 新的格式返回被关闭的客户端的个数。
+*/
+func (srv *Server) clientKill(cli *client.Client) {
+	var (
+		addr, laddr, clientType string
+		id                      int64
+		skipMe                  = true
+		newStyle                = cli.Argc != 3
+	)
+	if newStyle {
+		if cli.Argc < 3 || cli.Argc%2 != 0 {
+			cli.ResponseReError(re.ErrSyntaxError)
+			return
+		}
+		for i := 2; i < cli.Argc; i += 2 {
+			value := string(cli.Argv[i+1])
+			switch strings.ToUpper(string(cli.Argv[i])) {
+			case "ID":
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || n <= 0 {
+					cli.ResponseReError(re.ErrClientInvalidID)
+					return
+				}
+				id = n
+			case "TYPE":
+				clientType = strings.ToLower(value)
+				if !isValidClientType(clientType) {
+					cli.ResponseReError(re.ErrClientUnknownType, value)
+					return
+				}
+			case "USER":
+				// 没有ACL, 所有的客户端都是default用户
+				if value != RedisClientDefaultUser {
+					cli.ResponseReError(re.ErrClientNoSuchUser, value)
+					return
+				}
+			case "ADDR":
+				addr = value
+			case "LADDR":
+				laddr = value
+			case "SKIPME":
+				switch strings.ToLower(value) {
+				case "yes":
+					skipMe = true
+				case "no":
+					skipMe = false
+				default:
+					cli.ResponseReError(re.ErrSyntaxError)
+					return
+				}
+			default:
+				cli.ResponseReError(re.ErrSyntaxError)
+				return
+			}
+		}
+	} else {
+		addr = string(cli.Argv[2])
+		skipMe = false
+	}
+
+	killed := int64(0)
+	for _, c := range srv.getSortedClients() {
+		if addr != "" && c.RemoteAddr().String() != addr {
+			continue
+		}
+		if laddr != "" && c.LocalAddr().String() != laddr {
+			continue
+		}
+		if id != 0 && c.ID() != id {
+			continue
+		}
+		if clientType != "" && getClientType(c) != normalizeClientType(clientType) {
+			continue
+		}
+		if c == cli && skipMe {
+			continue
+		}
+		// 关闭自己的时候要先把回复发送出去
+		if c == cli {
+			cli.Flags |= client.RedisClientCloseAfterReply
+		} else {
+			c.CloseConn()
+			// 阻塞的客户端需要被唤醒之后才能发现连接已经关闭
+			srv.unblockClient(c)
+		}
+		killed++
+	}
+
+	if newStyle {
+		cli.Response(killed)
+	} else if killed == 0 {
+		cli.ResponseReError(re.ErrClientNoSuchClient)
+	} else {
+		cli.ResponseOK()
+	}
+}
+
+// CLIENT REPLY ON|OFF|SKIP
+func (srv *Server) clientReply(cli *client.Client) {
+	if cli.Argc != 3 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	switch strings.ToUpper(string(cli.Argv[2])) {
+	case "ON":
+		cli.Flags &^= client.RedisClientReplySkip | client.RedisClientReplyOff
+		cli.ResponseOK()
+	case "OFF":
+		cli.Flags |= client.RedisClientReplyOff
+	case "SKIP":
+		if cli.Flags&client.RedisClientReplyOff == 0 {
+			cli.Flags |= client.RedisClientReplySkipNext
+		}
+	default:
+		cli.ResponseReError(re.ErrSyntaxError)
+	}
+}
+
+// CLIENT PAUSE timeout [WRITE|ALL]
+func (srv *Server) clientPause(cli *client.Client) {
+	if cli.Argc != 3 && cli.Argc != 4 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	timeout, err := strconv.ParseInt(string(cli.Argv[2]), 10, 64)
+	if err != nil {
+		cli.ResponseReError(re.ErrClientPauseTimeout)
+		return
+	}
+	if timeout < 0 {
+		cli.ResponseReError(re.ErrClientPauseNegative)
+		return
+	}
+	var pauseType int32 = RedisClientPauseAll
+	if cli.Argc == 4 {
+		switch strings.ToUpper(string(cli.Argv[3])) {
+		case "WRITE":
+			pauseType = RedisClientPauseWrite
+		case "ALL":
+			pauseType = RedisClientPauseAll
+		default:
+			cli.ResponseReError(re.ErrClientPauseType)
+			return
+		}
+	}
+	srv.pauseClients(time.Now().Add(time.Duration(timeout)*time.Millisecond), pauseType)
+	cli.ResponseOK()
+}
+
+// CLIENT UNPAUSE
+func (srv *Server) clientUnpause(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	srv.unpauseClients()
+	cli.ResponseOK()
+}
+
+// CLIENT NO-EVICT ON|OFF
+func (srv *Server) clientNoEvict(cli *client.Client) {
+	if cli.Argc != 3 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	switch strings.ToUpper(string(cli.Argv[2])) {
+	case "ON":
+		cli.Flags |= client.RedisClientNoEvict
+	case "OFF":
+		cli.Flags &^= client.RedisClientNoEvict
+	default:
+		cli.ResponseReError(re.ErrSyntaxError)
+		return
+	}
+	cli.ResponseOK()
+}
+
+// 暂停执行客户端的命令, 多次暂停的时候取最长的时间以及最严格的类型
+func (srv *Server) pauseClients(end time.Time, pauseType int32) {
+	srv.clientPauseLock.Lock()
+	defer srv.clientPauseLock.Unlock()
+	if !srv.clientsArePaused() {
+		atomic.StoreInt32(&srv.clientPauseType, RedisClientPauseOff)
+		atomic.StoreInt64(&srv.clientPauseEnd, 0)
+	}
+	if pauseType > atomic.LoadInt32(&srv.clientPauseType) {
+		atomic.StoreInt32(&srv.clientPauseType, pauseType)
+	}
+	if end.UnixNano() > atomic.LoadInt64(&srv.clientPauseEnd) {
+		atomic.StoreInt64(&srv.clientPauseEnd, end.UnixNano())
+	}
+}
+
+func (srv *Server) unpauseClients() {
+	srv.clientPauseLock.Lock()
+	defer srv.clientPauseLock.Unlock()
+	atomic.StoreInt32(&srv.clientPauseType, RedisClientPauseOff)
+	atomic.StoreInt64(&srv.clientPauseEnd, 0)
+}
+
+// 是否有CLIENT PAUSE正在生效
+func (srv *Server) clientsArePaused() bool {
+	return atomic.LoadInt32(&srv.clientPauseType) != RedisClientPauseOff &&
+		time.Now().UnixNano() < atomic.LoadInt64(&srv.clientPauseEnd)
+}
+
+// CLIENT PAUSE的时候是否需要暂停客户端的命令, replica和master的命令以及CLIENT UNPAUSE不会被暂停
+func (srv *Server) clientPausesCommand(c *client.Client, cmd *client.Command) bool {
+	if !srv.clientsArePaused() || c.Flags&(client.RedisClientSlave|client.RedisClientMaster) != 0 {
+		return false
+	}
+	if cmd.GetName() == RedisServerCommandClient && c.Argc > 1 && strings.ToUpper(string(c.Argv[1])) == RedisClientSubCommandUnpause {
+		return false
+	}
+	if atomic.LoadInt32(&srv.clientPauseType) == RedisClientPauseAll {
+		return true
+	}
+	return cmd.Flags&(client.RedisCmdWrite|client.RedisCmdForceReplication) != 0
+}
+
+// 按照ID排序的所有客户端
+func (srv *Server) getSortedClients() []*client.Client {
+	srv.mu.RLock()
+	clients := make([]*client.Client, 0, len(srv.clients))
+	for _, c := range srv.clients {
+		clients = append(clients, c)
+	}
+	srv.mu.RUnlock()
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ID() < clients[j].ID()
+	})
+	return clients
+}
+
+func isValidClientType(clientType string) bool {
+	switch clientType {
+	case RedisClientTypeNormal, RedisClientTypeSlave, RedisClientTypeReplica, RedisClientTypeMaster, RedisClientTypePubSub:
+		return true
+	}
+	return false
+}
+
+// replica和slave是同一种类型
+func normalizeClientType(clientType string) string {
+	if clientType == RedisClientTypeReplica {
+		return RedisClientTypeSlave
+	}
+	return clientType
+}
+
+func getClientType(c *client.Client) string {
+	switch {
+	case c.Flags&client.RedisClientMaster != 0:
+		return RedisClientTypeMaster
+	case c.Flags&client.RedisClientSlave != 0 && c.Flags&client.RedisClientMonitor == 0:
+		return RedisClientTypeSlave
+	case c.PubSubChannels.Size()+c.PubSubPatterns.ListLength() > 0:
+		return RedisClientTypePubSub
+	}
+	return RedisClientTypeNormal
+}
+
+// 生成CLIENT LIST中一个客户端的信息
+func catClientInfoString(c *client.Client, now time.Time) string {
+	flags := ""
+	if c.Flags&client.RedisClientMonitor != 0 {
+		flags += "O"
+	} else if c.Flags&client.RedisClientSlave != 0 {
+		flags += "S"
+	}
+	if c.Flags&client.RedisClientMaster != 0 {
+		flags += "M"
+	}
+	if c.PubSubChannels.Size()+c.PubSubPatterns.ListLength() > 0 {
+		flags += "P"
+	}
+	if c.Flags&client.RedisClientCloseAfterReply != 0 {
+		flags += "A"
+	}
+	if c.Flags&client.RedisClientBlocked != 0 {
+		flags += "b"
+	}
+	if c.Flags&client.RedisClientNoEvict != 0 {
+		flags += "e"
+	}
+	if c.Flags&client.RedisClientTracking != 0 {
+		flags += "t"
+	}
+	if c.IsTrackingBrokenRedir() {
+		flags += "R"
+	}
+	if c.Flags&client.RedisClientTrackingBcast != 0 {
+		flags += "B"
+	}
+	if flags == "" {
+		flags = "N"
+	}
+
+	cmd := "NULL"
+	if c.Cmd != nil {
+		cmd = strings.ToLower(c.Cmd.GetName())
+	}
+	argvMem := 0
+	for _, arg := range c.Argv {
+		argvMem += len(arg)
+	}
+	qbuf, qbufFree, omem := c.Buffered(), c.QueryBufFree(), c.OutputBuffered()
+	redir := int64(-1)
+	if c.Flags&client.RedisClientTracking != 0 {
+		redir = c.TrackRedirect
+	}
+
+	return fmt.Sprintf("id=%d addr=%s laddr=%s fd=%d name=%s age=%d idle=%d flags=%s db=%d sub=%d psub=%d multi=-1 "+
+		"qbuf=%d qbuf-free=%d argv-mem=%d obl=0 oll=0 omem=%d tot-mem=%d events=r cmd=%s user=%s redir=%d resp=%d",
+		c.ID(), c.RemoteAddr(), c.LocalAddr(), c.FD(), c.Name,
+		int64(now.Sub(c.CreateTime())/time.Second), int64(now.Sub(c.LastInteraction())/time.Second),
+		flags, c.SelectedDatabase().GetID(), c.PubSubChannels.Size(), c.PubSubPatterns.ListLength(),
+		qbuf, qbufFree, argvMem, omem, qbuf+qbufFree+argvMem+omem, cmd, RedisClientDefaultUser, redir, c.Protocol())
+}
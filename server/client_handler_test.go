@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/database"
+)
+
+func newTestClient(id int64) (*client.Client, net.Conn) {
+	cn, peer := net.Pipe()
+	return client.NewClient(id, cn, database.NewDatabase(0)), peer
+}
+
+func TestCatClientInfoString(t *testing.T) {
+	c, peer := newTestClient(7)
+	defer peer.Close()
+	c.Name = "worker"
+	c.Flags |= client.RedisClientNoEvict
+	c.Cmd = client.NewCommand(RedisServerCommandClient, -2, "ar", nil)
+
+	info := catClientInfoString(c, c.CreateTime().Add(3*time.Second))
+	for _, field := range []string{"id=7 ", "name=worker ", "age=3 ", "idle=3 ", "flags=e ", "db=0 ", "cmd=client ", "user=default "} {
+		if !strings.Contains(info, field) {
+			t.Fatalf("client info %s should contain %s", info, field)
+		}
+	}
+	if getClientType(c) != RedisClientTypeNormal {
+		t.Fatalf("expect normal client, got %s", getClientType(c))
+	}
+	c.Flags |= client.RedisClientSlave
+	if getClientType(c) != RedisClientTypeSlave || normalizeClientType(RedisClientTypeReplica) != RedisClientTypeSlave {
+		t.Fatalf("expect slave client, got %s", getClientType(c))
+	}
+	if isValidClientType("unknown") {
+		t.Fatalf("unknown should not be a valid client type")
+	}
+}
+
+func TestClientPausesCommand(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	set := client.NewCommand("SET", -3, "wm", nil)
+	get := client.NewCommand("GET", 2, "r", nil)
+	set.Flags = client.RedisCmdWrite
+
+	if srv.clientPausesCommand(c, set) {
+		t.Fatalf("commands should not be paused without CLIENT PAUSE")
+	}
+	srv.pauseClients(time.Now().Add(time.Minute), RedisClientPauseWrite)
+	if !srv.clientPausesCommand(c, set) || srv.clientPausesCommand(c, get) {
+		t.Fatalf("CLIENT PAUSE WRITE should only pause write commands")
+	}
+	// 多次暂停的时候取最严格的类型
+	srv.pauseClients(time.Now().Add(time.Second), RedisClientPauseAll)
+	if !srv.clientPausesCommand(c, get) {
+		t.Fatalf("CLIENT PAUSE ALL should pause all commands")
+	}
+	c.Flags |= client.RedisClientSlave
+	if srv.clientPausesCommand(c, set) {
+		t.Fatalf("commands from replica should not be paused")
+	}
+	srv.unpauseClients()
+	if srv.clientsArePaused() {
+		t.Fatalf("clients should not be paused after CLIENT UNPAUSE")
+	}
+	srv.pauseClients(time.Now().Add(-time.Second), RedisClientPauseAll)
+	if srv.clientsArePaused() {
+		t.Fatalf("CLIENT PAUSE should be expired")
+	}
+}
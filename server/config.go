@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/encodings"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+const (
+	RedisConfigSubCommandGet       = "GET"
+	RedisConfigSubCommandSet       = "SET"
+	RedisConfigSubCommandRewrite   = "REWRITE"
+	RedisConfigSubCommandResetStat = "RESETSTAT"
+)
+
+// CONFIG subcommand [arguments]
+func (srv *Server) ConfigCommand(cli *client.Client) {
+	switch strings.ToUpper(string(cli.Argv[1])) {
+	case RedisConfigSubCommandGet:
+		srv.configGet(cli)
+	case RedisConfigSubCommandSet:
+		srv.configSet(cli)
+	case RedisConfigSubCommandRewrite:
+		srv.configRewrite(cli)
+	case RedisConfigSubCommandResetStat:
+		srv.configResetStat(cli)
+	default:
+		cli.ResponseReError(re.ErrConfigCommand, string(cli.Argv[1]))
+	}
+}
+
+// CONFIG GET parameter [parameter ...]
+func (srv *Server) configGet(cli *client.Client) {
+	if cli.Argc < 3 {
+		cli.ResponseReError(re.ErrConfigCommand, string(cli.Argv[1]))
+		return
+	}
+	ret := make([]string, 0)
+	matched := make(map[string]bool)
+	for _, name := range conf.ConfigParamNames() {
+		for _, pattern := range util.BytesToStrings(cli.Argv[2:]) {
+			if util.StringMatch(pattern, name, true) && !matched[name] {
+				matched[name] = true
+				value, _ := srv.Config.GetParam(name)
+				ret = append(ret, name, value)
+			}
+		}
+	}
+	cli.Response(tcp.NewStringMapReply(ret))
+}
+
+// CONFIG SET parameter value [parameter value ...]
+func (srv *Server) configSet(cli *client.Client) {
+	if cli.Argc < 4 || cli.Argc%2 != 0 {
+		cli.ResponseReError(re.ErrConfigCommand, string(cli.Argv[1]))
+		return
+	}
+	// 在配置的副本上修改，全部校验通过之后才会生效
+	newConfig := *srv.Config
+	changed := make(map[string]bool)
+	for i := 2; i < cli.Argc; i += 2 {
+		name := strings.ToLower(string(cli.Argv[i]))
+		if !conf.IsConfigParam(name) {
+			cli.ResponseReError(re.ErrConfigSetUnknownOption, string(cli.Argv[i]))
+			return
+		}
+		if conf.IsImmutableConfigParam(name) {
+			cli.ResponseReError(re.ErrConfigSetFailed, string(cli.Argv[i]), "can't set immutable config")
+			return
+		}
+		if changed[name] {
+			cli.ResponseReError(re.ErrConfigSetFailed, string(cli.Argv[i]), "duplicate parameter")
+			return
+		}
+		if err := newConfig.SetParam(name, string(cli.Argv[i+1])); err != nil {
+			cli.ResponseReError(re.ErrConfigSetFailed, string(cli.Argv[i]), err.Msg)
+			return
+		}
+		changed[name] = true
+	}
+	// 校验配置项之间的约束
+	if err := newConfig.Validate(); err != nil {
+		first := err.(conf.ConfigErrors)[0]
+		cli.ResponseReError(re.ErrConfigSetFailed, first.Param, first.Msg)
+		return
+	}
+	*srv.Config = newConfig
+	srv.applyConfig(changed)
+	cli.ResponseOK()
+}
+
+// 让修改过的配置项立即生效，其余的配置项在每次使用的时候都会从srv.Config中读取
+func (srv *Server) applyConfig(changed map[string]bool) {
+	if changed["log-level"] {
+		loggers.SetLevel(srv.Config.LogLevel)
+	}
+	if changed["save"] {
+		srv.saveParams, _ = conf.ParseSaveParams(srv.Config.Save)
+	}
+	if changed["client-output-buffer-limit"] {
+		srv.updateClientOutputBufferLimits()
+	}
+	if changed["maxmemory"] || changed["maxmemory-policy"] || changed["lfu-log-factor"] || changed["lfu-decay-time"] {
+		srv.updateEvictionPolicy()
+	}
+	if changed["list-max-listpack-size"] || changed["list-compress-depth"] ||
+		changed["hash-max-listpack-entries"] || changed["hash-max-listpack-value"] ||
+		changed["set-max-intset-entries"] ||
+		changed["zset-max-listpack-entries"] || changed["zset-max-listpack-value"] {
+		srv.updateEncodingConfig()
+	}
+	if changed["maxmemory"] {
+		// 调小maxmemory之后马上淘汰多出来的key
+		if err := srv.freeMemoryIfNeeded(); err != nil {
+			loggers.Warn("WARNING: the new maxmemory value set via CONFIG SET (%d) is smaller than the current memory usage (%d)",
+				srv.Config.MaxMemory, srv.evictionUsedMemory())
+		}
+	}
+}
+
+// 更新encodings中对象编码转换的限制, 只对之后创建或者转换的对象生效
+func (srv *Server) updateEncodingConfig() {
+	encodings.ListMaxListPackSize = srv.Config.ListMaxListPackSize
+	encodings.ListCompressDepth = srv.Config.ListCompressDepth
+	encodings.HashMaxListPackEntries = srv.Config.HashMaxListPackEntries
+	encodings.HashMaxListPackValue = srv.Config.HashMaxListPackValue
+	encodings.SetMaxIntSetEntries = srv.Config.SetMaxIntSetEntries
+	encodings.ZSetMaxListPackEntries = srv.Config.ZSetMaxListPackEntries
+	encodings.ZSetMaxListPackValue = srv.Config.ZSetMaxListPackValue
+}
+
+// CONFIG REWRITE
+func (srv *Server) configRewrite(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrConfigCommand, string(cli.Argv[1]))
+		return
+	}
+	if srv.Config.ConfigFile == "" {
+		cli.ResponseReError(re.ErrConfigNoConfigFile)
+		return
+	}
+	if err := rewriteConfigFile(srv.Config.ConfigFile, srv.Config); err != nil {
+		loggers.Warn("CONFIG REWRITE failed: %s", err)
+		cli.ResponseReError(re.ErrConfigRewriteFailed, err.Error())
+		return
+	}
+	loggers.Info("CONFIG REWRITE executed with success.")
+	cli.ResponseOK()
+}
+
+// CONFIG RESETSTAT
+func (srv *Server) configResetStat(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrConfigCommand, string(cli.Argv[1]))
+		return
+	}
+	srv.resetServerStats()
+	cli.ResponseOK()
+}
+
+/**
+CONFIG REWRITE:
+	和redis一样尽量保留原有配置文件的内容:
+		注释、空行以及不认识的配置项原样保留;
+		已有的配置项原地更新，行尾的注释也会保留，重复出现的配置项只保留第一个;
+		配置文件中没有并且和默认值不同的配置项追加到文件的末尾。
+	先写入临时文件，然后通过rename替换原有的配置文件。
+*/
+func rewriteConfigFile(path string, config *conf.ServerConfig) error {
+	var lines []string
+	if content, err := ioutil.ReadFile(path); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	rewritten := make(map[string]bool)
+	output := make([]string, 0, len(lines))
+	for _, line := range lines {
+		name, comment, ok := parseConfigLine(line)
+		if !ok || !conf.IsConfigParam(name) {
+			output = append(output, line)
+			continue
+		}
+		if rewritten[name] {
+			continue
+		}
+		rewritten[name] = true
+		value, _ := config.GetParamTOML(name)
+		output = append(output, fmt.Sprintf("%s = %s%s", name, value, comment))
+	}
+
+	defaults := conf.NewServerConfig()
+	for _, name := range conf.ConfigParamNames() {
+		if rewritten[name] {
+			continue
+		}
+		value, _ := config.GetParamTOML(name)
+		if defaultValue, _ := defaults.GetParamTOML(name); value != defaultValue {
+			output = append(output, fmt.Sprintf("%s = %s", name, value))
+		}
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "redis-go-config-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	content := strings.Join(output, "\n")
+	if len(output) != 0 {
+		content += "\n"
+	}
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		os.Chmod(tmp.Name(), info.Mode())
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// 解析TOML中的 key = value # comment, 返回配置项的名称以及行尾的注释(包含前面的空白)
+func parseConfigLine(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "[") {
+		return "", "", false
+	}
+	eq := strings.IndexByte(line, '=')
+	if eq < 0 {
+		return "", "", false
+	}
+	name := strings.Trim(strings.TrimSpace(line[:eq]), `"'`)
+
+	// 找到不在引号中的'#'
+	var quote byte
+	for i := eq + 1; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			j := i
+			for j > eq+1 && (line[j-1] == ' ' || line[j-1] == '\t') {
+				j--
+			}
+			return name, line[j:], true
+		}
+	}
+	return name, "", true
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SwanSpouse/redis_go/conf"
+)
+
+func TestParseConfigLine(t *testing.T) {
+	cases := []struct {
+		line    string
+		name    string
+		comment string
+		ok      bool
+	}{
+		{"port = 9736", "port", "", true},
+		{"  maxmemory-policy = \"allkeys-lru\"   # eviction", "maxmemory-policy", "   # eviction", true},
+		{"rdb-filename = \"dump#1.rdb\" # file", "rdb-filename", " # file", true},
+		{"# maxmemory = 100", "", "", false},
+		{"[section]", "", "", false},
+		{"", "", "", false},
+	}
+	for _, c := range cases {
+		name, comment, ok := parseConfigLine(c.line)
+		if name != c.name || comment != c.comment || ok != c.ok {
+			t.Fatalf("parse %q, expect (%q, %q, %v), got (%q, %q, %v)", c.line, c.name, c.comment, c.ok, name, comment, ok)
+		}
+	}
+}
+
+func TestRewriteConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redis-go-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "redis.toml")
+	origin := "# redis-go config\n" +
+		"port = 9736\n" +
+		"maxmemory = 100 # bytes\n" +
+		"maxmemory = 200\n" +
+		"unknown-option = 1\n"
+	if err := ioutil.WriteFile(path, []byte(origin), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := conf.NewServerConfig()
+	if err := config.SetParam("maxmemory", "1mb"); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SetParam("slowlog-max-len", "64"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rewriteConfigFile(path, config); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "# redis-go config\n" +
+		"port = 9736\n" +
+		"maxmemory = 1048576 # bytes\n" +
+		"unknown-option = 1\n" +
+		"slowlog-max-len = 64\n"
+	if string(content) != expect {
+		t.Fatalf("unexpected config file content:\n%s", content)
+	}
+
+	// 重写之后的配置文件再次重写不应该有任何变化
+	if err := rewriteConfigFile(path, config); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ioutil.ReadFile(path); string(again) != expect {
+		t.Fatalf("rewrite should be idempotent, got:\n%s", again)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".tmp") {
+			t.Fatalf("temp file %s should be removed", f.Name())
+		}
+	}
+}
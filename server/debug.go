@@ -0,0 +1,39 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	re "github.com/SwanSpouse/redis_go/error"
+)
+
+const (
+	RedisDebugSubCommandSleep = "SLEEP"
+)
+
+// DEBUG subcommand [arguments]
+func (srv *Server) Debug(cli *client.Client) {
+	switch strings.ToUpper(string(cli.Argv[1])) {
+	case RedisDebugSubCommandSleep:
+		srv.debugSleep(cli)
+	default:
+		cli.ResponseReError(re.ErrDebugCommand, string(cli.Argv[1]))
+	}
+}
+
+// DEBUG SLEEP seconds: 阻塞当前客户端seconds秒, seconds可以是小数, 用于测试latency monitor以及slow log
+func (srv *Server) debugSleep(cli *client.Client) {
+	if cli.Argc != 3 {
+		cli.ResponseReError(re.ErrDebugCommand, string(cli.Argv[1]))
+		return
+	}
+	seconds, err := strconv.ParseFloat(string(cli.Argv[2]), 64)
+	if err != nil || seconds < 0 {
+		cli.ResponseReError(re.ErrValueIsNotFloat)
+		return
+	}
+	time.Sleep(time.Duration(seconds * float64(time.Second)))
+	cli.ResponseOK()
+}
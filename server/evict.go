@@ -0,0 +1,233 @@
+package server
+
+import (
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/database"
+	"github.com/SwanSpouse/redis_go/encodings"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/loggers"
+)
+
+/**
+maxmemory淘汰策略:
+	和redis一样，这里并不使用精确的LRU/LFU算法，而是每次从数据库中随机采样maxmemory-samples个key，
+将它们按照空闲程度放入淘汰池(evictionPool)中，淘汰池按照idle从小到大排列，每次淘汰idle最大的key。
+淘汰池在多次淘汰之间是保留的，所以随着采样次数的增加，淘汰的结果会越来越接近真实的LRU/LFU。
+*/
+const (
+	EvictionPoolSize       = 16
+	EvictionMaxEmptyRounds = 16 // 连续这么多次都没有采样到可以淘汰的key的时候放弃淘汰
+)
+
+type evictionPoolEntry struct {
+	idle int64  // 空闲程度, 越大越优先被淘汰
+	key  string // key
+	dbID int    // key所在的数据库
+}
+
+// 当前的淘汰策略是否只淘汰设置了过期时间的key
+func isVolatilePolicy(policy string) bool {
+	return strings.HasPrefix(policy, "volatile-")
+}
+
+// 当前的淘汰策略是否需要使用淘汰池
+func isPoolPolicy(policy string) bool {
+	switch policy {
+	case conf.RedisMaxMemoryVolatileLRU, conf.RedisMaxMemoryVolatileLFU, conf.RedisMaxMemoryVolatileTTL,
+		conf.RedisMaxMemoryAllKeysLRU, conf.RedisMaxMemoryAllKeysLFU:
+		return true
+	}
+	return false
+}
+
+// 根据maxmemory-policy更新encodings中对象访问时的计数方式
+func (srv *Server) updateEvictionPolicy() {
+	encodings.MaxMemoryPolicyLFU = srv.Config.MaxMemoryPolicy == conf.RedisMaxMemoryAllKeysLFU ||
+		srv.Config.MaxMemoryPolicy == conf.RedisMaxMemoryVolatileLFU
+	encodings.LFULogFactor = srv.Config.LFULogFactor
+	encodings.LFUDecayTime = srv.Config.LFUDecayTime
+	// 使用LRU、LFU淘汰策略的时候每个对象需要自己的lru字段, 不能使用共享对象
+	encodings.SharedIntegersEnabled = srv.Config.MaxMemory == 0 || !(encodings.MaxMemoryPolicyLFU ||
+		srv.Config.MaxMemoryPolicy == conf.RedisMaxMemoryAllKeysLRU || srv.Config.MaxMemoryPolicy == conf.RedisMaxMemoryVolatileLRU)
+}
+
+// 计算key的空闲程度，淘汰池中idle越大的key越先被淘汰
+func evictionIdle(policy string, obj database.TBase) int64 {
+	switch policy {
+	case conf.RedisMaxMemoryAllKeysLRU, conf.RedisMaxMemoryVolatileLRU:
+		return obj.EstimateIdleTime()
+	case conf.RedisMaxMemoryAllKeysLFU, conf.RedisMaxMemoryVolatileLFU:
+		return int64(encodings.LFUCounterMax - obj.LFUDecrAndReturn())
+	case conf.RedisMaxMemoryVolatileTTL:
+		// 越早过期的key越先被淘汰
+		return math.MaxInt64 - obj.GetExpireTime().UnixNano()/1e6
+	}
+	return 0
+}
+
+/*
+	从数据库中采样一些key放入淘汰池。淘汰池按照idle从小到大排列，
+	如果淘汰池已满并且采样到的key比池子中所有的key都要"新"，则丢弃这个key。
+*/
+func (srv *Server) evictionPoolPopulate(db *database.Database, policy string) int {
+	samples := db.SampleKeys(srv.Config.MaxMemorySamples, isVolatilePolicy(policy))
+	for key, obj := range samples {
+		idle := evictionIdle(policy, obj)
+
+		// 找到第一个idle大于当前key的位置
+		k := 0
+		for k < EvictionPoolSize && srv.evictionPool[k].key != "" && srv.evictionPool[k].idle < idle {
+			k++
+		}
+		if k == 0 && srv.evictionPool[EvictionPoolSize-1].key != "" {
+			// 淘汰池已满，并且当前key比池子里所有的key都要"新"
+			continue
+		} else if k < EvictionPoolSize && srv.evictionPool[k].key == "" {
+			// 插入到空位上
+		} else if srv.evictionPool[EvictionPoolSize-1].key == "" {
+			// 右边还有空位，把k之后的元素右移
+			copy(srv.evictionPool[k+1:], srv.evictionPool[k:EvictionPoolSize-1])
+		} else {
+			// 没有空位了，丢弃idle最小的元素，把k之前的元素左移
+			k--
+			copy(srv.evictionPool[:k], srv.evictionPool[1:k+1])
+		}
+		srv.evictionPool[k] = &evictionPoolEntry{idle: idle, key: key, dbID: db.GetID()}
+	}
+	return len(samples)
+}
+
+// 从淘汰池中取出最适合被淘汰并且仍然存在于数据库中的key
+func (srv *Server) evictionPoolPop() (*database.Database, string, database.TBase) {
+	for k := EvictionPoolSize - 1; k >= 0; k-- {
+		entry := srv.evictionPool[k]
+		if entry.key == "" {
+			continue
+		}
+		srv.evictionPool[k] = &evictionPoolEntry{}
+		db := srv.Databases[entry.dbID]
+		if obj := db.LookupKeyNoTouch(entry.key); obj != nil {
+			return db, entry.key, obj
+		}
+	}
+	return nil, "", nil
+}
+
+// 为random策略随机选择一个key, 按照数据库依次轮流选择
+func (srv *Server) evictionRandomKey(policy string) (*database.Database, string, database.TBase) {
+	for i := 0; i < len(srv.Databases); i++ {
+		srv.evictionNextDB = (srv.evictionNextDB + 1) % len(srv.Databases)
+		db := srv.Databases[srv.evictionNextDB]
+		if db.DBSize() == 0 {
+			continue
+		}
+		// 采样可能落在空的slot上，所以多尝试几次
+		for try := 0; try < srv.Config.MaxMemorySamples; try++ {
+			for key, obj := range db.SampleKeys(srv.Config.MaxMemorySamples, isVolatilePolicy(policy)) {
+				return db, key, obj
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// 数据库中是否还有可以被淘汰的key, volatile策略只淘汰设置了过期时间的key
+func (srv *Server) hasEvictableKeys(policy string) bool {
+	for _, db := range srv.Databases {
+		if isVolatilePolicy(policy) {
+			if expires, _ := db.ExpiresStats(); expires > 0 {
+				return true
+			}
+		} else if db.DBSize() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	选择下一个需要被淘汰的key。
+	采样是从所有的key中进行的, volatile策略的一次采样可能一个设置了过期时间的key都没有, 这时继续采样,
+	直到数据库中没有可以淘汰的key或者连续EvictionMaxEmptyRounds次都没有采样到。
+*/
+func (srv *Server) evictionBestKey(policy string) (*database.Database, string, database.TBase) {
+	if !isPoolPolicy(policy) {
+		return srv.evictionRandomKey(policy)
+	}
+	for emptyRounds := 0; emptyRounds < EvictionMaxEmptyRounds; {
+		total := 0
+		for _, db := range srv.Databases {
+			total += srv.evictionPoolPopulate(db, policy)
+		}
+		db, key, obj := srv.evictionPoolPop()
+		if obj != nil {
+			return db, key, obj
+		}
+		if total == 0 {
+			if !srv.hasEvictableKeys(policy) {
+				break
+			}
+			emptyRounds++
+		}
+	}
+	return nil, "", nil
+}
+
+/*
+	在执行命令之前检查内存使用情况，如果超过了maxmemory就根据maxmemory-policy淘汰key。
+	如果无法释放足够的内存，则返回ErrOOMCommandNotAllowed，由调用方决定是否拒绝执行命令。
+*/
+func (srv *Server) freeMemoryIfNeeded() error {
+	if srv.Config.MaxMemory <= 0 {
+		return nil
+	}
+	used := srv.evictionUsedMemory()
+	if used <= srv.Config.MaxMemory {
+		return nil
+	}
+	policy := srv.Config.MaxMemoryPolicy
+	if policy == conf.RedisMaxMemoryNoEviction {
+		return re.ErrOOMCommandNotAllowed
+	}
+
+	srv.evictionLock.Lock()
+	defer srv.evictionLock.Unlock()
+
+	start := time.Now()
+	defer func() {
+		srv.latencyAddSampleIfNeeded(LatencyEventEvictionCycle, time.Since(start))
+	}()
+
+	memToFree := used - srv.Config.MaxMemory
+	var memFreed int64
+	for memFreed < memToFree {
+		db, key, obj := srv.evictionBestKey(policy)
+		if obj == nil {
+			break
+		}
+		delta := database.KeyMemory(key, obj)
+		delStart := time.Now()
+		db.RemoveKeyInDB([]string{key})
+		srv.latencyAddSampleIfNeeded(LatencyEventEvictionDel, time.Since(delStart))
+		srv.trackingInvalidateKey(nil, key)
+		memFreed += delta
+		atomic.AddInt64(&srv.statEvictedKeys, 1)
+		loggers.Debug("evict key:%s from db:%d, freed memory:%d", key, db.GetID(), delta)
+
+		// 把淘汰的key以DEL命令的形式写入aof
+		if srv.Config.AofState == conf.RedisAofOn {
+			srv.feedAppendOnlyFile(db.GetID(), 2, [][]byte{[]byte(handlers.RedisKeyCommandDel), []byte(key)})
+		}
+	}
+	if memFreed < memToFree {
+		loggers.Warn("can not free enough memory, used:%d maxmemory:%d freed:%d", used, srv.Config.MaxMemory, memFreed)
+		return re.ErrOOMCommandNotAllowed
+	}
+	return nil
+}
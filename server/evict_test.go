@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/database"
+	"github.com/SwanSpouse/redis_go/encodings"
+	re "github.com/SwanSpouse/redis_go/error"
+)
+
+func newEvictTestServer(policy string) *Server {
+	config := conf.NewServerConfig()
+	config.MaxMemoryPolicy = policy
+	srv := &Server{Config: config}
+	srv.initServer()
+	srv.initDB()
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		value := fmt.Sprintf("value-%d", i)
+		if i%2 == 0 {
+			// 偶数key设置过期时间, key越小越早过期
+			srv.Databases[0].SetKeyInDB(key, database.NewRedisStringObjectWithTTL(value, (i+1)*3600))
+		} else {
+			srv.Databases[0].SetKeyInDB(key, database.NewRedisStringObject(value))
+		}
+	}
+	// 人为设置maxmemory，使内存使用量超过maxmemory
+	srv.startupMemory = 0
+	srv.Config.MaxMemory = srv.datasetMemory() - 100
+	return srv
+}
+
+func TestFreeMemoryNoEviction(t *testing.T) {
+	srv := newEvictTestServer(conf.RedisMaxMemoryNoEviction)
+	if err := srv.freeMemoryIfNeeded(); err != re.ErrOOMCommandNotAllowed {
+		t.Fatalf("expect OOM error, got %+v", err)
+	}
+	if srv.Databases[0].DBSize() != 100 {
+		t.Fatalf("noeviction should not evict any key")
+	}
+}
+
+func TestFreeMemoryEvictKeys(t *testing.T) {
+	policies := []string{
+		conf.RedisMaxMemoryAllKeysLRU, conf.RedisMaxMemoryAllKeysLFU, conf.RedisMaxMemoryAllKeysRandom,
+		conf.RedisMaxMemoryVolatileLRU, conf.RedisMaxMemoryVolatileLFU, conf.RedisMaxMemoryVolatileRandom,
+		conf.RedisMaxMemoryVolatileTTL,
+	}
+	for _, policy := range policies {
+		srv := newEvictTestServer(policy)
+		if err := srv.freeMemoryIfNeeded(); err != nil {
+			t.Fatalf("policy %s free memory error %+v", policy, err)
+		}
+		if srv.Databases[0].DBSize() >= 100 || srv.statEvictedKeys == 0 {
+			t.Fatalf("policy %s should evict some keys", policy)
+		}
+		if isVolatilePolicy(policy) {
+			// 没有设置过期时间的key不应该被淘汰
+			for i := 1; i < 100; i += 2 {
+				if srv.Databases[0].LookupKeyNoTouch(fmt.Sprintf("key-%d", i)) == nil {
+					t.Fatalf("policy %s evict key without expire", policy)
+				}
+			}
+		}
+	}
+}
+
+func TestFreeMemoryIgnoreHeapGarbage(t *testing.T) {
+	srv := newEvictTestServer(conf.RedisMaxMemoryAllKeysRandom)
+	if err := srv.freeMemoryIfNeeded(); err != nil {
+		t.Fatalf("free memory error %+v", err)
+	}
+	evicted := srv.statEvictedKeys
+	// 堆上的垃圾还没有被回收, 不应该再次淘汰key
+	srv.usedMemorySample = srv.Config.MaxMemory * 2
+	if err := srv.freeMemoryIfNeeded(); err != nil {
+		t.Fatalf("free memory error %+v", err)
+	}
+	if srv.statEvictedKeys != evicted || srv.evictionUsedMemory() > srv.Config.MaxMemory {
+		t.Fatalf("evicted keys %d -> %d, used memory %d", evicted, srv.statEvictedKeys, srv.evictionUsedMemory())
+	}
+}
+
+func TestFreeMemoryVolatileTTL(t *testing.T) {
+	srv := newEvictTestServer(conf.RedisMaxMemoryVolatileTTL)
+	srv.Config.MaxMemorySamples = 100
+	srv.Config.MaxMemory = srv.datasetMemory() - 1
+	if err := srv.freeMemoryIfNeeded(); err != nil {
+		t.Fatalf("free memory error %+v", err)
+	}
+	// key-0 最先过期，应该最先被淘汰
+	if srv.Databases[0].LookupKeyNoTouch("key-0") != nil {
+		t.Fatalf("volatile-ttl should evict the key with the nearest expire time")
+	}
+}
+
+func TestSearchKeyNotTouchSharedObject(t *testing.T) {
+	db := database.NewDatabase(0)
+	db.SetKeyInDB("shared-1", database.NewRedisStringObject("1"))
+	db.SetKeyInDB("shared-2", database.NewRedisStringObject("1"))
+	shared := db.LookupKeyNoTouch("shared-1")
+	if shared.GetRefCount() != encodings.RedisSharedRefCount || db.LookupKeyNoTouch("shared-2") != shared {
+		t.Fatalf("small integers should use the shared object")
+	}
+	lru := shared.GetLRU()
+	shared.SetLRU(0)
+	defer shared.SetLRU(lru)
+
+	// 多个goroutine同时访问引用同一个共享对象的key, 在-race下不应该出现数据竞争
+	done := make(chan struct{})
+	for _, key := range []string{"shared-1", "shared-2"} {
+		go func(key string) {
+			for i := 0; i < 100; i++ {
+				db.SearchKeyInDB(key)
+			}
+			done <- struct{}{}
+		}(key)
+	}
+	<-done
+	<-done
+	if shared.GetLRU() != 0 {
+		t.Fatalf("lru of the shared object should not be updated, got %d", shared.GetLRU())
+	}
+}
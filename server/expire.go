@@ -0,0 +1,39 @@
+package server
+
+import (
+	"time"
+)
+
+/**
+主动过期:
+	和redis一样，在ServerCron中对每个数据库随机采样一些设置了过期时间的key，删除其中已经过期的key。
+	如果采样中过期的key超过了ActiveExpireCycleAcceptableStale，说明还有很多过期的key，继续采样。
+	每次执行的时间不超过ActiveExpireCycleTimeLimit，避免阻塞太久。
+*/
+const (
+	ActiveExpireCycleKeysPerLoop     = 20                    /* Keys for each DB loop. */
+	ActiveExpireCycleAcceptableStale = 10                    /* % of stale keys after which we do extra efforts. */
+	ActiveExpireCycleTimeLimit       = 25 * time.Millisecond /* Max time of each cycle. */
+)
+
+func (srv *Server) activeExpireCycle() {
+	start := time.Now()
+	defer func() {
+		srv.latencyAddSampleIfNeeded(LatencyEventExpireCycle, time.Since(start))
+	}()
+
+	for _, db := range srv.Databases {
+		if db.DBSize() == 0 {
+			continue
+		}
+		for {
+			sampled, expired := db.ActiveExpireSample(ActiveExpireCycleKeysPerLoop)
+			if sampled == 0 || expired*100/sampled <= ActiveExpireCycleAcceptableStale {
+				break
+			}
+			if time.Since(start) > ActiveExpireCycleTimeLimit {
+				return
+			}
+		}
+	}
+}
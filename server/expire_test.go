@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/database"
+)
+
+func TestKeyspaceExpiresStats(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	db := srv.Databases[0]
+	for i := 0; i < 10; i++ {
+		db.SetKeyInDB(fmt.Sprintf("volatile-%d", i), database.NewRedisStringObjectWithTTL("value", 3600))
+		db.SetKeyInDB(fmt.Sprintf("persist-%d", i), database.NewRedisStringObject("value"))
+	}
+	if expires, avgTTL := db.ExpiresStats(); expires != 10 || avgTTL != 0 {
+		t.Fatalf("expect 10 expires and no avg ttl before sampling, got %d %d", expires, avgTTL)
+	}
+	// 覆盖以及删除设置了过期时间的key
+	db.SetKeyInDB("volatile-0", database.NewRedisStringObject("value"))
+	db.SetKeyInDB("persist-0", database.NewRedisStringObjectWithTTL("value", 3600))
+	db.RemoveKeyInDB([]string{"volatile-1", "persist-1"})
+	if expires, _ := db.ExpiresStats(); expires != 9 {
+		t.Fatalf("expect 9 expires, got %d", expires)
+	}
+
+	srv.activeExpireCycle()
+	if _, avgTTL := db.ExpiresStats(); avgTTL <= 0 || avgTTL > 3600*1000 {
+		t.Fatalf("unexpected avg ttl %d", avgTTL)
+	}
+	db.FlushDB()
+	if expires, avgTTL := db.ExpiresStats(); expires != 0 || avgTTL != 0 {
+		t.Fatalf("expect no expires after flush, got %d %d", expires, avgTTL)
+	}
+}
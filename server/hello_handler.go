@@ -0,0 +1,77 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/tcp"
+)
+
+/**
+HELLO [protover [AUTH username password] [SETNAME clientname]]:
+	切换连接使用的协议版本, 返回服务器以及连接的信息。protover为3的时候连接切换到RESP3协议。
+	现在只有default用户并且没有设置密码, 所以AUTH的时候只检查用户名。
+*/
+const (
+	RedisHelloOptionAuth    = "AUTH"
+	RedisHelloOptionSetName = "SETNAME"
+)
+
+func (srv *Server) Hello(cli *client.Client) {
+	proto := cli.Protocol()
+	if cli.Argc >= 2 {
+		ver, err := strconv.ParseInt(string(cli.Argv[1]), 10, 64)
+		if err != nil {
+			cli.ResponseReError(re.ErrHelloProtocolVersion)
+			return
+		}
+		if ver < tcp.RespProto2 || ver > tcp.RespProto3 {
+			cli.ResponseReError(re.ErrNoProto)
+			return
+		}
+		proto = int(ver)
+	}
+
+	var username, name string
+	var setName bool
+	for i := 2; i < cli.Argc; i++ {
+		moreArgs := cli.Argc - 1 - i
+		option := strings.ToUpper(string(cli.Argv[i]))
+		if option == RedisHelloOptionAuth && moreArgs >= 2 {
+			username = string(cli.Argv[i+1])
+			i += 2
+		} else if option == RedisHelloOptionSetName && moreArgs >= 1 {
+			name = string(cli.Argv[i+1])
+			setName = true
+			i += 1
+		} else {
+			cli.ResponseReError(re.ErrHelloSyntax, string(cli.Argv[i]))
+			return
+		}
+	}
+	if username != "" && username != RedisClientDefaultUser {
+		cli.ResponseReError(re.ErrWrongPass)
+		return
+	}
+	if setName {
+		if !isValidClientName(name) {
+			cli.ResponseReError(re.ErrClientNameInvalid)
+			return
+		}
+		cli.Name = name
+	}
+	// 先切换协议, 返回值使用新的协议
+	cli.SetProtocol(proto)
+	cli.Response(tcp.MapReply{
+		"server", "redis",
+		"version", conf.RedisVersion,
+		"proto", proto,
+		"id", cli.ID(),
+		"mode", "standalone",
+		"role", "master",
+		"modules", tcp.ArrayReply{},
+	})
+}
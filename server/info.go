@@ -0,0 +1,360 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/encodings"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/**
+INFO [section [section ...]]
+	返回的格式和redis保持一致, 每个section以"# Section"开头, 每一行为"field:value", 行之间用\r\n分隔,
+	section之间用空行分隔。这样现有的redis exporter等监控工具可以直接解析。
+*/
+const (
+	RedisInfoSectionDefault      = "default"
+	RedisInfoSectionAll          = "all"
+	RedisInfoSectionEverything   = "everything"
+	RedisInfoSectionServer       = "server"
+	RedisInfoSectionClients      = "clients"
+	RedisInfoSectionMemory       = "memory"
+	RedisInfoSectionPersistence  = "persistence"
+	RedisInfoSectionStats        = "stats"
+	RedisInfoSectionReplication  = "replication"
+	RedisInfoSectionCPU          = "cpu"
+	RedisInfoSectionCommandStats = "commandstats"
+	RedisInfoSectionLatencyStats = "latencystats"
+	RedisInfoSectionErrorStats   = "errorstats"
+	RedisInfoSectionKeyspace     = "keyspace"
+)
+
+// 默认返回的section, commandstats需要显式指定或者使用all
+var defaultInfoSections = []string{
+	RedisInfoSectionServer,
+	RedisInfoSectionClients,
+	RedisInfoSectionMemory,
+	RedisInfoSectionPersistence,
+	RedisInfoSectionStats,
+	RedisInfoSectionReplication,
+	RedisInfoSectionCPU,
+	RedisInfoSectionErrorStats,
+	RedisInfoSectionKeyspace,
+}
+
+// INFO [section [section ...]]
+func (srv *Server) Info(cli *client.Client) {
+	sections := make(map[string]bool)
+	if cli.Argc == 1 {
+		sections[RedisInfoSectionDefault] = true
+	}
+	for _, section := range util.BytesToStrings(cli.Argv[1:]) {
+		sections[strings.ToLower(section)] = true
+	}
+	cli.Response(tcp.VerbatimReply{Format: "txt", Text: srv.genRedisInfoString(sections)})
+}
+
+// 生成INFO命令返回的内容
+func (srv *Server) genRedisInfoString(sections map[string]bool) string {
+	all := sections[RedisInfoSectionAll] || sections[RedisInfoSectionEverything]
+	if sections[RedisInfoSectionDefault] {
+		for _, section := range defaultInfoSections {
+			sections[section] = true
+		}
+	}
+	generators := []struct {
+		name string
+		gen  func() string
+	}{
+		{RedisInfoSectionServer, srv.genServerInfoString},
+		{RedisInfoSectionClients, srv.genClientsInfoString},
+		{RedisInfoSectionMemory, srv.genMemoryInfoString},
+		{RedisInfoSectionPersistence, srv.genPersistenceInfoString},
+		{RedisInfoSectionStats, srv.genStatsInfoString},
+		{RedisInfoSectionReplication, srv.genReplicationInfoString},
+		{RedisInfoSectionCPU, srv.genCPUInfoString},
+		{RedisInfoSectionCommandStats, srv.genCommandStatsInfoString},
+		{RedisInfoSectionLatencyStats, srv.genLatencyStatsInfoString},
+		{RedisInfoSectionErrorStats, srv.genErrorStatsInfoString},
+		{RedisInfoSectionKeyspace, srv.genKeyspaceInfoString},
+	}
+	infos := make([]string, 0)
+	for _, generator := range generators {
+		if all || sections[generator.name] {
+			infos = append(infos, generator.gen())
+		}
+	}
+	return strings.Join(infos, "\r\n")
+}
+
+// 生成一个section的内容
+func genInfoSectionString(title string, lines ...string) string {
+	info := "# " + title + "\r\n"
+	for _, line := range lines {
+		info += line + "\r\n"
+	}
+	return info
+}
+
+func (srv *Server) genServerInfoString() string {
+	uptime := int64(time.Since(srv.startTime) / time.Second)
+	executable, _ := os.Executable()
+	return genInfoSectionString("Server",
+		fmt.Sprintf("redis_version:%s", conf.RedisVersion),
+		"redis_git_sha1:00000000",
+		"redis_git_dirty:0",
+		"redis_build_id:0",
+		"redis_mode:standalone",
+		fmt.Sprintf("os:%s %s", runtime.GOOS, runtime.GOARCH),
+		fmt.Sprintf("arch_bits:%d", 32<<(^uint(0)>>63)),
+		"multiplexing_api:goroutine",
+		"atomicvar_api:sync-atomic",
+		fmt.Sprintf("go_version:%s", runtime.Version()),
+		fmt.Sprintf("process_id:%d", os.Getpid()),
+		"process_supervised:no",
+		fmt.Sprintf("run_id:%s", srv.runID),
+		fmt.Sprintf("tcp_port:%d", srv.Config.Port),
+		fmt.Sprintf("server_time_usec:%d", time.Now().UnixNano()/int64(time.Microsecond)),
+		fmt.Sprintf("uptime_in_seconds:%d", uptime),
+		fmt.Sprintf("uptime_in_days:%d", uptime/(3600*24)),
+		"hz:10",
+		"configured_hz:10",
+		fmt.Sprintf("lru_clock:%d", encodings.GetLRUClock()),
+		fmt.Sprintf("executable:%s", executable),
+		fmt.Sprintf("config_file:%s", srv.Config.ConfigFile),
+		"io_threads_active:0",
+	)
+}
+
+func (srv *Server) genClientsInfoString() string {
+	srv.mu.RLock()
+	connectedClients := len(srv.clients)
+	var maxInput, maxOutput int
+	for _, c := range srv.clients {
+		if input := c.Buffered(); input > maxInput {
+			maxInput = input
+		}
+		if output := c.OutputBuffered(); output > maxOutput {
+			maxOutput = output
+		}
+	}
+	srv.mu.RUnlock()
+	trackingClients, _, _, _ := srv.trackingStats()
+
+	return genInfoSectionString("Clients",
+		fmt.Sprintf("connected_clients:%d", connectedClients),
+		"cluster_connections:0",
+		fmt.Sprintf("maxclients:%d", srv.Config.MaxClients),
+		fmt.Sprintf("client_recent_max_input_buffer:%d", maxInput),
+		fmt.Sprintf("client_recent_max_output_buffer:%d", maxOutput),
+		fmt.Sprintf("blocked_clients:%d", srv.blockedClientsCount()),
+		fmt.Sprintf("tracking_clients:%d", trackingClients),
+		"clients_in_timeout_table:0",
+	)
+}
+
+func (srv *Server) genPersistenceInfoString() string {
+	bgSaveInProgress := 0
+	if srv.Status.Load() == RedisServerStatusRdbBgSaveInProcess {
+		bgSaveInProgress = 1
+	}
+	aofEnabled := 0
+	if srv.Config.AofState == conf.RedisAofOn {
+		aofEnabled = 1
+	}
+	lastSave := srv.rdbLastSave
+	if lastSave.IsZero() {
+		lastSave = srv.startTime
+	}
+	return genInfoSectionString("Persistence",
+		"loading:0",
+		"async_loading:0",
+		fmt.Sprintf("rdb_changes_since_last_save:%d", srv.Dirty),
+		fmt.Sprintf("rdb_bgsave_in_progress:%d", bgSaveInProgress),
+		fmt.Sprintf("rdb_last_save_time:%d", lastSave.Unix()),
+		"rdb_last_bgsave_status:ok",
+		"rdb_last_bgsave_time_sec:-1",
+		"rdb_current_bgsave_time_sec:-1",
+		fmt.Sprintf("aof_enabled:%d", aofEnabled),
+		"aof_rewrite_in_progress:0",
+		"aof_rewrite_scheduled:0",
+		"aof_last_rewrite_time_sec:-1",
+		"aof_current_rewrite_time_sec:-1",
+		"aof_last_bgrewrite_status:ok",
+		"aof_last_write_status:ok",
+	)
+}
+
+func (srv *Server) genStatsInfoString() string {
+	var hits, misses, expired int64
+	for _, db := range srv.Databases {
+		h, m, e := db.GetStats()
+		hits += h
+		misses += m
+		expired += e
+	}
+	srv.PubSubLock.RLock()
+	pubSubChannels := len(srv.PubSubChannels)
+	pubSubPatterns := srv.PubSubPatterns.ListLength()
+	srv.PubSubLock.RUnlock()
+
+	srv.statLock.Lock()
+	totalErrorReplies := srv.statTotalErrorReplies
+	srv.statLock.Unlock()
+	_, trackingKeys, trackingItems, trackingPrefixes := srv.trackingStats()
+
+	return genInfoSectionString("Stats",
+		fmt.Sprintf("total_connections_received:%d", atomic.LoadInt64(&srv.statNumConnections)),
+		fmt.Sprintf("total_commands_processed:%d", atomic.LoadInt64(&srv.statNumCommands)),
+		fmt.Sprintf("instantaneous_ops_per_sec:%d", srv.getInstantaneousMetric(StatsMetricCommand)),
+		fmt.Sprintf("total_net_input_bytes:%d", atomic.LoadInt64(&srv.statNetInputBytes)),
+		fmt.Sprintf("total_net_output_bytes:%d", atomic.LoadInt64(&srv.statNetOutputBytes)),
+		fmt.Sprintf("instantaneous_input_kbps:%.2f", float64(srv.getInstantaneousMetric(StatsMetricNetInput))/1024),
+		fmt.Sprintf("instantaneous_output_kbps:%.2f", float64(srv.getInstantaneousMetric(StatsMetricNetOutput))/1024),
+		fmt.Sprintf("rejected_connections:%d", atomic.LoadInt64(&srv.statRejectedConn)),
+		"sync_full:0",
+		"sync_partial_ok:0",
+		"sync_partial_err:0",
+		fmt.Sprintf("expired_keys:%d", expired),
+		"expired_stale_perc:0.00",
+		"expired_time_cap_reached_count:0",
+		fmt.Sprintf("evicted_keys:%d", atomic.LoadInt64(&srv.statEvictedKeys)),
+		fmt.Sprintf("keyspace_hits:%d", hits),
+		fmt.Sprintf("keyspace_misses:%d", misses),
+		fmt.Sprintf("pubsub_channels:%d", pubSubChannels),
+		fmt.Sprintf("pubsub_patterns:%d", pubSubPatterns),
+		"latest_fork_usec:0",
+		"total_forks:0",
+		fmt.Sprintf("tracking_total_keys:%d", trackingKeys),
+		fmt.Sprintf("tracking_total_items:%d", trackingItems),
+		fmt.Sprintf("tracking_total_prefixes:%d", trackingPrefixes),
+		fmt.Sprintf("total_error_replies:%d", totalErrorReplies),
+		fmt.Sprintf("client_query_buffer_limit_disconnections:%d", atomic.LoadInt64(&srv.statQbufLimitDisconns)),
+		fmt.Sprintf("client_output_buffer_limit_disconnections:%d", atomic.LoadInt64(&srv.statObufLimitDisconns)),
+	)
+}
+
+func (srv *Server) genReplicationInfoString() string {
+	return genInfoSectionString("Replication",
+		"role:master",
+		"connected_slaves:0",
+		"master_failover_state:no-failover",
+		fmt.Sprintf("master_replid:%s", srv.replID),
+		"master_replid2:0000000000000000000000000000000000000000",
+		"master_repl_offset:0",
+		"second_repl_offset:-1",
+		"repl_backlog_active:0",
+		"repl_backlog_size:1048576",
+		"repl_backlog_first_byte_offset:0",
+		"repl_backlog_histlen:0",
+	)
+}
+
+func (srv *Server) genCPUInfoString() string {
+	sys, user := util.GetCPUUsage()
+	return genInfoSectionString("CPU",
+		fmt.Sprintf("used_cpu_sys:%.6f", sys.Seconds()),
+		fmt.Sprintf("used_cpu_user:%.6f", user.Seconds()),
+		"used_cpu_sys_children:0.000000",
+		"used_cpu_user_children:0.000000",
+	)
+}
+
+func (srv *Server) genCommandStatsInfoString() string {
+	lines := make([]string, 0)
+	for _, name := range srv.getSortedCommandNames() {
+		cmd := srv.commandTable[name]
+		calls, rejected, failed := cmd.GetCalls(), cmd.GetRejectedCalls(), cmd.GetFailedCalls()
+		if calls == 0 && rejected == 0 && failed == 0 {
+			continue
+		}
+		usec := cmd.GetMicrosecond()
+		var usecPerCall float64
+		if calls > 0 {
+			usecPerCall = float64(usec) / float64(calls)
+		}
+		lines = append(lines, fmt.Sprintf("cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f,rejected_calls=%d,failed_calls=%d",
+			strings.ToLower(name), calls, usec, usecPerCall, rejected, failed))
+	}
+	return genInfoSectionString("Commandstats", lines...)
+}
+
+// 解析latency-tracking-info-percentiles配置, 忽略不合法的值
+func parseLatencyPercentiles(config string) []float64 {
+	percentiles := make([]float64, 0)
+	for _, field := range strings.Fields(config) {
+		percentile, err := strconv.ParseFloat(field, 64)
+		if err != nil || percentile < 0 || percentile > 100 {
+			continue
+		}
+		percentiles = append(percentiles, percentile)
+	}
+	return percentiles
+}
+
+func (srv *Server) genLatencyStatsInfoString() string {
+	percentiles := parseLatencyPercentiles(srv.Config.LatencyTrackingInfoPercentiles)
+	lines := make([]string, 0)
+	for _, name := range srv.getSortedCommandNames() {
+		histogram := srv.commandTable[name].GetLatencyHistogram()
+		if histogram == nil || histogram.TotalCount() == 0 {
+			continue
+		}
+		values := make([]string, 0, len(percentiles))
+		for _, percentile := range percentiles {
+			// 直方图中记录的单位是纳秒, 输出的单位是微秒
+			values = append(values, fmt.Sprintf("p%s=%.3f",
+				strconv.FormatFloat(percentile, 'f', -1, 64), float64(histogram.ValueAtPercentile(percentile))/1000))
+		}
+		lines = append(lines, fmt.Sprintf("latency_percentiles_usec_%s:%s", strings.ToLower(name), strings.Join(values, ",")))
+	}
+	return genInfoSectionString("Latencystats", lines...)
+}
+
+// 按照名称排序的命令列表，保证每次INFO返回的顺序一致
+func (srv *Server) getSortedCommandNames() []string {
+	names := make([]string, 0, len(srv.commandTable))
+	for name := range srv.commandTable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (srv *Server) genErrorStatsInfoString() string {
+	srv.statLock.Lock()
+	codes := make([]string, 0, len(srv.errorStats))
+	for code := range srv.errorStats {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	lines := make([]string, 0, len(codes))
+	for _, code := range codes {
+		lines = append(lines, fmt.Sprintf("errorstat_%s:count=%d", code, srv.errorStats[code]))
+	}
+	srv.statLock.Unlock()
+	return genInfoSectionString("Errorstats", lines...)
+}
+
+func (srv *Server) genKeyspaceInfoString() string {
+	lines := make([]string, 0)
+	for _, db := range srv.Databases {
+		keys := db.DBSize()
+		if keys == 0 {
+			continue
+		}
+		expires, avgTTL := db.ExpiresStats()
+		lines = append(lines, fmt.Sprintf("db%d:keys=%d,expires=%d,avg_ttl=%d", db.GetID(), keys, expires, avgTTL))
+	}
+	return genInfoSectionString("Keyspace", lines...)
+}
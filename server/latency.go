@@ -0,0 +1,332 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/tcp"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/**
+latency monitor:
+	和redis一样，当某个事件的耗时超过latency-monitor-threshold毫秒时记录一个样本。
+	每个事件保存最近LatencyTSLen个样本, 同一秒内的多个样本只保留最大的那个。
+	latency-monitor-threshold为0时关闭latency monitor。
+*/
+const (
+	RedisServerCommandLatency = "LATENCY"
+
+	RedisLatencySubCommandLatest  = "LATEST"
+	RedisLatencySubCommandHistory = "HISTORY"
+	RedisLatencySubCommandReset   = "RESET"
+	RedisLatencySubCommandGraph   = "GRAPH"
+	RedisLatencySubCommandDoctor  = "DOCTOR"
+
+	LatencyTSLen     = 160 /* History length for every monitored event. */
+	LatencyGraphCols = 80
+	LatencyGraphRows = 4
+
+	/* 被监控的事件 */
+	LatencyEventCommand        = "command"
+	LatencyEventAofWrite       = "aof-write"
+	LatencyEventAofFsyncAlways = "aof-fsync-always"
+	LatencyEventRdbSave        = "rdb-save"
+	LatencyEventExpireCycle    = "expire-cycle"
+	LatencyEventEvictionCycle  = "eviction-cycle"
+	LatencyEventEvictionDel    = "eviction-del"
+)
+
+type latencySample struct {
+	time    int64 /* We don't use time_t to force 4 bytes usage everywhere. */
+	latency int64 /* Latency in milliseconds. */
+}
+
+// 每个事件的样本保存在一个环形数组中
+type latencyTimeSeries struct {
+	idx     int                         /* Index of the next sample to store. */
+	max     int64                       /* Max latency observed for this event. */
+	samples [LatencyTSLen]latencySample /* Latest history. */
+}
+
+// LATENCY DOCTOR 使用的统计信息
+type latencyStats struct {
+	allTimeHigh int64   /* Absolute max observed since latest reset. */
+	avg         int64   /* Average of current samples. */
+	min         int64   /* Min of current samples. */
+	max         int64   /* Max of current samples. */
+	mad         int64   /* Mean absolute deviation. */
+	samples     int     /* Number of non-zero samples. */
+	period      float64 /* Number of seconds since first event and now. */
+}
+
+// 如果耗时超过了latency-monitor-threshold则记录一个样本
+func (srv *Server) latencyAddSampleIfNeeded(event string, duration time.Duration) {
+	threshold := srv.Config.LatencyMonitorThreshold
+	latency := int64(duration / time.Millisecond)
+	if threshold > 0 && latency >= threshold {
+		srv.latencyAddSample(event, latency)
+	}
+}
+
+func (srv *Server) latencyAddSample(event string, latency int64) {
+	srv.latencyLock.Lock()
+	defer srv.latencyLock.Unlock()
+
+	ts, ok := srv.latencyEvents[event]
+	if !ok {
+		ts = &latencyTimeSeries{}
+		srv.latencyEvents[event] = ts
+	}
+	if latency > ts.max {
+		ts.max = latency
+	}
+	now := time.Now().Unix()
+	// 同一秒内的样本只保留最大的
+	prev := (ts.idx + LatencyTSLen - 1) % LatencyTSLen
+	if ts.samples[prev].time == now {
+		if latency > ts.samples[prev].latency {
+			ts.samples[prev].latency = latency
+		}
+		return
+	}
+	ts.samples[ts.idx] = latencySample{time: now, latency: latency}
+	ts.idx = (ts.idx + 1) % LatencyTSLen
+}
+
+// 按照时间顺序返回所有的样本
+func (ts *latencyTimeSeries) history() []latencySample {
+	ret := make([]latencySample, 0)
+	for j := 0; j < LatencyTSLen; j++ {
+		sample := ts.samples[(ts.idx+j)%LatencyTSLen]
+		if sample.time == 0 {
+			continue
+		}
+		ret = append(ret, sample)
+	}
+	return ret
+}
+
+func (ts *latencyTimeSeries) latest() latencySample {
+	return ts.samples[(ts.idx+LatencyTSLen-1)%LatencyTSLen]
+}
+
+func (ts *latencyTimeSeries) analyze() *latencyStats {
+	stats := &latencyStats{allTimeHigh: ts.max}
+	history := ts.history()
+	if len(history) == 0 {
+		return stats
+	}
+	var sum int64
+	stats.min = math.MaxInt64
+	for _, sample := range history {
+		if sample.latency > stats.max {
+			stats.max = sample.latency
+		}
+		if sample.latency < stats.min {
+			stats.min = sample.latency
+		}
+		sum += sample.latency
+	}
+	stats.samples = len(history)
+	stats.avg = sum / int64(stats.samples)
+	stats.period = float64(time.Now().Unix()-history[0].time) / float64(stats.samples)
+	for _, sample := range history {
+		delta := sample.latency - stats.avg
+		if delta < 0 {
+			delta = -delta
+		}
+		stats.mad += delta
+	}
+	stats.mad /= int64(stats.samples)
+	return stats
+}
+
+// 按照名称排序的事件列表
+func (srv *Server) getSortedLatencyEvents() []string {
+	events := make([]string, 0, len(srv.latencyEvents))
+	for event := range srv.latencyEvents {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	return events
+}
+
+// LATENCY LATEST | HISTORY event | RESET [event ...] | GRAPH event | DOCTOR
+func (srv *Server) Latency(cli *client.Client) {
+	srv.latencyLock.Lock()
+	defer srv.latencyLock.Unlock()
+
+	subCommand := strings.ToUpper(string(cli.Argv[1]))
+	switch {
+	case subCommand == RedisLatencySubCommandLatest && cli.Argc == 2:
+		srv.latencyLatest(cli)
+	case subCommand == RedisLatencySubCommandHistory && cli.Argc == 3:
+		srv.latencyHistory(cli)
+	case subCommand == RedisLatencySubCommandReset && cli.Argc >= 2:
+		srv.latencyReset(cli)
+	case subCommand == RedisLatencySubCommandGraph && cli.Argc == 3:
+		srv.latencyGraph(cli)
+	case subCommand == RedisLatencySubCommandDoctor && cli.Argc == 2:
+		cli.Response(srv.createLatencyReport())
+	default:
+		cli.ResponseReError(re.ErrLatencyCommand, string(cli.Argv[1]))
+	}
+}
+
+// LATENCY LATEST: 每个事件返回[event, timestamp, latest, all time high]
+func (srv *Server) latencyLatest(cli *client.Client) {
+	ret := make(tcp.ArrayReply, 0, len(srv.latencyEvents))
+	for _, event := range srv.getSortedLatencyEvents() {
+		ts := srv.latencyEvents[event]
+		latest := ts.latest()
+		ret = append(ret, []interface{}{event, latest.time, latest.latency, ts.max})
+	}
+	cli.Response(ret)
+}
+
+// LATENCY HISTORY event: 返回[timestamp, latency]
+func (srv *Server) latencyHistory(cli *client.Client) {
+	ret := make(tcp.ArrayReply, 0)
+	if ts, ok := srv.latencyEvents[string(cli.Argv[2])]; ok {
+		for _, sample := range ts.history() {
+			ret = append(ret, []interface{}{sample.time, sample.latency})
+		}
+	}
+	cli.Response(ret)
+}
+
+// LATENCY RESET [event ...]: 返回被重置的事件个数
+func (srv *Server) latencyReset(cli *client.Client) {
+	var resets int
+	if cli.Argc == 2 {
+		resets = len(srv.latencyEvents)
+		srv.latencyEvents = make(map[string]*latencyTimeSeries)
+	} else {
+		for _, event := range util.BytesToStrings(cli.Argv[2:]) {
+			if _, ok := srv.latencyEvents[event]; ok {
+				delete(srv.latencyEvents, event)
+				resets++
+			}
+		}
+	}
+	cli.Response(resets)
+}
+
+// 根据样本距离现在的时间生成label, 例如: 10s 5m 2h 1d
+func latencyElapsedLabel(elapsed int64) string {
+	switch {
+	case elapsed < 60:
+		return fmt.Sprintf("%ds", elapsed)
+	case elapsed < 3600:
+		return fmt.Sprintf("%dm", elapsed/60)
+	case elapsed < 3600*24:
+		return fmt.Sprintf("%dh", elapsed/3600)
+	}
+	return fmt.Sprintf("%dd", elapsed/(3600*24))
+}
+
+func (ts *latencyTimeSeries) genSparkline(event string, now int64) string {
+	seq := util.NewSparklineSequence()
+	var min, max int64
+	for _, sample := range ts.history() {
+		if seq.Length() == 0 {
+			min, max = sample.latency, sample.latency
+		} else {
+			if sample.latency > max {
+				max = sample.latency
+			}
+			if sample.latency < min {
+				min = sample.latency
+			}
+		}
+		seq.AddSample(float64(sample.latency), latencyElapsedLabel(now-sample.time))
+	}
+	return fmt.Sprintf("%s - high %d ms, low %d ms (all time high %d ms)\n", event, max, min, ts.max) +
+		strings.Repeat("-", LatencyGraphCols) + "\n" +
+		seq.Render(LatencyGraphCols, LatencyGraphRows, util.SparklineFill)
+}
+
+// LATENCY GRAPH event
+func (srv *Server) latencyGraph(cli *client.Client) {
+	ts, ok := srv.latencyEvents[string(cli.Argv[2])]
+	if !ok {
+		cli.ResponseReError(re.ErrLatencyNoSamples, string(cli.Argv[2]))
+		return
+	}
+	cli.Response(ts.genSparkline(string(cli.Argv[2]), time.Now().Unix()))
+}
+
+// LATENCY DOCTOR: 生成人类可读的分析报告
+func (srv *Server) createLatencyReport() string {
+	if srv.Config.LatencyMonitorThreshold <= 0 && len(srv.latencyEvents) == 0 {
+		return "I'm sorry, Dave, I can't do that. Latency monitoring is disabled in this Redis instance. " +
+			"You may use \"CONFIG SET latency-monitor-threshold <milliseconds>.\" in order to enable it. " +
+			"If we weren't in a deep space mission I'd suggest to take a look at https://redis.io/topics/latency-monitor.\n"
+	}
+	if len(srv.latencyEvents) == 0 {
+		return "Dave, no latency spike was observed during the lifetime of this Redis instance, not in the slightest bit. " +
+			"I honestly think you ought to sleep tonight.\n"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Dave, I have observed latency spikes in this Redis instance. You don't mind talking about it, do you Dave?\n\n")
+	advices := make(map[string]bool)
+	for i, event := range srv.getSortedLatencyEvents() {
+		stats := srv.latencyEvents[event].analyze()
+		if stats.samples == 0 {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%d. %s: %d latency spikes (average %dms, mean deviation %dms, period %.2f sec). Worst all time event %dms.\n",
+			i+1, event, stats.samples, stats.avg, stats.mad, stats.period, stats.allTimeHigh))
+
+		switch event {
+		case LatencyEventCommand:
+			advices["slowlog"] = true
+		case LatencyEventAofWrite, LatencyEventAofFsyncAlways:
+			advices["aof"] = true
+		case LatencyEventRdbSave:
+			advices["rdb"] = true
+		case LatencyEventExpireCycle:
+			advices["expire"] = true
+		case LatencyEventEvictionCycle, LatencyEventEvictionDel:
+			advices["eviction"] = true
+		}
+	}
+
+	builder.WriteString("\nI have a few advices for you:\n\n")
+	if advices["slowlog"] {
+		if srv.Config.SlowLogLogSlowerThan < 0 || srv.Config.SlowLogLogSlowerThan > srv.Config.LatencyMonitorThreshold*1000 {
+			builder.WriteString(fmt.Sprintf("- Your current Slow Log configuration only logs events that are slower than your configured latency monitor threshold. "+
+				"Please use 'CONFIG SET slowlog-log-slower-than %d'.\n", srv.Config.LatencyMonitorThreshold*1000))
+		}
+		builder.WriteString("- Check your Slow Log to understand what are the commands you are running which are too slow to execute. " +
+			"Please check https://redis.io/commands/slowlog for more information.\n")
+		builder.WriteString("- Deleting, expiring or evicting (because of maxmemory policy) large objects is a blocking operation. " +
+			"If you have very large objects that are often deleted, expired, or evicted, try to fragment those objects into multiple smaller objects.\n")
+	}
+	if advices["aof"] {
+		if srv.Config.AofFSync == conf.RedisAofFSyncAlways {
+			builder.WriteString("- The system is slow to execute Redis code paths not containing system calls. " +
+				"Your fsync policy is set to 'always', which is very costly. Consider 'everysec' unless you need the maximum durability.\n")
+		}
+		builder.WriteString("- Writes to the append only file are slow. Make sure the disk is not busy with other I/O and consider a faster disk.\n")
+	}
+	if advices["rdb"] {
+		builder.WriteString("- Saving the RDB file blocks the server. Avoid calling SAVE on a busy instance and prefer BGSAVE.\n")
+	}
+	if advices["expire"] {
+		builder.WriteString("- Many keys are expiring at the same time. Consider adding some randomness to the expire time of your keys.\n")
+	}
+	if advices["eviction"] {
+		builder.WriteString(fmt.Sprintf("- Keys are evicted because the used memory reached maxmemory (%s). "+
+			"Evicting large objects is slow, consider a larger maxmemory or smaller objects.\n", util.BytesToHuman(srv.Config.MaxMemory)))
+	}
+	return builder.String()
+}
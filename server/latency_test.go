@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/conf"
+)
+
+func newLatencyTestServer(threshold int64) *Server {
+	config := conf.NewServerConfig()
+	config.LatencyMonitorThreshold = threshold
+	srv := &Server{Config: config}
+	srv.initServer()
+	return srv
+}
+
+func TestLatencyAddSampleIfNeeded(t *testing.T) {
+	srv := newLatencyTestServer(0)
+	srv.latencyAddSampleIfNeeded(LatencyEventCommand, time.Second)
+	if len(srv.latencyEvents) != 0 {
+		t.Fatalf("latency monitor is disabled, should not record any event")
+	}
+
+	srv = newLatencyTestServer(100)
+	srv.latencyAddSampleIfNeeded(LatencyEventCommand, 50*time.Millisecond)
+	if len(srv.latencyEvents) != 0 {
+		t.Fatalf("latency below threshold should not be recorded")
+	}
+	// 同一秒内的样本只保留最大的
+	srv.latencyAddSampleIfNeeded(LatencyEventCommand, 200*time.Millisecond)
+	srv.latencyAddSampleIfNeeded(LatencyEventCommand, 300*time.Millisecond)
+	srv.latencyAddSampleIfNeeded(LatencyEventCommand, 150*time.Millisecond)
+	ts := srv.latencyEvents[LatencyEventCommand]
+	history := ts.history()
+	if len(history) != 1 || history[0].latency != 300 || ts.max != 300 {
+		t.Fatalf("unexpected history %+v max %d", history, ts.max)
+	}
+	if latest := ts.latest(); latest.latency != 300 {
+		t.Fatalf("unexpected latest sample %+v", latest)
+	}
+}
+
+func TestLatencyTimeSeriesRing(t *testing.T) {
+	ts := &latencyTimeSeries{}
+	for i := 0; i < LatencyTSLen+10; i++ {
+		ts.samples[ts.idx] = latencySample{time: int64(i + 1), latency: int64(i)}
+		ts.idx = (ts.idx + 1) % LatencyTSLen
+	}
+	history := ts.history()
+	if len(history) != LatencyTSLen || history[0].latency != 10 || history[LatencyTSLen-1].latency != LatencyTSLen+9 {
+		t.Fatalf("unexpected history length %d", len(history))
+	}
+}
+
+func TestLatencyReport(t *testing.T) {
+	srv := newLatencyTestServer(0)
+	if report := srv.createLatencyReport(); !strings.Contains(report, "Latency monitoring is disabled") {
+		t.Fatalf("unexpected report %s", report)
+	}
+	srv = newLatencyTestServer(100)
+	if report := srv.createLatencyReport(); !strings.Contains(report, "no latency spike was observed") {
+		t.Fatalf("unexpected report %s", report)
+	}
+	srv.latencyAddSample(LatencyEventCommand, 500)
+	report := srv.createLatencyReport()
+	if !strings.Contains(report, "1. command: 1 latency spikes (average 500ms") || !strings.Contains(report, "Slow Log") {
+		t.Fatalf("unexpected report %s", report)
+	}
+
+	graph := srv.latencyEvents[LatencyEventCommand].genSparkline(LatencyEventCommand, time.Now().Unix())
+	if !strings.HasPrefix(graph, "command - high 500 ms, low 500 ms (all time high 500 ms)\n"+strings.Repeat("-", LatencyGraphCols)+"\n") {
+		t.Fatalf("unexpected graph %s", graph)
+	}
+}
+
+func TestLatencyEmptyReply(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	cn, peer := net.Pipe()
+	defer peer.Close()
+	go srv.IOLoop(cn)
+
+	// 没有样本的时候LATEST和HISTORY都和redis一样回复空数组
+	reader := bufio.NewReader(peer)
+	for _, cmd := range []string{
+		"*2\r\n$7\r\nLATENCY\r\n$6\r\nLATEST\r\n",
+		"*3\r\n$7\r\nLATENCY\r\n$7\r\nHISTORY\r\n$7\r\ncommand\r\n",
+	} {
+		peer.Write([]byte(cmd))
+		if line, _ := reader.ReadString('\n'); line != "*0\r\n" {
+			t.Fatalf("unexpected reply %q for %q", line, cmd)
+		}
+	}
+}
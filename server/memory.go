@@ -0,0 +1,270 @@
+package server
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/database"
+	"github.com/SwanSpouse/redis_go/encodings"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+const (
+	RedisServerCommandMemory = "MEMORY"
+
+	RedisMemorySubCommandUsage   = "USAGE"
+	RedisMemorySubCommandStats   = "STATS"
+	RedisMemorySubCommandDoctor  = "DOCTOR"
+	RedisMemorySubCommandSamples = "SAMPLES"
+)
+
+// MEMORY STATS 以及 MEMORY DOCTOR 使用的内存统计信息
+type memoryOverhead struct {
+	peakAllocated    int64 // 内存使用的峰值
+	totalAllocated   int64 // 当前的内存使用
+	startupAllocated int64 // 启动时的内存使用
+	dataset          int64 // 所有数据库中key和value占用的内存
+	overhead         int64 // 除数据集之外的内存使用
+	keys             int64 // 所有数据库中key的个数
+	dbs              []memoryDBOverhead
+}
+
+type memoryDBOverhead struct {
+	id     int
+	keys   int64
+	memory int64
+}
+
+// 在ServerCron中采样一次进程的内存使用情况
+func (srv *Server) sampleUsedMemory() {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	used := int64(memStats.HeapAlloc)
+	atomic.StoreInt64(&srv.usedMemorySample, used)
+	atomic.StoreInt64(&srv.usedMemoryRss, int64(memStats.Sys))
+	if used > atomic.LoadInt64(&srv.statPeakMemory) {
+		atomic.StoreInt64(&srv.statPeakMemory, used)
+	}
+}
+
+// 返回最近一次采样的内存使用量
+func (srv *Server) usedMemory() int64 {
+	return atomic.LoadInt64(&srv.usedMemorySample)
+}
+
+// 所有数据库中key和value占用的内存
+func (srv *Server) datasetMemory() int64 {
+	var total int64
+	for _, db := range srv.Databases {
+		total += db.UsedMemory()
+	}
+	return total
+}
+
+/*
+	maxmemory淘汰时使用的内存使用量: 启动时的内存加上数据集的内存。
+	HeapAlloc中包含还没有被GC回收的垃圾, 淘汰key释放的内存要等到GC之后才能体现出来,
+	用它判断是否超过maxmemory会把已经释放的内存重复计算, 导致淘汰过多的key。
+*/
+func (srv *Server) evictionUsedMemory() int64 {
+	return srv.startupMemory + srv.datasetMemory()
+}
+
+// 返回命令中涉及到的key, 用于在写命令执行之后更新key的内存统计以及client side caching
+func getCommandKeys(c *client.Client) []string {
+	return c.Cmd.GetKeys(c.Argv)
+}
+
+// 写命令可能会原地修改value，在命令执行之后重新估算这些key占用的内存
+func (srv *Server) updateKeysMemory(c *client.Client) {
+	db := c.SelectedDatabase()
+	for _, key := range getCommandKeys(c) {
+		db.UpdateKeyMemory(key)
+	}
+}
+
+// 从磁盘加载数据之后重新统计所有数据库的内存
+func (srv *Server) rebuildDatasetMemory() {
+	for _, db := range srv.Databases {
+		db.RebuildMemoryUsage()
+	}
+}
+
+func (srv *Server) getMemoryOverhead() *memoryOverhead {
+	mh := &memoryOverhead{
+		peakAllocated:    atomic.LoadInt64(&srv.statPeakMemory),
+		totalAllocated:   srv.usedMemory(),
+		startupAllocated: srv.startupMemory,
+		dbs:              make([]memoryDBOverhead, 0),
+	}
+	for _, db := range srv.Databases {
+		keys := int64(db.DBSize())
+		if keys == 0 {
+			continue
+		}
+		memory := db.UsedMemory()
+		mh.dataset += memory
+		mh.keys += keys
+		mh.dbs = append(mh.dbs, memoryDBOverhead{id: db.GetID(), keys: keys, memory: memory})
+	}
+	mh.overhead = mh.totalAllocated - mh.dataset
+	if mh.overhead < 0 {
+		mh.overhead = 0
+	}
+	return mh
+}
+
+// 数据集占除启动内存之外的内存的百分比
+func (mh *memoryOverhead) datasetPercentage() float64 {
+	net := mh.totalAllocated - mh.startupAllocated
+	if net <= 0 {
+		return 0
+	}
+	perc := float64(mh.dataset) * 100 / float64(net)
+	if perc > 100 {
+		perc = 100
+	}
+	return perc
+}
+
+func (srv *Server) Memory(cli *client.Client) {
+	switch strings.ToUpper(string(cli.Argv[1])) {
+	case RedisMemorySubCommandUsage:
+		srv.memoryUsage(cli)
+	case RedisMemorySubCommandStats:
+		srv.memoryStats(cli)
+	case RedisMemorySubCommandDoctor:
+		srv.memoryDoctor(cli)
+	default:
+		cli.ResponseReError(re.ErrMemoryCommand, string(cli.Argv[1]))
+	}
+}
+
+// MEMORY USAGE key [SAMPLES count]
+func (srv *Server) memoryUsage(cli *client.Client) {
+	if cli.Argc != 3 && cli.Argc != 5 {
+		cli.ResponseReError(re.ErrMemoryCommand, string(cli.Argv[1]))
+		return
+	}
+	samples := encodings.ObjectMemoryDefaultSamples
+	if cli.Argc == 5 {
+		if strings.ToUpper(string(cli.Argv[3])) != RedisMemorySubCommandSamples {
+			cli.ResponseReError(re.ErrSyntaxError)
+			return
+		}
+		count, err := strconv.Atoi(string(cli.Argv[4]))
+		if err != nil || count < 0 {
+			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
+			return
+		}
+		// SAMPLES 0 表示遍历所有的元素
+		samples = count
+	}
+	key := string(cli.Argv[2])
+	obj := cli.SelectedDatabase().LookupKeyNoTouch(key)
+	if obj == nil || obj.IsExpired() {
+		cli.Response(nil)
+		return
+	}
+	cli.Response(database.KeyMemoryUsage(key, obj, samples))
+}
+
+// MEMORY STATS
+func (srv *Server) memoryStats(cli *client.Client) {
+	mh := srv.getMemoryOverhead()
+	ret := []interface{}{
+		"peak.allocated", mh.peakAllocated,
+		"total.allocated", mh.totalAllocated,
+		"startup.allocated", mh.startupAllocated,
+	}
+	for _, db := range mh.dbs {
+		ret = append(ret, fmt.Sprintf("db.%d", db.id), []interface{}{
+			"keys", db.keys,
+			"dataset.bytes", db.memory,
+		})
+	}
+	ret = append(ret,
+		"overhead.total", mh.overhead,
+		"keys.count", mh.keys,
+		"keys.bytes-per-key", bytesPerKey(mh),
+		"dataset.bytes", mh.dataset,
+		"dataset.percentage", fmt.Sprintf("%.2f", mh.datasetPercentage()),
+		"peak.percentage", fmt.Sprintf("%.2f", peakPercentage(mh)),
+	)
+	cli.Response(ret)
+}
+
+func bytesPerKey(mh *memoryOverhead) int64 {
+	if mh.keys == 0 {
+		return 0
+	}
+	return (mh.totalAllocated - mh.startupAllocated) / mh.keys
+}
+
+func peakPercentage(mh *memoryOverhead) float64 {
+	if mh.peakAllocated == 0 {
+		return 0
+	}
+	return float64(mh.totalAllocated) * 100 / float64(mh.peakAllocated)
+}
+
+// MEMORY DOCTOR
+func (srv *Server) memoryDoctor(cli *client.Client) {
+	cli.Response(srv.getMemoryDoctorReport())
+}
+
+func (srv *Server) getMemoryDoctorReport() string {
+	mh := srv.getMemoryOverhead()
+	// 内存使用量太小的时候不做任何诊断
+	if mh.totalAllocated < 5*1024*1024 {
+		return "Hi, this instance is empty or is using very little memory, " +
+			"the memory doctor can't be used in these conditions. Please fill it with some data first."
+	}
+
+	issues := make([]string, 0)
+	if float64(mh.peakAllocated) > float64(mh.totalAllocated)*1.5 {
+		issues = append(issues, fmt.Sprintf(" * Peak memory: In the past this instance used more than 150%% the memory that is currently using. "+
+			"The peak was %s and is now %s. The allocator is normally not able to release memory after a peak.",
+			util.BytesToHuman(mh.peakAllocated), util.BytesToHuman(mh.totalAllocated)))
+	}
+	if mh.keys > 0 && mh.datasetPercentage() < 50 {
+		issues = append(issues, fmt.Sprintf(" * Dataset overhead: The dataset is only %.2f%% of the used memory, "+
+			"most of the memory is used by clients buffers or other internal structures.", mh.datasetPercentage()))
+	}
+	if used := srv.evictionUsedMemory(); srv.Config.MaxMemory > 0 && float64(used) > float64(srv.Config.MaxMemory)*0.9 {
+		issues = append(issues, fmt.Sprintf(" * Maxmemory: The used memory %s is close to maxmemory %s, keys will be evicted by policy '%s'.",
+			util.BytesToHuman(used), util.BytesToHuman(srv.Config.MaxMemory), srv.Config.MaxMemoryPolicy))
+	}
+	if len(issues) == 0 {
+		return "Hi, I can't find any memory issue in your instance. I can only account for what occurs on this base."
+	}
+	return "Hi, I detected a few issues in this instance memory:\n\n" + strings.Join(issues, "\n\n") + "\n"
+}
+
+// INFO 命令中memory部分的内容
+func (srv *Server) genMemoryInfoString() string {
+	mh := srv.getMemoryOverhead()
+	rss := atomic.LoadInt64(&srv.usedMemoryRss)
+	return genInfoSectionString("Memory",
+		fmt.Sprintf("used_memory:%d", mh.totalAllocated),
+		fmt.Sprintf("used_memory_human:%s", util.BytesToHuman(mh.totalAllocated)),
+		fmt.Sprintf("used_memory_rss:%d", rss),
+		fmt.Sprintf("used_memory_rss_human:%s", util.BytesToHuman(rss)),
+		fmt.Sprintf("used_memory_peak:%d", mh.peakAllocated),
+		fmt.Sprintf("used_memory_peak_human:%s", util.BytesToHuman(mh.peakAllocated)),
+		fmt.Sprintf("used_memory_peak_perc:%.2f%%", peakPercentage(mh)),
+		fmt.Sprintf("used_memory_overhead:%d", mh.overhead),
+		fmt.Sprintf("used_memory_startup:%d", mh.startupAllocated),
+		fmt.Sprintf("used_memory_dataset:%d", mh.dataset),
+		fmt.Sprintf("used_memory_dataset_perc:%.2f%%", mh.datasetPercentage()),
+		fmt.Sprintf("maxmemory:%d", srv.Config.MaxMemory),
+		fmt.Sprintf("maxmemory_human:%s", util.BytesToHuman(srv.Config.MaxMemory)),
+		fmt.Sprintf("maxmemory_policy:%s", srv.Config.MaxMemoryPolicy),
+		"mem_allocator:go",
+	)
+}
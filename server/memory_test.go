@@ -0,0 +1,41 @@
+package server
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/handlers"
+)
+
+func TestUpdateKeysMemoryMoveCommands(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	go io.Copy(io.Discard, peer)
+	c.SetDatabase(srv.Databases[0])
+
+	exec := func(argv ...string) {
+		c.Argv, c.Argc = newTestArgv(argv...), len(argv)
+		c.Cmd = srv.commandTable[argv[0]]
+		c.Cmd.Proc(c)
+		srv.updateKeysMemory(c)
+	}
+	for i := 0; i < 10; i++ {
+		exec(handlers.RedisListCommandRPush, "src", strings.Repeat("x", 100))
+	}
+	for _, argv := range [][]string{
+		{handlers.RedisListCommandRPopLPush, "src", "dst"},
+		{handlers.RedisListCommandLMove, "src", "dst", "LEFT", "RIGHT"},
+		{handlers.RedisListCommandBRPopLPush, "src", "dst", "0"},
+		{handlers.RedisListCommandBLMove, "src", "dst", "RIGHT", "LEFT", "0"},
+	} {
+		exec(argv...)
+		used := srv.Databases[0].UsedMemory()
+		srv.Databases[0].RebuildMemoryUsage()
+		if rebuilt := srv.Databases[0].UsedMemory(); used != rebuilt {
+			t.Fatalf("%v dataset memory drift, got %d expect %d", argv, used, rebuilt)
+		}
+	}
+}
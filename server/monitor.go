@@ -0,0 +1,150 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/**
+MONITOR:
+	MONITOR客户端会收到服务器执行的每一条命令, 格式和redis保持一致:
+		+1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+	AUTH、HELLO命令中的密码会被隐藏，带有'M'或者'a'标志的命令不会发送给MONITOR客户端。
+	没有MONITOR客户端的时候只需要一次原子操作，不会影响命令的执行。
+	执行命令的goroutine只把命令放入每个MONITOR客户端自己的队列中, 由MONITOR客户端的goroutine写入回复并发送,
+	慢的MONITOR客户端不会阻塞命令的执行。队列满了的时候和超过输出缓冲区限制一样关闭MONITOR客户端。
+*/
+const (
+	MonitorRedactedArgument = "(redacted)"
+	MonitorQueueSize        = 1024 /* 每个MONITOR客户端等待发送的命令的个数上限 */
+)
+
+type monitorClient struct {
+	c        *client.Client
+	msgs     chan string   // 等待发送给MONITOR客户端的命令
+	done     chan struct{} // 发送命令的goroutine退出之后关闭
+	overflow int32         // 队列已经满了, 客户端正在被关闭
+}
+
+// MONITOR
+func (srv *Server) Monitor(cli *client.Client) {
+	// 已经是slave或者monitor的客户端直接忽略
+	if cli.Flags&(client.RedisClientSlave|client.RedisClientMonitor) != 0 {
+		return
+	}
+	cli.Flags |= client.RedisClientMonitor
+	// 先写入OK, 保证OK在所有的命令之前发送
+	cli.ResponseOK()
+	mc := &monitorClient{c: cli, msgs: make(chan string, MonitorQueueSize), done: make(chan struct{})}
+	srv.monitorsLock.Lock()
+	srv.monitors[cli.ID()] = mc
+	atomic.StoreInt32(&srv.monitorCount, int32(len(srv.monitors)))
+	srv.monitorsLock.Unlock()
+	go mc.writeLoop()
+}
+
+// 把队列中的命令发送给MONITOR客户端, 每次把队列中已经有的命令一起发送
+func (mc *monitorClient) writeLoop() {
+	defer close(mc.done)
+	for msg := range mc.msgs {
+		mc.c.ResponseStatus(msg)
+		for pending := len(mc.msgs); pending > 0; pending-- {
+			mc.c.ResponseStatus(<-mc.msgs)
+		}
+		mc.c.Flush()
+	}
+}
+
+// 客户端断开连接的时候从monitors中删除
+func (srv *Server) removeMonitor(cli *client.Client) {
+	if cli.Flags&client.RedisClientMonitor == 0 {
+		return
+	}
+	srv.monitorsLock.Lock()
+	mc, ok := srv.monitors[cli.ID()]
+	delete(srv.monitors, cli.ID())
+	atomic.StoreInt32(&srv.monitorCount, int32(len(srv.monitors)))
+	srv.monitorsLock.Unlock()
+	if !ok {
+		return
+	}
+	// 关闭连接避免发送命令的goroutine阻塞在写socket上, 等它退出之后才能释放客户端
+	close(mc.msgs)
+	cli.CloseConn()
+	<-mc.done
+}
+
+// 隐藏AUTH以及HELLO AUTH中的用户名和密码
+func monitorRedactArgv(argv [][]byte) []string {
+	ret := util.BytesToStrings(argv)
+	switch strings.ToUpper(ret[0]) {
+	case handlers.RedisConnectionCommandAuth:
+		for i := 1; i < len(ret); i++ {
+			ret[i] = MonitorRedactedArgument
+		}
+	case handlers.RedisConnectionCommandHello:
+		// HELLO [protover [AUTH username password] [SETNAME clientname]]
+		for i := 2; i < len(ret); i++ {
+			if strings.ToUpper(ret[i]) == "AUTH" {
+				for j := i + 1; j < len(ret) && j <= i+2; j++ {
+					ret[j] = MonitorRedactedArgument
+				}
+				i += 2
+			}
+		}
+	}
+	return ret
+}
+
+// 生成发送给MONITOR客户端的内容
+func monitorFormatCommand(c *client.Client, dbID int, argv [][]byte, now time.Time) string {
+	var source string
+	if c.IsFakeClient() {
+		// 从AOF中加载的命令
+		source = fmt.Sprintf("%d lua", dbID)
+	} else if c.Flags&client.RedisClientMaster != 0 {
+		source = fmt.Sprintf("%d master", dbID)
+	} else {
+		source = fmt.Sprintf("%d %s", dbID, c.RemoteAddr().String())
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%d.%06d [%s]", now.Unix(), now.Nanosecond()/1000, source))
+	for _, arg := range monitorRedactArgv(argv) {
+		builder.WriteByte(' ')
+		builder.WriteString(util.QuoteRepr(arg))
+	}
+	return builder.String()
+}
+
+// 把客户端执行的命令发送给所有的MONITOR客户端
+func (srv *Server) feedMonitors(c *client.Client, cmd *client.Command) {
+	if atomic.LoadInt32(&srv.monitorCount) == 0 {
+		return
+	}
+	if cmd.Flags&(client.RedisCmdSkipMonitor|client.RedisCmdAdmin) != 0 || len(c.Argv) == 0 {
+		return
+	}
+	msg := monitorFormatCommand(c, c.SelectedDatabase().GetID(), c.Argv, time.Now())
+
+	srv.monitorsLock.RLock()
+	defer srv.monitorsLock.RUnlock()
+	for _, mc := range srv.monitors {
+		select {
+		case mc.msgs <- msg:
+		default:
+			// MONITOR客户端读取得太慢, 关闭连接之后IOLoop会释放客户端
+			if atomic.CompareAndSwapInt32(&mc.overflow, 0, 1) {
+				loggers.Warn("Client %d scheduled to be closed ASAP for overcoming of monitor queue limit.", mc.c.ID())
+				atomic.AddInt64(&srv.statObufLimitDisconns, 1)
+				mc.c.CloseConn()
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/handlers"
+)
+
+func TestMonitorRedactArgv(t *testing.T) {
+	ret := monitorRedactArgv(newTestArgv("auth", "user", "password"))
+	if ret[0] != "auth" || ret[1] != MonitorRedactedArgument || ret[2] != MonitorRedactedArgument {
+		t.Fatalf("auth arguments should be redacted, got %+v", ret)
+	}
+	ret = monitorRedactArgv(newTestArgv("HELLO", "3", "AUTH", "user", "password", "SETNAME", "name"))
+	expected := []string{"HELLO", "3", "AUTH", MonitorRedactedArgument, MonitorRedactedArgument, "SETNAME", "name"}
+	for i := range expected {
+		if ret[i] != expected[i] {
+			t.Fatalf("expect %+v, got %+v", expected, ret)
+		}
+	}
+	ret = monitorRedactArgv(newTestArgv("SET", "key", "value"))
+	if ret[1] != "key" || ret[2] != "value" {
+		t.Fatalf("set arguments should not be redacted, got %+v", ret)
+	}
+}
+
+func TestMonitorFormatCommand(t *testing.T) {
+	now := time.Unix(1339518083, 107412000)
+	msg := monitorFormatCommand(client.NewFakeClient(), 1, newTestArgv("set", "key", "a \"b\""), now)
+	if msg != `1339518083.107412 [1 lua] "set" "key" "a \"b\""` {
+		t.Fatalf("unexpected monitor message %s", msg)
+	}
+}
+
+func TestFeedMonitorsSlowClient(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	// 不读取monitor的回复, 模拟一个很慢的MONITOR客户端
+	monitor, monitorPeer := newTestClient(1)
+	defer monitorPeer.Close()
+	srv.Monitor(monitor)
+	c, peer := newTestClient(2)
+	defer peer.Close()
+	c.Argv, c.Argc = newTestArgv(handlers.RedisStringCommandSet, "key", "value"), 3
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < MonitorQueueSize*2; i++ {
+			srv.feedMonitors(c, srv.commandTable[handlers.RedisStringCommandSet])
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow monitor should not block the command")
+	}
+	if srv.statObufLimitDisconns != 1 {
+		t.Fatalf("slow monitor should be closed once, got %d", srv.statObufLimitDisconns)
+	}
+
+	removed := make(chan struct{})
+	go func() {
+		srv.removeMonitor(monitor)
+		close(removed)
+	}()
+	select {
+	case <-removed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("remove monitor should wait the writer to exit")
+	}
+	if srv.monitorCount != 0 {
+		t.Fatalf("expect no monitor, got %d", srv.monitorCount)
+	}
+}
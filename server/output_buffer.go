@@ -0,0 +1,56 @@
+package server
+
+import (
+	"sync/atomic"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/loggers"
+)
+
+/**
+client-output-buffer-limit:
+	和redis一样把客户端分为normal、replica、pubsub三类, 每一类都有hard limit以及soft limit:
+		输出缓冲区超过hard limit的时候立即关闭客户端;
+		输出缓冲区持续超过soft limit的时间超过soft seconds的时候关闭客户端。
+	和redis一样在写入回复的时候进行检查, 一个很大的回复超过hard limit之后也不会继续写入,
+	超过限制的客户端会丢弃输出缓冲区中的数据并关闭连接, 客户端的IOLoop读取失败之后再异步的释放客户端。
+	客户端的类型由客户端自己的goroutine在每条命令执行之后更新, master客户端不受限制。
+*/
+
+// 把配置中出现的类型更新到obufLimits中, 并把配置规范化为包含所有类型的格式, 和CONFIG GET的输出保持一致
+func (srv *Server) updateClientOutputBufferLimits() {
+	if srv.obufLimits == nil {
+		srv.obufLimits = make([]conf.ClientBufferLimit, conf.RedisObufClassCount)
+		conf.ParseClientOutputBufferLimit(conf.RedisDefaultClientOutputBufferLimit, srv.obufLimits)
+	}
+	if err := conf.ParseClientOutputBufferLimit(srv.Config.ClientOutputBufferLimit, srv.obufLimits); err != nil {
+		loggers.Warn("invalid client-output-buffer-limit %q: %s", srv.Config.ClientOutputBufferLimit, err)
+	}
+	srv.Config.ClientOutputBufferLimit = conf.FormatClientOutputBufferLimit(srv.obufLimits)
+}
+
+// 客户端写入回复的时候获取输出缓冲区的限制, 写入回复的可能是其他的goroutine, 只能根据类型查找
+func (srv *Server) getClientOutputBufferLimit(class int) conf.ClientBufferLimit {
+	return srv.obufLimits[class]
+}
+
+// 客户端因为输出缓冲区超过限制被关闭, 可能在其他的goroutine中调用, 不能读取客户端的状态
+func (srv *Server) clientOutputBufferLimitReached(c *client.Client) {
+	atomic.AddInt64(&srv.statObufLimitDisconns, 1)
+	loggers.Warn("Client id=%d addr=%s scheduled to be closed ASAP for overcoming of output buffer limits.", c.ID(), c.RemoteAddr())
+}
+
+// 客户端在client-output-buffer-limit中的类型, monitor客户端属于normal, master客户端不受限制返回-1
+func getClientObufClass(c *client.Client) int {
+	if c.Flags&client.RedisClientMaster != 0 {
+		return -1
+	}
+	switch getClientType(c) {
+	case RedisClientTypeSlave:
+		return conf.RedisObufClassReplica
+	case RedisClientTypePubSub:
+		return conf.RedisObufClassPubSub
+	}
+	return conf.RedisObufClassNormal
+}
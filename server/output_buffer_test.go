@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/conf"
+)
+
+func TestCheckOutputBufferLimit(t *testing.T) {
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	c.Response(strings.Repeat("a", 100))
+	limit := conf.ClientBufferLimit{HardLimitBytes: 1024, SoftLimitBytes: 64, SoftLimitSeconds: 10}
+
+	now := time.Now()
+	if c.CheckOutputBufferLimit(limit, now) {
+		t.Fatal("soft limit should not be reached at the first time")
+	}
+	if c.CheckOutputBufferLimit(limit, now.Add(5*time.Second)) {
+		t.Fatal("soft limit should not be reached before soft seconds")
+	}
+	if !c.CheckOutputBufferLimit(limit, now.Add(11*time.Second)) {
+		t.Fatal("soft limit should be reached after soft seconds")
+	}
+	limit.SoftLimitBytes = 0
+	if c.CheckOutputBufferLimit(limit, now) {
+		t.Fatal("soft limit 0 means no limit")
+	}
+	limit.HardLimitBytes = 64
+	if !c.CheckOutputBufferLimit(limit, now) {
+		t.Fatal("hard limit should be reached")
+	}
+}
+
+func TestClientOutputBufferLimitDisconnect(t *testing.T) {
+	config := conf.NewServerConfig()
+	config.ClientOutputBufferLimit = "normal 1kb 0 0"
+	srv := NewServer(config)
+	if srv.Config.ClientOutputBufferLimit != "normal 1024 0 0 replica 268435456 67108864 60 pubsub 33554432 8388608 60" {
+		t.Fatalf("client-output-buffer-limit should be normalized, got %s", srv.Config.ClientOutputBufferLimit)
+	}
+
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	c.SetOutputBufferLimitHook(srv.getClientOutputBufferLimit, func() {
+		srv.clientOutputBufferLimitReached(c)
+	})
+	c.SetOutputBufferClass(getClientObufClass(c))
+	// 写入回复的时候检查限制, 不需要等到发送的时候
+	c.Response(strings.Repeat("a", 2048))
+	if !c.IsCloseAsap() || c.OutputBuffered() != 0 {
+		t.Fatal("client should be closed asap and output buffer should be discarded")
+	}
+	if _, err := peer.Read(make([]byte, 1)); err == nil {
+		t.Fatal("connection should be closed")
+	}
+	if srv.statObufLimitDisconns != 1 {
+		t.Fatalf("expect 1 disconnection, got %d", srv.statObufLimitDisconns)
+	}
+}
+
+func TestSlowSubscriberDisconnected(t *testing.T) {
+	config := conf.NewServerConfig()
+	config.ClientOutputBufferLimit = "pubsub 64kb 0 0"
+	srv := NewServer(config)
+
+	// 订阅之后不再读取消息, 模拟一个很慢的订阅者
+	subscriber, subscriberPeer := net.Pipe()
+	defer subscriberPeer.Close()
+	go srv.IOLoop(subscriber)
+	subscriberPeer.Write([]byte("*2\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n"))
+	reader := bufio.NewReader(subscriberPeer)
+	for i := 0; i < 6; i++ {
+		reader.ReadString('\n')
+	}
+
+	publisher, publisherPeer := net.Pipe()
+	defer publisherPeer.Close()
+	go srv.IOLoop(publisher)
+	publisherReader := bufio.NewReader(publisherPeer)
+	message := strings.Repeat("m", 1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			fmt.Fprintf(publisherPeer, "*3\r\n$7\r\nPUBLISH\r\n$4\r\nnews\r\n$%d\r\n%s\r\n", len(message), message)
+			publisherReader.ReadString('\n')
+		}
+		// 其他客户端仍然可以查看订阅者的输出缓冲区
+		publisherPeer.Write([]byte("*2\r\n$6\r\nCLIENT\r\n$4\r\nLIST\r\n"))
+		publisherReader.ReadString('\n')
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow subscriber should not block PUBLISH")
+	}
+	if n := atomic.LoadInt64(&srv.statObufLimitDisconns); n != 1 {
+		t.Fatalf("slow subscriber should be closed once, got %d", n)
+	}
+	subscriberPeer.Close()
+	publisherPeer.Close()
+	waitClientsRemoved(t, srv)
+	// 断开连接的订阅者不再留在频道中
+	if n := srv.PubSubChannels["news"].ListLength(); n != 0 {
+		t.Fatalf("disconnected subscriber should be unsubscribed, got %d subscribers", n)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/loggers"
 	"github.com/SwanSpouse/redis_go/raw_type"
+	"github.com/SwanSpouse/redis_go/tcp"
 	"strings"
 )
 
@@ -39,7 +40,7 @@ func (srv *Server) Publish(cli *client.Client) {
 	srv.PubSubLock.RLock()
 	defer srv.PubSubLock.RUnlock()
 
-	receivers := srv.publishMessage(cli.Argv[1], cli.Argv[2])
+	receivers := srv.publishMessage(string(cli.Argv[1]), string(cli.Argv[2]))
 	cli.Response(receivers)
 }
 
@@ -48,7 +49,7 @@ func (srv *Server) PSubscribe(cli *client.Client) {
 	defer srv.PubSubLock.RUnlock()
 
 	for i := 1; i < len(cli.Argv); i++ {
-		srv.subscribePattern(cli, cli.Argv[i])
+		srv.subscribePattern(cli, string(cli.Argv[i]))
 	}
 }
 
@@ -60,7 +61,7 @@ func (srv *Server) PUnsubscribe(cli *client.Client) {
 		srv.unsubscribeAllPatterns(cli, true)
 	} else {
 		for i := 1; i < len(cli.Argv); i++ {
-			srv.unsubscribePattern(cli, cli.Argv[i], true)
+			srv.unsubscribePattern(cli, string(cli.Argv[i]), true)
 		}
 	}
 }
@@ -71,7 +72,7 @@ func (srv *Server) Subscribe(cli *client.Client) {
 	defer srv.PubSubLock.Unlock()
 
 	for i := 1; i < len(cli.Argv); i++ {
-		srv.subscribe(cli, cli.Argv[i])
+		srv.subscribe(cli, string(cli.Argv[i]))
 	}
 }
 
@@ -84,7 +85,7 @@ func (srv *Server) Unsubscribe(cli *client.Client) {
 		srv.unsubscribeAllChannels(cli)
 	} else {
 		for i := 1; i < len(cli.Argv); i++ {
-			srv.unsubscribe(cli, cli.Argv[i])
+			srv.unsubscribe(cli, string(cli.Argv[i]))
 		}
 	}
 }
@@ -93,7 +94,7 @@ func (srv *Server) PubSub(cli *client.Client) {
 	srv.PubSubLock.RLock()
 	defer srv.PubSubLock.RUnlock()
 
-	switch strings.ToUpper(cli.Argv[1]) {
+	switch strings.ToUpper(string(cli.Argv[1])) {
 	case RedisPubSubCommandPubSubCommandChannels:
 		srv.pubSubCommandChannels(cli)
 	case RedisPubSubCommandPubSubCommandNumSub:
@@ -101,11 +102,38 @@ func (srv *Server) PubSub(cli *client.Client) {
 	case RedisPubSubCommandPubSubNumPat:
 		srv.pubSunCommandNumPat(cli)
 	default:
-		cli.ResponseReError(re.ErrPubSubCommand, cli.Argv[1])
+		cli.ResponseReError(re.ErrPubSubCommand, string(cli.Argv[1]))
 	}
 	cli.Flush()
 }
 
+// 客户端断开连接的时候取消所有的订阅, 不需要回复客户端
+func (srv *Server) pubSubRemoveClient(cli *client.Client) {
+	if cli.PubSubChannels.Size()+cli.PubSubPatterns.ListLength() == 0 {
+		return
+	}
+	srv.PubSubLock.Lock()
+	defer srv.PubSubLock.Unlock()
+
+	for channelName := range cli.PubSubChannels.KeySet() {
+		if clients, ok := srv.PubSubChannels[channelName.(string)]; ok {
+			if node := clients.ListSearchKey(cli); node != nil {
+				clients.ListRemoveNode(node)
+			}
+		}
+	}
+	cli.PubSubChannels.Clear()
+	iterator := raw_type.ListGetIterator(srv.PubSubPatterns, raw_type.RedisListIteratorDirectionStartHead)
+	for item := iterator.ListNext(); item != nil; {
+		nextItem := iterator.ListNext()
+		if item.NodeValue().(*PubSubPattern).Cli == cli {
+			srv.PubSubPatterns.ListRemoveNode(item)
+		}
+		item = nextItem
+	}
+	cli.PubSubPatterns = raw_type.ListCreate()
+}
+
 // 发送消息
 func (srv *Server) publishMessage(channelName string, message string) int {
 	var receivers int
@@ -114,12 +142,12 @@ func (srv *Server) publishMessage(channelName string, message string) int {
 		iterator := raw_type.ListGetIterator(clients, raw_type.RedisListIteratorDirectionStartHead)
 		subClient := iterator.ListNext()
 		for subClient != nil {
-			responseSlice := make([]interface{}, 3)
+			responseSlice := make(tcp.PushReply, 3)
 			responseSlice[0] = PubSubResponseStringMessage
 			responseSlice[1] = channelName
 			responseSlice[2] = message
-			subClient.NodeValue().(*client.Client).Response(responseSlice)
-			subClient.NodeValue().(*client.Client).Flush()
+			// 只写入订阅者的输出缓冲区, 由订阅者自己发送, 读取得很慢的订阅者不会阻塞PUBLISH
+			subClient.NodeValue().(*client.Client).PushResponse(responseSlice)
 
 			subClient = iterator.ListNext()
 			receivers += 1
@@ -132,13 +160,12 @@ func (srv *Server) publishMessage(channelName string, message string) int {
 	for item != nil {
 		pubSubItem := item.NodeValue().(*PubSubPattern)
 		if pubSubItem.Exp.Match([]byte(channelName)) {
-			responseSlice := make([]interface{}, 4)
+			responseSlice := make(tcp.PushReply, 4)
 			responseSlice[0] = PubSubResponseStringMessage
 			responseSlice[1] = pubSubItem.Pattern
 			responseSlice[2] = channelName
 			responseSlice[3] = message
-			pubSubItem.Cli.Response(responseSlice)
-			pubSubItem.Cli.Flush()
+			pubSubItem.Cli.PushResponse(responseSlice)
 
 			receivers += 1
 		}
@@ -160,7 +187,7 @@ func (srv *Server) subscribe(cli *client.Client, channelName string) int {
 			srv.PubSubChannels[channelName].ListAddNodeTail(cli)
 		}
 	}
-	responseSlice := make([]interface{}, 3)
+	responseSlice := make(tcp.PushReply, 3)
 	responseSlice[0] = PubSubResponseStringSubscribe
 	responseSlice[1] = channelName
 	responseSlice[2] = cli.PubSubChannels.Size() + cli.PubSubPatterns.ListLength()
@@ -191,7 +218,7 @@ func (srv *Server) unsubscribe(cli *client.Client, channelName string) int {
 			}
 		}
 	}
-	responseSlice := make([]interface{}, 3)
+	responseSlice := make(tcp.PushReply, 3)
 	responseSlice[0] = PubSubResponseStringUnsubscribe
 	responseSlice[1] = channelName
 	responseSlice[2] = cli.PubSubChannels.Size() + cli.PubSubPatterns.ListLength()
@@ -224,7 +251,7 @@ func (srv *Server) subscribePattern(cli *client.Client, pattern string) int {
 			Exp:     exp,
 		})
 	}
-	responseSlice := make([]interface{}, 3)
+	responseSlice := make(tcp.PushReply, 3)
 	responseSlice[0] = PubSubResponseStringPSubscribe
 	responseSlice[1] = pattern
 	responseSlice[2] = cli.PubSubChannels.Size() + cli.PubSubPatterns.ListLength()
@@ -259,7 +286,7 @@ func (srv *Server) unsubscribePattern(cli *client.Client, pattern string, notify
 		cli.PubSubPatterns.ListRemoveNode(node)
 	}
 	if notifyClient {
-		responseSlice := make([]interface{}, 3)
+		responseSlice := make(tcp.PushReply, 3)
 		responseSlice[0] = PubSubResponseStringPUnsubscribe
 		responseSlice[1] = pattern
 		responseSlice[2] = cli.PubSubChannels.Size() + cli.PubSubPatterns.ListLength()
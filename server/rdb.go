@@ -1,6 +1,8 @@
 package server
 
 import (
+	"strconv"
+
 	"github.com/SwanSpouse/redis_go/client"
 	"github.com/SwanSpouse/redis_go/handlers"
 	"github.com/SwanSpouse/redis_go/loggers"
@@ -33,7 +35,7 @@ func (srv *Server) ResizeDatabase(dbSize, expiresSize uint32) {
 
 func (srv *Server) Set(key, value []byte, expiry int64) {
 	loggers.Info("rdb process set key:%s value:%s", key, value)
-	srv.FakeClient.Argv = []string{handlers.RedisStringCommandSet, string(key), string(value)}
+	srv.FakeClient.Argv = [][]byte{[]byte(handlers.RedisStringCommandSet), key, value}
 	srv.commandTable[handlers.RedisStringCommandSet].Proc(srv.FakeClient)
 }
 
@@ -43,7 +45,7 @@ func (srv *Server) StartHash(key []byte, length, expiry int64) {
 
 func (srv *Server) Hset(key, field, value []byte) {
 	loggers.Info("rdb process HSet key:%s field:%s, value:%s", key, field, value)
-	srv.FakeClient.Argv = []string{handlers.RedisHashCommandHSet, string(key), string(field), string(value)}
+	srv.FakeClient.Argv = [][]byte{[]byte(handlers.RedisHashCommandHSet), key, field, value}
 	srv.commandTable[handlers.RedisHashCommandHSet].Proc(srv.FakeClient)
 }
 
@@ -57,7 +59,8 @@ func (srv *Server) StartSet(key []byte, cardinality, expiry int64) {
 
 func (srv *Server) Sadd(key, member []byte) {
 	loggers.Info("rdb process SAdd key:%s, member:%s", key, member)
-	// TODO lmj
+	srv.FakeClient.Argv = [][]byte{[]byte(handlers.RedisSetCommandSADD), key, member}
+	srv.commandTable[handlers.RedisSetCommandSADD].Proc(srv.FakeClient)
 }
 
 func (srv *Server) EndSet(key []byte) {
@@ -70,7 +73,7 @@ func (srv *Server) StartList(key []byte, length, expiry int64) {
 
 func (srv *Server) Rpush(key, value []byte) {
 	loggers.Info("rdb process RPush key:%s value%s", key, value)
-	srv.FakeClient.Argv = []string{handlers.RedisListCommandRPush, string(key), string(value)}
+	srv.FakeClient.Argv = [][]byte{[]byte(handlers.RedisListCommandRPush), key, value}
 	srv.commandTable[handlers.RedisListCommandRPush].Proc(srv.FakeClient)
 }
 
@@ -85,7 +88,8 @@ func (srv *Server) StartZSet(key []byte, cardinality, expiry int64) {
 
 func (srv *Server) Zadd(key []byte, score float64, member []byte) {
 	loggers.Info("rdb process ZAdd key:%s", key)
-	// TODO lmj
+	srv.FakeClient.Argv = [][]byte{[]byte(handlers.RedisSortedSetCommandZAdd), key, []byte(strconv.FormatFloat(score, 'g', -1, 64)), member}
+	srv.commandTable[handlers.RedisSortedSetCommandZAdd].Proc(srv.FakeClient)
 }
 
 func (srv *Server) EndZSet(key []byte) {
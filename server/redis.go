@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 
 	"github.com/SwanSpouse/redis_go/conf"
 	"github.com/SwanSpouse/redis_go/loggers"
@@ -20,18 +21,50 @@ func redisGoFlagSet(opts *conf.ServerConfig) *flag.FlagSet {
 
 	flagSet.Int("sentinel-mode", opts.SentinelMode, "is sentinel mode")
 	flagSet.Int64("log-level", opts.LogLevel, "System Log Level")
-	flagSet.Duration("timeout", opts.Timeout, "timeout")
+	flagSet.Int64("timeout", opts.Timeout, "close the connection after a client is idle for N seconds, 0 disables the timeout")
 
 	flagSet.Int("port", opts.Port, "port")
 	flagSet.String("addr", opts.BindAddr, "addr")
 	flagSet.Int("reader-pool-size", opts.ReaderPoolSize, "reader-pool-size")
 	flagSet.Int("writer-pool-size", opts.WriterPoolSize, "writer-pool-size")
+	flagSet.Int("tcp-keepalive", opts.TCPKeepAlive, "send TCP ACKs to clients every this many seconds, 0 disables keepalive")
+	flagSet.Bool("tcp-nodelay", opts.TCPNoDelay, "disable Nagle's algorithm on client connections")
 
 	flagSet.Int("verbosity", opts.Verbosity, "verbosity")
-	flagSet.Int64("max-idle-time", opts.MaxIdleTime, "max-idle-time")
 	flagSet.Int("db-num", opts.DBNum, "db-num")
 
 	flagSet.Int64("client-max-query-buf-len", opts.ClientMaxQueryBufLen, "client-max-query-buf-len")
+	flagSet.Int64("proto-max-bulk-len", opts.ProtoMaxBulkLen, "max size of a single bulk in requests")
+
+	flagSet.Int("maxclients", opts.MaxClients, "max number of connected clients at the same time")
+	flagSet.Int64("maxmemory", opts.MaxMemory, "max number of memory bytes to use, 0 means no limit")
+	flagSet.String("maxmemory-policy", opts.MaxMemoryPolicy, "how to select what to remove when maxmemory is reached")
+	flagSet.Int("maxmemory-samples", opts.MaxMemorySamples, "number of keys sampled by LRU, LFU and minimal TTL algorithms")
+	flagSet.Int("lfu-log-factor", opts.LFULogFactor, "")
+	flagSet.Int("lfu-decay-time", opts.LFUDecayTime, "")
+
+	flagSet.Int("list-max-listpack-size", opts.ListMaxListPackSize, "max entries (positive) or max bytes (-1 to -5 for 4KB to 64KB) of each list listpack node")
+	flagSet.Int("list-compress-depth", opts.ListCompressDepth, "number of list quicklist nodes at each end that are not compressed, 0 disables compression")
+
+	flagSet.Int("hash-max-listpack-entries", opts.HashMaxListPackEntries, "max number of fields of a hash encoded as listpack")
+	flagSet.Int("hash-max-listpack-value", opts.HashMaxListPackValue, "max length of fields and values of a hash encoded as listpack")
+
+	flagSet.Int("set-max-intset-entries", opts.SetMaxIntSetEntries, "max number of members of a set encoded as intset")
+
+	flagSet.Int("zset-max-listpack-entries", opts.ZSetMaxListPackEntries, "max number of members of a sorted set encoded as listpack")
+	flagSet.Int("zset-max-listpack-value", opts.ZSetMaxListPackValue, "max length of members of a sorted set encoded as listpack")
+
+	flagSet.Bool("latency-tracking", opts.LatencyTracking, "enable per command latency histograms")
+	flagSet.String("latency-tracking-info-percentiles", opts.LatencyTrackingInfoPercentiles, "percentiles exposed by INFO latencystats")
+
+	flagSet.Int64("slowlog-log-slower-than", opts.SlowLogLogSlowerThan, "log commands slower than this many microseconds, negative disables the slow log")
+	flagSet.Int("slowlog-max-len", opts.SlowLogMaxLen, "max number of entries kept in the slow log")
+
+	flagSet.Int64("latency-monitor-threshold", opts.LatencyMonitorThreshold, "record events slower than this many milliseconds, 0 disables the latency monitor")
+
+	flagSet.Duration("shutdown-timeout", opts.ShutdownTimeout, "max time to wait for replicas to catch up when shutting down")
+
+	flagSet.String("client-output-buffer-limit", opts.ClientOutputBufferLimit, "disconnect clients whose output buffer exceeds the limits of their class")
 
 	flagSet.Int("aof-state", opts.AofState, "aof switch default off")
 	flagSet.String("aof-fsync", opts.AofFSync, "")
@@ -40,6 +73,7 @@ func redisGoFlagSet(opts *conf.ServerConfig) *flag.FlagSet {
 	flagSet.Int64("dirty", opts.Dirty, "")
 	flagSet.Int64("dirty-before-bg-save", opts.DirtyBeforeBgSave, "")
 	flagSet.String("rdb-filename", opts.RdbFilename, "")
+	flagSet.String("save", opts.Save, "save the DB after <seconds> seconds if at least <changes> write operations happened")
 	return flagSet
 }
 
@@ -69,19 +103,26 @@ func (p *Program) Init() {
 	if configFile != "" {
 		_, err := toml.DecodeFile(configFile, &cfg)
 		if err != nil {
-			loggers.Fatal("failed to load config file")
+			loggers.Fatal("failed to load config file %s: %s", configFile, err)
+		}
+		for _, key := range conf.UnknownConfigKeys(cfg) {
+			loggers.Warn("unknown config option '%s' in %s, ignored", key, configFile)
 		}
 	}
-	// TODO @lmj 在这里需要对配置文件中的配置进行校验，判断输入是否合法
 	options.Resolve(opts, flagSet, cfg)
+	if err := opts.Validate(); err != nil {
+		loggers.Fatal("invalid configuration, %s", err)
+	}
+	if configFile != "" {
+		if absPath, err := filepath.Abs(configFile); err == nil {
+			configFile = absPath
+		}
+		opts.ConfigFile = configFile
+	}
 	loggers.Info("input opts :%+v", opts)
 
 	server := NewServer(opts)
-	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", server.Config.BindAddr, server.Config.Port))
-	if err != nil || listener == nil {
-		os.Exit(1)
-	}
-	server.TcpListener = listener
+	server.TcpListener = server.listen()
 
 	p.Server = server
 }
@@ -95,15 +136,21 @@ func (p *Program) InitForMock(inputConfig *conf.ServerConfig) {
 		opts = inputConfig
 	}
 	server := NewServer(opts)
-	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", server.Config.BindAddr, server.Config.Port))
-	if err != nil || listener == nil {
-		os.Exit(1)
-	}
-	server.TcpListener = listener
+	server.TcpListener = server.listen()
 
 	p.Server = server
 }
 
+// 监听TCP端口, 失败的时候打印原因并退出
+func (srv *Server) listen() net.Listener {
+	addr := fmt.Sprintf("%s:%d", srv.Config.BindAddr, srv.Config.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil || listener == nil {
+		loggers.Fatal("failed to listen on %s: %v", addr, err)
+	}
+	return listener
+}
+
 func (p *Program) Start() {
 	// 依次启动后台服务
 	p.WaitGroup.Wrap(func() {
@@ -115,13 +162,22 @@ func (p *Program) Start() {
 	})
 }
 
+// 收到SIGTERM、SIGINT的时候调用, 和SHUTDOWN命令走相同的流程, 失败的时候服务器继续运行
+func (p *Program) Terminate() error {
+	loggers.Info("Received SIGTERM or SIGINT, scheduling shutdown...")
+	if err := p.prepareForShutdown(RedisShutdownNoFlags, false); err != nil {
+		loggers.Warn("SIGTERM received but errors trying to shut down the server, check the logs for more information")
+		return err
+	}
+	return nil
+}
+
+// 停止后台服务, 数据的持久化在Terminate或者SHUTDOWN命令中完成
 func (p *Program) Stop() {
 	if p.TcpListener != nil {
 		p.TcpListener.Close()
 	}
 
-	// TODO @Lmj 这里要对数据进行持久化
-
 	loggers.Info("REDIS GO: stopping subsystems")
 	close(p.ExitChan)
 	p.WaitGroup.Wait()
@@ -27,29 +27,76 @@ const (
 
 // Redis server
 type Server struct {
-	TcpListener      net.Listener
-	clientIDSequence int64 // client auto increasing sequence id
-	Config           *conf.ServerConfig
-	Databases        []*database.Database     /* database*/
-	dbIndex          int                      // rdb process current db
-	clients          map[int64]*client.Client // clientID -> client
-	FakeClient       *client.Client           // used in rdb and aof
-	password         string                   /* Pass for AUTH command, or NULL */
-	commandTable     map[string]*client.Command
-	mu               sync.RWMutex
-	Status           atomic.Value
-	Dirty            int64
-	rdbLastSave      time.Time
-	aofSelectDBId    int
-	aofLock          sync.Mutex // aof lock
-	aofBuf           []byte     // append only file buffer
-	aofLastSave      time.Time  // aof last save time
-	TimeEventLoop    *EventLoop // redis time event
-	WaitGroup        util.WaitGroupWrapper
-	ExitChan         chan int
-	PubSubLock       sync.RWMutex              // pub sub operation lock
-	PubSubChannels   map[string]*raw_type.List // channels a client is interested in (SUBSCRIBE)
-	PubSubPatterns   *raw_type.List            // patterns a client is interested in (SUBSCRIBE)
+	TcpListener           net.Listener
+	clientIDSequence      int64 // client auto increasing sequence id
+	Config                *conf.ServerConfig
+	Databases             []*database.Database     /* database*/
+	dbIndex               int                      // rdb process current db
+	clients               map[int64]*client.Client // clientID -> client
+	FakeClient            *client.Client           // used in rdb and aof
+	password              string                   /* Pass for AUTH command, or NULL */
+	commandTable          map[string]*client.Command
+	mu                    sync.RWMutex
+	Status                atomic.Value
+	Dirty                 int64
+	rdbLastSave           time.Time
+	aofSelectDBId         int
+	aofLock               sync.Mutex // aof lock
+	aofBuf                []byte     // append only file buffer
+	aofLastSave           time.Time  // aof last save time
+	TimeEventLoop         *EventLoop // redis time event
+	WaitGroup             util.WaitGroupWrapper
+	ExitChan              chan int
+	ShutdownChan          chan struct{}                         // closed when SHUTDOWN finished and the server should exit
+	PubSubLock            sync.RWMutex                          // pub sub operation lock
+	PubSubChannels        map[string]*raw_type.List             // channels a client is interested in (SUBSCRIBE)
+	PubSubPatterns        *raw_type.List                        // patterns a client is interested in (SUBSCRIBE)
+	evictionLock          sync.Mutex                            // maxmemory eviction lock
+	evictionPool          []*evictionPoolEntry                  // maxmemory eviction pool
+	evictionNextDB        int                                   // next db for random eviction policy
+	usedMemorySample      int64                                 // heap memory sampled in server cron
+	statEvictedKeys       int64                                 // number of evicted keys (maxmemory)
+	statPeakMemory        int64                                 // max used memory record
+	startupMemory         int64                                 // used memory after server initialization
+	usedMemoryRss         int64                                 // memory obtained from the OS
+	startTime             time.Time                             // server start time
+	runID                 string                                // ID always different at every exec
+	replID                string                                // my current replication ID
+	statLock              sync.Mutex                            // lock for stats which can not be updated atomically
+	statNumCommands       int64                                 // number of processed commands
+	statNumConnections    int64                                 // number of connections received
+	statRejectedConn      int64                                 // clients rejected because of maxclients
+	statObufLimitDisconns int64                                 // clients closed because of output buffer limits
+	statQbufLimitDisconns int64                                 // clients closed because of query buffer limits
+	statNetInputBytes     int64                                 // bytes read from network
+	statNetOutputBytes    int64                                 // bytes written to network
+	statTotalErrorReplies int64                                 // total number of issued error replies
+	errorStats            map[string]int64                      // error code -> count
+	instMetrics           [StatsMetricCount]instantaneousMetric // instantaneous metrics samples
+	slowLogLock           sync.Mutex                            // slow log lock
+	slowLog               slowLog                               // SLOWLOG ring buffer
+	monitorsLock          sync.RWMutex                          // monitors lock
+	monitors              map[int64]*monitorClient              // clientID -> MONITOR client
+	monitorCount          int32                                 // number of MONITOR clients, checked without lock
+	latencyLock           sync.Mutex                            // latency monitor lock
+	latencyEvents         map[string]*latencyTimeSeries         // event name -> latency samples
+	saveParams            []conf.SaveParam                      // save points array for RDB
+	inflightCommands      int64                                 // number of commands being executed
+	shutdownLock          sync.Mutex                            // shutdown state lock
+	shutdownState         int32                                 // RedisShutdownState*
+	shutdownAbort         chan struct{}                         // closed by SHUTDOWN ABORT
+	shutdownOnce          sync.Once                             // close ShutdownChan only once
+	clientPauseLock       sync.Mutex                            // CLIENT PAUSE lock
+	clientPauseType       int32                                 // RedisClientPause*
+	clientPauseEnd        int64                                 // CLIENT PAUSE end time in unix nano
+	obufLimits            []conf.ClientBufferLimit              // client-output-buffer-limit for each client class
+	trackingLock          sync.Mutex                            // client side caching lock
+	trackingTable         map[string]map[int64]bool             // key -> IDs of the clients that may cache the key
+	trackingPrefixes      map[string]map[int64]bool             // BCAST prefix -> IDs of the clients that subscribed the prefix
+	trackingClients       int64                                 // number of clients with tracking enabled
+	blockingLock          sync.Mutex                            // blocking list commands lock
+	blockingKeys          map[blockingKey]*raw_type.List        // (db, key) -> clients blocked on the key in FIFO order
+	blockedClients        map[int64]*blockedClient              // clientID -> blocked client
 }
 
 func NewServer(config *conf.ServerConfig) *Server {
@@ -59,6 +106,7 @@ func NewServer(config *conf.ServerConfig) *Server {
 		clients:        make(map[int64]*client.Client),
 		TimeEventLoop:  NewEventLoop(),
 		ExitChan:       make(chan int),
+		ShutdownChan:   make(chan struct{}),
 		PubSubChannels: make(map[string]*raw_type.List),
 		PubSubPatterns: raw_type.ListCreate(),
 	}
@@ -84,6 +132,15 @@ func NewServer(config *conf.ServerConfig) *Server {
 	return server
 }
 
+// 根据命令名查找命令, 命令名一般都是大写的, 先直接用参数查找, 不需要为命令名分配内存
+func (srv *Server) lookupCommand(name []byte) (*client.Command, bool) {
+	if cmd, ok := srv.commandTable[string(name)]; ok {
+		return cmd, true
+	}
+	cmd, ok := srv.commandTable[strings.ToUpper(string(name))]
+	return cmd, ok
+}
+
 // 判断server 此时是否可以对外提供服务
 func (srv *Server) isServiceAvailable() bool {
 	return srv.Status.Load() == RedisServerStatusNormal || srv.Status.Load() == RedisServerStatusRdbBgSaveInProcess
@@ -92,25 +149,53 @@ func (srv *Server) isServiceAvailable() bool {
 // 处理来自客户端的请求
 func (srv *Server) IOLoop(conn net.Conn) {
 	loggers.Info("TCP: new client(%s)", conn.RemoteAddr())
-	// TODO lmj srv.clients 有个数限制
 
-	c := client.NewClient(atomic.AddInt64(&srv.clientIDSequence, 1), conn, srv.getDefaultDB())
-	srv.addClient(c)
+	c := client.NewClient(atomic.AddInt64(&srv.clientIDSequence, 1), &statConn{Conn: conn, srv: srv}, srv.getDefaultDB())
+	// 客户端的个数超过了maxclients, 返回错误之后直接关闭连接
+	if !srv.addClient(c) {
+		loggers.Warn("Error accepting a client connection from %s: max number of clients reached", conn.RemoteAddr())
+		atomic.AddInt64(&srv.statRejectedConn, 1)
+		c.ResponseReError(re.ErrMaxClientsReached)
+		c.Flush()
+		c.Close()
+		client.ReturnClient(c)
+		return
+	}
+	atomic.AddInt64(&srv.statNumConnections, 1)
+	c.SetErrorReplyHook(srv.incrErrorReplyStat)
+	c.SetOutputBufferLimitHook(srv.getClientOutputBufferLimit, func() {
+		srv.clientOutputBufferLimitReached(c)
+	})
+	c.SetOutputBufferClass(getClientObufClass(c))
 
 	var err error
 	// handle client command
 	for {
+		// 在读取下一条命令之前发送之前的回复, 写socket的时间不计入命令的执行时间
+		c.Flush()
 		// read command from client
+		c.SetQueryBufferLimits(srv.Config.ProtoMaxBulkLen, srv.Config.ClientMaxQueryBufLen)
 		if err = c.ProcessInputBuffer(); err != nil {
 			if err == io.EOF {
 				err = nil
-				break
-			} else {
+			} else if err == re.ErrQueryBufferLimit {
+				loggers.Warn("Closing client that reached max query buffer length: %s", catClientInfoString(c, time.Now()))
+				atomic.AddInt64(&srv.statQbufLimitDisconns, 1)
+			} else if re.IsProtocolError(err) {
+				// 出现协议错误之后输入缓冲区中的数据已经不完整了, 回复错误之后关闭连接
 				loggers.Errorf("server read command error %+v", err)
 				c.ResponseReError(err)
-				continue
 			}
+			// 连接已经被关闭或者出现了网络错误
+			break
 		}
+		c.UpdateLastInteraction()
+		// 空的inline command以及*0、*-1这样长度小于等于0的multi bulk直接忽略
+		if c.Argc == 0 {
+			continue
+		}
+		// CLIENT REPLY SKIP只跳过下一条命令的回复
+		c.ResetReplySkip()
 		if !srv.isServiceAvailable() {
 			c.ResponseReError(re.ErrRedisRdbSaveInProcess)
 			continue
@@ -120,10 +205,10 @@ func (srv *Server) IOLoop(conn net.Conn) {
 			如果不在command table中,则返回command not found
 			如果在command table中，则获取到相应的command handler来进行处理。
 		*/
-		command, ok := srv.commandTable[strings.ToUpper(c.Argv[0])]
+		command, ok := srv.lookupCommand(c.Argv[0])
 		if !ok || command == nil {
-			loggers.Errorf(string(re.ErrUnknownCommand), c.Argv[0])
-			c.ResponseReError(re.ErrUnknownCommand, c.Argv[0])
+			loggers.Errorf(string(re.ErrUnknownCommand), string(c.Argv[0]))
+			c.ResponseReError(re.ErrUnknownCommand, string(c.Argv[0]))
 			continue
 		}
 		c.LastCmd = c.Cmd
@@ -135,16 +220,55 @@ func (srv *Server) IOLoop(conn net.Conn) {
 		*/
 		if (command.Arity > 0 && c.Argc != command.Arity) || (c.Argc < -command.Arity) {
 			loggers.Errorf("wrong number of args %+v", command)
-			c.ResponseReError(re.ErrWrongNumberOfArgs, c.Argv[0])
+			command.RecordRejectedCall()
+			c.ResponseReError(re.ErrWrongNumberOfArgs, string(c.Argv[0]))
 			continue
 		}
 
+		// 在执行命令之前检查是否超过了maxmemory, 如果无法释放足够的内存则拒绝会增加内存使用的命令
+		if srv.Config.MaxMemory > 0 {
+			if err := srv.freeMemoryIfNeeded(); err != nil && command.Flags&client.RedisCmdDenyOom > 0 {
+				command.RecordRejectedCall()
+				c.ResponseReError(err)
+				continue
+			}
+		}
+
 		// TODO 检查用户是否验证过身份
 		// TODO 集群模式等在这里进行一些操作
 		// TODO 判断是否是事务相关命令
-		// TODO 判断命令造成了多少个dirty, 执行时间等一些统计信息
-		// 在这里对client端发送过来的命令进行处理
+		// SHUTDOWN和CLIENT PAUSE的过程中会暂停执行命令
+		if !srv.beginCommand(c, command) {
+			c.ResponseReError(re.ErrShutdownInProgress)
+			continue
+		}
+		// 把命令发送给MONITOR客户端
+		srv.feedMonitors(c, command)
+
+		// 在这里对client端发送过来的命令进行处理, 并统计命令的执行时间以及是否执行失败
+		errorReplies := c.ErrorReplies
+		c.BlockedTime = 0
+		start := time.Now()
 		command.Proc(c)
+		// 阻塞命令等待的时间不计入执行时间
+		duration := time.Since(start) - c.BlockedTime
+		command.RecordCall(duration, c.ErrorReplies > errorReplies)
+		if srv.Config.LatencyTracking {
+			command.RecordLatency(duration)
+		}
+		srv.slowLogPushEntryIfNeeded(c, duration)
+		srv.latencyAddSampleIfNeeded(LatencyEventCommand, duration)
+		atomic.AddInt64(&srv.statNumCommands, 1)
+		// SUBSCRIBE等命令会改变客户端在client-output-buffer-limit中的类型
+		c.SetOutputBufferClass(getClientObufClass(c))
+
+		// 写命令可能修改了key对应的value，重新估算这些key的内存占用
+		if c.Cmd.Flags&client.RedisCmdWrite > 0 {
+			srv.updateKeysMemory(c)
+		}
+
+		// 写命令修改了key之后给缓存了这些key的客户端发送失效消息, 读命令则记录客户端读取过的key
+		srv.trackingHandleCommand(c)
 
 		// 在rdb save结束之后，重新统计dirty数量并记录本次rdb结束的时间
 		if c.Cmd.GetName() == RedisServerCommandSave {
@@ -159,10 +283,21 @@ func (srv *Server) IOLoop(conn net.Conn) {
 			loggers.Debug("Client exec a write cmd or make db dirty")
 			srv.propagate(c)
 			// 现在默认将每个写命令都刷写到aof文件中
-			srv.flushAppendOnlyFile()
-			c.Dirty = 0
+			srv.flushAppendOnlyFile(false)
+		}
+		// 写命令修改了key之后唤醒阻塞在这些key上的客户端
+		if c.Dirty != 0 && c.Cmd.Flags&client.RedisCmdWrite > 0 {
+			srv.handleClientsBlockedOnKeys(c.SelectedDatabase(), getCommandKeys(c))
+		}
+		c.Dirty = 0
+		srv.endCommand()
+		// CLIENT KILL杀掉自己的时候在回复之后关闭连接, 输出缓冲区超过限制的客户端不再处理命令
+		if c.Flags&client.RedisClientCloseAfterReply != 0 || c.IsCloseAsap() {
+			break
 		}
 	}
+	// 协议错误以及CLIENT KILL杀掉自己的时候需要在关闭连接之前发送回复
+	c.Flush()
 	loggers.Info("client %d-%s exiting ioLoop", c.ID(), c.RemoteAddr())
 	if err != nil {
 		loggers.Errorf("client %d %s", c.ID(), err)
@@ -188,33 +323,83 @@ func (srv *Server) TCPServe() {
 			}
 			break
 		}
+		srv.setTCPOptions(clientConn)
 		go srv.IOLoop(clientConn)
 	}
 	loggers.Info("TCP: closing %s", srv.TcpListener.Addr())
 }
 
-func (srv *Server) addClient(c *client.Client) {
+// 根据配置设置TCP连接的keepalive以及TCP_NODELAY
+func (srv *Server) setTCPOptions(conn net.Conn) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if err := tcpConn.SetNoDelay(srv.Config.TCPNoDelay); err != nil {
+		loggers.Warn("Error setting TCP_NODELAY on %s: %s", conn.RemoteAddr(), err)
+	}
+	if srv.Config.TCPKeepAlive > 0 {
+		tcpConn.SetKeepAlive(true)
+		tcpConn.SetKeepAlivePeriod(time.Duration(srv.Config.TCPKeepAlive) * time.Second)
+	} else {
+		tcpConn.SetKeepAlive(false)
+	}
+}
+
+// 返回false表示客户端的个数已经达到了maxclients
+func (srv *Server) addClient(c *client.Client) bool {
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
 
+	if len(srv.clients) >= srv.Config.MaxClients {
+		return false
+	}
 	srv.clients[c.ID()] = c
+	return true
 }
 
 func (srv *Server) removeClient(c *client.Client) {
+	srv.removeMonitor(c)
+	srv.disableTracking(c)
+	srv.pubSubRemoveClient(c)
+
+	// 先从clients中删除, 客户端放回pool之后可能马上被新的连接复用并修改ID
 	srv.mu.Lock()
-	defer srv.mu.Unlock()
+	delete(srv.clients, c.ID())
+	srv.mu.Unlock()
 	c.Close()
 	client.ReturnClient(c)
-	delete(srv.clients, c.ID())
 }
 
 func (srv *Server) initServer() {
 	srv.Status.Store(RedisServerStatusNormal)
+	srv.startTime = time.Now()
+	srv.runID = util.GetRandomHexChars(40)
+	srv.replID = util.GetRandomHexChars(40)
+	srv.errorStats = make(map[string]int64)
+	srv.monitors = make(map[int64]*monitorClient)
+	srv.trackingTable = make(map[string]map[int64]bool)
+	srv.trackingPrefixes = make(map[string]map[int64]bool)
+	srv.blockingKeys = make(map[blockingKey]*raw_type.List)
+	srv.blockedClients = make(map[int64]*blockedClient)
+	srv.latencyEvents = make(map[string]*latencyTimeSeries)
 	srv.aofSelectDBId = -1
 	if srv.Config.AofState == conf.RedisAofOn {
 		srv.aofBuf = make([]byte, 0)
 	}
-	loggers.Level = srv.Config.LogLevel
+	loggers.SetLevel(srv.Config.LogLevel)
+	srv.saveParams, _ = conf.ParseSaveParams(srv.Config.Save)
+	srv.updateClientOutputBufferLimits()
+
+	// init maxmemory eviction pool
+	srv.evictionPool = make([]*evictionPoolEntry, EvictionPoolSize)
+	for i := range srv.evictionPool {
+		srv.evictionPool[i] = &evictionPoolEntry{}
+	}
+	srv.updateEvictionPolicy()
+	srv.updateEncodingConfig()
+	srv.sampleUsedMemory()
+	srv.startupMemory = srv.usedMemory()
 }
 
 func (srv *Server) initDB() {
@@ -222,6 +407,10 @@ func (srv *Server) initDB() {
 	srv.Databases = make([]*database.Database, srv.Config.DBNum)
 	for i := 0; i < srv.Config.DBNum; i++ {
 		srv.Databases[i] = database.NewDatabase(i)
+		// 过期被删除的key需要通知开启了tracking的客户端
+		srv.Databases[i].SetExpiredKeyHook(func(key string) {
+			srv.trackingInvalidateKey(nil, key)
+		})
 	}
 }
 
@@ -277,6 +466,8 @@ func (srv *Server) loadDataFromDisk() {
 		loggers.Info("redis rdb start to load data from disk at %s", startTime.Format("20060102 15:04:05"))
 		srv.rdbLoad()
 	}
+	// 加载数据的过程中会原地修改value，重新统计数据库的内存占用
+	srv.rebuildDatasetMemory()
 }
 
 /**
@@ -290,4 +481,50 @@ ServerCron主要负责一下工作:
 	如果处于集群模式的话，对集群进行定期同步和连接测试。
 */
 func (srv *Server) ServerCron() {
+	// 更新内存占用的采样
+	srv.sampleUsedMemory()
+	// 更新瞬时指标的采样
+	srv.sampleInstantaneousMetrics()
+	// 主动删除过期的key, CLIENT PAUSE的时候不删除，避免修改数据集
+	if !srv.clientsArePaused() {
+		srv.activeExpireCycle()
+	}
+	// 满足save条件的时候在后台进行rdb持久化
+	srv.rdbSaveIfNeeded()
+	// 关闭空闲时间过长的客户端
+	srv.clientsCron()
+	// 唤醒阻塞超时的客户端
+	srv.blockedClientsCron()
+}
+
+// 对所有的客户端进行周期性的检查
+func (srv *Server) clientsCron() {
+	now := time.Now()
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	for _, c := range srv.clients {
+		srv.clientsCronHandleTimeout(c, now)
+	}
+}
+
+/**
+和redis一样, 关闭空闲时间超过timeout秒的客户端, 返回客户端是否被关闭。
+	replica、master、monitor以及订阅了频道的客户端不会因为空闲被关闭。
+*/
+func (srv *Server) clientsCronHandleTimeout(c *client.Client, now time.Time) bool {
+	// 阻塞的客户端不会因为空闲被关闭, 阻塞的超时在blockedClientsCron中处理
+	if c.Flags&client.RedisClientBlocked != 0 {
+		return false
+	}
+	if srv.Config.Timeout <= 0 ||
+		c.Flags&(client.RedisClientSlave|client.RedisClientMaster|client.RedisClientMonitor) != 0 ||
+		c.PubSubChannels.Size()+c.PubSubPatterns.ListLength() > 0 {
+		return false
+	}
+	if now.Sub(c.LastInteraction()) <= time.Duration(srv.Config.Timeout)*time.Second {
+		return false
+	}
+	loggers.Info("Closing idle client %d-%s", c.ID(), c.RemoteAddr())
+	c.CloseConn()
+	return true
 }
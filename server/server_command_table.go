@@ -59,7 +59,6 @@ func (srv *Server) populateCommandTable() {
 	hashHandler := new(handlers.HashHandler)
 	setHandler := new(handlers.SetHandler)
 	sortedSetHandler := new(handlers.SortedSetHandler)
-	clientHandler := new(handlers.ClientHandler)
 
 	// connection command
 	srv.commandTable[handlers.RedisConnectionCommandPing] = client.NewCommand(handlers.RedisConnectionCommandPing, 1, "r", connectionHandler.Ping)
@@ -67,134 +66,146 @@ func (srv *Server) populateCommandTable() {
 	srv.commandTable[handlers.RedisConnectionCommandSelect] = client.NewCommand(handlers.RedisConnectionCommandSelect, 2, "r", connectionHandler.CmdSelect)
 	srv.commandTable[handlers.RedisConnectionCommandEcho] = client.NewCommand(handlers.RedisConnectionCommandEcho, 2, "r", connectionHandler.Echo)
 	srv.commandTable[handlers.RedisConnectionCommandQuit] = client.NewCommand(handlers.RedisConnectionCommandQuit, 1, "r", connectionHandler.Quit)
+	srv.commandTable[handlers.RedisConnectionCommandHello] = client.NewCommand(handlers.RedisConnectionCommandHello, -1, "rs", srv.Hello)
 
 	// key command
-	srv.commandTable[handlers.RedisKeyCommandDel] = client.NewCommand(handlers.RedisKeyCommandDel, -2, "w", keyHandler.Del)
-	srv.commandTable[handlers.RedisKeyCommandObject] = client.NewCommand(handlers.RedisKeyCommandObject, -2, "r", keyHandler.Object)
-	srv.commandTable[handlers.RedisKeyCommandType] = client.NewCommand(handlers.RedisKeyCommandType, 2, "r", keyHandler.Type)
-	srv.commandTable[handlers.RedisKeyCommandExists] = client.NewCommand(handlers.RedisKeyCommandExists, 2, "r", keyHandler.Exists)
-	srv.commandTable[handlers.RedisKeyCommandDump] = client.NewCommand(handlers.RedisKeyCommandDump, 2, "ar", nil)
-	srv.commandTable[handlers.RedisKeyCommandExpire] = client.NewCommand(handlers.RedisKeyCommandExpire, 3, "w", nil)
-	srv.commandTable[handlers.RedisKeyCommandExpireAt] = client.NewCommand(handlers.RedisKeyCommandExpireAt, 3, "w", nil)
+	srv.commandTable[handlers.RedisKeyCommandDel] = client.NewCommand(handlers.RedisKeyCommandDel, -2, "w", keyHandler.Del).SetKeySpec(1, -1, 1)
+	srv.commandTable[handlers.RedisKeyCommandObject] = client.NewCommand(handlers.RedisKeyCommandObject, -2, "r", keyHandler.Object).SetKeySpec(2, 2, 1)
+	srv.commandTable[handlers.RedisKeyCommandType] = client.NewCommand(handlers.RedisKeyCommandType, 2, "r", keyHandler.Type).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandExists] = client.NewCommand(handlers.RedisKeyCommandExists, 2, "r", keyHandler.Exists).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandDump] = client.NewCommand(handlers.RedisKeyCommandDump, 2, "ar", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandExpire] = client.NewCommand(handlers.RedisKeyCommandExpire, 3, "w", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandExpireAt] = client.NewCommand(handlers.RedisKeyCommandExpireAt, 3, "w", nil).SetKeySpec(1, 1, 1)
 	srv.commandTable[handlers.RedisKeyCommandKeys] = client.NewCommand(handlers.RedisKeyCommandKeys, 2, "rS", nil)
-	srv.commandTable[handlers.RedisKeyCommandMigrate] = client.NewCommand(handlers.RedisKeyCommandMigrate, -6, "aw", nil)
-	srv.commandTable[handlers.RedisKeyCommandMove] = client.NewCommand(handlers.RedisKeyCommandMove, 3, "w", nil)
-	srv.commandTable[handlers.RedisKeyCommandPersist] = client.NewCommand(handlers.RedisKeyCommandPersist, 2, "w", nil)
-	srv.commandTable[handlers.RedisKeyCommandPExpire] = client.NewCommand(handlers.RedisKeyCommandPExpire, 3, "w", nil)
-	srv.commandTable[handlers.RedisKeyCommandPExpireAt] = client.NewCommand(handlers.RedisKeyCommandPExpireAt, 3, "w", nil)
-	srv.commandTable[handlers.RedisKeyCommandPTTL] = client.NewCommand(handlers.RedisKeyCommandPTTL, 2, "r", nil)
+	srv.commandTable[handlers.RedisKeyCommandMigrate] = client.NewCommand(handlers.RedisKeyCommandMigrate, -6, "aw", nil).SetKeySpec(3, 3, 1)
+	srv.commandTable[handlers.RedisKeyCommandMove] = client.NewCommand(handlers.RedisKeyCommandMove, 3, "w", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandPersist] = client.NewCommand(handlers.RedisKeyCommandPersist, 2, "w", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandPExpire] = client.NewCommand(handlers.RedisKeyCommandPExpire, 3, "w", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandPExpireAt] = client.NewCommand(handlers.RedisKeyCommandPExpireAt, 3, "w", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandPTTL] = client.NewCommand(handlers.RedisKeyCommandPTTL, 2, "r", nil).SetKeySpec(1, 1, 1)
 	srv.commandTable[handlers.RedisKeyCommandRandomKey] = client.NewCommand(handlers.RedisKeyCommandRandomKey, 1, "rR", keyHandler.RandomKey)
-	srv.commandTable[handlers.RedisKeyCommandRename] = client.NewCommand(handlers.RedisKeyCommandRename, 3, "w", keyHandler.Rename)
-	srv.commandTable[handlers.RedisKeyCommandRenameNx] = client.NewCommand(handlers.RedisKeyCommandRenameNx, 3, "w", nil)
-	srv.commandTable[handlers.RedisKeyCommandRestore] = client.NewCommand(handlers.RedisKeyCommandRestore, -4, "awm", nil)
-	srv.commandTable[handlers.RedisKeyCommandSort] = client.NewCommand(handlers.RedisKeyCommandSort, -2, "wm", nil)
-	srv.commandTable[handlers.RedisKeyCommandTTL] = client.NewCommand(handlers.RedisKeyCommandTTL, 2, "r", nil)
+	srv.commandTable[handlers.RedisKeyCommandRename] = client.NewCommand(handlers.RedisKeyCommandRename, 3, "w", keyHandler.Rename).SetKeySpec(1, 2, 1)
+	srv.commandTable[handlers.RedisKeyCommandRenameNx] = client.NewCommand(handlers.RedisKeyCommandRenameNx, 3, "w", nil).SetKeySpec(1, 2, 1)
+	srv.commandTable[handlers.RedisKeyCommandRestore] = client.NewCommand(handlers.RedisKeyCommandRestore, -4, "awm", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandSort] = client.NewCommand(handlers.RedisKeyCommandSort, -2, "wm", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisKeyCommandTTL] = client.NewCommand(handlers.RedisKeyCommandTTL, 2, "r", nil).SetKeySpec(1, 1, 1)
 	srv.commandTable[handlers.RedisKeyCommandScan] = client.NewCommand(handlers.RedisKeyCommandScan, 2, "r", nil)
 
 	// string command
-	srv.commandTable[handlers.RedisStringCommandAppend] = client.NewCommand(handlers.RedisStringCommandAppend, 3, "wm", stringHandler.Append)
-	srv.commandTable[handlers.RedisStringCommandSet] = client.NewCommand(handlers.RedisStringCommandSet, 3, "wm", stringHandler.Set)
-	srv.commandTable[handlers.RedisStringCommandMSet] = client.NewCommand(handlers.RedisStringCommandMSet, -3, "wm", stringHandler.MSet)
-	srv.commandTable[handlers.RedisStringCommandMSetNx] = client.NewCommand(handlers.RedisStringCommandMSetNx, -3, "wm", stringHandler.MSetNx)
-	srv.commandTable[handlers.RedisStringCommandSetNx] = client.NewCommand(handlers.RedisStringCommandSetNx, 3, "wm", stringHandler.SetNx)
-	srv.commandTable[handlers.RedisStringCommandGet] = client.NewCommand(handlers.RedisStringCommandGet, 2, "r", stringHandler.Get)
-	srv.commandTable[handlers.RedisStringCommandMGet] = client.NewCommand(handlers.RedisStringCommandMGet, -2, "r", stringHandler.MGet)
-	srv.commandTable[handlers.RedisStringCommandGetSet] = client.NewCommand(handlers.RedisStringCommandGetSet, 3, "wm", stringHandler.GetSet)
-	srv.commandTable[handlers.RedisStringCommandIncr] = client.NewCommand(handlers.RedisStringCommandIncr, 2, "wm", stringHandler.Incr)
-	srv.commandTable[handlers.RedisStringCommandIncrBy] = client.NewCommand(handlers.RedisStringCommandIncrBy, 3, "wm", stringHandler.IncrBy)
-	srv.commandTable[handlers.RedisStringCommandIncrByFloat] = client.NewCommand(handlers.RedisStringCommandIncrByFloat, 3, "wm", stringHandler.IncrByFloat)
-	srv.commandTable[handlers.RedisStringCommandDecr] = client.NewCommand(handlers.RedisStringCommandDecr, 2, "wm", stringHandler.Decr)
-	srv.commandTable[handlers.RedisStringCommandDecrBy] = client.NewCommand(handlers.RedisStringCommandDecrBy, 3, "wm", stringHandler.DecrBy)
-	srv.commandTable[handlers.RedisStringCommandStrLen] = client.NewCommand(handlers.RedisStringCommandStrLen, 2, "r", stringHandler.Strlen)
+	srv.commandTable[handlers.RedisStringCommandAppend] = client.NewCommand(handlers.RedisStringCommandAppend, 3, "wm", stringHandler.Append).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandSet] = client.NewCommand(handlers.RedisStringCommandSet, 3, "wm", stringHandler.Set).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandMSet] = client.NewCommand(handlers.RedisStringCommandMSet, -3, "wm", stringHandler.MSet).SetKeySpec(1, -1, 2)
+	srv.commandTable[handlers.RedisStringCommandMSetNx] = client.NewCommand(handlers.RedisStringCommandMSetNx, -3, "wm", stringHandler.MSetNx).SetKeySpec(1, -1, 2)
+	srv.commandTable[handlers.RedisStringCommandSetNx] = client.NewCommand(handlers.RedisStringCommandSetNx, 3, "wm", stringHandler.SetNx).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandGet] = client.NewCommand(handlers.RedisStringCommandGet, 2, "r", stringHandler.Get).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandMGet] = client.NewCommand(handlers.RedisStringCommandMGet, -2, "r", stringHandler.MGet).SetKeySpec(1, -1, 1)
+	srv.commandTable[handlers.RedisStringCommandGetSet] = client.NewCommand(handlers.RedisStringCommandGetSet, 3, "wm", stringHandler.GetSet).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandIncr] = client.NewCommand(handlers.RedisStringCommandIncr, 2, "wm", stringHandler.Incr).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandIncrBy] = client.NewCommand(handlers.RedisStringCommandIncrBy, 3, "wm", stringHandler.IncrBy).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandIncrByFloat] = client.NewCommand(handlers.RedisStringCommandIncrByFloat, 3, "wm", stringHandler.IncrByFloat).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandDecr] = client.NewCommand(handlers.RedisStringCommandDecr, 2, "wm", stringHandler.Decr).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandDecrBy] = client.NewCommand(handlers.RedisStringCommandDecrBy, 3, "wm", stringHandler.DecrBy).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandSetRange] = client.NewCommand(handlers.RedisStringCommandSetRange, 4, "wm", stringHandler.SetRange).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisStringCommandStrLen] = client.NewCommand(handlers.RedisStringCommandStrLen, 2, "r", stringHandler.Strlen).SetKeySpec(1, 1, 1)
 
 	// list command
-	srv.commandTable[handlers.RedisListCommandLIndex] = client.NewCommand(handlers.RedisListCommandLIndex, 3, "r", listHandler.LIndex)
-	srv.commandTable[handlers.RedisListCommandLInsert] = client.NewCommand(handlers.RedisListCommandLInsert, 5, "wm", listHandler.LInsert)
-	srv.commandTable[handlers.RedisListCommandLLen] = client.NewCommand(handlers.RedisListCommandLLen, 2, "r", listHandler.LLen)
-	srv.commandTable[handlers.RedisListCommandLPop] = client.NewCommand(handlers.RedisListCommandLPop, 2, "w", listHandler.LPop)
-	srv.commandTable[handlers.RedisListCommandLPush] = client.NewCommand(handlers.RedisListCommandLPush, -3, "wm", listHandler.LPush)
-	srv.commandTable[handlers.RedisListCommandLPushX] = client.NewCommand(handlers.RedisListCommandLPushX, 3, "wm", nil)
-	srv.commandTable[handlers.RedisListCommandLRange] = client.NewCommand(handlers.RedisListCommandLRange, 4, "r", listHandler.LRange)
-	srv.commandTable[handlers.RedisListCommandLRem] = client.NewCommand(handlers.RedisListCommandLRem, 4, "w", listHandler.LRem)
-	srv.commandTable[handlers.RedisListCommandLSet] = client.NewCommand(handlers.RedisListCommandLSet, 4, "wm", listHandler.LSet)
-	srv.commandTable[handlers.RedisListCommandLTrim] = client.NewCommand(handlers.RedisListCommandLTrim, 4, "w", listHandler.LTrim)
-	srv.commandTable[handlers.RedisListCommandRPop] = client.NewCommand(handlers.RedisListCommandRPop, 2, "w", listHandler.RPop)
-	srv.commandTable[handlers.RedisListCommandRPopLPush] = client.NewCommand(handlers.RedisListCommandRPopLPush, 3, "wm", nil)
-	srv.commandTable[handlers.RedisListCommandRPush] = client.NewCommand(handlers.RedisListCommandRPush, -3, "wm", listHandler.RPush)
-	srv.commandTable[handlers.RedisListCommandRpushX] = client.NewCommand(handlers.RedisListCommandRpushX, 3, "wm", nil)
-	srv.commandTable[handlers.RedisListCommandLDebug] = client.NewCommand(handlers.RedisListCommandLDebug, 2, "r", listHandler.Debug)
+	srv.commandTable[handlers.RedisListCommandLIndex] = client.NewCommand(handlers.RedisListCommandLIndex, 3, "r", listHandler.LIndex).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLInsert] = client.NewCommand(handlers.RedisListCommandLInsert, 5, "wm", listHandler.LInsert).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLLen] = client.NewCommand(handlers.RedisListCommandLLen, 2, "r", listHandler.LLen).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLPop] = client.NewCommand(handlers.RedisListCommandLPop, -2, "w", listHandler.LPop).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLPush] = client.NewCommand(handlers.RedisListCommandLPush, -3, "wm", listHandler.LPush).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLPushX] = client.NewCommand(handlers.RedisListCommandLPushX, -3, "wm", listHandler.LPushX).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLRange] = client.NewCommand(handlers.RedisListCommandLRange, 4, "r", listHandler.LRange).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLRem] = client.NewCommand(handlers.RedisListCommandLRem, 4, "w", listHandler.LRem).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLSet] = client.NewCommand(handlers.RedisListCommandLSet, 4, "wm", listHandler.LSet).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLTrim] = client.NewCommand(handlers.RedisListCommandLTrim, 4, "w", listHandler.LTrim).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandRPop] = client.NewCommand(handlers.RedisListCommandRPop, -2, "w", listHandler.RPop).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandRPopLPush] = client.NewCommand(handlers.RedisListCommandRPopLPush, 3, "wm", listHandler.RPopLPush).SetKeySpec(1, 2, 1)
+	srv.commandTable[handlers.RedisListCommandRPush] = client.NewCommand(handlers.RedisListCommandRPush, -3, "wm", listHandler.RPush).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandRpushX] = client.NewCommand(handlers.RedisListCommandRpushX, -3, "wm", listHandler.RPushX).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLMove] = client.NewCommand(handlers.RedisListCommandLMove, 5, "wm", listHandler.LMove).SetKeySpec(1, 2, 1)
+	srv.commandTable[handlers.RedisListCommandLPos] = client.NewCommand(handlers.RedisListCommandLPos, -3, "r", listHandler.LPos).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandLMPop] = client.NewCommand(handlers.RedisListCommandLMPop, -4, "w", listHandler.LMPop).SetGetKeysProc(client.NumKeysGetKeys(1))
+	srv.commandTable[handlers.RedisListCommandLDebug] = client.NewCommand(handlers.RedisListCommandLDebug, 2, "r", listHandler.Debug).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisListCommandBLPop] = client.NewCommand(handlers.RedisListCommandBLPop, -3, "ws", srv.BLPop).SetKeySpec(1, -2, 1)
+	srv.commandTable[handlers.RedisListCommandBRPop] = client.NewCommand(handlers.RedisListCommandBRPop, -3, "ws", srv.BRPop).SetKeySpec(1, -2, 1)
+	srv.commandTable[handlers.RedisListCommandBRPopLPush] = client.NewCommand(handlers.RedisListCommandBRPopLPush, 4, "wms", srv.BRPopLPush).SetKeySpec(1, 2, 1)
+	srv.commandTable[handlers.RedisListCommandBLMove] = client.NewCommand(handlers.RedisListCommandBLMove, 6, "wms", srv.BLMove).SetKeySpec(1, 2, 1)
+	srv.commandTable[handlers.RedisListCommandBLMPop] = client.NewCommand(handlers.RedisListCommandBLMPop, -5, "ws", srv.BLMPop).SetGetKeysProc(client.NumKeysGetKeys(2))
 
 	// hash command
-	srv.commandTable[handlers.RedisHashCommandHDel] = client.NewCommand(handlers.RedisHashCommandHDel, -3, "w", hashHandler.HDel)
-	srv.commandTable[handlers.RedisHashCommandHExists] = client.NewCommand(handlers.RedisHashCommandHExists, 3, "r", hashHandler.HExists)
-	srv.commandTable[handlers.RedisHashCommandHGet] = client.NewCommand(handlers.RedisHashCommandHGet, 3, "r", hashHandler.HGet)
-	srv.commandTable[handlers.RedisHashCommandHGetAll] = client.NewCommand(handlers.RedisHashCommandHGetAll, 2, "r", hashHandler.HGetAll)
-	srv.commandTable[handlers.RedisHashCommandHIncrBy] = client.NewCommand(handlers.RedisHashCommandHIncrBy, 4, "wm", hashHandler.HIncrBy)
-	srv.commandTable[handlers.RedisHashCommandHIncrByFloat] = client.NewCommand(handlers.RedisHashCommandHIncrByFloat, 4, "wm", hashHandler.HIncrByFloat)
-	srv.commandTable[handlers.RedisHashCommandHKeys] = client.NewCommand(handlers.RedisHashCommandHKeys, 2, "rS", hashHandler.HKeys)
-	srv.commandTable[handlers.RedisHashCommandHLen] = client.NewCommand(handlers.RedisHashCommandHLen, 2, "r", hashHandler.HLen)
-	srv.commandTable[handlers.RedisHashCommandHMGet] = client.NewCommand(handlers.RedisHashCommandHMGet, -3, "r", hashHandler.HMGet)
-	srv.commandTable[handlers.RedisHashCommandHMSet] = client.NewCommand(handlers.RedisHashCommandHMSet, -4, "wm", hashHandler.HMSet)
-	srv.commandTable[handlers.RedisHashCommandHSet] = client.NewCommand(handlers.RedisHashCommandHSet, 4, "wm", hashHandler.HSet)
-	srv.commandTable[handlers.RedisHashCommandHSetNX] = client.NewCommand(handlers.RedisHashCommandHSetNX, 4, "wm", hashHandler.HSetNX)
-	srv.commandTable[handlers.RedisHashCommandHVals] = client.NewCommand(handlers.RedisHashCommandHVals, 2, "rS", hashHandler.HVals)
-	srv.commandTable[handlers.RedisHashCommandHScan] = client.NewCommand(handlers.RedisHashCommandHScan, 3, "r", nil)
-	srv.commandTable[handlers.RedisHashCommandHStrLen] = client.NewCommand(handlers.RedisHashCommandHStrLen, 3, "r", hashHandler.HStrLen)
-	srv.commandTable[handlers.RedisHashCommandHDebug] = client.NewCommand(handlers.RedisHashCommandHDebug, 2, "r", hashHandler.HDebug)
+	srv.commandTable[handlers.RedisHashCommandHDel] = client.NewCommand(handlers.RedisHashCommandHDel, -3, "w", hashHandler.HDel).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHExists] = client.NewCommand(handlers.RedisHashCommandHExists, 3, "r", hashHandler.HExists).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHGet] = client.NewCommand(handlers.RedisHashCommandHGet, 3, "r", hashHandler.HGet).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHGetAll] = client.NewCommand(handlers.RedisHashCommandHGetAll, 2, "r", hashHandler.HGetAll).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHIncrBy] = client.NewCommand(handlers.RedisHashCommandHIncrBy, 4, "wm", hashHandler.HIncrBy).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHIncrByFloat] = client.NewCommand(handlers.RedisHashCommandHIncrByFloat, 4, "wm", hashHandler.HIncrByFloat).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHKeys] = client.NewCommand(handlers.RedisHashCommandHKeys, 2, "rS", hashHandler.HKeys).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHLen] = client.NewCommand(handlers.RedisHashCommandHLen, 2, "r", hashHandler.HLen).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHMGet] = client.NewCommand(handlers.RedisHashCommandHMGet, -3, "r", hashHandler.HMGet).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHMSet] = client.NewCommand(handlers.RedisHashCommandHMSet, -4, "wm", hashHandler.HMSet).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHSet] = client.NewCommand(handlers.RedisHashCommandHSet, 4, "wm", hashHandler.HSet).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHSetNX] = client.NewCommand(handlers.RedisHashCommandHSetNX, 4, "wm", hashHandler.HSetNX).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHVals] = client.NewCommand(handlers.RedisHashCommandHVals, 2, "rS", hashHandler.HVals).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHScan] = client.NewCommand(handlers.RedisHashCommandHScan, 3, "r", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHStrLen] = client.NewCommand(handlers.RedisHashCommandHStrLen, 3, "r", hashHandler.HStrLen).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisHashCommandHDebug] = client.NewCommand(handlers.RedisHashCommandHDebug, 2, "r", hashHandler.HDebug).SetKeySpec(1, 1, 1)
 
 	// set command
-	srv.commandTable[handlers.RedisSetCommandSADD] = client.NewCommand(handlers.RedisSetCommandSADD, -3, "wm", setHandler.SAdd)
-	srv.commandTable[handlers.RedisSetCommandSCARD] = client.NewCommand(handlers.RedisSetCommandSCARD, 2, "r", setHandler.SCard)
-	srv.commandTable[handlers.RedisSetCommandSDIFF] = client.NewCommand(handlers.RedisSetCommandSDIFF, -2, "rS", setHandler.SDiff)
-	srv.commandTable[handlers.RedisSetCommandSDIFFSTORE] = client.NewCommand(handlers.RedisSetCommandSDIFFSTORE, -3, "wm", setHandler.SDiffStore)
-	srv.commandTable[handlers.RedisSetCommandSINTER] = client.NewCommand(handlers.RedisSetCommandSINTER, -2, "rS", setHandler.SInter)
-	srv.commandTable[handlers.RedisSetCommandSINTERSTORE] = client.NewCommand(handlers.RedisSetCommandSINTERSTORE, -3, "wm", setHandler.SInterStore)
-	srv.commandTable[handlers.RedisSetCommandSISMEMBER] = client.NewCommand(handlers.RedisSetCommandSISMEMBER, 3, "r", setHandler.SIsMember)
-	srv.commandTable[handlers.RedisSetCommandSMEMBERS] = client.NewCommand(handlers.RedisSetCommandSMEMBERS, 2, "rS", setHandler.SMembers)
-	srv.commandTable[handlers.RedisSetCommandSMOVE] = client.NewCommand(handlers.RedisSetCommandSMOVE, 4, "w", setHandler.SMove)
-	srv.commandTable[handlers.RedisSetCommandSPOP] = client.NewCommand(handlers.RedisSetCommandSPOP, 2, "wRs", setHandler.SPop)
-	srv.commandTable[handlers.RedisSetCommandSRANDMEMBER] = client.NewCommand(handlers.RedisSetCommandSRANDMEMBER, -2, "rR", setHandler.SRandMember)
-	srv.commandTable[handlers.RedisSetCommandSREM] = client.NewCommand(handlers.RedisSetCommandSREM, -3, "r", setHandler.SRem)
-	srv.commandTable[handlers.RedisSetCommandSUNION] = client.NewCommand(handlers.RedisSetCommandSUNION, -2, "rS", nil)
-	srv.commandTable[handlers.RedisSetCommandSUNIONSTORE] = client.NewCommand(handlers.RedisSetCommandSUNIONSTORE, -3, "wm", nil)
-	srv.commandTable[handlers.RedisSetCommandSSCAN] = client.NewCommand(handlers.RedisSetCommandSSCAN, 2, "rS", nil)
+	srv.commandTable[handlers.RedisSetCommandSADD] = client.NewCommand(handlers.RedisSetCommandSADD, -3, "wm", setHandler.SAdd).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSetCommandSCARD] = client.NewCommand(handlers.RedisSetCommandSCARD, 2, "r", setHandler.SCard).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSetCommandSDIFF] = client.NewCommand(handlers.RedisSetCommandSDIFF, -2, "rS", setHandler.SDiff).SetKeySpec(1, -1, 1)
+	srv.commandTable[handlers.RedisSetCommandSDIFFSTORE] = client.NewCommand(handlers.RedisSetCommandSDIFFSTORE, -3, "wm", setHandler.SDiffStore).SetKeySpec(1, -1, 1)
+	srv.commandTable[handlers.RedisSetCommandSINTER] = client.NewCommand(handlers.RedisSetCommandSINTER, -2, "rS", setHandler.SInter).SetKeySpec(1, -1, 1)
+	srv.commandTable[handlers.RedisSetCommandSINTERSTORE] = client.NewCommand(handlers.RedisSetCommandSINTERSTORE, -3, "wm", setHandler.SInterStore).SetKeySpec(1, -1, 1)
+	srv.commandTable[handlers.RedisSetCommandSISMEMBER] = client.NewCommand(handlers.RedisSetCommandSISMEMBER, 3, "r", setHandler.SIsMember).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSetCommandSMEMBERS] = client.NewCommand(handlers.RedisSetCommandSMEMBERS, 2, "rS", setHandler.SMembers).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSetCommandSMOVE] = client.NewCommand(handlers.RedisSetCommandSMOVE, 4, "w", setHandler.SMove).SetKeySpec(1, 2, 1)
+	srv.commandTable[handlers.RedisSetCommandSPOP] = client.NewCommand(handlers.RedisSetCommandSPOP, 2, "wRs", setHandler.SPop).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSetCommandSRANDMEMBER] = client.NewCommand(handlers.RedisSetCommandSRANDMEMBER, -2, "rR", setHandler.SRandMember).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSetCommandSREM] = client.NewCommand(handlers.RedisSetCommandSREM, -3, "r", setHandler.SRem).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSetCommandSUNION] = client.NewCommand(handlers.RedisSetCommandSUNION, -2, "rS", nil).SetKeySpec(1, -1, 1)
+	srv.commandTable[handlers.RedisSetCommandSUNIONSTORE] = client.NewCommand(handlers.RedisSetCommandSUNIONSTORE, -3, "wm", nil).SetKeySpec(1, -1, 1)
+	srv.commandTable[handlers.RedisSetCommandSSCAN] = client.NewCommand(handlers.RedisSetCommandSSCAN, 2, "rS", nil).SetKeySpec(1, 1, 1)
 
 	// sorted set command
-	srv.commandTable[handlers.RedisSortedSetCommandZAdd] = client.NewCommand(handlers.RedisSortedSetCommandZAdd, -4, "wm", sortedSetHandler.ZAdd)
-	srv.commandTable[handlers.RedisSortedSetCommandZCard] = client.NewCommand(handlers.RedisSortedSetCommandZCard, 2, "r", sortedSetHandler.ZCard)
-	srv.commandTable[handlers.RedisSortedSetCommandZCount] = client.NewCommand(handlers.RedisSortedSetCommandZCount, 4, "r", sortedSetHandler.ZCount)
-	srv.commandTable[handlers.RedisSortedSetCommandZIncrBy] = client.NewCommand(handlers.RedisSortedSetCommandZIncrBy, 4, "wm", sortedSetHandler.ZIncrBy)
-	srv.commandTable[handlers.RedisSortedSetCommandZRange] = client.NewCommand(handlers.RedisSortedSetCommandZRange, -4, "r", sortedSetHandler.ZRange)
-	srv.commandTable[handlers.RedisSortedSetCommandZRangeByScore] = client.NewCommand(handlers.RedisSortedSetCommandZRangeByScore, -4, "r", sortedSetHandler.ZRangeByScore)
-	srv.commandTable[handlers.RedisSortedSetCommandZRank] = client.NewCommand(handlers.RedisSortedSetCommandZRank, 3, "r", sortedSetHandler.ZRank)
-	srv.commandTable[handlers.RedisSortedSetCommandZRem] = client.NewCommand(handlers.RedisSortedSetCommandZRem, -3, "w", sortedSetHandler.ZRem)
-	srv.commandTable[handlers.RedisSortedSetCommandZRemRangeByRank] = client.NewCommand(handlers.RedisSortedSetCommandZRemRangeByRank, 4, "w", sortedSetHandler.ZRemRangeByRank)
-	srv.commandTable[handlers.RedisSortedSetCommandZRemRangeByScore] = client.NewCommand(handlers.RedisSortedSetCommandZRemRangeByScore, 4, "w", sortedSetHandler.ZRemRangeByScore)
-	srv.commandTable[handlers.RedisSortedSetCommandZRevRange] = client.NewCommand(handlers.RedisSortedSetCommandZRevRange, -4, "r", sortedSetHandler.ZRevRange)
-	srv.commandTable[handlers.RedisSortedSetCommandZRevRangeByScore] = client.NewCommand(handlers.RedisSortedSetCommandZRevRangeByScore, -4, "r", sortedSetHandler.ZRevRangeByScore)
-	srv.commandTable[handlers.RedisSortedSetCommandZRevRank] = client.NewCommand(handlers.RedisSortedSetCommandZRevRank, 3, "r", sortedSetHandler.ZRevRank)
-	srv.commandTable[handlers.RedisSortedSetCommandZScore] = client.NewCommand(handlers.RedisSortedSetCommandZScore, 3, "r", sortedSetHandler.ZScore)
-	srv.commandTable[handlers.RedisSortedSetCommandZUnionStore] = client.NewCommand(handlers.RedisSortedSetCommandZUnionStore, -4, "wm", nil)
-	srv.commandTable[handlers.RedisSortedSetCommandZInterStore] = client.NewCommand(handlers.RedisSortedSetCommandZInterStore, -4, "wm", nil)
-	srv.commandTable[handlers.RedisSortedSetCommandZScan] = client.NewCommand(handlers.RedisSortedSetCommandZScan, 2, "r", nil)
+	srv.commandTable[handlers.RedisSortedSetCommandZAdd] = client.NewCommand(handlers.RedisSortedSetCommandZAdd, -4, "wm", sortedSetHandler.ZAdd).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZCard] = client.NewCommand(handlers.RedisSortedSetCommandZCard, 2, "r", sortedSetHandler.ZCard).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZCount] = client.NewCommand(handlers.RedisSortedSetCommandZCount, 4, "r", sortedSetHandler.ZCount).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZIncrBy] = client.NewCommand(handlers.RedisSortedSetCommandZIncrBy, 4, "wm", sortedSetHandler.ZIncrBy).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRange] = client.NewCommand(handlers.RedisSortedSetCommandZRange, -4, "r", sortedSetHandler.ZRange).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRangeByScore] = client.NewCommand(handlers.RedisSortedSetCommandZRangeByScore, -4, "r", sortedSetHandler.ZRangeByScore).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRank] = client.NewCommand(handlers.RedisSortedSetCommandZRank, 3, "r", sortedSetHandler.ZRank).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRem] = client.NewCommand(handlers.RedisSortedSetCommandZRem, -3, "w", sortedSetHandler.ZRem).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRemRangeByRank] = client.NewCommand(handlers.RedisSortedSetCommandZRemRangeByRank, 4, "w", sortedSetHandler.ZRemRangeByRank).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRemRangeByScore] = client.NewCommand(handlers.RedisSortedSetCommandZRemRangeByScore, 4, "w", sortedSetHandler.ZRemRangeByScore).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRevRange] = client.NewCommand(handlers.RedisSortedSetCommandZRevRange, -4, "r", sortedSetHandler.ZRevRange).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRevRangeByScore] = client.NewCommand(handlers.RedisSortedSetCommandZRevRangeByScore, -4, "r", sortedSetHandler.ZRevRangeByScore).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZRevRank] = client.NewCommand(handlers.RedisSortedSetCommandZRevRank, 3, "r", sortedSetHandler.ZRevRank).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZScore] = client.NewCommand(handlers.RedisSortedSetCommandZScore, 3, "r", sortedSetHandler.ZScore).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZUnionStore] = client.NewCommand(handlers.RedisSortedSetCommandZUnionStore, -4, "wm", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZInterStore] = client.NewCommand(handlers.RedisSortedSetCommandZInterStore, -4, "wm", nil).SetKeySpec(1, 1, 1)
+	srv.commandTable[handlers.RedisSortedSetCommandZScan] = client.NewCommand(handlers.RedisSortedSetCommandZScan, 2, "r", nil).SetKeySpec(1, 1, 1)
 
 	// server command
 	srv.commandTable[RedisServerCommandBGSRewriteAof] = client.NewCommand(RedisServerCommandBGSRewriteAof, 1, "ar", nil)
 	srv.commandTable[RedisServerCommandBGSave] = client.NewCommand(RedisServerCommandBGSave, 1, "ar", srv.BgSave)
-	srv.commandTable[RedisServerCommandClient] = client.NewCommand(RedisServerCommandClient, -2, "ar", nil)
-	srv.commandTable[RedisServerCommandConfig] = client.NewCommand(RedisServerCommandConfig, -2, "ar", nil)
+	srv.commandTable[RedisServerCommandClient] = client.NewCommand(RedisServerCommandClient, -2, "ar", srv.ClientCommand)
+	srv.commandTable[RedisServerCommandConfig] = client.NewCommand(RedisServerCommandConfig, -2, "ar", srv.ConfigCommand)
 	srv.commandTable[RedisServerCommandDBSize] = client.NewCommand(RedisServerCommandDBSize, 1, "r", nil)
-	srv.commandTable[RedisServerCommandDebug] = client.NewCommand(RedisServerCommandDebug, -2, "as", nil)
+	srv.commandTable[RedisServerCommandDebug] = client.NewCommand(RedisServerCommandDebug, -2, "as", srv.Debug)
 	srv.commandTable[RedisServerCommandFlushAll] = client.NewCommand(RedisServerCommandFlushAll, 1, "w", srv.FlushAll)
 	srv.commandTable[RedisServerCommandFlushDB] = client.NewCommand(RedisServerCommandFlushDB, 1, "w", srv.FlushDB)
-	srv.commandTable[RedisServerCommandInfo] = client.NewCommand(RedisServerCommandInfo, -1, "rlt", nil)
+	srv.commandTable[RedisServerCommandInfo] = client.NewCommand(RedisServerCommandInfo, -1, "rlt", srv.Info)
+	srv.commandTable[RedisServerCommandMemory] = client.NewCommand(RedisServerCommandMemory, -2, "r", srv.Memory)
+	srv.commandTable[RedisServerCommandLatency] = client.NewCommand(RedisServerCommandLatency, -2, "aslt", srv.Latency)
 	srv.commandTable[RedisServerCommandLastSave] = client.NewCommand(RedisServerCommandLastSave, 1, "r", nil)
-	srv.commandTable[RedisServerCommandMonitor] = client.NewCommand(RedisServerCommandMonitor, 1, "ars", nil)
+	srv.commandTable[RedisServerCommandMonitor] = client.NewCommand(RedisServerCommandMonitor, 1, "ars", srv.Monitor)
 	srv.commandTable[RedisServerCommandPSync] = client.NewCommand(RedisServerCommandPSync, 1, "ars", nil)
-	srv.commandTable[RedisServerCommandShutDown] = client.NewCommand(RedisServerCommandShutDown, -1, "ar", nil)
+	srv.commandTable[RedisServerCommandShutDown] = client.NewCommand(RedisServerCommandShutDown, -1, "ar", srv.Shutdown)
 	srv.commandTable[RedisServerCommandSave] = client.NewCommand(RedisServerCommandSave, 1, "ars", srv.Save)
 	srv.commandTable[RedisServerCommandSlaveOf] = client.NewCommand(RedisServerCommandSlaveOf, 3, "ast", nil)
-	srv.commandTable[RedisServerCommandSlowLog] = client.NewCommand(RedisServerCommandSlowLog, -2, "r", nil)
+	srv.commandTable[RedisServerCommandSlowLog] = client.NewCommand(RedisServerCommandSlowLog, -2, "r", srv.SlowLog)
 	srv.commandTable[RedisServerCommandSync] = client.NewCommand(RedisServerCommandSync, 1, "ars", nil)
 	srv.commandTable[RedisServerCommandTime] = client.NewCommand(RedisServerCommandTime, 1, "rR", nil)
 	srv.commandTable[RedisServerCommandAofDebug] = client.NewCommand(RedisServerCommandAofDebug, 1, "r", srv.AofDebug)
@@ -211,9 +222,6 @@ func (srv *Server) populateCommandTable() {
 	srv.commandTable[RedisPubSubCommandUnsubscribe] = client.NewCommand(RedisPubSubCommandUnsubscribe, -1, "rpslt", srv.Unsubscribe)
 	srv.commandTable[RedisPubSubCommandPubSub] = client.NewCommand(RedisPubSubCommandPubSub, -2, "r", srv.PubSub)
 
-	// client command
-	srv.commandTable[handlers.RedisClientCommand] = client.NewCommand(handlers.RedisClientCommand, 1, "r", clientHandler.Client)
-
 	// debug command
 	srv.commandTable[RedisDebugCommandRuntimeStat] = client.NewCommand(RedisDebugCommandRuntimeStat, 1, "r", srv.RuntimeStat)
 
@@ -1,11 +1,15 @@
 package server
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/SwanSpouse/redis_go/client"
 	"github.com/SwanSpouse/redis_go/database"
 	"github.com/SwanSpouse/redis_go/encodings"
 	re "github.com/SwanSpouse/redis_go/error"
 	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/raw_type"
 	"github.com/SwanSpouse/redis_go/rdb"
 )
 
@@ -43,6 +47,10 @@ const (
 
 func (srv *Server) doSave(cli *client.Client, encoder *rdb.Encoder) {
 	loggers.Info("redis rdb save start")
+	start := time.Now()
+	defer func() {
+		srv.latencyAddSampleIfNeeded(LatencyEventRdbSave, time.Since(start))
+	}()
 	encoder.EncodeHeader()
 	for dbNo, db := range srv.Databases {
 		if db.DBSize() == 0 {
@@ -58,22 +66,23 @@ func (srv *Server) doSave(cli *client.Client, encoder *rdb.Encoder) {
 					} else {
 						encoder.EncodeType(rdb.TypeString)
 						encoder.EncodeRawString(key)
-						encoder.EncodeRawString(ts.GetValue().(string))
+						encoder.EncodeRawString(ts.String())
 					}
 				case encodings.RedisTypeList:
 					if tl, ok := redisObj.(database.TList); !ok {
 						cli.ResponseReError(re.ErrImpossible)
 					} else {
-						encoder.EncodeType(rdb.TypeList)
+						encoder.EncodeType(rdb.TypeListQuicklist)
 						encoder.EncodeRawString(key)
-						encoder.EncodeLength(uint32(tl.LLen()))
-						for _, item := range tl.GetAllMembers() {
-							encoder.EncodeRawString(item)
-						}
+						encoder.EncodeQuickList(tl.QuickListNodes())
 					}
 				case encodings.RedisTypeHash:
 					if th, ok := redisObj.(database.THash); !ok {
 						cli.ResponseReError(re.ErrImpossible)
+					} else if th.GetEncoding() == encodings.RedisEncodingListPack {
+						encoder.EncodeType(rdb.TypeHashZiplist)
+						encoder.EncodeRawString(key)
+						encoder.EncodeZiplist(th.HGetAll())
 					} else {
 						encoder.EncodeType(rdb.TypeHash)
 						encoder.EncodeRawString(key)
@@ -85,9 +94,41 @@ func (srv *Server) doSave(cli *client.Client, encoder *rdb.Encoder) {
 						}
 					}
 				case encodings.RedisTypeSet:
-					// TODO
+					if ts, ok := redisObj.(database.TSet); !ok {
+						cli.ResponseReError(re.ErrImpossible)
+					} else if ts.GetEncoding() == encodings.RedisEncodingIntSet {
+						encoder.EncodeType(rdb.TypeSetIntset)
+						encoder.EncodeRawString(key)
+						encoder.EncodeString(ts.GetValue().(*raw_type.IntSet).RawBytes())
+					} else {
+						encoder.EncodeType(rdb.TypeSet)
+						encoder.EncodeRawString(key)
+						encoder.EncodeLength(uint32(ts.SCard()))
+						for _, member := range ts.SMembers() {
+							encoder.EncodeRawString(member)
+						}
+					}
 				case encodings.RedisTypeZSet:
-					// TODO
+					if tzs, ok := redisObj.(database.TZSet); !ok {
+						cli.ResponseReError(re.ErrImpossible)
+					} else if members, scores := tzs.ZMembers(); tzs.GetEncoding() == encodings.RedisEncodingListPack {
+						entries := make([]string, 0, len(members)*2)
+						for i, member := range members {
+							entries = append(entries, member)
+							entries = append(entries, strconv.FormatFloat(scores[i], 'g', -1, 64))
+						}
+						encoder.EncodeType(rdb.TypeZSetZiplist)
+						encoder.EncodeRawString(key)
+						encoder.EncodeZiplist(entries)
+					} else {
+						encoder.EncodeType(rdb.TypeZSet)
+						encoder.EncodeRawString(key)
+						encoder.EncodeLength(uint32(len(members)))
+						for i, member := range members {
+							encoder.EncodeRawString(member)
+							encoder.EncodeFloat(scores[i])
+						}
+					}
 				}
 			}
 		}
@@ -101,6 +142,7 @@ func (srv *Server) doSave(cli *client.Client, encoder *rdb.Encoder) {
 // TODO @lmj 这里是不是应该有锁。
 func (srv *Server) FlushDB(cli *client.Client) {
 	cli.SelectedDatabase().FlushDB()
+	srv.trackingInvalidateKeysOnFlush()
 	cli.ResponseOK()
 }
 
@@ -109,6 +151,7 @@ func (srv *Server) FlushAll(cli *client.Client) {
 	for _, db := range srv.Databases {
 		db.FlushDB()
 	}
+	srv.trackingInvalidateKeysOnFlush()
 	cli.ResponseOK()
 }
 
@@ -146,6 +189,41 @@ func (srv *Server) BgSave(cli *client.Client) {
 	go srv.doSave(cli, encoder)
 }
 
+// 检查save配置中的条件，只要有一个满足就在后台进行rdb持久化
+func (srv *Server) rdbSaveIfNeeded() {
+	if len(srv.saveParams) == 0 || srv.Status.Load() != RedisServerStatusNormal {
+		return
+	}
+	lastSave := srv.rdbLastSave
+	if lastSave.IsZero() {
+		lastSave = srv.startTime
+	}
+	dirty := srv.Dirty
+	for _, sp := range srv.saveParams {
+		if dirty >= sp.Changes && dirty > 0 && time.Since(lastSave) >= time.Duration(sp.Seconds)*time.Second {
+			loggers.Info("%d changes in %d seconds. Saving...", sp.Changes, sp.Seconds)
+			srv.rdbBackgroundSave(dirty)
+			return
+		}
+	}
+}
+
+// 由ServerCron触发的后台持久化, 完成之后从Dirty中减去本次保存的修改次数
+func (srv *Server) rdbBackgroundSave(dirty int64) {
+	encoder, err := rdb.NewEncoder(srv.Config.RdbFilename)
+	if err != nil {
+		loggers.Errorf("Failed opening the RDB file %s for saving: %s", srv.Config.RdbFilename, err)
+		return
+	}
+	srv.Status.Store(RedisServerStatusRdbBgSaveInProcess)
+	go func() {
+		defer srv.Status.Store(RedisServerStatusNormal)
+		srv.doSave(client.NewFakeClient(), encoder)
+		srv.Dirty -= dirty
+		srv.rdbLastSave = time.Now()
+	}()
+}
+
 // TODO @lmj
 func (srv *Server) Command(cli *client.Client) {
 	cli.ResponseOK()
@@ -163,7 +241,7 @@ func (srv *Server) AofDebug(cli *client.Client) {
 
 func (srv *Server) AofFlush(cli *client.Client) {
 	loggers.Debug("current aof buf:%s", string(srv.aofBuf))
-	srv.flushAppendOnlyFile()
+	srv.flushAppendOnlyFile(false)
 	loggers.Debug("current aof buf:%s", string(srv.aofBuf))
 	cli.ResponseOK()
 }
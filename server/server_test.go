@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/handlers"
+)
+
+// 把字符串参数转换为Client.Argv的格式
+func newTestArgv(args ...string) [][]byte {
+	argv := make([][]byte, len(args))
+	for i, arg := range args {
+		argv[i] = []byte(arg)
+	}
+	return argv
+}
+
+func TestMaxClients(t *testing.T) {
+	config := conf.NewServerConfig()
+	config.Port = 9738
+	config.MaxClients = 1
+	srv := NewServer(config)
+	srv.TcpListener = srv.listen()
+	defer srv.TcpListener.Close()
+	go srv.TCPServe()
+
+	first, err := net.Dial("tcp", srv.TcpListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	first.Write([]byte("*1\r\n$4\r\nPING\r\n"))
+	if line, _ := bufio.NewReader(first).ReadString('\n'); line != "$4\r\n" {
+		t.Fatalf("unexpected reply %q", line)
+	}
+
+	second, err := net.Dial("tcp", srv.TcpListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(second)
+	if line, _ := reader.ReadString('\n'); line != "-ERR max number of clients reached\r\n" {
+		t.Fatalf("unexpected reply %q", line)
+	}
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Fatal("rejected connection should be closed")
+	}
+	if srv.statRejectedConn != 1 || srv.statNumConnections != 1 {
+		t.Fatalf("expect 1 rejected and 1 accepted connection, got %d %d", srv.statRejectedConn, srv.statNumConnections)
+	}
+
+	// 第一个连接关闭之后从clients中删除, 新的连接可以复用client
+	first.Close()
+	waitClientsRemoved(t, srv)
+	third, err := net.Dial("tcp", srv.TcpListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	third.Write([]byte("*1\r\n$4\r\nPING\r\n"))
+	if line, _ := bufio.NewReader(third).ReadString('\n'); line != "$4\r\n" {
+		t.Fatalf("unexpected reply %q", line)
+	}
+	third.Close()
+	waitClientsRemoved(t, srv)
+}
+
+// 等待所有的客户端退出, 避免IOLoop在测试结束之后继续运行
+func waitClientsRemoved(t *testing.T, srv *Server) {
+	for i := 0; i < 100; i++ {
+		srv.mu.RLock()
+		count := len(srv.clients)
+		srv.mu.RUnlock()
+		if count == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("clients should be removed after the connections are closed")
+}
+
+func TestClientsCronHandleTimeout(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	later := c.LastInteraction().Add(time.Minute)
+
+	if srv.clientsCronHandleTimeout(c, later) {
+		t.Fatal("clients should never timeout when timeout is 0")
+	}
+	if err := srv.Config.SetParam("timeout", "10"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.clientsCronHandleTimeout(c, c.LastInteraction().Add(5*time.Second)) {
+		t.Fatal("client should not timeout before timeout seconds")
+	}
+	c.Flags |= client.RedisClientMonitor
+	if srv.clientsCronHandleTimeout(c, later) {
+		t.Fatal("monitor client should not timeout")
+	}
+	c.Flags &^= client.RedisClientMonitor
+	if !srv.clientsCronHandleTimeout(c, later) {
+		t.Fatal("idle client should timeout")
+	}
+	if _, err := peer.Read(make([]byte, 1)); err == nil {
+		t.Fatal("connection of idle client should be closed")
+	}
+}
+
+func TestCommandProcWithoutFlush(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	c.Argv, c.Argc = newTestArgv(handlers.RedisConnectionCommandPing), 1
+	c.Cmd = srv.commandTable[handlers.RedisConnectionCommandPing]
+
+	// net.Pipe没有缓冲区, 如果在命令中写socket会一直阻塞
+	done := make(chan struct{})
+	go func() {
+		c.Cmd.Proc(c)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("command proc should not write the reply to the socket")
+	}
+	if c.OutputBuffered() == 0 {
+		t.Fatal("reply should be kept in the output buffer until the command is timed")
+	}
+}
+
+func TestIgnoreEmptyMultiBulk(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	cn, peer := net.Pipe()
+	defer peer.Close()
+	go srv.IOLoop(cn)
+
+	// *-1以及*0被忽略, 连接上后面的命令正常执行
+	reader := bufio.NewReader(peer)
+	peer.Write([]byte("*-1\r\n*0\r\n*2\r\n$6\r\nEXISTS\r\n$3\r\nkey\r\n"))
+	if line, _ := reader.ReadString('\n'); line != ":0\r\n" {
+		t.Fatalf("unexpected reply %q", line)
+	}
+
+	// 协议错误和redis一样带有ERR前缀, 回复之后关闭连接
+	peer.Write([]byte("*1\r\nfoo\r\n"))
+	if line, _ := reader.ReadString('\n'); line != "-ERR Protocol error: expected '$', got 'f'\r\n" {
+		t.Fatalf("unexpected reply %q", line)
+	}
+	peer.Close()
+	waitClientsRemoved(t, srv)
+}
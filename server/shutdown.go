@@ -0,0 +1,253 @@
+package server
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/loggers"
+	"github.com/SwanSpouse/redis_go/rdb"
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+/**
+SHUTDOWN [NOSAVE|SAVE] [NOW] [FORCE] [ABORT]:
+	SHUTDOWN命令和SIGTERM、SIGINT信号走相同的流程:
+		1. 暂停执行写命令，等待所有的replica追上(输出缓冲区中的数据全部发送完)，最多等待shutdown-timeout，NOW跳过这一步;
+		   在等待的过程中可以通过SHUTDOWN ABORT取消;
+		2. 暂停执行所有的命令，等待正在执行的命令执行完成;
+		3. 把aof_buf中的数据写入AOF文件并fsync;
+		4. 如果配置了save或者指定了SAVE，进行最后一次RDB持久化，NOSAVE则跳过;
+		5. 停止监听端口，关闭所有的客户端并退出。
+	第3、4步失败的时候会取消SHUTDOWN，服务器继续提供服务，FORCE则忽略这些错误继续退出。
+*/
+const (
+	RedisShutdownSubCommandNoSave = "NOSAVE"
+	RedisShutdownSubCommandSave   = "SAVE"
+	RedisShutdownSubCommandNow    = "NOW"
+	RedisShutdownSubCommandForce  = "FORCE"
+	RedisShutdownSubCommandAbort  = "ABORT"
+
+	RedisShutdownNoFlags = 0
+	RedisShutdownSave    = 1 << 0 /* Force SAVE on SHUTDOWN even if no save points are configured. */
+	RedisShutdownNoSave  = 1 << 1 /* Don't SAVE on SHUTDOWN. */
+	RedisShutdownNow     = 1 << 2 /* Don't wait for replicas to catch up. */
+	RedisShutdownForce   = 1 << 3 /* Don't let errors prevent shutdown. */
+
+	RedisShutdownStateNone         = 0 /* No shutdown in progress */
+	RedisShutdownStateWaitReplicas = 1 /* Waiting for replicas, write commands are paused */
+	RedisShutdownStatePersisting   = 2 /* Persisting data, all commands are paused */
+	RedisShutdownStateFinished     = 3 /* Server is going to exit */
+
+	ShutdownPollInterval = 10 * time.Millisecond
+)
+
+// SHUTDOWN [NOSAVE|SAVE] [NOW] [FORCE] [ABORT]
+func (srv *Server) Shutdown(cli *client.Client) {
+	flags := RedisShutdownNoFlags
+	abort := false
+	for _, arg := range util.BytesToStrings(cli.Argv[1:]) {
+		switch strings.ToUpper(arg) {
+		case RedisShutdownSubCommandNoSave:
+			flags |= RedisShutdownNoSave
+		case RedisShutdownSubCommandSave:
+			flags |= RedisShutdownSave
+		case RedisShutdownSubCommandNow:
+			flags |= RedisShutdownNow
+		case RedisShutdownSubCommandForce:
+			flags |= RedisShutdownForce
+		case RedisShutdownSubCommandAbort:
+			abort = true
+		default:
+			cli.ResponseReError(re.ErrSyntaxError)
+			return
+		}
+	}
+	if (abort && (flags != RedisShutdownNoFlags || cli.Argc != 2)) ||
+		(flags&RedisShutdownSave != 0 && flags&RedisShutdownNoSave != 0) {
+		cli.ResponseReError(re.ErrSyntaxError)
+		return
+	}
+	if abort {
+		if err := srv.abortShutdown(); err != nil {
+			cli.ResponseReError(err)
+			return
+		}
+		cli.ResponseOK()
+		return
+	}
+	// 成功的时候客户端的连接会被直接关闭，不会收到任何回复
+	if err := srv.prepareForShutdown(flags, true); err != nil {
+		cli.ResponseReError(err)
+	}
+}
+
+// 取消正在等待replica的SHUTDOWN
+func (srv *Server) abortShutdown() error {
+	srv.shutdownLock.Lock()
+	defer srv.shutdownLock.Unlock()
+	if atomic.LoadInt32(&srv.shutdownState) != RedisShutdownStateWaitReplicas || srv.shutdownAbort == nil {
+		return re.ErrNoShutdownInProgress
+	}
+	close(srv.shutdownAbort)
+	srv.shutdownAbort = nil
+	return nil
+}
+
+/**
+执行SHUTDOWN的流程，成功的时候关闭ShutdownChan通知主程序退出。
+	inCommand表示是否是在命令中调用的, 这个时候调用者本身也是一个正在执行的命令。
+*/
+func (srv *Server) prepareForShutdown(flags int, inCommand bool) error {
+	srv.shutdownLock.Lock()
+	if !atomic.CompareAndSwapInt32(&srv.shutdownState, RedisShutdownStateNone, RedisShutdownStateWaitReplicas) {
+		srv.shutdownLock.Unlock()
+		return re.ErrShutdownInProgress
+	}
+	abort := make(chan struct{})
+	srv.shutdownAbort = abort
+	srv.shutdownLock.Unlock()
+	loggers.Info("User requested shutdown...")
+
+	if flags&RedisShutdownNow == 0 && !srv.waitForReplicas(abort, srv.Config.ShutdownTimeout) {
+		loggers.Warn("Shutdown was aborted while waiting for replicas")
+		atomic.StoreInt32(&srv.shutdownState, RedisShutdownStateNone)
+		return re.ErrShutdownFailed
+	}
+
+	srv.shutdownLock.Lock()
+	srv.shutdownAbort = nil
+	atomic.StoreInt32(&srv.shutdownState, RedisShutdownStatePersisting)
+	srv.shutdownLock.Unlock()
+
+	// 等待正在执行的命令执行完成
+	var self int64
+	if inCommand {
+		self = 1
+	}
+	for atomic.LoadInt64(&srv.inflightCommands) > self {
+		time.Sleep(ShutdownPollInterval)
+	}
+
+	if err := srv.persistOnShutdown(flags); err != nil {
+		if flags&RedisShutdownForce == 0 {
+			loggers.Warn("Errors trying to shut down the server: %s, check the logs for more information", err)
+			atomic.StoreInt32(&srv.shutdownState, RedisShutdownStateNone)
+			return re.ErrShutdownFailed
+		}
+		loggers.Warn("Error trying to persist data on shutdown: %s, exiting anyway (FORCE)", err)
+	}
+
+	atomic.StoreInt32(&srv.shutdownState, RedisShutdownStateFinished)
+	if srv.TcpListener != nil {
+		srv.TcpListener.Close()
+	}
+	srv.closeAllClients()
+	loggers.Info("Redis is now ready to exit, bye bye...")
+	srv.shutdownOnce.Do(func() {
+		close(srv.ShutdownChan)
+	})
+	return nil
+}
+
+// 等待replica把输出缓冲区中的数据全部读走, 超时或者全部追上的时候返回true, 被取消的时候返回false
+func (srv *Server) waitForReplicas(abort chan struct{}, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		lagging := 0
+		srv.mu.RLock()
+		for _, c := range srv.clients {
+			if c.Flags&client.RedisClientSlave != 0 && c.OutputBuffered() > 0 {
+				lagging++
+			}
+		}
+		srv.mu.RUnlock()
+		if lagging == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			loggers.Warn("%d replicas didn't catch up before shutdown timeout", lagging)
+			return true
+		}
+		select {
+		case <-abort:
+			return false
+		case <-time.After(ShutdownPollInterval):
+		}
+	}
+}
+
+// 把AOF缓冲区中的数据写入文件并fsync, 配置了save或者指定SAVE的时候进行RDB持久化
+func (srv *Server) persistOnShutdown(flags int) error {
+	if len(srv.aofBuf) != 0 {
+		loggers.Info("Calling fsync() on the AOF file.")
+		srv.flushAppendOnlyFile(true)
+		if len(srv.aofBuf) != 0 {
+			return re.ProtoErrorf("failed to flush %d bytes to the AOF file", len(srv.aofBuf))
+		}
+	}
+	if flags&RedisShutdownNoSave == 0 && (flags&RedisShutdownSave != 0 || len(srv.saveParams) > 0) {
+		// 等待正在进行的后台持久化结束，避免同时写同一个文件
+		for srv.Status.Load() != RedisServerStatusNormal {
+			time.Sleep(ShutdownPollInterval)
+		}
+		loggers.Info("Saving the final RDB snapshot before exiting.")
+		encoder, err := rdb.NewEncoder(srv.Config.RdbFilename)
+		if err != nil {
+			return err
+		}
+		srv.doSave(client.NewFakeClient(), encoder)
+		srv.Dirty = 0
+		srv.rdbLastSave = time.Now()
+	}
+	return nil
+}
+
+// 把输出缓冲区中的数据发送给客户端之后关闭连接
+func (srv *Server) closeAllClients() {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	for _, c := range srv.clients {
+		c.Flush()
+		c.CloseConn()
+	}
+}
+
+/**
+在执行命令之前调用, 返回false表示服务器正在退出，命令不能再执行。
+	SHUTDOWN等待replica的时候暂停执行写命令，持久化数据的时候暂停执行所有的命令(SHUTDOWN ABORT除外)。
+	CLIENT PAUSE生效的时候同样会暂停执行命令，直到超时或者CLIENT UNPAUSE。
+*/
+func (srv *Server) beginCommand(c *client.Client, cmd *client.Command) bool {
+	for {
+		atomic.AddInt64(&srv.inflightCommands, 1)
+		state := atomic.LoadInt32(&srv.shutdownState)
+		if !shutdownPausesCommand(state, cmd) && !srv.clientPausesCommand(c, cmd) {
+			return true
+		}
+		atomic.AddInt64(&srv.inflightCommands, -1)
+		if state == RedisShutdownStateFinished {
+			return false
+		}
+		time.Sleep(ShutdownPollInterval)
+	}
+}
+
+// 命令执行完成, 和beginCommand成对调用
+func (srv *Server) endCommand() {
+	atomic.AddInt64(&srv.inflightCommands, -1)
+}
+
+func shutdownPausesCommand(state int32, cmd *client.Command) bool {
+	switch state {
+	case RedisShutdownStateWaitReplicas:
+		return cmd.Flags&client.RedisCmdWrite != 0
+	case RedisShutdownStatePersisting:
+		return cmd.GetName() != RedisServerCommandShutDown
+	case RedisShutdownStateFinished:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+)
+
+func TestShutdownPausesCommand(t *testing.T) {
+	set := client.NewCommand("SET", -3, "wm", nil)
+	get := client.NewCommand("GET", 2, "r", nil)
+	shutdown := client.NewCommand(RedisServerCommandShutDown, -1, "ar", nil)
+	set.Flags = client.RedisCmdWrite
+	cases := []struct {
+		state  int32
+		cmd    *client.Command
+		paused bool
+	}{
+		{RedisShutdownStateNone, set, false},
+		{RedisShutdownStateWaitReplicas, set, true},
+		{RedisShutdownStateWaitReplicas, get, false},
+		{RedisShutdownStateWaitReplicas, shutdown, false},
+		{RedisShutdownStatePersisting, get, true},
+		{RedisShutdownStatePersisting, shutdown, false},
+		{RedisShutdownStateFinished, shutdown, true},
+	}
+	for _, c := range cases {
+		if paused := shutdownPausesCommand(c.state, c.cmd); paused != c.paused {
+			t.Fatalf("state %d command %s, expect paused %v, got %v", c.state, c.cmd.GetName(), c.paused, paused)
+		}
+	}
+}
+
+func TestShutdownWithFinalSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redis-go-shutdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := conf.NewServerConfig()
+	config.Port = 9737
+	config.RdbFilename = filepath.Join(dir, "dump.rdb")
+	config.Save = "3600 1"
+	srv := NewServer(config)
+	srv.TcpListener = srv.listen()
+	go srv.TCPServe()
+
+	cn, err := net.Dial("tcp", srv.TcpListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cn.Close()
+	reader := bufio.NewReader(cn)
+
+	// 没有正在进行的SHUTDOWN的时候不能取消
+	cn.Write([]byte("*2\r\n$8\r\nSHUTDOWN\r\n$5\r\nABORT\r\n"))
+	if line, _ := reader.ReadString('\n'); line != "-ERR No shutdown in progress.\r\n" {
+		t.Fatalf("unexpected reply %q", line)
+	}
+
+	// SHUTDOWN成功的时候不会有回复, 连接会被直接关闭
+	cn.Write([]byte("*1\r\n$8\r\nSHUTDOWN\r\n"))
+	cn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if line, err := reader.ReadString('\n'); err == nil {
+		t.Fatalf("connection should be closed, got %q", line)
+	}
+
+	select {
+	case <-srv.ShutdownChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ShutdownChan should be closed after SHUTDOWN")
+	}
+	if _, err := os.Stat(config.RdbFilename); err != nil {
+		t.Fatalf("final rdb snapshot should be saved, %s", err)
+	}
+	if _, err := net.Dial("tcp", srv.TcpListener.Addr().String()); err == nil {
+		t.Fatal("server should stop accepting connections")
+	}
+	if err := srv.prepareForShutdown(RedisShutdownNoFlags, false); err == nil {
+		t.Fatal("shutdown twice should fail")
+	}
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/tcp"
+)
+
+/**
+SLOWLOG:
+	记录执行时间超过slowlog-log-slower-than微秒的命令，最多保留slowlog-max-len条记录。
+	slowlog-log-slower-than为负数时关闭慢查询日志, 为0时记录所有的命令。
+	记录保存在一个环形数组中，新的记录会覆盖最老的记录。
+*/
+const (
+	RedisSlowLogSubCommandGet   = "GET"
+	RedisSlowLogSubCommandLen   = "LEN"
+	RedisSlowLogSubCommandReset = "RESET"
+
+	SlowLogEntryMaxArgc   = 32  /* 每条记录最多保存的参数个数 */
+	SlowLogEntryMaxString = 128 /* 每个参数最多保存的字节数 */
+	SlowLogDefaultGetLen  = 10  /* SLOWLOG GET 默认返回的记录条数 */
+)
+
+type slowLogEntry struct {
+	id         int64    // 唯一递增的id
+	time       int64    // 命令执行的unix时间戳, 单位秒
+	duration   int64    // 命令的执行时间, 单位微秒
+	argv       []string // 截断之后的命令参数
+	peerID     string   // 客户端地址
+	clientName string   // 客户端名称
+}
+
+type slowLog struct {
+	entries []*slowLogEntry // 环形数组
+	next    int             // 下一条记录写入的位置
+	length  int             // 当前记录的条数
+	entryID int64           // 下一条记录的id
+}
+
+// 截断过多的参数以及过长的参数, 避免慢查询日志占用过多的内存
+func slowLogTruncateArgv(argv [][]byte) []string {
+	argc := len(argv)
+	if argc > SlowLogEntryMaxArgc {
+		argc = SlowLogEntryMaxArgc
+	}
+	ret := make([]string, argc)
+	for i := 0; i < argc; i++ {
+		if argc != len(argv) && i == argc-1 {
+			// 最后一个参数用来记录省略了多少个参数
+			ret[i] = fmt.Sprintf("... (%d more arguments)", len(argv)-argc+1)
+		} else if len(argv[i]) > SlowLogEntryMaxString {
+			ret[i] = fmt.Sprintf("%s... (%d more bytes)", argv[i][:SlowLogEntryMaxString], len(argv[i])-SlowLogEntryMaxString)
+		} else {
+			ret[i] = string(argv[i])
+		}
+	}
+	return ret
+}
+
+// slowlog-max-len可能在运行时被修改，保留最新的maxLen条记录
+func (sl *slowLog) resize(maxLen int) {
+	if maxLen < 0 {
+		maxLen = 0
+	}
+	if len(sl.entries) == maxLen {
+		return
+	}
+	latest := sl.latest(maxLen)
+	sl.entries = make([]*slowLogEntry, maxLen)
+	sl.length = len(latest)
+	sl.next = 0
+	if maxLen > 0 {
+		// latest是从新到旧排列的, 按照从旧到新的顺序放回环形数组
+		for i := len(latest) - 1; i >= 0; i-- {
+			sl.entries[sl.next] = latest[i]
+			sl.next = (sl.next + 1) % maxLen
+		}
+	}
+}
+
+func (sl *slowLog) push(entry *slowLogEntry) {
+	if len(sl.entries) == 0 {
+		return
+	}
+	sl.entries[sl.next] = entry
+	sl.next = (sl.next + 1) % len(sl.entries)
+	if sl.length < len(sl.entries) {
+		sl.length++
+	}
+}
+
+// 返回最新的count条记录, 从新到旧排列; count小于0时返回所有的记录
+func (sl *slowLog) latest(count int) []*slowLogEntry {
+	if count < 0 || count > sl.length {
+		count = sl.length
+	}
+	ret := make([]*slowLogEntry, 0, count)
+	for i := 0; i < count; i++ {
+		idx := (sl.next - 1 - i + len(sl.entries)) % len(sl.entries)
+		ret = append(ret, sl.entries[idx])
+	}
+	return ret
+}
+
+func (sl *slowLog) reset() {
+	for i := range sl.entries {
+		sl.entries[i] = nil
+	}
+	sl.next = 0
+	sl.length = 0
+}
+
+// 在IOLoop中命令执行完成之后调用，如果命令的执行时间超过了阈值则记录到慢查询日志中
+func (srv *Server) slowLogPushEntryIfNeeded(c *client.Client, duration time.Duration) {
+	slowerThan := srv.Config.SlowLogLogSlowerThan
+	if slowerThan < 0 {
+		return
+	}
+	usec := int64(duration / time.Microsecond)
+	if usec < slowerThan {
+		return
+	}
+	entry := &slowLogEntry{
+		time:       time.Now().Unix(),
+		duration:   usec,
+		argv:       slowLogTruncateArgv(c.Argv),
+		peerID:     c.RemoteAddr().String(),
+		clientName: c.Name,
+	}
+
+	srv.slowLogLock.Lock()
+	defer srv.slowLogLock.Unlock()
+	srv.slowLog.resize(srv.Config.SlowLogMaxLen)
+	entry.id = srv.slowLog.entryID
+	srv.slowLog.entryID++
+	srv.slowLog.push(entry)
+}
+
+// SLOWLOG GET [count] | SLOWLOG LEN | SLOWLOG RESET
+func (srv *Server) SlowLog(cli *client.Client) {
+	switch strings.ToUpper(string(cli.Argv[1])) {
+	case RedisSlowLogSubCommandGet:
+		srv.slowLogGet(cli)
+	case RedisSlowLogSubCommandLen:
+		srv.slowLogLen(cli)
+	case RedisSlowLogSubCommandReset:
+		srv.slowLogReset(cli)
+	default:
+		cli.ResponseReError(re.ErrSlowLogCommand, string(cli.Argv[1]))
+	}
+}
+
+// SLOWLOG GET [count], count为-1时返回所有的记录
+func (srv *Server) slowLogGet(cli *client.Client) {
+	if cli.Argc > 3 {
+		cli.ResponseReError(re.ErrSlowLogCommand, string(cli.Argv[1]))
+		return
+	}
+	count := SlowLogDefaultGetLen
+	if cli.Argc == 3 {
+		var err error
+		if count, err = strconv.Atoi(string(cli.Argv[2])); err != nil || count < -1 {
+			cli.ResponseReError(re.ErrNotIntegerOrOutOfRange)
+			return
+		}
+	}
+
+	srv.slowLogLock.Lock()
+	srv.slowLog.resize(srv.Config.SlowLogMaxLen)
+	entries := srv.slowLog.latest(count)
+	srv.slowLogLock.Unlock()
+
+	ret := make(tcp.ArrayReply, 0, len(entries))
+	for _, entry := range entries {
+		ret = append(ret, []interface{}{entry.id, entry.time, entry.duration, entry.argv, entry.peerID, entry.clientName})
+	}
+	cli.Response(ret)
+}
+
+// SLOWLOG LEN
+func (srv *Server) slowLogLen(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrSlowLogCommand, string(cli.Argv[1]))
+		return
+	}
+	srv.slowLogLock.Lock()
+	srv.slowLog.resize(srv.Config.SlowLogMaxLen)
+	length := srv.slowLog.length
+	srv.slowLogLock.Unlock()
+	cli.Response(length)
+}
+
+// SLOWLOG RESET
+func (srv *Server) slowLogReset(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrSlowLogCommand, string(cli.Argv[1]))
+		return
+	}
+	srv.slowLogLock.Lock()
+	srv.slowLog.reset()
+	srv.slowLogLock.Unlock()
+	cli.ResponseOK()
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/SwanSpouse/redis_go/conf"
+)
+
+func TestSlowLogTruncateArgv(t *testing.T) {
+	argv := make([][]byte, 0)
+	for i := 0; i < 40; i++ {
+		argv = append(argv, []byte(fmt.Sprintf("arg-%d", i)))
+	}
+	argv[1] = []byte(strings.Repeat("a", SlowLogEntryMaxString+10))
+
+	ret := slowLogTruncateArgv(argv)
+	if len(ret) != SlowLogEntryMaxArgc {
+		t.Fatalf("expect %d args, got %d", SlowLogEntryMaxArgc, len(ret))
+	}
+	if ret[0] != "arg-0" {
+		t.Fatalf("short argument should not be truncated, got %s", ret[0])
+	}
+	if ret[1] != strings.Repeat("a", SlowLogEntryMaxString)+"... (10 more bytes)" {
+		t.Fatalf("long argument should be truncated, got %s", ret[1])
+	}
+	if ret[SlowLogEntryMaxArgc-1] != "... (9 more arguments)" {
+		t.Fatalf("unexpected last argument %s", ret[SlowLogEntryMaxArgc-1])
+	}
+
+	ret = slowLogTruncateArgv(newTestArgv("GET", "key"))
+	if len(ret) != 2 || ret[0] != "GET" || ret[1] != "key" {
+		t.Fatalf("argv should not be truncated, got %+v", ret)
+	}
+}
+
+func TestSlowLogRing(t *testing.T) {
+	sl := &slowLog{}
+	sl.resize(3)
+	for i := int64(0); i < 5; i++ {
+		sl.push(&slowLogEntry{id: i})
+	}
+	if sl.length != 3 {
+		t.Fatalf("expect 3 entries, got %d", sl.length)
+	}
+	entries := sl.latest(-1)
+	for i, id := range []int64{4, 3, 2} {
+		if entries[i].id != id {
+			t.Fatalf("expect entry %d at %d, got %d", id, i, entries[i].id)
+		}
+	}
+	if entries = sl.latest(1); len(entries) != 1 || entries[0].id != 4 {
+		t.Fatalf("expect the latest entry, got %+v", entries)
+	}
+
+	// 缩小slowlog-max-len之后只保留最新的记录
+	sl.resize(2)
+	if entries = sl.latest(-1); len(entries) != 2 || entries[0].id != 4 || entries[1].id != 3 {
+		t.Fatalf("unexpected entries after shrink %+v", entries)
+	}
+	sl.resize(4)
+	sl.push(&slowLogEntry{id: 5})
+	if entries = sl.latest(-1); len(entries) != 3 || entries[0].id != 5 || entries[2].id != 3 {
+		t.Fatalf("unexpected entries after grow %+v", entries)
+	}
+
+	sl.reset()
+	if sl.length != 0 || len(sl.latest(-1)) != 0 {
+		t.Fatalf("slow log should be empty after reset")
+	}
+
+	// slowlog-max-len为0时不记录任何命令
+	sl.resize(0)
+	sl.push(&slowLogEntry{id: 6})
+	if sl.length != 0 {
+		t.Fatalf("slow log should be disabled")
+	}
+}
+
+func TestSlowLogGetEmpty(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	cn, peer := net.Pipe()
+	defer peer.Close()
+	go srv.IOLoop(cn)
+
+	// 没有记录的时候和redis一样回复空数组
+	peer.Write([]byte("*2\r\n$7\r\nSLOWLOG\r\n$3\r\nGET\r\n"))
+	if line, _ := bufio.NewReader(peer).ReadString('\n'); line != "*0\r\n" {
+		t.Fatalf("unexpected SLOWLOG GET reply %q", line)
+	}
+}
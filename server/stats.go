@@ -0,0 +1,145 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"unicode"
+
+	"github.com/SwanSpouse/redis_go/util"
+)
+
+const (
+	StatsMetricSamples = 16 /* Number of samples per metric. */
+
+	StatsMetricCommand   = 0 /* Number of commands executed. */
+	StatsMetricNetInput  = 1 /* Bytes read to network .*/
+	StatsMetricNetOutput = 2 /* Bytes written to network. */
+	StatsMetricCount     = 3
+)
+
+// 统计网络读写字节数的连接
+type statConn struct {
+	net.Conn
+	srv *Server
+}
+
+func (sc *statConn) Read(b []byte) (int, error) {
+	n, err := sc.Conn.Read(b)
+	atomic.AddInt64(&sc.srv.statNetInputBytes, int64(n))
+	return n, err
+}
+
+func (sc *statConn) Write(b []byte) (int, error) {
+	n, err := sc.Conn.Write(b)
+	atomic.AddInt64(&sc.srv.statNetOutputBytes, int64(n))
+	return n, err
+}
+
+// 实现syscall.Conn, CLIENT LIST中需要获取连接的文件描述符
+func (sc *statConn) SyscallConn() (syscall.RawConn, error) {
+	if conn, ok := sc.Conn.(syscall.Conn); ok {
+		return conn.SyscallConn()
+	}
+	return nil, errors.New("connection does not support syscall.Conn")
+}
+
+/*
+	和redis一样，在ServerCron中对各种指标进行采样，保存最近StatsMetricSamples次的采样结果，
+	瞬时值为这些采样结果的平均值。
+*/
+type instantaneousMetric struct {
+	lastSampleTime  int64                     /* Timestamp of last sample in ms */
+	lastSampleCount int64                     /* Count in last sample */
+	samples         [StatsMetricSamples]int64 /* 每秒的增量 */
+	idx             int
+}
+
+func (srv *Server) trackInstantaneousMetric(metric int, currentReading int64) {
+	srv.statLock.Lock()
+	defer srv.statLock.Unlock()
+
+	m := &srv.instMetrics[metric]
+	now := util.GetCurrentMillisecond()
+	t := now - m.lastSampleTime
+	ops := currentReading - m.lastSampleCount
+	var opsSec int64
+	if t > 0 {
+		opsSec = ops * 1000 / t
+	}
+	m.samples[m.idx] = opsSec
+	m.idx = (m.idx + 1) % StatsMetricSamples
+	m.lastSampleTime = now
+	m.lastSampleCount = currentReading
+}
+
+func (srv *Server) getInstantaneousMetric(metric int) int64 {
+	srv.statLock.Lock()
+	defer srv.statLock.Unlock()
+
+	var sum int64
+	for _, sample := range srv.instMetrics[metric].samples {
+		sum += sample
+	}
+	return sum / StatsMetricSamples
+}
+
+// 在ServerCron中调用，对各种瞬时指标进行采样
+func (srv *Server) sampleInstantaneousMetrics() {
+	srv.trackInstantaneousMetric(StatsMetricCommand, atomic.LoadInt64(&srv.statNumCommands))
+	srv.trackInstantaneousMetric(StatsMetricNetInput, atomic.LoadInt64(&srv.statNetInputBytes))
+	srv.trackInstantaneousMetric(StatsMetricNetOutput, atomic.LoadInt64(&srv.statNetOutputBytes))
+}
+
+// 获取错误回复的错误码, 如果错误信息不是以大写的错误码开头则默认为ERR
+func getErrorCode(msg string) string {
+	code := msg
+	if idx := strings.IndexByte(msg, ' '); idx > 0 {
+		code = msg[:idx]
+	}
+	for _, ch := range code {
+		if !unicode.IsUpper(ch) {
+			return "ERR"
+		}
+	}
+	if code == "" {
+		return "ERR"
+	}
+	return code
+}
+
+// 统计每一种错误回复的次数，用于INFO errorstats
+func (srv *Server) incrErrorReplyStat(msg string) {
+	srv.statLock.Lock()
+	defer srv.statLock.Unlock()
+
+	srv.statTotalErrorReplies += 1
+	srv.errorStats[getErrorCode(msg)] += 1
+}
+
+// 重置INFO中的各种统计信息, 用于CONFIG RESETSTAT
+func (srv *Server) resetServerStats() {
+	for _, cmd := range srv.commandTable {
+		cmd.ResetStats()
+	}
+	for _, db := range srv.Databases {
+		db.ResetStats()
+	}
+	atomic.StoreInt64(&srv.statNumCommands, 0)
+	atomic.StoreInt64(&srv.statNumConnections, 0)
+	atomic.StoreInt64(&srv.statRejectedConn, 0)
+	atomic.StoreInt64(&srv.statObufLimitDisconns, 0)
+	atomic.StoreInt64(&srv.statQbufLimitDisconns, 0)
+	atomic.StoreInt64(&srv.statNetInputBytes, 0)
+	atomic.StoreInt64(&srv.statNetOutputBytes, 0)
+	atomic.StoreInt64(&srv.statEvictedKeys, 0)
+	atomic.StoreInt64(&srv.statPeakMemory, srv.usedMemory())
+
+	srv.statLock.Lock()
+	defer srv.statLock.Unlock()
+	srv.statTotalErrorReplies = 0
+	srv.errorStats = make(map[string]int64)
+	srv.instMetrics = [StatsMetricCount]instantaneousMetric{}
+}
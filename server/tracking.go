@@ -0,0 +1,429 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/SwanSpouse/redis_go/client"
+	re "github.com/SwanSpouse/redis_go/error"
+	"github.com/SwanSpouse/redis_go/tcp"
+)
+
+/**
+CLIENT TRACKING:
+	client side caching, 客户端开启tracking之后, 缓存的key被修改的时候服务端会给客户端发送失效消息。
+	默认模式:
+		服务端在trackingTable中记录每个key被哪些客户端读取过, key被修改之后给这些客户端发送一次失效消息, 然后删除记录。
+		OPTIN模式下只记录CLIENT CACHING yes之后的下一条命令读取的key, OPTOUT模式下不记录CLIENT CACHING no之后的下一条命令读取的key。
+	BCAST模式:
+		客户端通过PREFIX关注一些key前缀, 没有PREFIX的时候关注所有的key。匹配前缀的key被修改之后都会发送失效消息, 服务端不需要记录客户端读取过哪些key。
+	NOLOOP表示客户端自己修改的key不会给自己发送失效消息。
+	失效消息:
+		RESP3的客户端收到的是push消息: >2\r\n$10\r\ninvalidate\r\n*1\r\n$3\r\nkey\r\n
+		RESP2的连接不支持push消息, 需要通过REDIRECT转发给另一个订阅了__redis__:invalidate频道的连接。
+		FLUSHDB、FLUSHALL之后给所有开启了tracking的客户端发送key为null的失效消息。
+	和aof的propagate一样, 写命令执行之后根据client.Dirty判断是否修改了key, 过期删除以及maxmemory淘汰的key也会发送失效消息。
+*/
+const (
+	RedisClientSubCommandTracking     = "TRACKING"
+	RedisClientSubCommandTrackingInfo = "TRACKINGINFO"
+	RedisClientSubCommandCaching      = "CACHING"
+	RedisClientSubCommandGetRedir     = "GETREDIR"
+
+	RedisTrackingInvalidateChannel  = "__redis__:invalidate"
+	RedisTrackingMessageInvalidate  = "invalidate"
+	RedisTrackingMessageRedirBroken = "tracking-redir-broken"
+)
+
+/**
+CLIENT TRACKING ON|OFF [REDIRECT client-id] [PREFIX prefix [PREFIX prefix ...]] [BCAST] [OPTIN] [OPTOUT] [NOLOOP]
+	再次执行CLIENT TRACKING ON的时候会更新OPTIN、OPTOUT、NOLOOP以及REDIRECT, PREFIX会添加到已有的前缀中。
+*/
+func (srv *Server) clientTracking(cli *client.Client) {
+	if cli.Argc < 3 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	var redir int64
+	var options int
+	var prefixes []string
+	for i := 3; i < cli.Argc; i++ {
+		moreArgs := cli.Argc - 1 - i
+		option := strings.ToUpper(string(cli.Argv[i]))
+		if option == "REDIRECT" && moreArgs > 0 {
+			i++
+			if redir != 0 {
+				cli.ResponseReError(re.ErrTrackingRedirMulti)
+				return
+			}
+			id, err := strconv.ParseInt(string(cli.Argv[i]), 10, 64)
+			if err != nil || id <= 0 {
+				cli.ResponseReError(re.ErrClientInvalidID)
+				return
+			}
+			redir = id
+		} else if option == "BCAST" {
+			options |= client.RedisClientTrackingBcast
+		} else if option == "OPTIN" {
+			options |= client.RedisClientTrackingOptIn
+		} else if option == "OPTOUT" {
+			options |= client.RedisClientTrackingOptOut
+		} else if option == "NOLOOP" {
+			options |= client.RedisClientTrackingNoLoop
+		} else if option == "PREFIX" && moreArgs > 0 {
+			i++
+			prefixes = append(prefixes, string(cli.Argv[i]))
+		} else {
+			cli.ResponseReError(re.ErrSyntaxError)
+			return
+		}
+	}
+
+	switch strings.ToUpper(string(cli.Argv[2])) {
+	case "ON":
+		if options&client.RedisClientTrackingBcast == 0 && len(prefixes) > 0 {
+			cli.ResponseReError(re.ErrTrackingPrefixNoBcast)
+			return
+		}
+		if cli.Flags&client.RedisClientTracking != 0 &&
+			(cli.Flags^options)&client.RedisClientTrackingBcast != 0 {
+			cli.ResponseReError(re.ErrTrackingSwitchBcast)
+			return
+		}
+		if options&client.RedisClientTrackingOptIn != 0 && options&client.RedisClientTrackingOptOut != 0 {
+			cli.ResponseReError(re.ErrTrackingOptInOptOut)
+			return
+		}
+		if options&client.RedisClientTrackingBcast != 0 &&
+			options&(client.RedisClientTrackingOptIn|client.RedisClientTrackingOptOut) != 0 {
+			cli.ResponseReError(re.ErrTrackingOptBcast)
+			return
+		}
+		if redir != 0 && srv.lookupClient(redir) == nil {
+			cli.ResponseReError(re.ErrTrackingRedirNotExist)
+			return
+		}
+		if !checkTrackingPrefixes(cli, prefixes) {
+			return
+		}
+		srv.enableTracking(cli, redir, options, prefixes)
+	case "OFF":
+		srv.disableTracking(cli)
+	default:
+		cli.ResponseReError(re.ErrSyntaxError)
+		return
+	}
+	cli.ResponseOK()
+}
+
+// CLIENT CACHING YES|NO
+func (srv *Server) clientCaching(cli *client.Client) {
+	if cli.Argc != 3 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	if cli.Flags&client.RedisClientTracking == 0 {
+		cli.ResponseReError(re.ErrTrackingCachingMode)
+		return
+	}
+	switch strings.ToUpper(string(cli.Argv[2])) {
+	case "YES":
+		if cli.Flags&client.RedisClientTrackingOptIn == 0 {
+			cli.ResponseReError(re.ErrTrackingCachingYes)
+			return
+		}
+	case "NO":
+		if cli.Flags&client.RedisClientTrackingOptOut == 0 {
+			cli.ResponseReError(re.ErrTrackingCachingNo)
+			return
+		}
+	default:
+		cli.ResponseReError(re.ErrSyntaxError)
+		return
+	}
+	// 只对下一条命令生效, 在trackingHandleCommand中清除
+	cli.Flags |= client.RedisClientTrackingCaching
+	cli.ResponseOK()
+}
+
+// CLIENT GETREDIR, 没有开启tracking返回-1, 没有REDIRECT返回0
+func (srv *Server) clientGetRedir(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	if cli.Flags&client.RedisClientTracking == 0 {
+		cli.Response(-1)
+		return
+	}
+	cli.Response(cli.TrackRedirect)
+}
+
+// CLIENT TRACKINGINFO
+func (srv *Server) clientTrackingInfo(cli *client.Client) {
+	if cli.Argc != 2 {
+		cli.ResponseReError(re.ErrClientCommand, string(cli.Argv[1]))
+		return
+	}
+	flags := tcp.SetReply{}
+	if cli.Flags&client.RedisClientTracking == 0 {
+		flags = append(flags, "off")
+	} else {
+		flags = append(flags, "on")
+		names := []struct {
+			flag int
+			name string
+		}{
+			{client.RedisClientTrackingBcast, "bcast"},
+			{client.RedisClientTrackingOptIn, "optin"},
+			{client.RedisClientTrackingOptOut, "optout"},
+			{client.RedisClientTrackingCaching, "caching-yes"},
+			{client.RedisClientTrackingNoLoop, "noloop"},
+		}
+		for _, item := range names {
+			if cli.Flags&item.flag == 0 {
+				continue
+			}
+			// OPTOUT模式下CACHING表示的是CACHING no
+			if item.flag == client.RedisClientTrackingCaching && cli.Flags&client.RedisClientTrackingOptOut != 0 {
+				flags = append(flags, "caching-no")
+				continue
+			}
+			flags = append(flags, item.name)
+		}
+		if cli.IsTrackingBrokenRedir() {
+			flags = append(flags, "broken_redirect")
+		}
+	}
+	redirect := int64(-1)
+	if cli.Flags&client.RedisClientTracking != 0 {
+		redirect = cli.TrackRedirect
+	}
+	prefixes := tcp.ArrayReply{}
+	for _, prefix := range cli.TrackPrefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	cli.Response(tcp.MapReply{
+		"flags", flags,
+		"redirect", redirect,
+		"prefixes", prefixes,
+	})
+}
+
+// 同一个客户端的前缀之间不能互相包含
+func checkTrackingPrefixes(cli *client.Client, prefixes []string) bool {
+	for i, prefix := range prefixes {
+		for _, existing := range cli.TrackPrefixes {
+			if strings.HasPrefix(prefix, existing) || strings.HasPrefix(existing, prefix) {
+				cli.ResponseReError(re.ErrTrackingPrefixExisting, prefix, existing)
+				return false
+			}
+		}
+		for j, other := range prefixes {
+			if i != j && (strings.HasPrefix(prefix, other) || strings.HasPrefix(other, prefix)) {
+				cli.ResponseReError(re.ErrTrackingPrefixProvided, prefix, other)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (srv *Server) lookupClient(id int64) *client.Client {
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	return srv.clients[id]
+}
+
+func (srv *Server) enableTracking(cli *client.Client, redir int64, options int, prefixes []string) {
+	srv.trackingLock.Lock()
+	defer srv.trackingLock.Unlock()
+	if cli.Flags&client.RedisClientTracking == 0 {
+		atomic.AddInt64(&srv.trackingClients, 1)
+	}
+	cli.Flags |= client.RedisClientTracking
+	cli.Flags &^= client.RedisClientTrackingBcast | client.RedisClientTrackingOptIn |
+		client.RedisClientTrackingOptOut | client.RedisClientTrackingNoLoop
+	cli.ResetTrackingBrokenRedir()
+	cli.Flags |= options
+	cli.TrackRedirect = redir
+
+	if options&client.RedisClientTrackingBcast == 0 {
+		return
+	}
+	// 没有指定前缀的时候关注所有的key
+	if len(prefixes) == 0 && len(cli.TrackPrefixes) == 0 {
+		prefixes = []string{""}
+	}
+	for _, prefix := range prefixes {
+		ids, ok := srv.trackingPrefixes[prefix]
+		if !ok {
+			ids = make(map[int64]bool)
+			srv.trackingPrefixes[prefix] = ids
+		}
+		ids[cli.ID()] = true
+		cli.TrackPrefixes = append(cli.TrackPrefixes, prefix)
+	}
+}
+
+// 关闭tracking, 默认模式下trackingTable中的记录在发送失效消息的时候再删除
+func (srv *Server) disableTracking(cli *client.Client) {
+	if cli.Flags&client.RedisClientTracking == 0 {
+		return
+	}
+	srv.trackingLock.Lock()
+	defer srv.trackingLock.Unlock()
+	for _, prefix := range cli.TrackPrefixes {
+		if ids, ok := srv.trackingPrefixes[prefix]; ok {
+			delete(ids, cli.ID())
+			if len(ids) == 0 {
+				delete(srv.trackingPrefixes, prefix)
+			}
+		}
+	}
+	cli.TrackPrefixes = nil
+	cli.TrackRedirect = 0
+	cli.ResetTrackingBrokenRedir()
+	cli.Flags &^= client.RedisClientTracking | client.RedisClientTrackingBcast |
+		client.RedisClientTrackingOptIn | client.RedisClientTrackingOptOut | client.RedisClientTrackingCaching |
+		client.RedisClientTrackingNoLoop
+	atomic.AddInt64(&srv.trackingClients, -1)
+}
+
+// 命令执行之后调用
+func (srv *Server) trackingHandleCommand(c *client.Client) {
+	if c.Cmd.Flags&client.RedisCmdWrite > 0 && c.Dirty != 0 {
+		srv.trackingInvalidateKeys(c, getCommandKeys(c))
+	} else if c.Cmd.Flags&client.RedisCmdReadOnly > 0 {
+		srv.trackingRememberKeys(c)
+	}
+	// CLIENT CACHING只对下一条命令生效
+	if c.Flags&client.RedisClientTrackingCaching != 0 && !isClientCachingCommand(c) {
+		c.Flags &^= client.RedisClientTrackingCaching
+	}
+}
+
+func isClientCachingCommand(c *client.Client) bool {
+	return c.Cmd.GetName() == RedisServerCommandClient && c.Argc > 1 &&
+		strings.ToUpper(string(c.Argv[1])) == RedisClientSubCommandCaching
+}
+
+// 记录客户端读取过的key, 只有默认模式需要记录
+func (srv *Server) trackingRememberKeys(c *client.Client) {
+	if c.Flags&client.RedisClientTracking == 0 || c.Flags&client.RedisClientTrackingBcast != 0 {
+		return
+	}
+	caching := c.Flags&client.RedisClientTrackingCaching != 0
+	if (c.Flags&client.RedisClientTrackingOptIn != 0 && !caching) || (c.Flags&client.RedisClientTrackingOptOut != 0 && caching) {
+		return
+	}
+	keys := getCommandKeys(c)
+	if len(keys) == 0 {
+		return
+	}
+	srv.trackingLock.Lock()
+	defer srv.trackingLock.Unlock()
+	for _, key := range keys {
+		ids, ok := srv.trackingTable[key]
+		if !ok {
+			ids = make(map[int64]bool)
+			srv.trackingTable[key] = ids
+		}
+		ids[c.ID()] = true
+	}
+}
+
+func (srv *Server) trackingInvalidateKeys(c *client.Client, keys []string) {
+	for _, key := range keys {
+		srv.trackingInvalidateKey(c, key)
+	}
+}
+
+/**
+key被修改之后给缓存了这个key的客户端发送失效消息, c是修改key的客户端, 过期以及淘汰的时候为nil。
+默认模式下发送之后删除trackingTable中的记录, 客户端需要再次读取这个key才会继续收到失效消息。
+*/
+func (srv *Server) trackingInvalidateKey(c *client.Client, key string) {
+	if atomic.LoadInt64(&srv.trackingClients) == 0 {
+		return
+	}
+	srv.trackingLock.Lock()
+	targets := make(map[int64]bool)
+	for prefix, ids := range srv.trackingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			for id := range ids {
+				targets[id] = true
+			}
+		}
+	}
+	for id := range srv.trackingTable[key] {
+		targets[id] = false
+	}
+	delete(srv.trackingTable, key)
+	srv.trackingLock.Unlock()
+
+	for id, bcast := range targets {
+		target := srv.lookupClient(id)
+		if target == nil || target.Flags&client.RedisClientTracking == 0 {
+			continue
+		}
+		// 默认模式下的记录可能是切换到BCAST模式之前留下的
+		if !bcast && target.Flags&client.RedisClientTrackingBcast != 0 {
+			continue
+		}
+		if target == c && target.Flags&client.RedisClientTrackingNoLoop != 0 {
+			continue
+		}
+		srv.sendTrackingMessage(target, tcp.ArrayReply{key})
+	}
+}
+
+// FLUSHDB、FLUSHALL之后给所有开启了tracking的客户端发送key为null的失效消息
+func (srv *Server) trackingInvalidateKeysOnFlush() {
+	if atomic.LoadInt64(&srv.trackingClients) == 0 {
+		return
+	}
+	for _, c := range srv.getSortedClients() {
+		if c.Flags&client.RedisClientTracking != 0 {
+			srv.sendTrackingMessage(c, nil)
+		}
+	}
+	srv.trackingLock.Lock()
+	srv.trackingTable = make(map[string]map[int64]bool)
+	srv.trackingLock.Unlock()
+}
+
+/**
+发送失效消息, keys为nil的时候表示所有的key都失效了。
+REDIRECT的客户端已经关闭的时候, 只给RESP3的客户端发送一次tracking-redir-broken消息。
+c一般属于其他的goroutine, 消息只写入接收者的输出缓冲区, 由接收者自己发送, 读取得很慢的客户端不会阻塞修改key的客户端。
+*/
+func (srv *Server) sendTrackingMessage(c *client.Client, keys interface{}) {
+	receiver := c
+	if c.TrackRedirect != 0 {
+		receiver = srv.lookupClient(c.TrackRedirect)
+		if receiver == nil {
+			if c.MarkTrackingBrokenRedir() && c.Protocol() == tcp.RespProto3 {
+				c.PushResponse(tcp.PushReply{RedisTrackingMessageRedirBroken, c.TrackRedirect})
+			}
+			return
+		}
+	}
+	if receiver.Protocol() == tcp.RespProto3 {
+		receiver.PushResponse(tcp.PushReply{RedisTrackingMessageInvalidate, keys})
+	} else if receiver.PubSubChannels.ContainsKey(RedisTrackingInvalidateChannel) {
+		// RESP2的连接只能通过发布订阅的消息格式接收
+		receiver.PushResponse(tcp.PushReply{PubSubResponseStringMessage, RedisTrackingInvalidateChannel, keys})
+	}
+}
+
+// INFO中的tracking统计, items是所有key被记录的客户端的总数
+func (srv *Server) trackingStats() (clients int64, keys int, items int, prefixes int) {
+	srv.trackingLock.Lock()
+	defer srv.trackingLock.Unlock()
+	for _, ids := range srv.trackingTable {
+		items += len(ids)
+	}
+	return atomic.LoadInt64(&srv.trackingClients), len(srv.trackingTable), items, len(srv.trackingPrefixes)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/SwanSpouse/redis_go/client"
+	"github.com/SwanSpouse/redis_go/conf"
+	"github.com/SwanSpouse/redis_go/handlers"
+	"github.com/SwanSpouse/redis_go/tcp"
+)
+
+func TestCommandGetKeys(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	cases := []struct {
+		argv     []string
+		expected []string
+	}{
+		{[]string{handlers.RedisStringCommandGet, "a"}, []string{"a"}},
+		{[]string{handlers.RedisKeyCommandDel, "a", "b", "c"}, []string{"a", "b", "c"}},
+		{[]string{handlers.RedisStringCommandMSet, "a", "1", "b", "2"}, []string{"a", "b"}},
+		{[]string{handlers.RedisKeyCommandRename, "a", "b"}, []string{"a", "b"}},
+		{[]string{handlers.RedisKeyCommandKeys, "*"}, nil},
+		{[]string{handlers.RedisListCommandRPopLPush, "a", "b"}, []string{"a", "b"}},
+		{[]string{handlers.RedisListCommandLMove, "a", "b", "LEFT", "RIGHT"}, []string{"a", "b"}},
+		{[]string{handlers.RedisListCommandBRPopLPush, "a", "b", "0"}, []string{"a", "b"}},
+		{[]string{handlers.RedisListCommandBLMove, "a", "b", "LEFT", "RIGHT", "0"}, []string{"a", "b"}},
+		{[]string{handlers.RedisSetCommandSMOVE, "a", "b", "m"}, []string{"a", "b"}},
+		{[]string{handlers.RedisListCommandBLPop, "a", "b", "0"}, []string{"a", "b"}},
+		{[]string{handlers.RedisListCommandBLMPop, "0", "2", "a", "b", "LEFT"}, []string{"a", "b"}},
+		{[]string{handlers.RedisListCommandBLMPop, "0", "3", "a", "b"}, nil},
+	}
+	for _, c := range cases {
+		keys := srv.commandTable[c.argv[0]].GetKeys(newTestArgv(c.argv...))
+		if len(keys) != len(c.expected) {
+			t.Fatalf("%v expect keys %v, got %v", c.argv, c.expected, keys)
+		}
+		for i := range keys {
+			if keys[i] != c.expected[i] {
+				t.Fatalf("%v expect keys %v, got %v", c.argv, c.expected, keys)
+			}
+		}
+	}
+}
+
+func TestTrackingPrefixes(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	// 错误回复会直接flush, 需要读取掉
+	go io.Copy(io.Discard, peer)
+	srv.enableTracking(c, 0, client.RedisClientTrackingBcast, []string{"user:", "order:"})
+	if clients, _, _, prefixes := srv.trackingStats(); clients != 1 || prefixes != 2 {
+		t.Fatalf("unexpected tracking stats clients=%d prefixes=%d", clients, prefixes)
+	}
+	if checkTrackingPrefixes(c, []string{"user:1"}) || checkTrackingPrefixes(c, []string{"a", "ab"}) {
+		t.Fatalf("overlapping prefixes should be rejected")
+	}
+	if !checkTrackingPrefixes(c, []string{"item:", "cart:"}) {
+		t.Fatalf("prefixes without overlap should be accepted")
+	}
+	srv.disableTracking(c)
+	if clients, _, _, prefixes := srv.trackingStats(); clients != 0 || prefixes != 0 || len(c.TrackPrefixes) != 0 {
+		t.Fatalf("tracking state should be cleared, clients=%d prefixes=%d", clients, prefixes)
+	}
+}
+
+func TestTrackingSlowReceiver(t *testing.T) {
+	srv := NewServer(conf.NewServerConfig())
+	// 不读取失效消息, 模拟一个很慢的客户端
+	c, peer := newTestClient(1)
+	defer peer.Close()
+	c.SetProtocol(tcp.RespProto3)
+	srv.addClient(c)
+	srv.enableTracking(c, 0, client.RedisClientTrackingBcast, nil)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			srv.trackingInvalidateKey(nil, "key")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow tracking client should not block the writer")
+	}
+
+	// REDIRECT的客户端不存在的时候只标记一次
+	redirected, redirectedPeer := newTestClient(2)
+	defer redirectedPeer.Close()
+	srv.addClient(redirected)
+	srv.enableTracking(redirected, 100, 0, nil)
+	srv.sendTrackingMessage(redirected, nil)
+	if !redirected.IsTrackingBrokenRedir() || redirected.MarkTrackingBrokenRedir() {
+		t.Fatal("broken redirect should be marked once")
+	}
+	srv.removeClient(redirected)
+	srv.removeClient(c)
+}
@@ -1,6 +1,8 @@
 package tcp
 
 import (
+	"math"
+
 	re "github.com/SwanSpouse/redis_go/error"
 )
 
@@ -44,9 +46,9 @@ func (buf buffer) FirstWord() string {
 func (buf buffer) ParseInt() (int64, error) {
 	data := buf.TrimCRLF()
 	if len(data) < 2 {
-		return 0, re.ProtoErrorf("Protocol error: expected ':', got ' '")
+		return 0, re.ProtoErrorf("ERR Protocol error: expected ':', got ' '")
 	} else if data[0] != ':' {
-		return 0, re.ProtoErrorf("Protocol error: expected ':', got '%s'", string(data[0]))
+		return 0, re.ProtoErrorf("ERR Protocol error: expected ':', got '%s'", string(data[0]))
 	}
 
 	n, m := int64(0), int64(1)
@@ -66,34 +68,47 @@ func (buf buffer) ParseInt() (int64, error) {
 func (buf buffer) ParseMessage(prefix byte) (string, error) {
 	data := buf.TrimCRLF()
 	if len(data) < 1 {
-		return "", re.ProtoErrorf("Protocol error: expected '%s', got ' '", string(prefix))
+		return "", re.ProtoErrorf("ERR Protocol error: expected '%s', got ' '", string(prefix))
 	} else if data[0] != prefix {
-		return "", re.ProtoErrorf("Protocol error: expected '%s', got '%s'", string(prefix), string(data[0]))
+		return "", re.ProtoErrorf("ERR Protocol error: expected '%s', got '%s'", string(prefix), string(data[0]))
 	}
 	return string(data[1:]), nil
 }
 
+// ParseArrayLen parses the length of a multi bulk, negative lengths such as *-1 are returned as 0
+func (buf buffer) ParseArrayLen() (int64, error) {
+	data := buf.TrimCRLF()
+	if len(data) > 2 && data[0] == '*' && data[1] == '-' {
+		// 和redis一样, 长度小于等于0的multi bulk请求被忽略, 但是长度仍然需要是合法的数字
+		if _, err := append(buffer{'*'}, data[2:]...).ParseSize('*', re.ErrInvalidMultiBulkLength); err != nil {
+			return 0, err
+		}
+		return 0, nil
+	}
+	return buf.ParseSize('*', re.ErrInvalidMultiBulkLength)
+}
+
 // ParseSize parses a size with prefix
 func (buf buffer) ParseSize(prefix byte, fallback error) (int64, error) {
 	data := buf.TrimCRLF()
 
 	if len(data) == 0 {
-		return 0, re.ProtoErrorf("Protocol error: expected '%s', got ' '", string(prefix))
+		return 0, re.ProtoErrorf("ERR Protocol error: expected '%s', got ' '", string(prefix))
 	} else if data[0] != prefix {
-		return 0, re.ProtoErrorf("Protocol error: expected '%s', got '%s'", string(prefix), string(data[0]))
+		return 0, re.ProtoErrorf("ERR Protocol error: expected '%s', got '%s'", string(prefix), string(data[0]))
 	} else if len(data) < 2 {
 		return 0, fallback
 	}
 	var n int64
 	for _, c := range data[1:] {
-		if c >= '0' && c <= '9' {
-			n = n*10 + int64(c-'0')
-		} else {
+		if c < '0' || c > '9' {
 			return 0, fallback
 		}
-	}
-	if n < 0 {
-		return 0, fallback
+		// overflow
+		if n > (math.MaxInt64-int64(c-'0'))/10 {
+			return 0, fallback
+		}
+		n = n*10 + int64(c-'0')
 	}
 	return n, nil
 }
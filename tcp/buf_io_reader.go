@@ -11,12 +11,21 @@ import (
 )
 
 type BufIoReader struct {
-	rd  io.Reader
-	buf []byte
-	r   int // reader index
-	w   int // writer index
+	rd             io.Reader
+	buf            []byte
+	r              int   // reader index
+	w              int   // writer index
+	maxBulkLen     int64 // max length of a bulk, 0 means no limit
+	maxQueryBufLen int64 // max size of the buffer, 0 means no limit
 }
 
+const (
+	// MaxInlineSize is the max length of a line without CRLF, the same as PROTO_INLINE_MAX_SIZE
+	MaxInlineSize = 64 * 1024
+	// MaxMultiBulkLen is the max number of arguments of a request
+	MaxMultiBulkLen = 1024 * 1024
+)
+
 // Reader连接池
 var ReaderPool = &sync.Pool{
 	New: func() interface{} {
@@ -45,8 +54,25 @@ func (r *BufIoReader) reset(buf []byte, rd io.Reader) {
 	*r = BufIoReader{buf: buf, rd: rd}
 }
 
+// sets the max length of a bulk and the max size of the buffer, 0 means no limit
+func (r *BufIoReader) SetLimits(maxBulkLen, maxQueryBufLen int64) {
+	r.maxBulkLen = maxBulkLen
+	r.maxQueryBufLen = maxQueryBufLen
+}
+
+// returns the number of free bytes in the buffer
+func (r *BufIoReader) Free() int {
+	return len(r.buf) - r.w
+}
+
 // compact moves the unread chunk to the beginning of the buffer
 func (r *BufIoReader) compact() {
+	// release the large buffer allocated for a big bulk
+	if r.r == r.w && len(r.buf) > MaxBufferSize {
+		r.buf = MkStdBuffer()
+		r.r, r.w = 0, 0
+		return
+	}
 	if r.r > 0 {
 		copy(r.buf, r.buf[r.r:r.w])
 		r.w = r.w - r.r
@@ -62,38 +88,62 @@ func (r *BufIoReader) Buffered() int {
 
 // make sure that sz bytes can be buffered
 func (r *BufIoReader) require(sz int) error {
-	extra := sz - r.Buffered()
-	if extra < 1 {
+	if sz-r.Buffered() < 1 {
 		return nil
 	}
+	if r.maxQueryBufLen > 0 && int64(sz) > r.maxQueryBufLen {
+		return re.ErrQueryBufferLimit
+	}
 	// compact first
 	r.compact()
 
-	// grow the buffer if necessary
-	if n := r.w + extra; n > len(r.buf) {
-		buf := make([]byte, n)
-		copy(buf, buf[:r.w])
-		r.buf = buf
+	// read data into buffer, the buffer grows with the data received instead of the size claimed by the client
+	for r.w < sz {
+		if r.w == len(r.buf) {
+			r.grow(sz)
+		}
+		n, err := r.rd.Read(r.buf[r.w:])
+		r.w += n
+		if err != nil && r.w < sz {
+			if err == io.EOF && r.w > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
 	}
+	return nil
+}
 
-	// read data into buffer
-	n, err := io.ReadAtLeast(r.rd, r.buf[r.w:], extra)
-	r.w += n
-
-	return err
+// grow the buffer by doubling its size, but not more than sz bytes
+func (r *BufIoReader) grow(sz int) {
+	n := 2 * len(r.buf)
+	if n == 0 || n > sz {
+		n = sz
+	}
+	buf := make([]byte, n)
+	copy(buf, r.buf[:r.w])
+	r.buf = buf
 }
 
 // tries to read more data into the buffer
 func (r *BufIoReader) fill() error {
 	r.compact()
 
-	if r.w < len(r.buf) {
-		n, err := r.rd.Read(r.buf[r.w:])
-		r.w += n
-		//log.Info("current io reader buffer %s", string(r.buf[r.r:r.w]))
-		return err
+	if r.w == len(r.buf) {
+		if r.maxQueryBufLen > 0 && int64(2*len(r.buf)) > r.maxQueryBufLen {
+			return re.ErrQueryBufferLimit
+		}
+		r.grow(2*len(r.buf) + 1)
 	}
-	return nil
+	n, err := r.rd.Read(r.buf[r.w:])
+	r.w += n
+	//log.Info("current io reader buffer %s", string(r.buf[r.r:r.w]))
+	return err
+}
+
+// reads more data into the buffer, the buffered data is kept for the following reads
+func (r *BufIoReader) Fill() error {
+	return r.fill()
 }
 
 // peek byte of the buffer
@@ -106,30 +156,23 @@ func (r *BufIoReader) PeekByte() (byte, error) {
 
 // PeekLine returns the next line until CRLF without reading it
 func (r *BufIoReader) PeekLine(offset int) (buffer, error) {
-	index := -1
-
-	// try to find the end of the line
-	start := r.r + offset
-	if start < r.w {
-		index = bytes.IndexByte(r.buf[start:r.w], '\n')
-	}
-
-	// try to read more data into the buffer if not in the buffer
-	if index < 0 {
+	for {
+		// try to find the end of the line
+		start := r.r + offset
+		if start < r.w {
+			if index := bytes.IndexByte(r.buf[start:r.w], '\n'); index >= 0 {
+				return buffer(r.buf[start : start+index+1]), nil
+			}
+		}
+		// fail if the line is too long
+		if r.w-start >= MaxInlineSize {
+			return nil, re.ErrInlineRequestTooLong
+		}
+		// try to read more data into the buffer if not in the buffer
 		if err := r.fill(); err != nil {
 			return nil, err
 		}
-		start = r.r + offset
-		if start < r.w {
-			index = bytes.IndexByte(r.buf[start:r.w], '\n')
-		}
 	}
-
-	// fail if still nothing found
-	if index < 0 {
-		return nil, re.ErrInlineRequestTooLong
-	}
-	return buffer(r.buf[start : start+index+1]), nil
 }
 
 /*
@@ -217,10 +260,12 @@ func (r *BufIoReader) ReadInlineString() (string, error) {
 
 func (r *BufIoReader) ReadArrayLen() (int, error) {
 	line, err := r.ReadLine()
-	if err != nil {
+	if err == re.ErrInlineRequestTooLong {
+		return 0, re.ErrMultiBulkCountTooBig
+	} else if err != nil {
 		return 0, err
 	}
-	sz, err := line.ParseSize('*', re.ErrInvalidMultiBulkLength)
+	sz, err := line.ParseArrayLen()
 	if err != nil {
 		return 0, err
 	}
@@ -229,10 +274,34 @@ func (r *BufIoReader) ReadArrayLen() (int, error) {
 
 func (r *BufIoReader) ReadBulkLen() (int64, error) {
 	line, err := r.ReadLine()
+	if err == re.ErrInlineRequestTooLong {
+		return 0, re.ErrBulkCountTooBig
+	} else if err != nil {
+		return 0, err
+	}
+	sz, err := line.ParseSize('$', re.ErrInvalidBulkLength)
 	if err != nil {
 		return 0, err
 	}
-	return line.ParseSize('$', re.ErrInvalidBulkLength)
+	if r.maxBulkLen > 0 && sz > r.maxBulkLen {
+		return 0, re.ErrInvalidBulkLength
+	}
+	return sz, nil
+}
+
+// 读取长度为sz的bulk内容以及结尾的\r\n, 返回的数据引用的是缓冲区, 只在下一次读取之前有效
+func (r *BufIoReader) ReadBulkBody(sz int64) ([]byte, error) {
+	if err := r.require(int(sz + 2)); err != nil {
+		return nil, err
+	}
+	end := r.r + int(sz)
+	// bulk string的结尾必须是\r\n, 否则说明客户端声明的长度和实际的数据不一致
+	if r.buf[end] != '\r' || r.buf[end+1] != '\n' {
+		return nil, re.ErrBulkWithoutCRLF
+	}
+	body := r.buf[r.r:end]
+	r.r = end + 2
+	return body, nil
 }
 
 func (r *BufIoReader) ReadBulk(p []byte) ([]byte, error) {
@@ -240,12 +309,11 @@ func (r *BufIoReader) ReadBulk(p []byte) ([]byte, error) {
 	if err != nil {
 		return p, err
 	}
-	if err := r.require(int(sz + 2)); err != nil {
+	body, err := r.ReadBulkBody(sz)
+	if err != nil {
 		return p, err
 	}
-	p = append(p, r.buf[r.r:r.r+int(sz)]...)
-	r.r += int(sz + 2)
-	return p, nil
+	return append(p, body...), nil
 }
 
 func (r *BufIoReader) ReadBulkString() (string, error) {
@@ -253,12 +321,11 @@ func (r *BufIoReader) ReadBulkString() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if err := r.require(int(sz + 2)); err != nil {
+	body, err := r.ReadBulkBody(sz)
+	if err != nil {
 		return "", err
 	}
-	s := string(r.buf[r.r : r.r+int(sz)])
-	r.r += int(sz + 2)
-	return s, nil
+	return string(body), nil
 }
 
 func (r *BufIoReader) Scan(vv ...interface{}) error {
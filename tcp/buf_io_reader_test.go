@@ -0,0 +1,152 @@
+package tcp
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	re "github.com/SwanSpouse/redis_go/error"
+)
+
+func newTestReader(rd io.Reader, maxBulkLen, maxQueryBufLen int64) *BufIoReader {
+	r := new(BufIoReader)
+	r.Reset(rd)
+	r.SetLimits(maxBulkLen, maxQueryBufLen)
+	return r
+}
+
+// 读取一个multi bulk请求, 和client.ProcessInputBuffer的流程一致
+func readRequest(r *BufIoReader) ([]string, error) {
+	n, err := r.ReadArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	if n > MaxMultiBulkLen {
+		return nil, re.ErrInvalidMultiBulkLength
+	}
+	args := make([]string, 0)
+	for i := 0; i < n; i++ {
+		arg, err := r.ReadBulkString()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+func TestReadRequestFragmented(t *testing.T) {
+	big := strings.Repeat("x", 3*MaxBufferSize)
+	data := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$" + strconv.Itoa(len(big)) + "\r\n" + big + "\r\n*1\r\n$4\r\nPING\r\n"
+	r := newTestReader(iotest.OneByteReader(strings.NewReader(data)), 0, 0)
+
+	args, err := readRequest(r)
+	if err != nil || len(args) != 3 || args[0] != "SET" || args[2] != big {
+		t.Fatalf("unexpected request %d %v", len(args), err)
+	}
+	args, err = readRequest(r)
+	if err != nil || len(args) != 1 || args[0] != "PING" {
+		t.Fatalf("unexpected request %v %v", args, err)
+	}
+	if len(r.buf) != MaxBufferSize {
+		t.Fatalf("large buffer should be released, got %d", len(r.buf))
+	}
+	if _, err = readRequest(r); err != io.EOF {
+		t.Fatalf("expect EOF, got %v", err)
+	}
+}
+
+func TestReadRequestLimits(t *testing.T) {
+	cases := []struct {
+		data string
+		err  error
+	}{
+		{"*2147483648\r\n", re.ErrInvalidMultiBulkLength},
+		{"*99999999999999999999\r\n", re.ErrInvalidMultiBulkLength},
+		{"*" + strings.Repeat("1", MaxInlineSize) + "\r\n", re.ErrMultiBulkCountTooBig},
+		{"*1\r\n$" + strings.Repeat("1", MaxInlineSize) + "\r\n", re.ErrBulkCountTooBig},
+		{"*1\r\n$2048\r\n", re.ErrInvalidBulkLength},
+		{"*1\r\n$-1\r\n", re.ErrInvalidBulkLength},
+		{"*1\r\n$1000\r\n" + strings.Repeat("x", 100), io.ErrUnexpectedEOF},
+		// 声明的长度和实际的数据不一致
+		{"*1\r\n$3\r\nPINGX\r\n", re.ErrBulkWithoutCRLF},
+		{"*2\r\n$4\r\nPING\r\n$3\r\nab\r\n\r\n", re.ErrBulkWithoutCRLF},
+	}
+	for _, c := range cases {
+		r := newTestReader(strings.NewReader(c.data), 1024, 0)
+		if _, err := readRequest(r); err != c.err {
+			t.Fatalf("data %.20q expect %v, got %v", c.data, c.err, err)
+		}
+	}
+
+	// 输入缓冲区的大小超过了限制
+	r := newTestReader(strings.NewReader("*1\r\n$1000000\r\n"), 0, 4096)
+	if _, err := readRequest(r); err != re.ErrQueryBufferLimit {
+		t.Fatalf("expect query buffer limit error, got %v", err)
+	}
+	r = newTestReader(strings.NewReader("*1"+strings.Repeat("1", 3*MaxBufferSize)), 0, MaxBufferSize+1)
+	if _, err := r.PeekLine(1); err != re.ErrQueryBufferLimit {
+		t.Fatalf("expect query buffer limit error, got %v", err)
+	}
+}
+
+func TestReadRequestIgnoredArrayLen(t *testing.T) {
+	// 和redis一样, *0以及长度为负数的multi bulk被忽略, 不会关闭连接
+	r := newTestReader(strings.NewReader("*0\r\n*-1\r\n*-100\r\n*1\r\n$4\r\nPING\r\n*-\r\n*-1a\r\n"), 0, 0)
+	for i := 0; i < 3; i++ {
+		if args, err := readRequest(r); err != nil || len(args) != 0 {
+			t.Fatalf("request %d should be ignored, got %v %v", i, args, err)
+		}
+	}
+	if args, err := readRequest(r); err != nil || len(args) != 1 || args[0] != "PING" {
+		t.Fatalf("unexpected request %v %v", args, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := readRequest(r); err != re.ErrInvalidMultiBulkLength {
+			t.Fatalf("expect invalid multibulk length, got %v", err)
+		}
+	}
+}
+
+func TestReadRequestUnexpectedPrefix(t *testing.T) {
+	r := newTestReader(strings.NewReader("*1\r\nfoo\r\n"), 0, 0)
+	if _, err := readRequest(r); err == nil || err.Error() != "ERR Protocol error: expected '$', got 'f'" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func FuzzBufIoReader(f *testing.F) {
+	f.Add([]byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n"))
+	f.Add([]byte("*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"))
+	f.Add([]byte("*2147483647\r\n"))
+	f.Add([]byte("*1\r\n$99999999999\r\n"))
+	f.Add([]byte("*-1\r\n$-1\r\n:1\r\n+OK\r\n-ERR\r\n"))
+	f.Add([]byte("PING\r\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		const maxQueryBufLen = 4 * MaxBufferSize
+		r := newTestReader(bytes.NewReader(data), 1024, maxQueryBufLen)
+		for i := 0; i < 64; i++ {
+			var err error
+			switch i % 3 {
+			case 0:
+				_, err = readRequest(r)
+			case 1:
+				_, err = r.PeekType()
+			case 2:
+				_, err = r.ReadInt()
+			}
+			if len(r.buf) > maxQueryBufLen {
+				t.Fatalf("buffer size %d exceeds the limit", len(r.buf))
+			}
+			if r.r < 0 || r.r > r.w || r.w > len(r.buf) {
+				t.Fatalf("invalid reader state r=%d w=%d len=%d", r.r, r.w, len(r.buf))
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF || err == re.ErrQueryBufferLimit {
+				return
+			}
+		}
+	})
+}
@@ -74,8 +74,13 @@ var WriterPool = &sync.Pool{
 
 type BufIoWriter struct {
 	io.Writer
-	buf []byte
-	mu  sync.Mutex
+	buf         []byte
+	spare       []byte     // 正在发送的缓冲区发送完之后留给下一次使用
+	mu          sync.Mutex // 保护buf, 写连接的时候不持有这个锁, 发送回复期间仍然可以继续写入以及查询缓冲区的大小
+	flushMu     sync.Mutex // 保证同一时间只有一个goroutine写连接
+	flushing    int        // 正在写入连接的字节数
+	maxBuffered int        // 大于0的时候, 数组等回复在缓冲区超过这个大小之后不再继续写入
+	proto       int        // RESP protocol version
 }
 
 func NewBufIoWriter(cn net.Conn) *BufIoWriter {
@@ -94,14 +99,27 @@ func InitBufIoWriterPool(size int) {
 	}
 }
 
-// returns the number of buffered bytes
+// returns the number of buffered bytes, including the bytes being written to the connection
 func (w *BufIoWriter) Buffered() int {
 	w.mu.Lock()
-	n := len(w.buf)
+	n := len(w.buf) + w.flushing
 	w.mu.Unlock()
 	return n
 }
 
+// sets the max buffered bytes, a big aggregate reply stops growing once the limit is reached
+func (w *BufIoWriter) SetMaxBuffered(n int) {
+	w.mu.Lock()
+	w.maxBuffered = n
+	w.mu.Unlock()
+}
+
+func (w *BufIoWriter) exceedsMaxBuffered() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.maxBuffered > 0 && len(w.buf)+w.flushing >= w.maxBuffered
+}
+
 func (w *BufIoWriter) appendSize(c byte, n int64) {
 	w.buf = append(w.buf, c)
 	w.buf = append(w.buf, strconv.FormatInt(n, 10)...)
@@ -109,19 +127,30 @@ func (w *BufIoWriter) appendSize(c byte, n int64) {
 }
 
 func (w *BufIoWriter) reset(buf []byte, wr io.Writer) {
-	*w = BufIoWriter{buf: buf[:0], Writer: wr}
+	*w = BufIoWriter{buf: buf[:0], Writer: wr, proto: RespProto2}
 }
 
+// swaps the buffer out and writes it without holding mu, a big buffer is released after written
 func (w *BufIoWriter) flush() error {
+	w.mu.Lock()
 	if len(w.buf) == 0 {
+		w.mu.Unlock()
 		return nil
 	}
+	buf := w.buf
+	w.buf, w.spare = w.spare[:0], nil
+	w.flushing = len(buf)
+	w.mu.Unlock()
+
+	_, err := w.Write(buf)
 
-	if _, err := w.Write(w.buf); err != nil {
-		return err
+	w.mu.Lock()
+	w.flushing = 0
+	if cap(buf) <= MaxBufferSize {
+		w.spare = buf[:0]
 	}
-	w.buf = w.buf[:0]
-	return nil
+	w.mu.Unlock()
+	return err
 }
 
 // appends an array header  to the output buffer
@@ -212,10 +241,19 @@ func (w *BufIoWriter) AppendOK() {
 
 // flush pending buffer
 func (w *BufIoWriter) Flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	return w.flush()
+}
+
+// discards all buffered data, a large buffer is released
+func (w *BufIoWriter) Discard() {
 	w.mu.Lock()
-	err := w.flush()
-	defer w.mu.Unlock()
-	return err
+	if cap(w.buf) > MaxBufferSize {
+		w.buf = MkStdBuffer()
+	}
+	w.buf = w.buf[:0]
+	w.mu.Unlock()
 }
 
 // resets the writer with an new interface
@@ -227,7 +265,7 @@ func (w *BufIoWriter) Reset(writer io.Writer) {
 func (w *BufIoWriter) Append(v interface{}) error {
 	switch v := v.(type) {
 	case nil:
-		w.AppendNil()
+		w.AppendNull()
 	case error:
 		msg := v.Error()
 		if !strings.HasPrefix(msg, "ERR ") {
@@ -277,6 +315,9 @@ func (w *BufIoWriter) Append(v interface{}) error {
 	case float64:
 		w.AppendInlineString(strconv.FormatFloat(v, 'f', -1, 64))
 	default:
+		if ok, err := w.appendResp3Type(v); ok {
+			return err
+		}
 		switch reflect.TypeOf(v).Kind() {
 		case reflect.Slice:
 			s := reflect.ValueOf(v)
@@ -285,7 +326,7 @@ func (w *BufIoWriter) Append(v interface{}) error {
 				return nil
 			}
 			w.AppendArrayLen(s.Len())
-			for i := 0; i < s.Len(); i++ {
+			for i := 0; i < s.Len() && !w.exceedsMaxBuffered(); i++ {
 				w.Append(s.Index(i).Interface())
 			}
 		case reflect.Map:
@@ -296,6 +337,9 @@ func (w *BufIoWriter) Append(v interface{}) error {
 			}
 			w.AppendArrayLen(s.Len() * 2)
 			for _, key := range s.MapKeys() {
+				if w.exceedsMaxBuffered() {
+					break
+				}
 				w.Append(key.Interface())
 				w.Append(s.MapIndex(key).Interface())
 			}
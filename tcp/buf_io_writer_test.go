@@ -0,0 +1,30 @@
+package tcp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBufIoWriterMaxBuffered(t *testing.T) {
+	var out bytes.Buffer
+	w := new(BufIoWriter)
+	w.Reset(&out)
+	w.SetMaxBuffered(1024)
+	values := make([]string, 1000)
+	for i := range values {
+		values[i] = strings.Repeat("v", 100)
+	}
+	// 超过限制之后剩下的元素不再写入
+	w.Append(values)
+	if n := w.Buffered(); n < 1024 || n > 1024+200 {
+		t.Fatalf("big reply should stop growing at the limit, got %d bytes", n)
+	}
+	w.Discard()
+	w.SetMaxBuffered(0)
+	w.Append(ArrayReply{"a", "b"})
+	w.Flush()
+	if out.String() != "*2\r\n$1\r\na\r\n$1\r\nb\r\n" || w.Buffered() != 0 {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
@@ -0,0 +1,274 @@
+package tcp
+
+import (
+	"strings"
+
+	re "github.com/SwanSpouse/redis_go/error"
+)
+
+/**
+RESP3:
+	客户端通过HELLO 3切换到RESP3协议之后, 服务端可以返回更多类型的回复:
+		null         : _\r\n
+		double       : ,1.5\r\n
+		boolean      : #t\r\n
+		big number   : (3492890328409238509324850943850943825024385\r\n
+		verbatim     : =15\r\ntxt:Some string\r\n
+		map          : %2\r\n+first\r\n:1\r\n+second\r\n:2\r\n
+		set          : ~2\r\n+orange\r\n+apple\r\n
+		attribute    : |1\r\n+key-popularity\r\n...\r\n 紧跟着的是真正的回复
+		push         : >3\r\n+message\r\n+channel\r\n+hello\r\n
+	handler只需要用下面的类型声明回复的结构, BufIoWriter根据连接的协议版本决定输出的格式,
+	RESP2连接中map会展开成数组, set、push都按照数组输出, double、big number以及verbatim都按照bulk string输出。
+	和普通的slice一样, RESP2连接中空的map和set返回(empty list or set), RESP3连接中返回空的map和set。
+*/
+const (
+	RespProto2 = 2
+	RespProto3 = 3
+)
+
+// 数组回复, 和普通的slice不同, 空数组会返回*0而不是(empty list or set)
+type ArrayReply []interface{}
+
+// null数组回复, RESP2中输出*-1, 例如BLPOP超时的返回值
+type NullArrayReply struct{}
+
+// map回复, 按照key value key value的顺序保存
+type MapReply []interface{}
+
+// set回复
+type SetReply []interface{}
+
+// push回复, 用于发布订阅等服务端主动推送的消息
+type PushReply []interface{}
+
+// double回复, 保存的是已经格式化好的数字, RESP2中按照bulk string输出
+type DoubleReply string
+
+// boolean回复, RESP2中按照整数1和0输出
+type BoolReply bool
+
+// big number回复, RESP2中按照bulk string输出
+type BigNumberReply string
+
+// verbatim string回复, Format是三个字符的格式, 例如txt、mkd
+type VerbatimReply struct {
+	Format string
+	Text   string
+}
+
+// attribute回复, RESP2中只输出Reply, Attributes会被忽略
+type AttributeReply struct {
+	Attributes MapReply
+	Reply      interface{}
+}
+
+// 把[]string转换成map回复, 例如HGETALL、CONFIG GET的返回值
+func NewStringMapReply(keyValues []string) MapReply {
+	ret := make(MapReply, len(keyValues))
+	for i, item := range keyValues {
+		ret[i] = item
+	}
+	return ret
+}
+
+// 把[]string转换成array回复, 空的时候回复*0而不是(empty list or set)
+func NewStringArrayReply(items []string) ArrayReply {
+	ret := make(ArrayReply, len(items))
+	for i, item := range items {
+		ret[i] = item
+	}
+	return ret
+}
+
+// 把[]string转换成set回复, 例如SMEMBERS的返回值
+func NewStringSetReply(members []string) SetReply {
+	ret := make(SetReply, len(members))
+	for i, item := range members {
+		ret[i] = item
+	}
+	return ret
+}
+
+func (w *BufIoWriter) isResp3() bool {
+	return w.Protocol() == RespProto3
+}
+
+// 设置连接使用的协议版本
+func (w *BufIoWriter) SetProtocol(proto int) {
+	w.mu.Lock()
+	w.proto = proto
+	w.mu.Unlock()
+}
+
+// 连接使用的协议版本
+func (w *BufIoWriter) Protocol() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.proto
+}
+
+// appends an aggregate header to the output buffer, RESP2 always uses array
+func (w *BufIoWriter) appendAggregateLen(resp3Prefix byte, n int) {
+	w.mu.Lock()
+	if w.proto == RespProto3 {
+		w.appendSize(resp3Prefix, int64(n))
+	} else {
+		w.appendSize('*', int64(n))
+	}
+	w.mu.Unlock()
+}
+
+// appends a null to the output buffer
+func (w *BufIoWriter) AppendNull() {
+	w.mu.Lock()
+	if w.proto == RespProto3 {
+		w.buf = append(w.buf, "_\r\n"...)
+	} else {
+		w.buf = append(w.buf, BinNIL...)
+	}
+	w.mu.Unlock()
+}
+
+// appends a null array to the output buffer
+func (w *BufIoWriter) AppendNullArray() {
+	w.mu.Lock()
+	if w.proto == RespProto3 {
+		w.buf = append(w.buf, "_\r\n"...)
+	} else {
+		w.buf = append(w.buf, "*-1\r\n"...)
+	}
+	w.mu.Unlock()
+}
+
+// appends a double to the output buffer
+func (w *BufIoWriter) AppendDouble(s string) {
+	if !w.isResp3() {
+		w.AppendBulkString(s)
+		return
+	}
+	// RESP3中的无穷大使用inf和-inf表示
+	switch strings.ToLower(strings.TrimPrefix(s, "+")) {
+	case "inf", "infinity":
+		s = "inf"
+	case "-inf", "-infinity":
+		s = "-inf"
+	}
+	w.mu.Lock()
+	w.buf = append(w.buf, ',')
+	w.buf = append(w.buf, s...)
+	w.buf = append(w.buf, BinCRLF...)
+	w.mu.Unlock()
+}
+
+// appends a boolean to the output buffer
+func (w *BufIoWriter) AppendBool(v bool) {
+	if !w.isResp3() {
+		if v {
+			w.AppendInt(1)
+		} else {
+			w.AppendInt(0)
+		}
+		return
+	}
+	w.mu.Lock()
+	if v {
+		w.buf = append(w.buf, "#t\r\n"...)
+	} else {
+		w.buf = append(w.buf, "#f\r\n"...)
+	}
+	w.mu.Unlock()
+}
+
+// appends a big number to the output buffer
+func (w *BufIoWriter) AppendBigNumber(s string) {
+	if !w.isResp3() {
+		w.AppendBulkString(s)
+		return
+	}
+	w.mu.Lock()
+	w.buf = append(w.buf, '(')
+	w.buf = append(w.buf, s...)
+	w.buf = append(w.buf, BinCRLF...)
+	w.mu.Unlock()
+}
+
+// appends a verbatim string to the output buffer
+func (w *BufIoWriter) AppendVerbatim(format, text string) {
+	if !w.isResp3() {
+		w.AppendBulkString(text)
+		return
+	}
+	w.mu.Lock()
+	w.appendSize('=', int64(len(text)+4))
+	w.buf = append(w.buf, format...)
+	w.buf = append(w.buf, ':')
+	w.buf = append(w.buf, text...)
+	w.buf = append(w.buf, BinCRLF...)
+	w.mu.Unlock()
+}
+
+// appends the elements of an aggregate reply
+func (w *BufIoWriter) appendElements(items []interface{}) error {
+	for _, item := range items {
+		// 超过了输出缓冲区的限制, 客户端马上会被关闭, 剩下的元素不再写入
+		if w.exceedsMaxBuffered() {
+			return nil
+		}
+		if err := w.Append(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 输出RESP3中新增的回复类型, 不是这些类型的时候返回false
+func (w *BufIoWriter) appendResp3Type(v interface{}) (bool, error) {
+	switch v := v.(type) {
+	case ArrayReply:
+		w.AppendArrayLen(len(v))
+		return true, w.appendElements(v)
+	case MapReply:
+		if len(v) == 0 && !w.isResp3() {
+			w.AppendError(re.ErrEmptyListOrSet.Error())
+			return true, nil
+		}
+		if w.isResp3() {
+			w.appendAggregateLen('%', len(v)/2)
+		} else {
+			w.AppendArrayLen(len(v))
+		}
+		return true, w.appendElements(v)
+	case SetReply:
+		if len(v) == 0 && !w.isResp3() {
+			w.AppendError(re.ErrEmptyListOrSet.Error())
+			return true, nil
+		}
+		w.appendAggregateLen('~', len(v))
+		return true, w.appendElements(v)
+	case PushReply:
+		w.appendAggregateLen('>', len(v))
+		return true, w.appendElements(v)
+	case NullArrayReply:
+		w.AppendNullArray()
+	case DoubleReply:
+		w.AppendDouble(string(v))
+	case BoolReply:
+		w.AppendBool(bool(v))
+	case BigNumberReply:
+		w.AppendBigNumber(string(v))
+	case VerbatimReply:
+		w.AppendVerbatim(v.Format, v.Text)
+	case AttributeReply:
+		if w.isResp3() {
+			w.appendAggregateLen('|', len(v.Attributes)/2)
+			if err := w.appendElements(v.Attributes); err != nil {
+				return true, err
+			}
+		}
+		return true, w.Append(v.Reply)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
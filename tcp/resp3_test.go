@@ -0,0 +1,48 @@
+package tcp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAppendResp3(t *testing.T) {
+	cases := []struct {
+		value interface{}
+		resp2 string
+		resp3 string
+	}{
+		{nil, "$-1\r\n", "_\r\n"},
+		{MapReply{"a", "1", "b", 2}, "*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n:2\r\n", "%2\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n:2\r\n"},
+		{MapReply{}, "-(empty list or set)\r\n", "%0\r\n"},
+		{SetReply{"x"}, "*1\r\n$1\r\nx\r\n", "~1\r\n$1\r\nx\r\n"},
+		{PushReply{"message", "ch", "hi"}, "*3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n", ">3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n"},
+		{ArrayReply{}, "*0\r\n", "*0\r\n"},
+		{DoubleReply("1.5"), "$3\r\n1.5\r\n", ",1.5\r\n"},
+		{DoubleReply("+Inf"), "$4\r\n+Inf\r\n", ",inf\r\n"},
+		{BoolReply(true), ":1\r\n", "#t\r\n"},
+		{BoolReply(false), ":0\r\n", "#f\r\n"},
+		{BigNumberReply("12345678901234567890"), "$20\r\n12345678901234567890\r\n", "(12345678901234567890\r\n"},
+		{VerbatimReply{Format: "txt", Text: "hello"}, "$5\r\nhello\r\n", "=9\r\ntxt:hello\r\n"},
+		{AttributeReply{Attributes: MapReply{"ttl", 10}, Reply: "v"}, "$1\r\nv\r\n", "|1\r\n$3\r\nttl\r\n:10\r\n$1\r\nv\r\n"},
+		{MapReply{"modules", ArrayReply{}, "proto", nil}, "*4\r\n$7\r\nmodules\r\n*0\r\n$5\r\nproto\r\n$-1\r\n", "%2\r\n$7\r\nmodules\r\n*0\r\n$5\r\nproto\r\n_\r\n"},
+	}
+	for _, c := range cases {
+		for _, proto := range []int{RespProto2, RespProto3} {
+			var out bytes.Buffer
+			w := new(BufIoWriter)
+			w.Reset(&out)
+			w.SetProtocol(proto)
+			if err := w.Append(c.value); err != nil {
+				t.Fatalf("append %#v error %v", c.value, err)
+			}
+			w.Flush()
+			expected := c.resp2
+			if proto == RespProto3 {
+				expected = c.resp3
+			}
+			if out.String() != expected {
+				t.Fatalf("RESP%d %#v: expect %q, got %q", proto, c.value, expected, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package util
+
+import (
+	"syscall"
+	"time"
+)
+
+// 返回进程在内核态以及用户态使用的CPU时间
+func GetCPUUsage() (sys time.Duration, user time.Duration) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, 0
+	}
+	return time.Duration(usage.Stime.Nano()), time.Duration(usage.Utime.Nano())
+}
@@ -0,0 +1,8 @@
+package util
+
+import "time"
+
+// windows 下不支持 getrusage, 始终返回0
+func GetCPUUsage() (sys time.Duration, user time.Duration) {
+	return 0, 0
+}
@@ -0,0 +1,161 @@
+package util
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+/**
+HDR(High Dynamic Range) Histogram:
+	按照2的幂次把值域划分成多个bucket，每个bucket再等分成subBucketCount个sub bucket，
+	这样在整个值域内都能保持significantFigures位有效数字的精度，并且占用的内存是固定的。
+	这里的实现参考了HdrHistogram_c以及hdrhistogram-go，只保留了记录和计算百分位的功能。
+	所有的计数都使用原子操作，可以在多个goroutine中并发的记录。
+*/
+type Histogram struct {
+	lowestTrackableValue        int64
+	highestTrackableValue       int64
+	unitMagnitude               int64
+	subBucketHalfCountMagnitude int64
+	subBucketHalfCount          int64
+	subBucketCount              int64
+	subBucketMask               int64
+	bucketCount                 int64
+	totalCount                  int64
+	counts                      []int64
+}
+
+// 创建一个记录[lowest, highest]范围内的值的直方图, significantFigures 为有效数字的位数(1~5)
+func NewHistogram(lowest, highest int64, significantFigures int) *Histogram {
+	if lowest < 1 {
+		lowest = 1
+	}
+	if significantFigures < 1 {
+		significantFigures = 1
+	} else if significantFigures > 5 {
+		significantFigures = 5
+	}
+	largestValueWithSingleUnitResolution := 2 * math.Pow10(significantFigures)
+	subBucketCountMagnitude := int64(math.Ceil(math.Log2(largestValueWithSingleUnitResolution)))
+	subBucketHalfCountMagnitude := subBucketCountMagnitude - 1
+	if subBucketHalfCountMagnitude < 0 {
+		subBucketHalfCountMagnitude = 0
+	}
+	unitMagnitude := int64(math.Floor(math.Log2(float64(lowest))))
+	subBucketCount := int64(1) << uint(subBucketHalfCountMagnitude+1)
+	subBucketHalfCount := subBucketCount / 2
+	subBucketMask := (subBucketCount - 1) << uint(unitMagnitude)
+
+	// 计算覆盖highest需要多少个bucket
+	smallestUntrackableValue := subBucketCount << uint(unitMagnitude)
+	bucketCount := int64(1)
+	for smallestUntrackableValue <= highest {
+		if smallestUntrackableValue > math.MaxInt64/2 {
+			bucketCount++
+			break
+		}
+		smallestUntrackableValue <<= 1
+		bucketCount++
+	}
+	return &Histogram{
+		lowestTrackableValue:        lowest,
+		highestTrackableValue:       highest,
+		unitMagnitude:               unitMagnitude,
+		subBucketHalfCountMagnitude: subBucketHalfCountMagnitude,
+		subBucketHalfCount:          subBucketHalfCount,
+		subBucketCount:              subBucketCount,
+		subBucketMask:               subBucketMask,
+		bucketCount:                 bucketCount,
+		counts:                      make([]int64, (bucketCount+1)*subBucketHalfCount),
+	}
+}
+
+func (h *Histogram) getBucketIndex(value int64) int64 {
+	pow2Ceiling := int64(64 - bits.LeadingZeros64(uint64(value|h.subBucketMask)))
+	return pow2Ceiling - h.unitMagnitude - (h.subBucketHalfCountMagnitude + 1)
+}
+
+func (h *Histogram) getSubBucketIndex(value, bucketIndex int64) int64 {
+	return value >> uint(bucketIndex+h.unitMagnitude)
+}
+
+func (h *Histogram) countsIndex(bucketIndex, subBucketIndex int64) int64 {
+	return ((bucketIndex + 1) << uint(h.subBucketHalfCountMagnitude)) + (subBucketIndex - h.subBucketHalfCount)
+}
+
+func (h *Histogram) countsIndexFor(value int64) int64 {
+	bucketIndex := h.getBucketIndex(value)
+	return h.countsIndex(bucketIndex, h.getSubBucketIndex(value, bucketIndex))
+}
+
+// 根据counts数组的下标计算对应区间的最大值
+func (h *Histogram) highestEquivalentValueAt(index int64) int64 {
+	bucketIndex := (index >> uint(h.subBucketHalfCountMagnitude)) - 1
+	subBucketIndex := (index & (h.subBucketHalfCount - 1)) + h.subBucketHalfCount
+	if bucketIndex < 0 {
+		subBucketIndex -= h.subBucketHalfCount
+		bucketIndex = 0
+	}
+	lowest := subBucketIndex << uint(bucketIndex+h.unitMagnitude)
+	adjustedBucket := bucketIndex
+	if subBucketIndex >= h.subBucketCount {
+		adjustedBucket++
+	}
+	return lowest + (int64(1) << uint(h.unitMagnitude+adjustedBucket)) - 1
+}
+
+// 记录一个值, 超出范围的值会被记录到最接近的边界上
+func (h *Histogram) RecordValue(value int64) {
+	if value < h.lowestTrackableValue {
+		value = h.lowestTrackableValue
+	} else if value > h.highestTrackableValue {
+		value = h.highestTrackableValue
+	}
+	index := h.countsIndexFor(value)
+	if index < 0 || index >= int64(len(h.counts)) {
+		return
+	}
+	atomic.AddInt64(&h.counts[index], 1)
+	atomic.AddInt64(&h.totalCount, 1)
+}
+
+// 返回记录的值的个数
+func (h *Histogram) TotalCount() int64 {
+	return atomic.LoadInt64(&h.totalCount)
+}
+
+// 返回percentile(0~100)百分位对应的值, 没有记录过任何值时返回0
+func (h *Histogram) ValueAtPercentile(percentile float64) int64 {
+	total := h.TotalCount()
+	if total == 0 {
+		return 0
+	}
+	if percentile > 100 {
+		percentile = 100
+	}
+	countAtPercentile := int64(percentile/100*float64(total) + 0.5)
+	if countAtPercentile < 1 {
+		countAtPercentile = 1
+	}
+	var count int64
+	for i := range h.counts {
+		count += atomic.LoadInt64(&h.counts[i])
+		if count >= countAtPercentile {
+			value := h.highestEquivalentValueAt(int64(i))
+			if value > h.highestTrackableValue {
+				value = h.highestTrackableValue
+			}
+			return value
+		}
+	}
+	return 0
+}
+
+// 清空所有的记录
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		atomic.StoreInt64(&h.counts[i], 0)
+	}
+	atomic.StoreInt64(&h.totalCount, 0)
+}
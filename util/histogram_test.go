@@ -0,0 +1,54 @@
+package util
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Histogram", func() {
+	It("empty histogram", func() {
+		h := NewHistogram(1, 1000*1000*1000, 2)
+		Expect(h.TotalCount()).To(Equal(int64(0)))
+		Expect(h.ValueAtPercentile(50)).To(Equal(int64(0)))
+	})
+
+	It("value at percentile", func() {
+		h := NewHistogram(1, 1000*1000*1000, 2)
+		for i := int64(1); i <= 100; i++ {
+			h.RecordValue(i * 1000)
+		}
+		Expect(h.TotalCount()).To(Equal(int64(100)))
+
+		// 2位有效数字的精度要求误差在1%以内
+		p50 := h.ValueAtPercentile(50)
+		Expect(p50).To(BeNumerically("~", 50*1000, 500))
+		p99 := h.ValueAtPercentile(99)
+		Expect(p99).To(BeNumerically("~", 99*1000, 990))
+		p100 := h.ValueAtPercentile(100)
+		Expect(p100).To(BeNumerically("~", 100*1000, 1000))
+		Expect(p50).To(BeNumerically("<=", p99))
+	})
+
+	It("small values are exact", func() {
+		h := NewHistogram(1, 1000, 2)
+		h.RecordValue(1)
+		h.RecordValue(2)
+		h.RecordValue(3)
+		Expect(h.ValueAtPercentile(0)).To(Equal(int64(1)))
+		Expect(h.ValueAtPercentile(50)).To(Equal(int64(2)))
+		Expect(h.ValueAtPercentile(100)).To(Equal(int64(3)))
+	})
+
+	It("out of range values and reset", func() {
+		h := NewHistogram(1, 1000, 2)
+		h.RecordValue(0)
+		h.RecordValue(1000 * 1000)
+		Expect(h.TotalCount()).To(Equal(int64(2)))
+		Expect(h.ValueAtPercentile(0)).To(Equal(int64(1)))
+		Expect(h.ValueAtPercentile(100)).To(Equal(int64(1000)))
+
+		h.Reset()
+		Expect(h.TotalCount()).To(Equal(int64(0)))
+		Expect(h.ValueAtPercentile(100)).To(Equal(int64(0)))
+	})
+})
@@ -37,3 +37,32 @@ func FloatToSimpleString(input float64) string {
 	}
 	return ret
 }
+
+// 把字节数转换成便于阅读的格式, 例如 1.50K、2.00M
+func BytesToHuman(n int64) string {
+	d := float64(n)
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%dB", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.2fK", d/1024)
+	case n < 1024*1024*1024:
+		return fmt.Sprintf("%.2fM", d/(1024*1024))
+	case n < 1024*1024*1024*1024:
+		return fmt.Sprintf("%.2fG", d/(1024*1024*1024))
+	default:
+		return fmt.Sprintf("%.2fT", d/(1024*1024*1024*1024))
+	}
+}
+
+// 和redis的string2ll一样, 只有转换回字符串之后和原来完全相同的时候才认为是整数, 例如"01"、"+1"都不是整数
+func StringToInt64(input string) (int64, bool) {
+	if len(input) == 0 || len(input) > 20 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(input, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != input {
+		return 0, false
+	}
+	return n, true
+}
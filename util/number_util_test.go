@@ -40,3 +40,27 @@ var _ = Describe("FormatFloatString", func() {
 		Expect(ret).To(Equal("5.10"))
 	})
 })
+
+var _ = Describe("BytesToHuman", func() {
+	It("BytesToHuman", func() {
+		Expect(BytesToHuman(0)).To(Equal("0B"))
+		Expect(BytesToHuman(1023)).To(Equal("1023B"))
+		Expect(BytesToHuman(1536)).To(Equal("1.50K"))
+		Expect(BytesToHuman(2 * 1024 * 1024)).To(Equal("2.00M"))
+		Expect(BytesToHuman(3 * 1024 * 1024 * 1024)).To(Equal("3.00G"))
+	})
+})
+
+var _ = Describe("StringToInt64", func() {
+	It("StringToInt64", func() {
+		for input, expected := range map[string]int64{"0": 0, "-1": -1, "9223372036854775807": 9223372036854775807, "-9223372036854775808": -9223372036854775808} {
+			ret, ok := StringToInt64(input)
+			Expect(ok).To(BeTrue())
+			Expect(ret).To(Equal(expected))
+		}
+		for _, input := range []string{"", "01", "+1", "-0", " 1", "1.0", "9223372036854775808", "abc"} {
+			_, ok := StringToInt64(input)
+			Expect(ok).To(BeFalse())
+		}
+	})
+})
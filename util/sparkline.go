@@ -0,0 +1,140 @@
+package util
+
+import (
+	"math"
+	"strings"
+)
+
+/**
+ASCII sparkline:
+	和redis中的sparkline.c一样，把一组数据渲染成多行的ASCII图形，用于LATENCY GRAPH。
+	每一列对应一个样本, 样本的label会竖着打印在图形的下方。
+*/
+const (
+	SparklineNoFlags  = 0
+	SparklineFill     = 1 /* Fill the area under the curve. */
+	SparklineLogScale = 2 /* Use logarithmic scale. */
+
+	sparklineLabelMarginTop = 1
+)
+
+var (
+	sparklineCharset     = []byte("_-`")
+	sparklineCharsetFill = []byte("_o#")
+)
+
+type sparklineSample struct {
+	value float64
+	label string
+}
+
+type SparklineSequence struct {
+	samples []sparklineSample
+	min     float64
+	max     float64
+	labels  int
+}
+
+func NewSparklineSequence() *SparklineSequence {
+	return &SparklineSequence{samples: make([]sparklineSample, 0)}
+}
+
+// 添加一个样本, label为空时这一列下方不打印任何内容
+func (seq *SparklineSequence) AddSample(value float64, label string) {
+	if len(seq.samples) == 0 {
+		seq.min, seq.max = value, value
+	} else {
+		seq.min = math.Min(seq.min, value)
+		seq.max = math.Max(seq.max, value)
+	}
+	if label != "" {
+		seq.labels++
+	}
+	seq.samples = append(seq.samples, sparklineSample{value: value, label: label})
+}
+
+func (seq *SparklineSequence) Length() int {
+	return len(seq.samples)
+}
+
+// 渲染[offset, offset+length)范围内的样本
+func (seq *SparklineSequence) renderRange(builder *strings.Builder, rows int, offset int, length int, flags int) {
+	relmax := seq.max - seq.min
+	steps := len(sparklineCharset) * rows
+	optFill := flags&SparklineFill != 0
+	optLog := flags&SparklineLogScale != 0
+	if optLog {
+		relmax = math.Log(relmax + 1)
+	} else if relmax == 0 {
+		relmax = 1
+	}
+
+	chars := make([]byte, length)
+	for row, loop := 0, true; loop; {
+		loop = false
+		for i := range chars {
+			chars[i] = ' '
+		}
+		for i := 0; i < length; i++ {
+			sample := seq.samples[i+offset]
+			if row < rows {
+				// 打印图形部分
+				relval := sample.value - seq.min
+				if optLog {
+					relval = math.Log(relval + 1)
+				}
+				step := int(relval * float64(steps) / relmax)
+				if step < 0 {
+					step = 0
+				}
+				if step >= steps {
+					step = steps - 1
+				}
+				charIdx := step - (rows-row-1)*len(sparklineCharset)
+				loop = true
+				if charIdx >= 0 && charIdx < len(sparklineCharset) {
+					if optFill {
+						chars[i] = sparklineCharsetFill[charIdx]
+					} else {
+						chars[i] = sparklineCharset[charIdx]
+					}
+				} else if optFill && charIdx >= len(sparklineCharset) {
+					chars[i] = '|'
+				}
+			} else {
+				// 图形和label之间的空行
+				if seq.labels > 0 && row-rows < sparklineLabelMarginTop {
+					loop = true
+					break
+				}
+				// 竖着打印label
+				labelChar := row - rows - sparklineLabelMarginTop
+				if sample.label != "" && len(sample.label) > labelChar {
+					loop = true
+					chars[i] = sample.label[labelChar]
+				}
+			}
+		}
+		if loop {
+			row++
+			builder.Write(chars)
+			builder.WriteByte('\n')
+		}
+	}
+}
+
+// 把样本渲染成rows行高的图形, 每columns个样本换一次行
+func (seq *SparklineSequence) Render(columns int, rows int, flags int) string {
+	var builder strings.Builder
+	for j := 0; j < len(seq.samples); j += columns {
+		sublen := len(seq.samples) - j
+		if sublen > columns {
+			sublen = columns
+		}
+		if j != 0 {
+			builder.WriteByte('\n')
+		}
+		seq.renderRange(&builder, rows, j, sublen, flags)
+	}
+	return builder.String()
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sparkline", func() {
+	It("render sparkline with labels", func() {
+		seq := NewSparklineSequence()
+		seq.AddSample(100, "1s")
+		seq.AddSample(200, "2s")
+		seq.AddSample(300, "3s")
+		seq.AddSample(500, "4s")
+		Expect(seq.Length()).To(Equal(4))
+
+		Expect(seq.Render(80, 4, SparklineFill)).To(Equal(
+			"   #\n" +
+				"  _|\n" +
+				" _||\n" +
+				"_|||\n" +
+				"    \n" +
+				"1234\n" +
+				"ssss\n"))
+	})
+
+	It("render sparkline with multiple chunks", func() {
+		seq := NewSparklineSequence()
+		seq.AddSample(1, "")
+		seq.AddSample(1, "")
+		seq.AddSample(1, "")
+		Expect(seq.Render(2, 1, SparklineNoFlags)).To(Equal("__\n\n_\n"))
+	})
+})
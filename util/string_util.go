@@ -0,0 +1,245 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 把参数转换为字符串, 每个字符串都是单独的拷贝, 不会引用原来的内存。
+// 请求的解析是二进制安全的, 但是handler以及数据结构仍然使用string保存key和value, 保存参数的时候在这里转换
+func BytesToStrings(args [][]byte) []string {
+	ret := make([]string, len(args))
+	for i, arg := range args {
+		ret[i] = string(arg)
+	}
+	return ret
+}
+
+// 和redis中的sdscatrepr一样, 返回带双引号的字符串, 不可打印的字符会被转义
+func QuoteRepr(s string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch ch {
+		case '\\', '"':
+			builder.WriteByte('\\')
+			builder.WriteByte(ch)
+		case '\n':
+			builder.WriteString("\\n")
+		case '\r':
+			builder.WriteString("\\r")
+		case '\t':
+			builder.WriteString("\\t")
+		case '\a':
+			builder.WriteString("\\a")
+		case '\b':
+			builder.WriteString("\\b")
+		default:
+			if ch >= 0x20 && ch < 0x7f {
+				builder.WriteByte(ch)
+			} else {
+				builder.WriteString(fmt.Sprintf("\\x%02x", ch))
+			}
+		}
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+// 和redis中的stringmatchlen一样的glob匹配, 支持 * ? [abc] [^a-z] 以及 \ 转义
+func StringMatch(pattern string, str string, nocase bool) bool {
+	p, s := 0, 0
+	for p < len(pattern) && (s < len(str) || pattern[p] == '*') {
+		switch pattern[p] {
+		case '*':
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+			if p+1 == len(pattern) {
+				return true
+			}
+			for ; s <= len(str); s++ {
+				if StringMatch(pattern[p+1:], str[s:], nocase) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			s++
+		case '[':
+			p++
+			not := p < len(pattern) && pattern[p] == '^'
+			if not {
+				p++
+			}
+			match := false
+			for p < len(pattern) && pattern[p] != ']' {
+				if pattern[p] == '\\' && p+1 < len(pattern) {
+					p++
+					if pattern[p] == str[s] {
+						match = true
+					}
+				} else if p+2 < len(pattern) && pattern[p+1] == '-' {
+					start, end, c := pattern[p], pattern[p+2], str[s]
+					if start > end {
+						start, end = end, start
+					}
+					if nocase {
+						start, end, c = toLower(start), toLower(end), toLower(c)
+					}
+					p += 2
+					if c >= start && c <= end {
+						match = true
+					}
+				} else if equalByte(pattern[p], str[s], nocase) {
+					match = true
+				}
+				p++
+			}
+			if not {
+				match = !match
+			}
+			if !match {
+				return false
+			}
+			s++
+		case '\\':
+			if p+1 < len(pattern) {
+				p++
+			}
+			fallthrough
+		default:
+			if !equalByte(pattern[p], str[s], nocase) {
+				return false
+			}
+			s++
+		}
+		p++
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern) && s == len(str)
+}
+
+// 和redis中的sdssplitargs一样, 按照空白字符拆分参数, 支持双引号(可以使用\n \r \t \b \a \xHH等转义)以及单引号(只能转义\')
+// 引号没有闭合或者闭合的引号后面不是空白字符的时候返回false
+func SplitArgs(line string) ([]string, bool) {
+	args := make([]string, 0)
+	p := 0
+	for {
+		// skip blanks
+		for p < len(line) && isSpace(line[p]) {
+			p++
+		}
+		if p == len(line) {
+			return args, true
+		}
+		var builder strings.Builder
+		inq, insq, done := false, false, false
+		for !done {
+			if inq {
+				if p == len(line) {
+					// unterminated quotes
+					return nil, false
+				}
+				if line[p] == '\\' && p+3 < len(line) && line[p+1] == 'x' && isHexDigit(line[p+2]) && isHexDigit(line[p+3]) {
+					builder.WriteByte(hexDigitToInt(line[p+2])*16 + hexDigitToInt(line[p+3]))
+					p += 3
+				} else if line[p] == '\\' && p+1 < len(line) {
+					p++
+					switch line[p] {
+					case 'n':
+						builder.WriteByte('\n')
+					case 'r':
+						builder.WriteByte('\r')
+					case 't':
+						builder.WriteByte('\t')
+					case 'b':
+						builder.WriteByte('\b')
+					case 'a':
+						builder.WriteByte('\a')
+					default:
+						builder.WriteByte(line[p])
+					}
+				} else if line[p] == '"' {
+					// closing quote must be followed by a space or nothing at all
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, false
+					}
+					done = true
+				} else {
+					builder.WriteByte(line[p])
+				}
+			} else if insq {
+				if p == len(line) {
+					// unterminated quotes
+					return nil, false
+				}
+				if line[p] == '\\' && p+1 < len(line) && line[p+1] == '\'' {
+					p++
+					builder.WriteByte('\'')
+				} else if line[p] == '\'' {
+					// closing quote must be followed by a space or nothing at all
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, false
+					}
+					done = true
+				} else {
+					builder.WriteByte(line[p])
+				}
+			} else {
+				if p == len(line) {
+					break
+				}
+				switch line[p] {
+				case ' ', '\n', '\r', '\t', '\v', '\f':
+					done = true
+				case '"':
+					inq = true
+				case '\'':
+					insq = true
+				default:
+					builder.WriteByte(line[p])
+				}
+			}
+			if p < len(line) {
+				p++
+			}
+		}
+		args = append(args, builder.String())
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\v' || c == '\f'
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigitToInt(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func equalByte(a, b byte, nocase bool) bool {
+	if nocase {
+		return toLower(a) == toLower(b)
+	}
+	return a == b
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QuoteRepr", func() {
+	It("QuoteRepr", func() {
+		Expect(QuoteRepr("")).To(Equal(`""`))
+		Expect(QuoteRepr("set")).To(Equal(`"set"`))
+		Expect(QuoteRepr("a\"b\\c")).To(Equal(`"a\"b\\c"`))
+		Expect(QuoteRepr("a\r\n\t")).To(Equal(`"a\r\n\t"`))
+		Expect(QuoteRepr("\x00\xff")).To(Equal(`"\x00\xff"`))
+	})
+})
+
+var _ = Describe("StringMatch", func() {
+	It("StringMatch", func() {
+		Expect(StringMatch("*", "", false)).To(BeTrue())
+		Expect(StringMatch("*", "maxmemory", false)).To(BeTrue())
+		Expect(StringMatch("maxmemory*", "maxmemory-policy", false)).To(BeTrue())
+		Expect(StringMatch("*memory*", "maxmemory-samples", false)).To(BeTrue())
+		Expect(StringMatch("max?emory", "maxmemory", false)).To(BeTrue())
+		Expect(StringMatch("max?emory", "maxemory", false)).To(BeFalse())
+		Expect(StringMatch("h[ae]llo", "hello", false)).To(BeTrue())
+		Expect(StringMatch("h[^e]llo", "hello", false)).To(BeFalse())
+		Expect(StringMatch("h[a-c]llo", "hbllo", false)).To(BeTrue())
+		Expect(StringMatch("h\\*llo", "h*llo", false)).To(BeTrue())
+		Expect(StringMatch("h\\*llo", "hello", false)).To(BeFalse())
+		Expect(StringMatch("PORT", "port", true)).To(BeTrue())
+		Expect(StringMatch("PORT", "port", false)).To(BeFalse())
+		Expect(StringMatch("port", "port-x", false)).To(BeFalse())
+	})
+})
+
+var _ = Describe("SplitArgs", func() {
+	It("SplitArgs", func() {
+		args, ok := SplitArgs("")
+		Expect(ok).To(BeTrue())
+		Expect(args).To(BeEmpty())
+		args, ok = SplitArgs("  \t ")
+		Expect(ok).To(BeTrue())
+		Expect(args).To(BeEmpty())
+		args, ok = SplitArgs("SET  key\tvalue ")
+		Expect(ok).To(BeTrue())
+		Expect(args).To(Equal([]string{"SET", "key", "value"}))
+		args, ok = SplitArgs(`SET "my key" 'my value'`)
+		Expect(ok).To(BeTrue())
+		Expect(args).To(Equal([]string{"SET", "my key", "my value"}))
+		args, ok = SplitArgs(`"a\r\n\t\"\x41\x4a" 'it\'s' "" ''`)
+		Expect(ok).To(BeTrue())
+		Expect(args).To(Equal([]string{"a\r\n\t\"AJ", "it's", "", ""}))
+		args, ok = SplitArgs(`'a\nb' "\xzz"`)
+		Expect(ok).To(BeTrue())
+		Expect(args).To(Equal([]string{`a\nb`, "xzz"}))
+
+		_, ok = SplitArgs(`SET "key`)
+		Expect(ok).To(BeFalse())
+		_, ok = SplitArgs(`SET 'key`)
+		Expect(ok).To(BeFalse())
+		_, ok = SplitArgs(`SET "key"value`)
+		Expect(ok).To(BeFalse())
+		_, ok = SplitArgs(`SET 'key'value`)
+		Expect(ok).To(BeFalse())
+	})
+})